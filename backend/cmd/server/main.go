@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/manjurulhoque/swift-share/backend/config"
 	"github.com/manjurulhoque/swift-share/backend/database"
 	"github.com/manjurulhoque/swift-share/backend/docs"
+	"github.com/manjurulhoque/swift-share/backend/geoip"
 	"github.com/manjurulhoque/swift-share/backend/middleware"
 	"github.com/manjurulhoque/swift-share/backend/routes"
+	"github.com/manjurulhoque/swift-share/backend/services"
 	"github.com/manjurulhoque/swift-share/backend/storage"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -55,6 +58,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize GeoIP resolver (optional)
+	if err := geoip.InitDefaultResolver(context.Background()); err != nil {
+		logger.Error("Failed to initialize GeoIP database", "error", err, "path", config.AppConfig.GeoIP.DatabasePath)
+		os.Exit(1)
+	}
+
+	// Archive extractions run in the background of the server that started them, those cut off by
+	// a restart will not finish
+	if count, err := services.NewExtractionService().FailInterruptedJobs(); err != nil {
+		logger.Error("Failed to fail interrupted archive extractions", "error", err)
+	} else if count > 0 {
+		logger.Info("Failed archive extractions interrupted by a restart", "count", count)
+	}
+
+	// Trash the files of expired transfers in the background
+	go services.NewTransferService().RunExpiryWorker(context.Background(), config.AppConfig.Transfer.ExpiryCheckInterval)
+
+	// Keep storage usage counters in line with the stored files, this also fills them in on upgrade
+	go services.NewQuotaService().RunReconcileWorker(context.Background(), config.AppConfig.Quota.ReconcileInterval)
+
+	// Retry queued emails, send digests and tell owners about share links that expire soon
+	go services.NewEmailService().RunWorker(context.Background(), config.AppConfig.Notification.WorkerInterval)
+	go services.NewShareService().RunExpiryNoticeWorker(context.Background(), config.AppConfig.Notification.WorkerInterval)
+
 	// Create Gin router
 	router := gin.New()
 
@@ -4,28 +4,39 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Upload   UploadConfig
-	Storage  StorageConfig
-	CORS     CORSConfig
-	Redis    RedisConfig
-	Email    EmailConfig
-	Logging  LoggingConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Upload        UploadConfig
+	Storage       StorageConfig
+	CORS          CORSConfig
+	Redis         RedisConfig
+	Email         EmailConfig
+	Notification  NotificationConfig
+	GeoIP         GeoIPConfig
+	Transfer      TransferConfig
+	Watermark     WatermarkConfig
+	Permission    PermissionConfig
+	Invite        InviteConfig
+	AccessRequest AccessRequestConfig
+	Quota         QuotaConfig
+	Logging       LoggingConfig
 }
 
 type ServerConfig struct {
 	Port    string
 	Host    string
 	GinMode string
+	AppURL  string // address of the web app, links in emails point there
 }
 
 type DatabaseConfig struct {
@@ -89,6 +100,46 @@ type EmailConfig struct {
 	FromName     string
 }
 
+type NotificationConfig struct {
+	WorkerInterval   time.Duration // how often the outbox is delivered, digests are sent and expiring links are checked
+	MaxAttempts      int           // delivery attempts before an email is given up
+	DigestInterval   time.Duration // how long notifications are batched for users who get digests
+	ExpiryNoticeTime time.Duration // how long before a share link expires its owner is told
+}
+
+type GeoIPConfig struct {
+	DatabasePath   string        // path to a MaxMind-format .mmdb file, empty disables GeoIP
+	ReloadInterval time.Duration // how often the file is checked for changes
+}
+
+type TransferConfig struct {
+	DefaultExpiryDays   int           // expiry used when an upload does not set expiry_days
+	MaxExpiryDays       int           // longest expiry a sender can choose
+	ExpiryCheckInterval time.Duration // how often expired transfers are cleaned up
+}
+
+type WatermarkConfig struct {
+	MaxFileSize int64 // largest image or PDF that is watermarked on the fly
+}
+
+type PermissionConfig struct {
+	CacheTTL time.Duration // how long a resolved role is reused, zero disables the cache
+}
+
+type InviteConfig struct {
+	ExpiryDays int // how long a collaborator invite can be accepted, resending starts it over
+}
+
+type AccessRequestConfig struct {
+	MaxPerHour int // access requests a user can make in an hour
+}
+
+type QuotaConfig struct {
+	DefaultQuota      int64         // bytes a user can store unless an admin sets their quota, zero is unlimited
+	WarningPercents   []int         // usage percentages at which users are warned, in increasing order
+	ReconcileInterval time.Duration // how often usage counters are checked against the stored files
+}
+
 type LoggingConfig struct {
 	Level     string // debug, info, warn, error
 	Format    string // json, text
@@ -111,6 +162,7 @@ func LoadConfig() {
 			Port:    getEnv("PORT", "8080"),
 			Host:    getEnv("HOST", "localhost"),
 			GinMode: getEnv("GIN_MODE", "debug"),
+			AppURL:  strings.TrimRight(getEnv("APP_URL", "http://localhost:3000"), "/"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -158,6 +210,38 @@ func LoadConfig() {
 			FromEmail:    getEnv("FROM_EMAIL", ""),
 			FromName:     getEnv("FROM_NAME", "Swift Share"),
 		},
+		Notification: NotificationConfig{
+			WorkerInterval:   time.Duration(getEnvAsInt("EMAIL_WORKER_INTERVAL", 60)) * time.Second,
+			MaxAttempts:      getEnvAsInt("EMAIL_MAX_ATTEMPTS", 5),
+			DigestInterval:   time.Duration(getEnvAsInt("EMAIL_DIGEST_INTERVAL", 86400)) * time.Second,
+			ExpiryNoticeTime: time.Duration(getEnvAsInt("SHARE_LINK_EXPIRY_NOTICE", 86400)) * time.Second,
+		},
+		GeoIP: GeoIPConfig{
+			DatabasePath:   getEnv("GEOIP_DB_PATH", ""),
+			ReloadInterval: time.Duration(getEnvAsInt("GEOIP_RELOAD_INTERVAL", 60)) * time.Second,
+		},
+		Transfer: TransferConfig{
+			DefaultExpiryDays:   getEnvAsInt("TRANSFER_DEFAULT_EXPIRY_DAYS", 7),
+			MaxExpiryDays:       getEnvAsInt("TRANSFER_MAX_EXPIRY_DAYS", 30),
+			ExpiryCheckInterval: time.Duration(getEnvAsInt("TRANSFER_EXPIRY_CHECK_INTERVAL", 300)) * time.Second,
+		},
+		Watermark: WatermarkConfig{
+			MaxFileSize: getEnvAsInt64("WATERMARK_MAX_FILE_SIZE", 52428800), // 50MB
+		},
+		Permission: PermissionConfig{
+			CacheTTL: time.Duration(getEnvAsInt("PERMISSION_CACHE_TTL", 30)) * time.Second,
+		},
+		Invite: InviteConfig{
+			ExpiryDays: getEnvAsInt("COLLABORATOR_INVITE_EXPIRY_DAYS", 14),
+		},
+		AccessRequest: AccessRequestConfig{
+			MaxPerHour: getEnvAsInt("ACCESS_REQUEST_MAX_PER_HOUR", 10),
+		},
+		Quota: QuotaConfig{
+			DefaultQuota:      getEnvAsInt64("STORAGE_DEFAULT_QUOTA", 5368709120), // 5GB
+			WarningPercents:   getEnvAsIntSlice("STORAGE_QUOTA_WARNING_PERCENTS", []int{80, 95}),
+			ReconcileInterval: time.Duration(getEnvAsInt("STORAGE_USAGE_RECONCILE_INTERVAL", 3600)) * time.Second,
+		},
 		Logging: LoggingConfig{
 			Level:     getEnv("LOG_LEVEL", "info"),
 			Format:    getEnv("LOG_FORMAT", "json"),
@@ -280,6 +364,22 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+func getEnvAsIntSlice(key string, defaultValue []int) []int {
+	var result []int
+	for _, item := range getEnvAsSlice(key, nil) {
+		intValue, err := strconv.Atoi(item)
+		if err != nil {
+			return defaultValue
+		}
+		result = append(result, intValue)
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	sort.Ints(result)
+	return result
+}
+
 func splitAndTrim(s, sep string) []string {
 	var result []string
 	for _, item := range splitString(s, sep) {
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// AccessRequestController lets users ask for access to files and folders and lets owners answer
+type AccessRequestController struct {
+	accessRequestService *services.AccessRequestService
+	auditService         *services.AuditService
+}
+
+func NewAccessRequestController() *AccessRequestController {
+	return &AccessRequestController{
+		accessRequestService: services.NewAccessRequestService(),
+		auditService:         services.NewAuditService(),
+	}
+}
+
+// RequestFileAccess godoc
+// @Summary Request access to a file
+// @Description Ask the owner of a file for a role on it. The owner is notified and can approve or deny the request.
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param request body models.AccessRequestCreateRequest true "Requested role and message"
+// @Success 201 {object} utils.APIResponse "Access requested successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or you own the file"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Failure 409 {object} utils.APIResponse "Access already granted or requested"
+// @Failure 429 {object} utils.APIResponse "Too many access requests"
+// @Router /files/{id}/access-requests [post]
+func (arc *AccessRequestController) RequestFileAccess(c *gin.Context) {
+	arc.requestAccess(c, true)
+}
+
+// RequestFolderAccess godoc
+// @Summary Request access to a folder
+// @Description Ask the owner of a folder for a role on it. The owner is notified and can approve or deny the request.
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Param request body models.AccessRequestCreateRequest true "Requested role and message"
+// @Success 201 {object} utils.APIResponse "Access requested successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or you own the folder"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Failure 409 {object} utils.APIResponse "Access already granted or requested"
+// @Failure 429 {object} utils.APIResponse "Too many access requests"
+// @Router /folders/{id}/access-requests [post]
+func (arc *AccessRequestController) RequestFolderAccess(c *gin.Context) {
+	arc.requestAccess(c, false)
+}
+
+// GetAccessRequests godoc
+// @Summary Get access requests
+// @Description Get the access requests you made and the ones made for items you own
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param direction query string false "incoming (for your items) or outgoing (made by you), both by default"
+// @Param status query string false "pending, approved, denied or cancelled"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Access requests retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /access-requests [get]
+func (arc *AccessRequestController) GetAccessRequests(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	requests, total, err := arc.accessRequestService.ListRequests(user.ID, c.Query("direction"), c.Query("status"), page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve access requests")
+		return
+	}
+
+	responses := make([]models.AccessRequestResponse, 0, len(requests))
+	for i := range requests {
+		responses = append(responses, requests[i].ToResponse())
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Access requests retrieved successfully", gin.H{
+		"access_requests": responses,
+		"total":           total,
+		"current_page":    page,
+		"total_pages":     totalPages,
+		"page_size":       limit,
+	})
+}
+
+// GetAccessRequest godoc
+// @Summary Get an access request
+// @Description Get an access request you made or one made for an item you own
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Access request ID"
+// @Success 200 {object} utils.APIResponse "Access request retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid access request ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Access request not found"
+// @Router /access-requests/{id} [get]
+func (arc *AccessRequestController) GetAccessRequest(c *gin.Context) {
+	user, requestID, ok := accessRequestRequest(c)
+	if !ok {
+		return
+	}
+
+	request, err := arc.accessRequestService.GetRequest(user.ID, requestID)
+	if err != nil {
+		accessRequestErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Access request retrieved successfully", request.ToResponse())
+}
+
+// ApproveAccessRequest godoc
+// @Summary Approve an access request
+// @Description Add the requester as a collaborator on your file or folder, with the requested role unless you pick another one
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Access request ID"
+// @Param request body models.ApproveAccessRequest false "Role, access expiry and message"
+// @Success 200 {object} utils.APIResponse "Access request approved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Access request not found"
+// @Failure 409 {object} utils.APIResponse "Access request is no longer pending"
+// @Router /access-requests/{id}/approve [post]
+func (arc *AccessRequestController) ApproveAccessRequest(c *gin.Context) {
+	user, requestID, ok := accessRequestRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.ApproveAccessRequest
+	if c.Request.ContentLength != 0 && !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	request, err := arc.accessRequestService.ApproveRequest(user.ID, requestID, req)
+	if err != nil {
+		accessRequestErrorResponse(c, err)
+		return
+	}
+
+	arc.auditService.LogEvent(&user.ID, models.ActionAccessApprove, models.ResourceAccessRequest, &request.ID,
+		fmt.Sprintf("%s given %s access to %s %q", request.Requester.Email, request.GrantedRole, request.ItemType(), request.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Access request approved successfully", request.ToResponse())
+}
+
+// DenyAccessRequest godoc
+// @Summary Deny an access request
+// @Description Turn down an access request for your file or folder
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Access request ID"
+// @Param request body models.DenyAccessRequest false "Message to the requester"
+// @Success 200 {object} utils.APIResponse "Access request denied successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Access request not found"
+// @Failure 409 {object} utils.APIResponse "Access request is no longer pending"
+// @Router /access-requests/{id}/deny [post]
+func (arc *AccessRequestController) DenyAccessRequest(c *gin.Context) {
+	user, requestID, ok := accessRequestRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.DenyAccessRequest
+	if c.Request.ContentLength != 0 && !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	request, err := arc.accessRequestService.DenyRequest(user.ID, requestID, req)
+	if err != nil {
+		accessRequestErrorResponse(c, err)
+		return
+	}
+
+	arc.auditService.LogEvent(&user.ID, models.ActionAccessDeny, models.ResourceAccessRequest, &request.ID,
+		fmt.Sprintf("Request of %s for %s access to %s %q denied", request.Requester.Email, request.Role, request.ItemType(), request.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Access request denied successfully", request.ToResponse())
+}
+
+// CancelAccessRequest godoc
+// @Summary Cancel an access request
+// @Description Withdraw a pending access request you made
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Access request ID"
+// @Success 200 {object} utils.APIResponse "Access request cancelled successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid access request ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Access request not found"
+// @Failure 409 {object} utils.APIResponse "Access request is no longer pending"
+// @Router /access-requests/{id}/cancel [post]
+func (arc *AccessRequestController) CancelAccessRequest(c *gin.Context) {
+	user, requestID, ok := accessRequestRequest(c)
+	if !ok {
+		return
+	}
+
+	request, err := arc.accessRequestService.CancelRequest(user.ID, requestID)
+	if err != nil {
+		accessRequestErrorResponse(c, err)
+		return
+	}
+
+	arc.auditService.LogEvent(&user.ID, models.ActionAccessCancel, models.ResourceAccessRequest, &request.ID,
+		fmt.Sprintf("Request for %s access to %s %q cancelled", request.Role, request.ItemType(), request.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Access request cancelled successfully", request.ToResponse())
+}
+
+func (arc *AccessRequestController) requestAccess(c *gin.Context, isFile bool) {
+	user, resourceID, ok := inviteItemRequest(c, isFile)
+	if !ok {
+		return
+	}
+
+	var req models.AccessRequestCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	request, err := arc.accessRequestService.RequestAccess(user, resourceID, isFile, req)
+	if err != nil {
+		accessRequestErrorResponse(c, err)
+		return
+	}
+
+	arc.auditService.LogEvent(&user.ID, models.ActionAccessRequest, models.ResourceAccessRequest, &request.ID,
+		fmt.Sprintf("%s access requested to %s %q", request.Role, request.ItemType(), request.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Access requested successfully", request.ToResponse())
+}
+
+// accessRequestRequest reads the user and the access request ID of a request
+func accessRequestRequest(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid access request ID")
+		return nil, uuid.Nil, false
+	}
+	return user, requestID, true
+}
+
+// accessRequestErrorResponse maps access request service errors to HTTP responses
+func accessRequestErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccessRequestNotFound), errors.Is(err, services.ErrAccessRequestItemNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAccessRequestOwner), errors.Is(err, services.ErrAccessRequestDriveItem):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAccessRequestHasAccess), errors.Is(err, services.ErrAccessRequestPending),
+		errors.Is(err, services.ErrAccessRequestNotPending):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrAccessRequestRateLimited):
+		utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process access request")
+	}
+}
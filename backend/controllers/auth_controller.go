@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -15,20 +16,26 @@ import (
 )
 
 type AuthController struct {
-	userService  *services.UserService
-	auditService *services.AuditService
+	userService   *services.UserService
+	auditService  *services.AuditService
+	inviteService *services.InviteService
+	quotaService  *services.QuotaService
+	planService   *services.PlanService
 }
 
 func NewAuthController() *AuthController {
 	return &AuthController{
-		userService:  services.NewUserService(),
-		auditService: services.NewAuditService(),
+		userService:   services.NewUserService(),
+		auditService:  services.NewAuditService(),
+		inviteService: services.NewInviteService(),
+		quotaService:  services.NewQuotaService(),
+		planService:   services.NewPlanService(),
 	}
 }
 
 // Register godoc
 // @Summary Register a new user
-// @Description Create a new user account
+// @Description Create a new user account. With the invite_token of a collaborator invite sent to the same address, the address is verified and every pending invite to it becomes a collaborator entry.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -58,6 +65,13 @@ func (ac *AuthController) Register(c *gin.Context) {
 		Password:  req.Password,
 	}
 
+	// An invite token proves the address receives mail, a bad one only leaves it unverified
+	if req.InviteToken != "" {
+		if _, err := ac.inviteService.VerifyToken(req.InviteToken, req.Email); err == nil {
+			user.EmailVerified = true
+		}
+	}
+
 	if err := database.GetDB().Create(&user).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to create user")
 		return
@@ -67,6 +81,16 @@ func (ac *AuthController) Register(c *gin.Context) {
 	ac.auditService.LogEvent(nil, models.ActionRegister, models.ResourceUser, &user.ID,
 		"User registered successfully", c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
 
+	invites, err := ac.inviteService.ConvertPendingInvites(&user)
+	if err != nil {
+		config.GetLogger().Error("Failed to convert collaborator invites", "error", err, "user_id", user.ID)
+	}
+	for _, invite := range invites {
+		ac.auditService.LogEvent(&user.ID, models.ActionInviteAccept, models.ResourceInvite, &invite.ID,
+			fmt.Sprintf("Invite to %s %q accepted on sign up", invite.ItemType(), invite.ItemName()),
+			c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+	}
+
 	// Generate JWT tokens
 	accessToken, err := middleware.GenerateToken(user)
 	if err != nil {
@@ -86,6 +110,7 @@ func (ac *AuthController) Register(c *gin.Context) {
 			"access_token":  accessToken,
 			"refresh_token": refreshToken,
 		},
+		"accepted_invites": len(invites),
 	}
 
 	utils.SuccessResponse(c, http.StatusCreated, "User registered successfully", response)
@@ -173,7 +198,7 @@ func (ac *AuthController) Login(c *gin.Context) {
 
 // GetProfile godoc
 // @Summary Get user profile
-// @Description Get the current user's profile information
+// @Description Get the current user's profile information together with the limits of their plan
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -188,7 +213,15 @@ func (ac *AuthController) GetProfile(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Profile retrieved successfully", user.ToResponse())
+	limits, ok := planLimits(c, ac.planService, user.ID)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Profile retrieved successfully", models.ProfileResponse{
+		UserResponse: user.ToResponse(),
+		Limits:       *limits,
+	})
 }
 
 // UpdateProfile godoc
@@ -357,33 +390,34 @@ func (ac *AuthController) GetDashboardStats(c *gin.Context) {
 	var stats struct {
 		TotalFiles     int64 `json:"total_files"`
 		StorageUsed    int64 `json:"storage_used"`
+		StorageQuota   int64 `json:"storage_quota"` // 0 when unlimited
 		SharedFiles    int64 `json:"shared_files"`
 		TotalDownloads int64 `json:"total_downloads"`
 	}
 
 	// Count total files (excluding trashed)
 	database.GetDB().Model(&models.File{}).
-		Where("user_id = ? AND is_trashed = false", user.ID).
+		Where("is_trashed = false").Scopes(models.OwnedBy(user.ID)).
 		Count(&stats.TotalFiles)
 
-	// Calculate total storage used (excluding trashed)
-	database.GetDB().Model(&models.File{}).
-		Where("user_id = ? AND is_trashed = false", user.ID).
-		Select("COALESCE(SUM(file_size), 0)").
-		Scan(&stats.StorageUsed)
+	// Storage used comes from the usage counter, it includes versions and trashed files
+	if quota, err := ac.quotaService.GetQuota(user.ID); err == nil {
+		stats.StorageUsed = quota.Used
+		stats.StorageQuota = quota.Quota
+	}
 
 	// Count shared files (files with collaborators or public files)
 	var sharedCount int64
 	database.GetDB().Model(&models.File{}).
 		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("files.user_id = ? AND files.is_trashed = false AND (files.is_public = true OR collaborators.id IS NOT NULL)", user.ID).
+		Where("files.drive_id IS NULL AND files.user_id = ? AND files.is_trashed = false AND (files.is_public = true OR collaborators.id IS NOT NULL)", user.ID).
 		Distinct("files.id").
 		Count(&sharedCount)
 	stats.SharedFiles = sharedCount
 
 	// Calculate total downloads
 	database.GetDB().Model(&models.File{}).
-		Where("user_id = ? AND is_trashed = false", user.ID).
+		Where("is_trashed = false").Scopes(models.OwnedBy(user.ID)).
 		Select("COALESCE(SUM(download_count), 0)").
 		Scan(&stats.TotalDownloads)
 
@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+type CommentController struct {
+	commentService *services.CommentService
+	shareService   *services.ShareService
+	auditService   *services.AuditService
+}
+
+func NewCommentController() *CommentController {
+	return &CommentController{
+		commentService: services.NewCommentService(),
+		shareService:   services.NewShareService(),
+		auditService:   services.NewAuditService(),
+	}
+}
+
+// GetFileComments godoc
+// @Summary Get file comments
+// @Description Get the comment threads of a file with their replies. Owners and collaborators of any role can read comments.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param include_resolved query bool false "Include resolved threads (default: false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Threads per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Comments retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "No access to the file"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Router /files/{id}/comments [get]
+func (cc *CommentController) GetFileComments(c *gin.Context) {
+	actor, target, ok := cc.userTarget(c, models.ResourceFile)
+	if !ok {
+		return
+	}
+	cc.listComments(c, actor, target)
+}
+
+// CreateFileComment godoc
+// @Summary Comment on a file
+// @Description Start a comment thread on a file, optionally anchored to a page or region, or reply to a thread with parent_id. Mention people who can see the file as @email. Requires the owner, commenter or editor role.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param request body models.CommentCreateRequest true "Comment"
+// @Success 201 {object} utils.APIResponse "Comment created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to comment"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Router /files/{id}/comments [post]
+func (cc *CommentController) CreateFileComment(c *gin.Context) {
+	actor, target, ok := cc.userTarget(c, models.ResourceFile)
+	if !ok {
+		return
+	}
+	cc.createComment(c, actor, target)
+}
+
+// GetFolderComments godoc
+// @Summary Get folder comments
+// @Description Get the comment threads of a folder with their replies. Owners and collaborators of any role can read comments.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Param include_resolved query bool false "Include resolved threads (default: false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Threads per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Comments retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid folder ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "No access to the folder"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Router /folders/{id}/comments [get]
+func (cc *CommentController) GetFolderComments(c *gin.Context) {
+	actor, target, ok := cc.userTarget(c, models.ResourceFolder)
+	if !ok {
+		return
+	}
+	cc.listComments(c, actor, target)
+}
+
+// CreateFolderComment godoc
+// @Summary Comment on a folder
+// @Description Start a comment thread on a folder or reply to a thread with parent_id. Mention people who can see the folder as @email. Requires the owner, commenter or editor role.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Param request body models.CommentCreateRequest true "Comment"
+// @Success 201 {object} utils.APIResponse "Comment created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to comment"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Router /folders/{id}/comments [post]
+func (cc *CommentController) CreateFolderComment(c *gin.Context) {
+	actor, target, ok := cc.userTarget(c, models.ResourceFolder)
+	if !ok {
+		return
+	}
+	cc.createComment(c, actor, target)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Change the text of your own comment. People mentioned for the first time are notified.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Comment ID"
+// @Param request body models.CommentUpdateRequest true "New comment text"
+// @Success 200 {object} utils.APIResponse "Comment updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not your comment"
+// @Failure 404 {object} utils.APIResponse "Comment not found"
+// @Router /comments/{id} [put]
+func (cc *CommentController) UpdateComment(c *gin.Context) {
+	user, commentID, ok := commentRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.CommentUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	comment, err := cc.commentService.UpdateComment(user.ID, commentID, req)
+	if err != nil {
+		commentErrorResponse(c, err)
+		return
+	}
+
+	cc.auditService.LogEvent(&user.ID, models.ActionCommentUpdate, models.ResourceComment, &comment.ID,
+		"Comment edited", c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Comment updated successfully", comment.ToResponse())
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete your own comment, or any comment on a file or folder you own. Deleting the first comment of a thread deletes the whole thread.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Comment ID"
+// @Success 200 {object} utils.APIResponse "Comment deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid comment ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to delete the comment"
+// @Failure 404 {object} utils.APIResponse "Comment not found"
+// @Router /comments/{id} [delete]
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	user, commentID, ok := commentRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := cc.commentService.DeleteComment(user.ID, commentID); err != nil {
+		commentErrorResponse(c, err)
+		return
+	}
+
+	cc.auditService.LogEvent(&user.ID, models.ActionCommentDelete, models.ResourceComment, &commentID,
+		"Comment deleted", c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Comment deleted successfully", nil)
+}
+
+// ResolveComment godoc
+// @Summary Resolve a comment thread
+// @Description Mark a thread as resolved. The owner, editors and the person who started the thread can resolve it.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Comment ID of the thread"
+// @Success 200 {object} utils.APIResponse "Thread resolved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid comment ID or not a thread"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to resolve the thread"
+// @Failure 404 {object} utils.APIResponse "Comment not found"
+// @Router /comments/{id}/resolve [post]
+func (cc *CommentController) ResolveComment(c *gin.Context) {
+	cc.setResolved(c, true)
+}
+
+// UnresolveComment godoc
+// @Summary Reopen a comment thread
+// @Description Reopen a resolved thread. The owner, editors and the person who started the thread can reopen it.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Comment ID of the thread"
+// @Success 200 {object} utils.APIResponse "Thread reopened successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid comment ID or not a thread"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to reopen the thread"
+// @Failure 404 {object} utils.APIResponse "Comment not found"
+// @Router /comments/{id}/unresolve [post]
+func (cc *CommentController) UnresolveComment(c *gin.Context) {
+	cc.setResolved(c, false)
+}
+
+// GetPublicShareFileComments godoc
+// @Summary Get comments on a shared file
+// @Description Get the comment threads of a file in a share. The share link must have the comment or edit permission.
+// @Tags public
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param fileId path string true "File ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param include_resolved query bool false "Include resolved threads (default: false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Threads per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Comments retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow comments"
+// @Failure 404 {object} utils.APIResponse "File not found in share"
+// @Router /public/share/{token}/files/{fileId}/comments [get]
+func (cc *CommentController) GetPublicShareFileComments(c *gin.Context) {
+	actor, target, ok := cc.shareTarget(c, models.ResourceFile)
+	if !ok {
+		return
+	}
+	cc.listComments(c, actor, target)
+}
+
+// CreatePublicShareFileComment godoc
+// @Summary Comment on a shared file
+// @Description Start a thread or reply on a file in a share. The share link must have the comment or edit permission. Visitors can give their name as author_name.
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param fileId path string true "File ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param request body models.CommentCreateRequest true "Comment"
+// @Success 201 {object} utils.APIResponse "Comment created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow comments"
+// @Failure 404 {object} utils.APIResponse "File not found in share"
+// @Router /public/share/{token}/files/{fileId}/comments [post]
+func (cc *CommentController) CreatePublicShareFileComment(c *gin.Context) {
+	actor, target, ok := cc.shareTarget(c, models.ResourceFile)
+	if !ok {
+		return
+	}
+	cc.createComment(c, actor, target)
+}
+
+// GetPublicShareFolderComments godoc
+// @Summary Get comments on a shared folder
+// @Description Get the comment threads of a folder in a share. The share link must have the comment or edit permission.
+// @Tags public
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param folderId path string true "Folder ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param include_resolved query bool false "Include resolved threads (default: false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Threads per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Comments retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow comments"
+// @Failure 404 {object} utils.APIResponse "Folder not found in share"
+// @Router /public/share/{token}/folders/{folderId}/comments [get]
+func (cc *CommentController) GetPublicShareFolderComments(c *gin.Context) {
+	actor, target, ok := cc.shareTarget(c, models.ResourceFolder)
+	if !ok {
+		return
+	}
+	cc.listComments(c, actor, target)
+}
+
+// CreatePublicShareFolderComment godoc
+// @Summary Comment on a shared folder
+// @Description Start a thread or reply on a folder in a share. The share link must have the comment or edit permission. Visitors can give their name as author_name.
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param folderId path string true "Folder ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param request body models.CommentCreateRequest true "Comment"
+// @Success 201 {object} utils.APIResponse "Comment created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow comments"
+// @Failure 404 {object} utils.APIResponse "Folder not found in share"
+// @Router /public/share/{token}/folders/{folderId}/comments [post]
+func (cc *CommentController) CreatePublicShareFolderComment(c *gin.Context) {
+	actor, target, ok := cc.shareTarget(c, models.ResourceFolder)
+	if !ok {
+		return
+	}
+	cc.createComment(c, actor, target)
+}
+
+func (cc *CommentController) listComments(c *gin.Context, actor services.CommentActor, target *services.CommentTarget) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	includeResolved := c.Query("include_resolved") == "true"
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	comments, total, err := cc.commentService.ListComments(actor, target, includeResolved, page, limit)
+	if err != nil {
+		commentErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]models.CommentResponse, 0, len(comments))
+	for i := range comments {
+		responses = append(responses, comments[i].ToResponse())
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Comments retrieved successfully", gin.H{
+		"comments":     responses,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
+
+func (cc *CommentController) createComment(c *gin.Context, actor services.CommentActor, target *services.CommentTarget) {
+	var req models.CommentCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	comment, err := cc.commentService.CreateComment(actor, target, req)
+	if err != nil {
+		commentErrorResponse(c, err)
+		return
+	}
+
+	details := fmt.Sprintf("Comment added to %s", target.Name)
+	if actor.UserID == nil {
+		details = fmt.Sprintf("Comment added to %s via share link by %s", target.Name, comment.ToResponse().Author.Name)
+	}
+	cc.auditService.LogEvent(actor.UserID, models.ActionCommentCreate, models.ResourceComment, &comment.ID,
+		details, c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Comment created successfully", comment.ToResponse())
+}
+
+func (cc *CommentController) setResolved(c *gin.Context, resolved bool) {
+	user, commentID, ok := commentRequest(c)
+	if !ok {
+		return
+	}
+
+	comment, err := cc.commentService.SetResolved(user.ID, commentID, resolved)
+	if err != nil {
+		commentErrorResponse(c, err)
+		return
+	}
+
+	message, details := "Thread resolved successfully", "Comment thread resolved"
+	if !resolved {
+		message, details = "Thread reopened successfully", "Comment thread reopened"
+	}
+	cc.auditService.LogEvent(&user.ID, models.ActionCommentResolve, models.ResourceComment, &comment.ID,
+		details, c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, message, comment.ToResponse())
+}
+
+// userTarget resolves the file or folder in the :id path parameter for the signed-in user. It
+// responds and returns false when the request cannot go ahead.
+func (cc *CommentController) userTarget(c *gin.Context, resource string) (services.CommentActor, *services.CommentTarget, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return services.CommentActor{}, nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+resource+" ID")
+		return services.CommentActor{}, nil, false
+	}
+
+	var target *services.CommentTarget
+	if resource == models.ResourceFolder {
+		target, err = cc.commentService.FolderTarget(id)
+	} else {
+		target, err = cc.commentService.FileTarget(id)
+	}
+	if err != nil {
+		utils.NotFoundResponse(c, resourceLabel(resource))
+		return services.CommentActor{}, nil, false
+	}
+
+	return services.CommentActor{UserID: &user.ID}, target, true
+}
+
+// shareTarget resolves the file or folder in the path within the share unlocked by
+// ShareAccessMiddleware. It responds and returns false when the request cannot go ahead.
+func (cc *CommentController) shareTarget(c *gin.Context, resource string) (services.CommentActor, *services.CommentTarget, bool) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return services.CommentActor{}, nil, false
+	}
+
+	param := "fileId"
+	if resource == models.ResourceFolder {
+		param = "folderId"
+	}
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+resource+" ID")
+		return services.CommentActor{}, nil, false
+	}
+
+	var target *services.CommentTarget
+	if resource == models.ResourceFolder {
+		if _, err = cc.shareService.GetSharedFolder(shareLink, id); err == nil {
+			target, err = cc.commentService.FolderTarget(id)
+		}
+	} else {
+		if _, err = cc.shareService.GetSharedFile(shareLink, id); err == nil {
+			target, err = cc.commentService.FileTarget(id)
+		}
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, fmt.Sprintf("%s not found in share", resourceLabel(resource)))
+		return services.CommentActor{}, nil, false
+	}
+
+	actor := services.CommentActor{ShareLink: shareLink, Email: middleware.GetShareEmailFromContext(c)}
+	return actor, target, true
+}
+
+// commentRequest reads the user and the comment ID of a request on a single comment
+func commentRequest(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid comment ID")
+		return nil, uuid.Nil, false
+	}
+	return user, commentID, true
+}
+
+// commentErrorResponse maps comment service errors to HTTP responses
+func commentErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCommentNotFound), errors.Is(err, services.ErrCommentTarget):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCommentForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
+
+func resourceLabel(resource string) string {
+	if resource == models.ResourceFolder {
+		return "Folder"
+	}
+	return "File"
+}
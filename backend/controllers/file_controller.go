@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,19 +30,37 @@ import (
 var appLogger *slog.Logger
 
 type FileController struct {
-	auditService        *services.AuditService
-	fileAccessService   *services.FileAccessService
-	trashService        *services.TrashService
-	collaboratorService *services.CollaboratorService
+	auditService         *services.AuditService
+	fileAccessService    *services.FileAccessService
+	trashService         *services.TrashService
+	collaboratorService  *services.CollaboratorService
+	inviteService        *services.InviteService
+	archiveService       *services.ArchiveService
+	extractionService    *services.ExtractionService
+	zipService           *services.ZipService
+	transferService      *services.TransferService
+	authorizationService *services.AuthorizationService
+	versionService       *services.VersionService
+	quotaService         *services.QuotaService
+	planService          *services.PlanService
 }
 
 func NewFileController() *FileController {
 	appLogger = config.GetLogger()
 	return &FileController{
-		auditService:        services.NewAuditService(),
-		fileAccessService:   services.NewFileAccessService(),
-		trashService:        services.NewTrashService(),
-		collaboratorService: services.NewCollaboratorService(),
+		auditService:         services.NewAuditService(),
+		fileAccessService:    services.NewFileAccessService(),
+		trashService:         services.NewTrashService(),
+		collaboratorService:  services.NewCollaboratorService(),
+		inviteService:        services.NewInviteService(),
+		archiveService:       services.NewArchiveService(),
+		extractionService:    services.NewExtractionService(),
+		zipService:           services.NewZipService(),
+		transferService:      services.NewTransferService(),
+		authorizationService: services.NewAuthorizationService(),
+		versionService:       services.NewVersionService(),
+		quotaService:         services.NewQuotaService(),
+		planService:          services.NewPlanService(),
 	}
 }
 
@@ -58,7 +78,7 @@ func (fc *FileController) GetCollaborators(c *gin.Context) {
 		return
 	}
 
-	collaborators, err := fc.collaboratorService.GetCollaborators(user.ID, fileID, true)
+	collaborators, err := fc.collaboratorService.GetCollaborators(resourceOwner(c, user), fileID, true)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -72,7 +92,8 @@ func (fc *FileController) GetCollaborators(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Collaborators fetched successfully", responses)
 }
 
-// AddCollaborator adds a collaborator to a file with a specific role
+// AddCollaborator adds a collaborator to a file with a specific role, email addresses without an
+// account are sent an invite
 func (fc *FileController) AddCollaborator(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
@@ -91,7 +112,11 @@ func (fc *FileController) AddCollaborator(c *gin.Context) {
 		return
 	}
 
-	collaborator, err := fc.collaboratorService.AddCollaborator(user.ID, fileID, req, true)
+	collaborator, err := fc.collaboratorService.AddCollaborator(resourceOwner(c, user), fileID, req, true)
+	if errors.Is(err, services.ErrCollaboratorNotRegistered) {
+		createInvite(c, fc.inviteService, fc.auditService, user, fileID, req, true)
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -125,7 +150,7 @@ func (fc *FileController) UpdateCollaborator(c *gin.Context) {
 		return
 	}
 
-	collaborator, err := fc.collaboratorService.UpdateCollaborator(user.ID, fileID, collaboratorID, req, true)
+	collaborator, err := fc.collaboratorService.UpdateCollaborator(resourceOwner(c, user), fileID, collaboratorID, req, true)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -154,7 +179,7 @@ func (fc *FileController) RemoveCollaborator(c *gin.Context) {
 		return
 	}
 
-	if err := fc.collaboratorService.RemoveCollaborator(user.ID, fileID, collaboratorID, true); err != nil {
+	if err := fc.collaboratorService.RemoveCollaborator(resourceOwner(c, user), fileID, collaboratorID, true); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -176,7 +201,7 @@ func (fc *FileController) RemoveCollaborator(c *gin.Context) {
 // @Success 201 {object} utils.APIResponse "File uploaded successfully"
 // @Failure 400 {object} utils.APIResponse "Validation error"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
-// @Failure 413 {object} utils.APIResponse "File too large"
+// @Failure 413 {object} utils.APIResponse "File too large or storage quota exceeded"
 // @Failure 500 {object} utils.APIResponse "Internal server error"
 // @Router /files/upload [post]
 func (fc *FileController) UploadFile(c *gin.Context) {
@@ -198,8 +223,8 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	if header.Size > 10<<20 {
-		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "File size exceeds 10MB limit")
+	limits, ok := planLimits(c, fc.planService, user.ID)
+	if !ok || !checkFileSizes(c, []*multipart.FileHeader{header}, limits.MaxFileSize) {
 		return
 	}
 
@@ -211,9 +236,16 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 	fileExtension := filepath.Ext(header.Filename)
 	fileName := fileID.String() + fileExtension
 
+	// Charge the file to the user's quota before storing anything
+	if err := fc.quotaService.Reserve(user.ID, header.Size); err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
 	// Read the uploaded file into memory (could be streamed to avoid large memory, but max size is limited)
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
+		fc.quotaService.Release(user.ID, header.Size)
 		utils.InternalServerErrorResponse(c, "Failed to read uploaded file")
 		return
 	}
@@ -223,6 +255,7 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 	objectKey := filepath.Join(user.ID.String(), fileName) // folder per user
 	urlOrPath, err := storageSvc.UploadFile(c.Request.Context(), objectKey, fileBytes, header.Header.Get("Content-Type"))
 	if err != nil {
+		fc.quotaService.Release(user.ID, header.Size)
 		utils.InternalServerErrorResponse(c, "Failed to upload file to storage")
 		return
 	}
@@ -248,6 +281,7 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 
 	if err := database.GetDB().Create(&fileModel).Error; err != nil {
 		storageSvc.DeleteFile(c.Request.Context(), objectKey)
+		fc.quotaService.Release(user.ID, header.Size)
 		utils.InternalServerErrorResponse(c, "Failed to save file record")
 		return
 	}
@@ -269,13 +303,14 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 // @Param description formData string false "File description"
 // @Param tags formData string false "File tags"
 // @Param is_public formData bool false "Make files public"
-// @Param recipients formData string false "Recipients email addresses"
+// @Param recipients formData string false "Comma separated recipient email addresses, creates a transfer"
+// @Param title formData string false "Transfer title"
 // @Param message formData string false "Message for recipients"
-// @Param expiry_days formData int false "Expiry days for share links"
+// @Param expiry_days formData int false "Days until the transfer expires and its files are trashed"
 // @Success 201 {object} utils.APIResponse "Files uploaded successfully"
 // @Failure 400 {object} utils.APIResponse "Validation error"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
-// @Failure 413 {object} utils.APIResponse "File too large"
+// @Failure 413 {object} utils.APIResponse "File too large or storage quota exceeded"
 // @Failure 500 {object} utils.APIResponse "Internal server error"
 // @Router /files/upload-multiple [post]
 func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
@@ -307,35 +342,87 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 	description := c.PostForm("description")
 	tags := c.PostForm("tags")
 	isPublic := c.PostForm("is_public") == "true"
-	// recipients := c.PostForm("recipients")
+	recipients := c.PostForm("recipients")
 	message := c.PostForm("message")
 	folderIDStr := c.PostForm("folder_id")
-	// expiryDaysStr := c.PostForm("expiry_days")
 	var folderID *uuid.UUID
-	// expiryDays := 7 // default
-	// if expiryDaysStr != "" {
-	// 	if days, err := strconv.Atoi(expiryDaysStr); err == nil && days > 0 {
-	// 		expiryDays = days
-	// 	}
-	// }
-	// validate folder id
+
+	// Recipients turn the upload into a transfer, validate them before storing anything
+	var transferReq *models.TransferCreateRequest
+	if strings.TrimSpace(recipients) != "" {
+		emails, err := services.ParseRecipients(recipients)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		expiryDays, _ := strconv.Atoi(c.PostForm("expiry_days"))
+		if expiryDays, err = fc.transferService.ExpiryDays(expiryDays); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		title := c.PostForm("title")
+		if len(title) > 255 || len(message) > 2000 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Transfer title or message is too long")
+			return
+		}
+		transferReq = &models.TransferCreateRequest{
+			Title:      title,
+			Message:    message,
+			Recipients: emails,
+			ExpiryDays: expiryDays,
+		}
+	}
+
+	// validate folder id. Editors can upload into folders shared with them, the files then belong
+	// to the folder's owner and record the editor as uploader.
+	ownerID, userID := user.ID, user.ID
+	var driveID *uuid.UUID
 	if folderIDStr != "" {
-		var folder models.Folder
-		if err := database.GetDB().Where("id = ? AND user_id = ?", folderIDStr, user.ID).First(&folder).Error; err != nil {
+		parsed, err := uuid.Parse(folderIDStr)
+		if err != nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
 			return
 		}
+		folder, err := fc.authorizationService.AuthorizeFolder(user.ID, parsed, models.RoleEditor)
+		if err != nil || folder.IsTrashed {
+			if err == nil || errors.Is(err, services.ErrResourceNotFound) || errors.Is(err, services.ErrAccessDenied) {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+			} else {
+				accessErrorResponse(c, err, "Folder")
+			}
+			return
+		}
 		folderID = &folder.ID
+		ownerID = folder.OwnerID()
+		userID, driveID = folder.ContentOwner(user.ID)
 	}
-
-	// Validate file sizes
-	for _, file := range files {
-		if file.Size > 10<<20 {
-			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File %s exceeds 10MB limit", file.Filename))
+	if ownerID != user.ID {
+		if isPublic {
+			utils.ErrorResponse(c, http.StatusForbidden, "Only the folder owner can upload public files")
+			return
+		}
+		if transferReq != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Transfers can only be sent from your own folders")
 			return
 		}
 	}
 
+	// Validate file sizes against the plan of whoever the files will belong to
+	limits, ok := planLimits(c, fc.planService, ownerID)
+	if !ok || !checkFileSizes(c, files, limits.MaxFileSize) {
+		return
+	}
+
+	// Charge all files to the owner's quota before storing any of them, failed files are released
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.Size
+	}
+	if err := fc.quotaService.Reserve(ownerID, totalSize); err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
 	// Process files concurrently
 	type uploadResult struct {
 		File     *models.File
@@ -352,6 +439,13 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 		go func(header *multipart.FileHeader) {
 			defer wg.Done()
 
+			stored := false
+			defer func() {
+				if !stored {
+					fc.quotaService.Release(ownerID, header.Size)
+				}
+			}()
+
 			file, err := header.Open()
 			if err != nil {
 				results <- uploadResult{Error: err, Filename: header.Filename}
@@ -373,7 +467,7 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 
 			// Upload to storage
 			storageSvc := storage.GetStorage()
-			objectKey := filepath.Join(user.ID.String(), fileName)
+			objectKey := filepath.Join(ownerID.String(), fileName)
 			urlOrPath, err := storageSvc.UploadFile(c.Request.Context(), objectKey, fileBytes, header.Header.Get("Content-Type"))
 			if err != nil {
 				results <- uploadResult{Error: err, Filename: header.Filename}
@@ -382,7 +476,8 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 
 			// Create file model
 			fileModel := &models.File{
-				UserID:        user.ID,
+				UserID:        userID,
+				DriveID:       driveID,
 				FileName:      fileName,
 				OriginalName:  header.Filename,
 				FilePath:      urlOrPath,
@@ -394,6 +489,10 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 				Tags:          tags,
 				FolderID:      folderID,
 			}
+			if ownerID != user.ID {
+				fileModel.UploaderName = user.GetFullName()
+				fileModel.UploaderEmail = user.Email
+			}
 
 			// Save to database
 			if err := database.GetDB().Create(fileModel).Error; err != nil {
@@ -401,6 +500,7 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 				results <- uploadResult{Error: err, Filename: header.Filename}
 				return
 			}
+			stored = true
 
 			// Update ACL based on visibility
 			if err := storageSvc.SetObjectPublic(c.Request.Context(), objectKey, isPublic); err != nil {
@@ -433,6 +533,33 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 		}
 	}
 
+	// Bundle the uploaded files into a transfer and email the recipients
+	var transfer *models.Transfer
+	if transferReq != nil && len(uploadedFiles) > 0 {
+		fileIDs := make([]uuid.UUID, 0, len(uploadedFiles))
+		for _, file := range uploadedFiles {
+			fileIDs = append(fileIDs, file.ID)
+		}
+
+		transfer, err = fc.transferService.CreateTransfer(user.ID, fileIDs, *transferReq)
+		if err != nil {
+			// The files are uploaded, report the transfer failure without failing the request
+			appLogger.Error("Failed to create transfer", "error", err, "user_id", user.ID)
+			errors = append(errors, "Failed to create transfer")
+		} else {
+			fc.auditService.LogEvent(&user.ID, models.ActionTransferCreate, models.ResourceTransfer, &transfer.ID,
+				fmt.Sprintf("Transfer of %d files sent to %d recipients", len(fileIDs), len(transferReq.Recipients)),
+				c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+			sender := *user
+			go func(transfer *models.Transfer) {
+				if err := fc.transferService.NotifyRecipients(transfer, sender); err != nil {
+					appLogger.Error("Failed to email transfer recipients", "error", err, "transfer_id", transfer.ID)
+				}
+			}(transfer)
+		}
+	}
+
 	// Prepare response
 	response := gin.H{
 		"uploaded_files": uploadedFiles,
@@ -445,42 +572,19 @@ func (fc *FileController) UploadMultipleFiles(c *gin.Context) {
 		response["errors"] = errors
 	}
 
-	// Create share links if recipients are provided
-	// if recipients != "" && len(uploadedFiles) > 0 {
-	// 	storageSvc := storage.GetStorage()
-	// 	shareService := services.NewShareService(storageSvc)
-
-	// 	for _, fileResponse := range uploadedFiles {
-	// 		// Create share link request
-	// 		shareRequest := models.ShareLinkCreateRequest{
-	// 			FileID:       fileResponse.ID,
-	// 			Description:  message,
-	// 			MaxDownloads: 0, // unlimited
-	// 		}
-
-	// 		// Set expiry date
-	// 		if expiryDays > 0 {
-	// 			expiryDate := time.Now().AddDate(0, 0, expiryDays)
-	// 			shareRequest.ExpiresAt = &expiryDate
-	// 		}
-
-	// 		_, shareErr := shareService.CreateShareLinkWithExpiration(c.Request.Context(), user.ID, fileResponse.ID, shareRequest)
-	// 		if shareErr != nil {
-	// 			// Log error but don't fail the upload
-	// 			fmt.Printf("Failed to create share link for file %s: %v", fileResponse.OriginalName, shareErr)
-	// 		}
-	// 	}
-	// }
+	if transfer != nil {
+		response["transfer"] = transfer.ToResponse(requestBaseURL(c))
+	}
 
 	status := http.StatusCreated
-	message = fmt.Sprintf("Successfully uploaded %d files", len(uploadedFiles))
+	responseMessage := fmt.Sprintf("Successfully uploaded %d files", len(uploadedFiles))
 
 	if len(errors) > 0 {
 		status = http.StatusPartialContent
-		message = fmt.Sprintf("Uploaded %d files with %d errors", len(uploadedFiles), len(errors))
+		responseMessage = fmt.Sprintf("Uploaded %d files with %d errors", len(uploadedFiles), len(errors))
 	}
 
-	utils.SuccessResponse(c, status, message, response)
+	utils.SuccessResponse(c, status, responseMessage, response)
 }
 
 // GetFiles godoc
@@ -515,48 +619,27 @@ func (fc *FileController) GetFiles(c *gin.Context) {
 		limit = 10
 	}
 
-	// Owner files or collaborator files (exclude trashed items)
+	granted, err := fc.authorizationService.GrantedFiles(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve files")
+		return
+	}
+
+	// Owner files or files shared with the user, directly or through a folder above them
+	// (exclude trashed items)
 	query := database.GetDB().Model(&models.File{}).
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("(files.user_id = ? OR collaborators.user_id = ?) AND files.is_trashed = false", user.ID, user.ID)
+		Where("((files.drive_id IS NULL AND files.user_id = ?) OR files.id IN (?)) AND files.is_trashed = false", user.ID, granted)
 
 	if search != "" {
 		query = query.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	var total int64
-	// Use subquery to count distinct files (exclude trashed items)
-	countQuery := database.GetDB().Model(&models.File{}).
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("(files.user_id = ? OR collaborators.user_id = ?) AND files.is_trashed = false", user.ID, user.ID)
-
-	if search != "" {
-		countQuery = countQuery.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	countQuery.Distinct("files.id").Count(&total)
+	query.Count(&total)
 
 	var files []models.File
 	offset := (page - 1) * limit
-
-	// Use subquery to get distinct file IDs with proper ordering
-	subquery := database.GetDB().Model(&models.File{}).
-		Select("DISTINCT files.id, files.created_at").
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("files.user_id = ? OR collaborators.user_id = ?", user.ID, user.ID)
-
-	if search != "" {
-		subquery = subquery.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	subquery = subquery.Order("files.created_at DESC").Offset(offset).Limit(limit)
-
-	// Get the actual files using the subquery
-	if err := database.GetDB().Model(&models.File{}).
-		Preload("User").
-		Where("files.id IN (?)", subquery.Select("files.id")).
-		Order("files.created_at DESC").
-		Find(&files).Error; err != nil {
+	if err := query.Preload("User").Order("files.created_at DESC").Offset(offset).Limit(limit).Find(&files).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to retrieve files")
 		return
 	}
@@ -610,75 +693,26 @@ func (fc *FileController) GetSharedWithMeFiles(c *gin.Context) {
 		limit = 10
 	}
 
-	// Get files where user is a collaborator (not the owner)
+	granted, err := fc.authorizationService.GrantedFiles(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve shared files")
+		return
+	}
+
+	// Get files shared with the user, directly or through a folder above them, that they do not own
 	query := database.GetDB().Model(&models.File{}).
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("collaborators.user_id = ? AND files.is_trashed = false", user.ID)
+		Where("files.id IN (?) AND (files.drive_id IS NOT NULL OR files.user_id <> ?) AND files.is_trashed = false", granted, user.ID)
 
 	if search != "" {
 		query = query.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	var total int64
-	countQuery := database.GetDB().Model(&models.File{}).
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("collaborators.user_id = ? AND files.is_trashed = false", user.ID)
-
-	if search != "" {
-		countQuery = countQuery.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	countQuery.Distinct("files.id").Count(&total)
+	query.Count(&total)
 
 	var files []models.File
 	offset := (page - 1) * limit
-
-	// Get distinct file IDs with proper ordering using window function
-	subquery := database.GetDB().Model(&models.File{}).
-		Select("files.id, ROW_NUMBER() OVER (ORDER BY files.created_at DESC) as rn").
-		Joins("LEFT JOIN collaborators ON files.id = collaborators.file_id").
-		Where("collaborators.user_id = ? AND files.is_trashed = false", user.ID)
-
-	if search != "" {
-		subquery = subquery.Where("original_name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	// Create a subquery to get the IDs with row numbers, then filter by row number for pagination
-	fileIDsQuery := database.GetDB().Raw(`
-		SELECT id FROM (
-			SELECT DISTINCT files.id, ROW_NUMBER() OVER (ORDER BY files.created_at DESC) as rn
-			FROM files 
-			LEFT JOIN collaborators ON files.id = collaborators.file_id 
-			WHERE collaborators.user_id = ? AND files.is_trashed = false AND files.deleted_at IS NULL
-		) ranked_files 
-		WHERE rn > ? AND rn <= ?
-	`, user.ID, offset, offset+limit)
-
-	if search != "" {
-		fileIDsQuery = database.GetDB().Raw(`
-			SELECT id FROM (
-				SELECT DISTINCT files.id, ROW_NUMBER() OVER (ORDER BY files.created_at DESC) as rn
-				FROM files 
-				LEFT JOIN collaborators ON files.id = collaborators.file_id 
-				WHERE collaborators.user_id = ? AND files.is_trashed = false AND files.deleted_at IS NULL
-				AND (files.original_name LIKE ? OR files.description LIKE ?)
-			) ranked_files 
-			WHERE rn > ? AND rn <= ?
-		`, user.ID, "%"+search+"%", "%"+search+"%", offset, offset+limit)
-	}
-
-	var fileIDs []string
-	if err := fileIDsQuery.Scan(&fileIDs).Error; err != nil {
-		utils.InternalServerErrorResponse(c, "Failed to retrieve shared files")
-		return
-	}
-
-	// Get the actual files using the IDs
-	if err := database.GetDB().Model(&models.File{}).
-		Preload("User").
-		Where("files.id IN (?)", fileIDs).
-		Order("files.created_at DESC").
-		Find(&files).Error; err != nil {
+	if err := query.Preload("User").Order("files.created_at DESC").Offset(offset).Limit(limit).Find(&files).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to retrieve shared files")
 		return
 	}
@@ -732,13 +766,9 @@ func (fc *FileController) GetFile(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
 		return
 	}
-	if file.UserID != user.ID {
-		var perm models.Collaborator
-		err := database.GetDB().Where("file_id = ? AND user_id = ?", file.ID, user.ID).First(&perm).Error
-		if err != nil || perm.IsExpired() {
-			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this file")
-			return
-		}
+	if err := fc.authorizationService.RequireFile(user.ID, &file, models.RoleViewer); err != nil {
+		accessErrorResponse(c, err, "File")
+		return
 	}
 
 	// Track file access (view)
@@ -749,7 +779,7 @@ func (fc *FileController) GetFile(c *gin.Context) {
 
 // UpdateFile godoc
 // @Summary Update file information
-// @Description Update file description, tags, or public status
+// @Description Rename a file or update its description, tags or public status. Editors can change everything except the public status.
 // @Tags files
 // @Accept json
 // @Produce json
@@ -779,12 +809,27 @@ func (fc *FileController) UpdateFile(c *gin.Context) {
 		return
 	}
 
+	// Editors and owners get here, FileRoleMiddleware has checked the role
 	var file models.File
-	if err := database.GetDB().Preload("User").Where("id = ? AND user_id = ?", fileID, user.ID).First(&file).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "File not found or you are not the owner")
+	if err := database.GetDB().Preload("User").Where("id = ?", fileID).First(&file).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
 		return
 	}
 
+	// Making a file public shares it, which only the owner may do
+	if req.IsPublic != file.IsPublic && file.OwnerID() != user.ID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the owner can change whether a file is public")
+		return
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != file.OriginalName {
+		if fileNameTaken(file.OwnerID(), file.FolderID, name, file.ID) {
+			utils.ErrorResponse(c, http.StatusConflict, "File with this name already exists")
+			return
+		}
+		file.OriginalName = name
+		file.FileExtension = filepath.Ext(name)
+	}
 	file.Description = req.Description
 	file.Tags = req.Tags
 	file.IsPublic = req.IsPublic
@@ -795,7 +840,7 @@ func (fc *FileController) UpdateFile(c *gin.Context) {
 	}
 
 	// Update ACL on storage to reflect new public/private status
-	objectKey := filepath.Join(user.ID.String(), file.FileName)
+	objectKey := file.ObjectKey()
 	storageSvc := storage.GetStorage()
 	if err := storageSvc.SetObjectPublic(c.Request.Context(), objectKey, file.IsPublic); err != nil {
 		appLogger.Error("Failed to update object ACL on update", "key", objectKey, "error", err)
@@ -834,21 +879,11 @@ func (fc *FileController) GeneratePresignedURL(c *gin.Context) {
 		return
 	}
 
-	var file models.File
-	// Allow if requester is owner OR has a non-expired collaborator permission
-	if err := database.GetDB().Where("id = ?", fileID).First(&file).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
 		return
 	}
-	if file.UserID != user.ID {
-		// not owner, check collaborator permission
-		var perm models.Collaborator
-		err := database.GetDB().Where("file_id = ? AND user_id = ?", file.ID, user.ID).First(&perm).Error
-		if err != nil || perm.IsExpired() {
-			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this file")
-			return
-		}
-	}
 
 	// Default expiration 15 minutes
 	expMinutes, _ := strconv.Atoi(c.DefaultQuery("expiration", "15"))
@@ -857,7 +892,7 @@ func (fc *FileController) GeneratePresignedURL(c *gin.Context) {
 	}
 
 	storageSvc := storage.GetStorage()
-	objectKey := filepath.Join(user.ID.String(), file.FileName)
+	objectKey := file.ObjectKey()
 
 	url, err := storageSvc.GeneratePresignedURL(c.Request.Context(), objectKey, time.Duration(expMinutes)*time.Minute)
 	if err != nil {
@@ -903,14 +938,15 @@ func (fc *FileController) DeleteFile(c *gin.Context) {
 		return
 	}
 
+	ownerID := resourceOwner(c, user)
 	var file models.File
-	if err := database.GetDB().Where("id = ? AND user_id = ?", fileID, user.ID).First(&file).Error; err != nil {
+	if err := database.GetDB().Where("id = ?", fileID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
 		return
 	}
 
 	// Move to trash instead of permanently deleting
-	if err := fc.trashService.MoveFileToTrash(user.ID, fileID); err != nil {
+	if err := fc.trashService.MoveFileToTrash(ownerID, fileID); err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to move file to trash")
 		return
 	}
@@ -947,19 +983,11 @@ func (fc *FileController) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	var file models.File
-	if err := database.GetDB().Where("id = ?", fileID).First(&file).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
 		return
 	}
-	if file.UserID != user.ID {
-		var perm models.Collaborator
-		err := database.GetDB().Where("file_id = ? AND user_id = ?", file.ID, user.ID).First(&perm).Error
-		if err != nil || perm.IsExpired() {
-			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this file")
-			return
-		}
-	}
 
 	// Increment download count
 	database.GetDB().Model(&file).Update("download_count", file.DownloadCount+1)
@@ -1058,21 +1086,12 @@ func (fc *FileController) GetFileAccessHistory(c *gin.Context) {
 	}
 
 	// Check if user has access to the file
-	var file models.File
-	if err := database.GetDB().Where("id = ?", fileID).First(&file).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
 		return
 	}
 
-	if file.UserID != user.ID {
-		var perm models.Collaborator
-		err := database.GetDB().Where("file_id = ? AND user_id = ?", file.ID, user.ID).First(&perm).Error
-		if err != nil || perm.IsExpired() {
-			utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this file")
-			return
-		}
-	}
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	if limit < 1 || limit > 200 {
 		limit = 50
@@ -1109,7 +1128,7 @@ func (fc *FileController) GetFileAccessHistory(c *gin.Context) {
 // @Success 200 {object} utils.APIResponse "File moved successfully"
 // @Failure 400 {object} utils.APIResponse "Invalid request"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
-// @Failure 403 {object} utils.APIResponse "Forbidden - Only owner can move file"
+// @Failure 403 {object} utils.APIResponse "Forbidden - Editor access to the file and destination required"
 // @Failure 404 {object} utils.APIResponse "File not found or destination folder not found"
 // @Router /files/{id}/move [post]
 func (fc *FileController) MoveFile(c *gin.Context) {
@@ -1130,9 +1149,9 @@ func (fc *FileController) MoveFile(c *gin.Context) {
 		return
 	}
 
-	// Get the file and ensure user owns it
+	// Editors and owners get here, FileRoleMiddleware has checked the role
 	var file models.File
-	if err := database.GetDB().Where("id = ? AND user_id = ?", fileID, user.ID).First(&file).Error; err != nil {
+	if err := database.GetDB().Where("id = ?", fileID).First(&file).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
 		return
 	}
@@ -1142,25 +1161,20 @@ func (fc *FileController) MoveFile(c *gin.Context) {
 		return
 	}
 
-	// Check if destination folder exists and user has access (if specified)
+	// The destination must be a folder of the file's owner the user can edit, only the owner can
+	// move the file to the top level
 	if req.FolderID != nil {
-		var destFolder models.Folder
-		if err := database.GetDB().Where("id = ? AND user_id = ?", *req.FolderID, user.ID).First(&destFolder).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "Destination folder not found or access denied")
+		if _, err := fc.authorizationService.AuthorizeDestination(user.ID, *req.FolderID, file.OwnerID()); err != nil {
+			destinationErrorResponse(c, err)
 			return
 		}
+	} else if file.OwnerID() != user.ID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the owner can move a file to the top level")
+		return
 	}
 
 	// Check if file with same name already exists in destination
-	var existingFile models.File
-	query := database.GetDB().Where("user_id = ? AND original_name = ? AND id != ?", user.ID, file.OriginalName, file.ID)
-	if req.FolderID != nil {
-		query = query.Where("folder_id = ?", *req.FolderID)
-	} else {
-		query = query.Where("folder_id IS NULL")
-	}
-
-	if err := query.First(&existingFile).Error; err == nil {
+	if fileNameTaken(file.OwnerID(), req.FolderID, file.OriginalName, file.ID) {
 		utils.ErrorResponse(c, http.StatusConflict, "File with this name already exists in destination")
 		return
 	}
@@ -1173,6 +1187,8 @@ func (fc *FileController) MoveFile(c *gin.Context) {
 		utils.InternalServerErrorResponse(c, "Failed to move file")
 		return
 	}
+	// Access inherited from the old and new parent folders changes with the move
+	services.InvalidatePermissions()
 
 	// Log the move event
 	fc.auditService.LogEvent(&user.ID, models.ActionFileMove, models.ResourceFile, &file.ID,
@@ -1180,3 +1196,490 @@ func (fc *FileController) MoveFile(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "File moved successfully", file.ToResponse())
 }
+
+// GetFileVersions godoc
+// @Summary List the versions of a file
+// @Description List the stored versions of a file, newest first. Anyone with access to the file can list them.
+// @Tags files
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Versions per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Versions retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Router /files/{id}/versions [get]
+func (fc *FileController) GetFileVersions(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	versions, err := fc.versionService.GetFileVersions(user.ID, fileID, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrAccessDenied) {
+			accessErrorResponse(c, err, "File")
+		} else {
+			utils.NotFoundResponse(c, "File")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Versions retrieved successfully", versions)
+}
+
+// UploadFileVersion godoc
+// @Summary Upload a new version of a file
+// @Description Replace the content of a file and keep the previous content as a version. Requires editor access. Uploading content identical to the current version changes nothing.
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param file formData file true "New content"
+// @Param comment formData string false "Version comment"
+// @Success 201 {object} utils.APIResponse "Version uploaded successfully"
+// @Success 200 {object} utils.APIResponse "Content unchanged"
+// @Failure 400 {object} utils.APIResponse "Validation error"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Editor access required"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Failure 413 {object} utils.APIResponse "File too large or storage quota exceeded"
+// @Router /files/{id}/versions [post]
+func (fc *FileController) UploadFileVersion(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	// Editors and owners get here, FileRoleMiddleware has checked the role
+	var file models.File
+	if err := database.GetDB().Where("id = ?", fileID).First(&file).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+	if file.IsTrashed {
+		utils.ErrorResponse(c, http.StatusBadRequest, "File is in trash")
+		return
+	}
+
+	upload, header, err := c.Request.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No file provided")
+		return
+	}
+	defer upload.Close()
+
+	limits, ok := planLimits(c, fc.planService, file.OwnerID())
+	if !ok || !checkFileSizes(c, []*multipart.FileHeader{header}, limits.MaxFileSize) {
+		return
+	}
+	comment := c.PostForm("comment")
+	if len(comment) > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Comment is too long")
+		return
+	}
+
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to read uploaded file")
+		return
+	}
+
+	version, created, err := fc.versionService.UploadVersion(c.Request.Context(), user.ID, &file, data, header.Header.Get("Content-Type"), comment)
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		quotaErrorResponse(c, err)
+		return
+	}
+	if err != nil {
+		appLogger.Error("Failed to upload version", "error", err, "file_id", file.ID)
+		utils.InternalServerErrorResponse(c, "Failed to upload version")
+		return
+	}
+	if !created {
+		utils.SuccessResponse(c, http.StatusOK, "Content unchanged, no new version was created", version.ToResponse())
+		return
+	}
+
+	fc.auditService.LogEvent(&user.ID, models.ActionFileUpdate, models.ResourceFile, &file.ID,
+		fmt.Sprintf("New version %d uploaded: %s", version.VersionNumber, file.OriginalName), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Version uploaded successfully", version.ToResponse())
+}
+
+// GetArchiveEntries godoc
+// @Summary List the entries of an archive
+// @Description List the files and folders inside a zip, tar or tar.gz file without downloading it
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Success 200 {object} utils.APIResponse "Archive entries retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID or unsupported archive"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Failure 422 {object} utils.APIResponse "Archive rejected"
+// @Router /files/{id}/archive [get]
+func (fc *FileController) GetArchiveEntries(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
+		return
+	}
+
+	listing, err := fc.archiveService.ListEntries(c.Request.Context(), file)
+	if err != nil {
+		archiveErrorResponse(c, err)
+		return
+	}
+
+	fc.fileAccessService.LogFileAccess(user.ID, file.ID, models.ActionView)
+
+	utils.SuccessResponse(c, http.StatusOK, "Archive entries retrieved successfully", listing)
+}
+
+// DownloadArchiveEntry godoc
+// @Summary Download a single entry of an archive
+// @Description Stream one file from inside a zip, tar or tar.gz file
+// @Tags files
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param path query string true "Path of the entry inside the archive"
+// @Success 200 {file} binary "Entry content"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID, entry path or unsupported archive"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "File or entry not found"
+// @Failure 422 {object} utils.APIResponse "Archive rejected"
+// @Router /files/{id}/archive/entry [get]
+func (fc *FileController) DownloadArchiveEntry(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	entryPath := c.Query("path")
+	if entryPath == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Entry path is required")
+		return
+	}
+
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
+		return
+	}
+
+	reader, entry, err := fc.archiveService.OpenEntry(c.Request.Context(), file, entryPath)
+	if err != nil {
+		archiveErrorResponse(c, err)
+		return
+	}
+	defer reader.Close()
+
+	fc.fileAccessService.LogFileAccess(user.ID, file.ID, models.ActionDownload)
+
+	fc.auditService.LogEvent(&user.ID, models.ActionFileDownload, models.ResourceFile, &file.ID,
+		fmt.Sprintf("Archive entry downloaded: %s from %s", entry.Path, file.OriginalName), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	contentType := utils.GetMimeType(entry.Name)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", entry.Name))
+	c.DataFromReader(http.StatusOK, entry.Size, contentType, reader, nil)
+}
+
+// ExtractArchive godoc
+// @Summary Extract an archive into a folder
+// @Description Unpack a zip, tar or tar.gz file into a folder tree as a background job
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param request body models.ArchiveExtractRequest true "Target folder and name collision rule"
+// @Success 202 {object} utils.APIResponse "Extraction started"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID or unsupported archive"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "File or folder not found"
+// @Router /files/{id}/extract [post]
+func (fc *FileController) ExtractArchive(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	var req models.ArchiveExtractRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	file, err := fc.authorizationService.AuthorizeFile(user.ID, fileID, models.RoleViewer)
+	if err != nil {
+		accessErrorResponse(c, err, "File")
+		return
+	}
+
+	if req.FolderID != nil {
+		var folder models.Folder
+		if err := database.GetDB().Where("id = ?", *req.FolderID).Scopes(models.OwnedBy(user.ID)).First(&folder).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Target folder not found or access denied")
+			return
+		}
+	}
+
+	job, err := fc.extractionService.StartExtraction(user.ID, file, req)
+	if err != nil {
+		archiveErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Extraction started", job.ToResponse())
+}
+
+// GetExtraction godoc
+// @Summary Get the status of an extraction job
+// @Description Get the progress and per-entry results of an archive extraction
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param jobId path string true "Extraction job ID"
+// @Success 200 {object} utils.APIResponse "Extraction retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Extraction job not found"
+// @Router /files/{id}/extract/{jobId} [get]
+func (fc *FileController) GetExtraction(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := fc.extractionService.GetExtraction(user.ID, fileID, jobID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Extraction job not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Extraction retrieved successfully", job.ToResponse())
+}
+
+// DownloadZip godoc
+// @Summary Download several files and folders as a zip
+// @Description Stream a zip archive of the selected files and folders, keeping the folder structure
+// @Tags files
+// @Accept json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param request body models.FileDownloadZipRequest true "Files and folders to include"
+// @Success 200 {file} binary "Zip archive"
+// @Failure 400 {object} utils.APIResponse "Validation error"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "File or folder not found"
+// @Router /files/download-zip [post]
+func (fc *FileController) DownloadZip(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.FileDownloadZipRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	if len(req.FileIDs) == 0 && len(req.FolderIDs) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No files or folders selected")
+		return
+	}
+
+	var items []services.ZipItem
+	for _, fileID := range req.FileIDs {
+		var file models.File
+		if err := database.GetDB().Where("id = ? AND is_trashed = ?", fileID, false).First(&file).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, fmt.Sprintf("File not found: %s", fileID))
+			return
+		}
+		if err := fc.authorizationService.RequireFile(user.ID, &file, models.RoleViewer); err != nil {
+			if errors.Is(err, services.ErrAccessDenied) {
+				utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("You do not have access to file: %s", file.OriginalName))
+			} else {
+				accessErrorResponse(c, err, "File")
+			}
+			return
+		}
+		items = append(items, services.ZipItem{Path: file.OriginalName, File: &file})
+	}
+
+	for _, folderID := range req.FolderIDs {
+		var folder models.Folder
+		if err := database.GetDB().Where("id = ? AND is_trashed = ?", folderID, false).First(&folder).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, fmt.Sprintf("Folder not found: %s", folderID))
+			return
+		}
+		if err := fc.authorizationService.RequireFolder(user.ID, &folder, models.RoleViewer); err != nil {
+			if errors.Is(err, services.ErrAccessDenied) {
+				utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("You do not have access to folder: %s", folder.Name))
+			} else {
+				accessErrorResponse(c, err, "Folder")
+			}
+			return
+		}
+		folderItems, err := fc.zipService.CollectFolder(&folder)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to collect folder contents")
+			return
+		}
+		items = append(items, folderItems...)
+	}
+
+	archiveName := fmt.Sprintf("swift-share-%s.zip", time.Now().Format("20060102-150405"))
+	if len(req.FileIDs) == 0 && len(req.FolderIDs) == 1 && len(items) > 0 {
+		archiveName = strings.SplitN(items[0].Path, "/", 2)[0] + ".zip"
+	}
+
+	fc.auditService.LogEvent(&user.ID, models.ActionFileDownload, models.ResourceFile, nil,
+		fmt.Sprintf("Zip download of %d files and %d folders", len(req.FileIDs), len(req.FolderIDs)), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	streamZip(c, fc.zipService, archiveName, items, func(file *models.File) {
+		fc.fileAccessService.LogFileAccess(user.ID, file.ID, models.ActionDownload)
+	})
+}
+
+// streamZip writes the items as a zip download. onFile is called for every included file.
+func streamZip(c *gin.Context, zipService *services.ZipService, archiveName string, items []services.ZipItem, onFile func(*models.File)) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", archiveName))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	if err := zipService.WriteZip(c.Request.Context(), c.Writer, items, onFile); err != nil {
+		// Headers are already sent, the client sees a truncated archive
+		appLogger.Error("Failed to stream zip", "archive", archiveName, "error", err)
+		c.Abort()
+	}
+}
+
+// streamStoredFile streams a file's content from the storage backend as an attachment
+func streamStoredFile(c *gin.Context, file *models.File) {
+	rc, err := storage.GetStorage().OpenFile(c.Request.Context(), file.ObjectKey())
+	if err != nil {
+		appLogger.Error("Failed to open stored file", "file_id", file.ID, "error", err)
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found in storage")
+		return
+	}
+	defer rc.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.OriginalName))
+	c.DataFromReader(http.StatusOK, file.FileSize, file.MimeType, rc, nil)
+}
+
+// destinationErrorResponse responds to a rejected move destination
+func destinationErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrResourceNotFound) || errors.Is(err, services.ErrAccessDenied) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Destination folder not found or access denied")
+		return
+	}
+	accessErrorResponse(c, err, "Folder")
+}
+
+// fileNameTaken checks if another file of the owner with the name exists in the folder, or at the
+// owner's top level when folderID is nil
+func fileNameTaken(ownerID uuid.UUID, folderID *uuid.UUID, name string, excludeID uuid.UUID) bool {
+	query := database.GetDB().Model(&models.File{}).
+		Where("original_name = ? AND id != ?", name, excludeID).Scopes(models.OwnedBy(ownerID))
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("folder_id IS NULL")
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// archiveErrorResponse maps archive service errors to HTTP responses
+func archiveErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrArchiveUnsupported), errors.Is(err, services.ErrArchiveInvalidPath):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrArchiveEntryNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrArchiveCorrupt), errors.Is(err, services.ErrArchiveTooManyEntries),
+		errors.Is(err, services.ErrArchiveUnsafe):
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		appLogger.Error("Failed to read archive", "error", err)
+		utils.InternalServerErrorResponse(c, "Failed to read archive")
+	}
+}
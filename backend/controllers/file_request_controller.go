@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// maxFileRequestUploadFiles limits how many files one upload request may contain
+const maxFileRequestUploadFiles = 20
+
+type FileRequestController struct {
+	fileRequestService  *services.FileRequestService
+	auditService        *services.AuditService
+	notificationService *services.NotificationService
+	planService         *services.PlanService
+}
+
+func NewFileRequestController() *FileRequestController {
+	return &FileRequestController{
+		fileRequestService:  services.NewFileRequestService(),
+		auditService:        services.NewAuditService(),
+		notificationService: services.NewNotificationService(),
+		planService:         services.NewPlanService(),
+	}
+}
+
+// CreateFileRequest godoc
+// @Summary Create a file request
+// @Description Create an upload-only link that collects files from people without an account into a folder
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.FileRequestCreateRequest true "File request details"
+// @Success 201 {object} utils.APIResponse "File request created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /file-requests [post]
+func (frc *FileRequestController) CreateFileRequest(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.FileRequestCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	fileRequest, err := frc.fileRequestService.CreateFileRequest(user.ID, req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	frc.auditService.LogEvent(&user.ID, models.ActionFileRequestCreate, models.ResourceFileRequest, &fileRequest.ID,
+		fmt.Sprintf("File request created: %s", fileRequest.Title), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusCreated, "File request created successfully", fileRequest.ToResponse(requestBaseURL(c)))
+}
+
+// GetFileRequests godoc
+// @Summary Get file requests
+// @Description Get the current user's file requests
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "File requests retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /file-requests [get]
+func (frc *FileRequestController) GetFileRequests(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	fileRequests, total, err := frc.fileRequestService.GetFileRequests(user.ID, page, limit)
+	if err != nil {
+		config.GetLogger().Error("Failed to get file requests", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve file requests")
+		return
+	}
+
+	baseURL := requestBaseURL(c)
+	responses := make([]models.FileRequestResponse, 0, len(fileRequests))
+	for _, fileRequest := range fileRequests {
+		responses = append(responses, fileRequest.ToResponse(baseURL))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "File requests retrieved successfully", gin.H{
+		"file_requests": responses,
+		"total":         total,
+		"current_page":  page,
+		"total_pages":   int((total + int64(limit) - 1) / int64(limit)),
+		"page_size":     limit,
+	})
+}
+
+// GetFileRequest godoc
+// @Summary Get a file request
+// @Description Get a file request owned by the current user
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File request ID"
+// @Success 200 {object} utils.APIResponse "File request retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file request ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Router /file-requests/{id} [get]
+func (frc *FileRequestController) GetFileRequest(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file request ID")
+		return
+	}
+
+	fileRequest, err := frc.fileRequestService.GetFileRequest(user.ID, requestID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "File request retrieved successfully", fileRequest.ToResponse(requestBaseURL(c)))
+}
+
+// UpdateFileRequest godoc
+// @Summary Update a file request
+// @Description Update the details and limits of a file request, or close and reopen it
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File request ID"
+// @Param request body models.FileRequestUpdateRequest true "Fields to update"
+// @Success 200 {object} utils.APIResponse "File request updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Router /file-requests/{id} [put]
+func (frc *FileRequestController) UpdateFileRequest(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file request ID")
+		return
+	}
+
+	var req models.FileRequestUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	fileRequest, err := frc.fileRequestService.UpdateFileRequest(user.ID, requestID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrFileRequestNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+			return
+		}
+		config.GetLogger().Error("Failed to update file request", "error", err, "request_id", requestID)
+		utils.InternalServerErrorResponse(c, "Failed to update file request")
+		return
+	}
+
+	frc.auditService.LogEvent(&user.ID, models.ActionFileRequestUpdate, models.ResourceFileRequest, &fileRequest.ID,
+		fmt.Sprintf("File request updated: %s", fileRequest.Title), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "File request updated successfully", fileRequest.ToResponse(requestBaseURL(c)))
+}
+
+// DeleteFileRequest godoc
+// @Summary Delete a file request
+// @Description Delete a file request. Files it collected stay in the target folder.
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File request ID"
+// @Success 200 {object} utils.APIResponse "File request deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file request ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Router /file-requests/{id} [delete]
+func (frc *FileRequestController) DeleteFileRequest(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file request ID")
+		return
+	}
+
+	if err := frc.fileRequestService.DeleteFileRequest(user.ID, requestID); err != nil {
+		if errors.Is(err, services.ErrFileRequestNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+			return
+		}
+		config.GetLogger().Error("Failed to delete file request", "error", err, "request_id", requestID)
+		utils.InternalServerErrorResponse(c, "Failed to delete file request")
+		return
+	}
+
+	frc.auditService.LogEvent(&user.ID, models.ActionFileRequestDelete, models.ResourceFileRequest, &requestID,
+		"File request deleted", c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "File request deleted successfully", nil)
+}
+
+// GetFileRequestUploads godoc
+// @Summary Get files received by a file request
+// @Description Get the files uploaded to a file request with the uploader details
+// @Tags file-requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File request ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Uploads retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file request ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Router /file-requests/{id}/uploads [get]
+func (frc *FileRequestController) GetFileRequestUploads(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file request ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	files, total, err := frc.fileRequestService.GetFileRequestUploads(user.ID, requestID, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrFileRequestNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+			return
+		}
+		config.GetLogger().Error("Failed to get file request uploads", "error", err, "request_id", requestID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve uploads")
+		return
+	}
+
+	responses := make([]models.FileResponse, 0, len(files))
+	for _, file := range files {
+		responses = append(responses, file.ToResponse())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Uploads retrieved successfully", gin.H{
+		"files":        responses,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  int((total + int64(limit) - 1) / int64(limit)),
+		"page_size":    limit,
+	})
+}
+
+// GetPublicFileRequest godoc
+// @Summary Get a public file request
+// @Description Get the title, instructions and limits of a file request. The target folder's contents are never shown.
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "File request token"
+// @Success 200 {object} utils.APIResponse "File request retrieved successfully"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Router /public/requests/{token} [get]
+func (frc *FileRequestController) GetPublicFileRequest(c *gin.Context) {
+	fileRequest, err := frc.fileRequestService.GetFileRequestByToken(c.Param("token"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+		return
+	}
+
+	limits, ok := planLimits(c, frc.planService, fileRequest.UserID)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "File request retrieved successfully", fileRequest.ToPublicInfo(limits.MaxFileSize))
+}
+
+// UploadToFileRequest godoc
+// @Summary Upload files to a file request
+// @Description Upload files to a file request without an account. Files land in the request's target folder with the uploader details recorded.
+// @Tags public
+// @Accept multipart/form-data
+// @Produce json
+// @Param token path string true "File request token"
+// @Param files formData file true "Files to upload"
+// @Param uploader_name formData string false "Uploader name"
+// @Param uploader_email formData string false "Uploader email, required when the request says so"
+// @Success 201 {object} utils.APIResponse "Files uploaded successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 404 {object} utils.APIResponse "File request not found"
+// @Failure 410 {object} utils.APIResponse "File request closed"
+// @Failure 413 {object} utils.APIResponse "File too large or the owner's storage is full"
+// @Failure 415 {object} utils.APIResponse "File type not allowed"
+// @Router /public/requests/{token}/upload [post]
+func (frc *FileRequestController) UploadToFileRequest(c *gin.Context) {
+	fileRequest, err := frc.fileRequestService.GetFileRequestByToken(c.Param("token"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File request not found")
+		return
+	}
+
+	if !fileRequest.IsOpen() {
+		utils.ErrorResponse(c, http.StatusGone, "File request is no longer accepting files")
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+
+	files := c.Request.MultipartForm.File["files"]
+	if len(files) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No files provided")
+		return
+	}
+	if len(files) > maxFileRequestUploadFiles {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d files can be uploaded at once", maxFileRequestUploadFiles))
+		return
+	}
+
+	uploader, err := readUploader(c, "")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if fileRequest.RequireEmail && uploader.Email == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Uploader email is required")
+		return
+	}
+
+	limits, ok := planLimits(c, frc.planService, fileRequest.UserID)
+	if !ok || !checkUploadPolicy(c, files, fileRequest.EffectiveMaxFileSize(limits.MaxFileSize)) {
+		return
+	}
+
+	uploadedBy := describeUploader(uploader)
+	var uploaded []models.FileRequestUploadResult
+	var names []string
+	var uploadErrors []string
+	closed, overQuota := 0, 0
+	for _, header := range files {
+		file, err := frc.fileRequestService.SaveUpload(c.Request.Context(), fileRequest, header, uploader)
+		if err != nil {
+			if errors.Is(err, services.ErrFileRequestClosed) {
+				closed++
+				uploadErrors = append(uploadErrors, fmt.Sprintf("%s was not accepted, the file request is closed or full", header.Filename))
+				continue
+			}
+			if errors.Is(err, services.ErrQuotaExceeded) {
+				overQuota++
+				uploadErrors = append(uploadErrors, fmt.Sprintf("%s was not accepted, the owner's storage is full", header.Filename))
+				continue
+			}
+			config.GetLogger().Error("Failed to save file request upload", "error", err, "request_id", fileRequest.ID, "file", header.Filename)
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Failed to upload %s", header.Filename))
+			continue
+		}
+
+		frc.auditService.LogEvent(&fileRequest.UserID, models.ActionFileRequestUpload, models.ResourceFile, &file.ID,
+			fmt.Sprintf("File uploaded to file request %q by %s: %s", fileRequest.Title, uploadedBy, file.OriginalName),
+			c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+		uploaded = append(uploaded, models.FileRequestUploadResult{Name: file.OriginalName, Size: file.FileSize})
+		names = append(names, file.OriginalName)
+	}
+
+	if len(uploaded) == 0 {
+		if closed == len(files) {
+			utils.ErrorResponse(c, http.StatusGone, "File request is no longer accepting files")
+			return
+		}
+		if overQuota == len(files) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "The owner's storage is full")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to upload files")
+		return
+	}
+
+	message := fmt.Sprintf("%s uploaded %d file(s) to %q: %s", uploadedBy, len(names), fileRequest.Title, summarizeNames(names, 5))
+	if _, err := frc.notificationService.Notify(fileRequest.UserID, models.NotificationFileRequestUpload,
+		"New files for "+fileRequest.Title, message, models.ResourceFileRequest, &fileRequest.ID); err != nil {
+		config.GetLogger().Error("Failed to notify file request owner", "error", err, "request_id", fileRequest.ID)
+	}
+
+	response := gin.H{
+		"uploaded_files": uploaded,
+		"total_files":    len(files),
+		"success_count":  len(uploaded),
+		"error_count":    len(uploadErrors),
+	}
+	if len(uploadErrors) > 0 {
+		response["errors"] = uploadErrors
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Files uploaded successfully", response)
+}
+
+// requestBaseURL returns the scheme and host the request was made to
+func requestBaseURL(c *gin.Context) string {
+	if c.Request.TLS == nil {
+		return "http://" + c.Request.Host
+	}
+	return "https://" + c.Request.Host
+}
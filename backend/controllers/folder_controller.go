@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -17,18 +18,26 @@ import (
 )
 
 type FolderController struct {
-	auditService        *services.AuditService
-	trashService        *services.TrashService
-	collaboratorService *services.CollaboratorService
-	logger              *slog.Logger
+	auditService         *services.AuditService
+	trashService         *services.TrashService
+	collaboratorService  *services.CollaboratorService
+	inviteService        *services.InviteService
+	fileAccessService    *services.FileAccessService
+	zipService           *services.ZipService
+	authorizationService *services.AuthorizationService
+	logger               *slog.Logger
 }
 
 func NewFolderController() *FolderController {
 	return &FolderController{
-		auditService:        services.NewAuditService(),
-		trashService:        services.NewTrashService(),
-		collaboratorService: services.NewCollaboratorService(),
-		logger:              config.GetLogger(),
+		auditService:         services.NewAuditService(),
+		trashService:         services.NewTrashService(),
+		collaboratorService:  services.NewCollaboratorService(),
+		inviteService:        services.NewInviteService(),
+		fileAccessService:    services.NewFileAccessService(),
+		zipService:           services.NewZipService(),
+		authorizationService: services.NewAuthorizationService(),
+		logger:               config.GetLogger(),
 	}
 }
 
@@ -57,18 +66,27 @@ func (fc *FolderController) CreateFolder(c *gin.Context) {
 		return
 	}
 
-	// Check if parent folder exists and user has access
+	// Check if parent folder exists and user can edit it. Folders created inside a folder shared
+	// with the user belong to the parent's owner, like everything else in that tree.
+	ownerID, userID := user.ID, user.ID
+	var driveID *uuid.UUID
 	if req.ParentID != nil {
-		var parentFolder models.Folder
-		if err := database.GetDB().Where("id = ? AND user_id = ?", *req.ParentID, user.ID).First(&parentFolder).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "Parent folder not found or access denied")
+		parentFolder, err := fc.authorizationService.AuthorizeFolder(user.ID, *req.ParentID, models.RoleEditor)
+		if err != nil || parentFolder.IsTrashed {
+			if err == nil || errors.Is(err, services.ErrResourceNotFound) || errors.Is(err, services.ErrAccessDenied) {
+				utils.ErrorResponse(c, http.StatusNotFound, "Parent folder not found or access denied")
+			} else {
+				accessErrorResponse(c, err, "Folder")
+			}
 			return
 		}
+		ownerID = parentFolder.OwnerID()
+		userID, driveID = parentFolder.ContentOwner(user.ID)
 	}
 
 	// Check if folder with same name already exists in the same parent
 	var existingFolder models.Folder
-	query := database.GetDB().Where("user_id = ? AND name = ?", user.ID, req.Name)
+	query := database.GetDB().Where("name = ?", req.Name).Scopes(models.OwnedBy(ownerID))
 	if req.ParentID != nil {
 		query = query.Where("parent_id = ?", *req.ParentID)
 	} else {
@@ -81,7 +99,8 @@ func (fc *FolderController) CreateFolder(c *gin.Context) {
 	}
 
 	folder := models.Folder{
-		UserID:   user.ID,
+		UserID:   userID,
+		DriveID:  driveID,
 		ParentID: req.ParentID,
 		Name:     req.Name,
 		Color:    req.Color,
@@ -147,21 +166,25 @@ func (fc *FolderController) GetFolders(c *gin.Context) {
 		}
 		parentID = &parsed
 
-		// Verify user has access to parent folder
-		var parentFolder models.Folder
-		if err := database.GetDB().Where("id = ? AND user_id = ?", *parentID, user.ID).First(&parentFolder).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "Parent folder not found or access denied")
+		// Verify user has access to parent folder, directly or through a folder above it
+		if _, err := fc.authorizationService.AuthorizeFolder(user.ID, *parentID, models.RoleViewer); err != nil {
+			if errors.Is(err, services.ErrResourceNotFound) || errors.Is(err, services.ErrAccessDenied) {
+				utils.ErrorResponse(c, http.StatusNotFound, "Parent folder not found or access denied")
+			} else {
+				accessErrorResponse(c, err, "Folder")
+			}
 			return
 		}
 	}
 	fc.logger.Info("Getting folders", "user_id", user.ID, "parent_id", parentID, "search", search)
 
-	// Build query for folders (exclude trashed items)
-	folderQuery := database.GetDB().Model(&models.Folder{}).Where("user_id = ? AND is_trashed = false", user.ID)
+	// Build query for folders (exclude trashed items). Everything inside a folder the user can
+	// view is listed, whoever owns it.
+	folderQuery := database.GetDB().Model(&models.Folder{}).Where("is_trashed = false")
 	if parentID != nil {
 		folderQuery = folderQuery.Where("parent_id = ?", *parentID)
 	} else {
-		folderQuery = folderQuery.Where("parent_id IS NULL")
+		folderQuery = folderQuery.Where("parent_id IS NULL").Scopes(models.OwnedBy(user.ID))
 	}
 
 	if search != "" {
@@ -169,11 +192,11 @@ func (fc *FolderController) GetFolders(c *gin.Context) {
 	}
 
 	// Build query for files (exclude trashed items)
-	fileQuery := database.GetDB().Model(&models.File{}).Where("user_id = ? AND is_trashed = false", user.ID)
+	fileQuery := database.GetDB().Model(&models.File{}).Where("is_trashed = false")
 	if parentID != nil {
 		fileQuery = fileQuery.Where("folder_id = ?", *parentID)
 	} else {
-		fileQuery = fileQuery.Where("folder_id IS NULL")
+		fileQuery = fileQuery.Where("folder_id IS NULL").Scopes(models.OwnedBy(user.ID))
 	}
 
 	if search != "" {
@@ -222,6 +245,7 @@ func (fc *FolderController) GetFolders(c *gin.Context) {
 		var currentFolder models.Folder
 		if err := database.GetDB().Where("id = ?", *parentID).First(&currentFolder).Error; err == nil {
 			breadcrumbs, _ = currentFolder.GetBreadcrumbs(database.GetDB())
+			breadcrumbs = fc.visibleBreadcrumbs(user.ID, breadcrumbs)
 		}
 	}
 
@@ -241,6 +265,17 @@ func (fc *FolderController) GetFolders(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Directory contents retrieved successfully", response)
 }
 
+// visibleBreadcrumbs drops the leading folders the user cannot view, so a collaborator browsing
+// a shared folder does not see the names of the owner's folders above it
+func (fc *FolderController) visibleBreadcrumbs(userID uuid.UUID, breadcrumbs []models.Breadcrumb) []models.Breadcrumb {
+	for i, crumb := range breadcrumbs {
+		if _, err := fc.authorizationService.AuthorizeFolder(userID, crumb.ID, models.RoleViewer); err == nil {
+			return breadcrumbs[i:]
+		}
+	}
+	return nil
+}
+
 // GetSharedWithMeFolders godoc
 // @Summary Get folders shared with me
 // @Description Get folders that have been shared with the current user as a collaborator
@@ -274,65 +309,26 @@ func (fc *FolderController) GetSharedWithMeFolders(c *gin.Context) {
 		limit = 10
 	}
 
-	// Get folders where user is a collaborator (not the owner)
+	folderIDs, err := fc.authorizationService.GrantedFolderIDs(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve shared folders")
+		return
+	}
+
+	// Get folders shared with the user, directly or through a folder above them, that they do not own
 	query := database.GetDB().Model(&models.Folder{}).
-		Joins("LEFT JOIN collaborators ON folders.id = collaborators.folder_id").
-		Where("collaborators.user_id = ? AND folders.is_trashed = false", user.ID)
+		Where("folders.id IN ? AND (folders.drive_id IS NOT NULL OR folders.user_id <> ?) AND folders.is_trashed = false", folderIDs, user.ID)
 
 	if search != "" {
 		query = query.Where("name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	var total int64
-	countQuery := database.GetDB().Model(&models.Folder{}).
-		Joins("LEFT JOIN collaborators ON folders.id = collaborators.folder_id").
-		Where("collaborators.user_id = ? AND folders.is_trashed = false", user.ID)
-
-	if search != "" {
-		countQuery = countQuery.Where("name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	countQuery.Distinct("folders.id").Count(&total)
+	query.Count(&total)
 
 	var folders []models.Folder
 	offset := (page - 1) * limit
-
-	// Get distinct folder IDs with proper ordering using window function
-	folderIDsQuery := database.GetDB().Raw(`
-		SELECT id FROM (
-			SELECT DISTINCT folders.id, ROW_NUMBER() OVER (ORDER BY folders.created_at DESC) as rn
-			FROM folders 
-			LEFT JOIN collaborators ON folders.id = collaborators.folder_id 
-			WHERE collaborators.user_id = ? AND folders.is_trashed = false AND folders.deleted_at IS NULL
-		) ranked_folders 
-		WHERE rn > ? AND rn <= ?
-	`, user.ID, offset, offset+limit)
-
-	if search != "" {
-		folderIDsQuery = database.GetDB().Raw(`
-			SELECT id FROM (
-				SELECT DISTINCT folders.id, ROW_NUMBER() OVER (ORDER BY folders.created_at DESC) as rn
-				FROM folders 
-				LEFT JOIN collaborators ON folders.id = collaborators.folder_id 
-				WHERE collaborators.user_id = ? AND folders.is_trashed = false AND folders.deleted_at IS NULL
-				AND (folders.name LIKE ? OR folders.description LIKE ?)
-			) ranked_folders 
-			WHERE rn > ? AND rn <= ?
-		`, user.ID, "%"+search+"%", "%"+search+"%", offset, offset+limit)
-	}
-
-	var folderIDs []string
-	if err := folderIDsQuery.Scan(&folderIDs).Error; err != nil {
-		utils.InternalServerErrorResponse(c, "Failed to retrieve shared folders")
-		return
-	}
-
-	// Get the actual folders using the IDs
-	if err := database.GetDB().Model(&models.Folder{}).
-		Preload("User").
-		Where("folders.id IN (?)", folderIDs).
-		Order("folders.created_at DESC").
-		Find(&folders).Error; err != nil {
+	if err := query.Preload("User").Order("folders.created_at DESC").Offset(offset).Limit(limit).Find(&folders).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to retrieve shared folders")
 		return
 	}
@@ -374,6 +370,7 @@ func (fc *FolderController) GetSharedWithMeFolders(c *gin.Context) {
 // @Success 200 {object} utils.APIResponse "Folder retrieved successfully"
 // @Failure 400 {object} utils.APIResponse "Invalid folder ID"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
 // @Failure 404 {object} utils.APIResponse "Folder not found"
 // @Router /folders/{id} [get]
 func (fc *FolderController) GetFolder(c *gin.Context) {
@@ -390,10 +387,14 @@ func (fc *FolderController) GetFolder(c *gin.Context) {
 	}
 
 	var folder models.Folder
-	if err := database.GetDB().Preload("User").Where("id = ? AND user_id = ?", folderID, user.ID).First(&folder).Error; err != nil {
+	if err := database.GetDB().Preload("User").Where("id = ?", folderID).First(&folder).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
 		return
 	}
+	if err := fc.authorizationService.RequireFolder(user.ID, &folder, models.RoleViewer); err != nil {
+		accessErrorResponse(c, err, "Folder")
+		return
+	}
 
 	// Get counts
 	var fileCount, subfolderCount int64
@@ -419,6 +420,7 @@ func (fc *FolderController) GetFolder(c *gin.Context) {
 // @Success 200 {object} utils.APIResponse "Folder updated successfully"
 // @Failure 400 {object} utils.APIResponse "Validation error"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Editor access required"
 // @Failure 404 {object} utils.APIResponse "Folder not found"
 // @Router /folders/{id} [put]
 func (fc *FolderController) UpdateFolder(c *gin.Context) {
@@ -439,15 +441,20 @@ func (fc *FolderController) UpdateFolder(c *gin.Context) {
 		return
 	}
 
+	// Editors and owners get here, FolderRoleMiddleware has checked the role
 	var folder models.Folder
-	if err := database.GetDB().Where("id = ? AND user_id = ?", folderID, user.ID).First(&folder).Error; err != nil {
+	if err := database.GetDB().Where("id = ?", folderID).First(&folder).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
 		return
 	}
+	if services.IsDriveRoot(folder.ID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, services.ErrDriveRootFolder.Error())
+		return
+	}
 
 	// Check if folder with same name already exists in the same parent (excluding current folder)
 	var existingFolder models.Folder
-	query := database.GetDB().Where("user_id = ? AND name = ? AND id != ?", user.ID, req.Name, folder.ID)
+	query := database.GetDB().Where("name = ? AND id != ?", req.Name, folder.ID).Scopes(models.OwnedBy(folder.OwnerID()))
 	if folder.ParentID != nil {
 		query = query.Where("parent_id = ?", *folder.ParentID)
 	} else {
@@ -488,6 +495,7 @@ func (fc *FolderController) UpdateFolder(c *gin.Context) {
 // @Success 200 {object} utils.APIResponse "Folder moved successfully"
 // @Failure 400 {object} utils.APIResponse "Validation error"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Editor access required"
 // @Failure 404 {object} utils.APIResponse "Folder not found"
 // @Router /folders/{id}/move [post]
 func (fc *FolderController) MoveFolder(c *gin.Context) {
@@ -508,17 +516,26 @@ func (fc *FolderController) MoveFolder(c *gin.Context) {
 		return
 	}
 
+	// Editors and owners get here, FolderRoleMiddleware has checked the role
 	var folder models.Folder
-	if err := database.GetDB().Where("id = ? AND user_id = ?", folderID, user.ID).First(&folder).Error; err != nil {
+	if err := database.GetDB().Where("id = ?", folderID).First(&folder).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
 		return
 	}
+	if services.IsDriveRoot(folder.ID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, services.ErrDriveRootFolder.Error())
+		return
+	}
 
-	// Check if destination parent exists and user has access
+	// The destination must be a folder of the same owner the user can edit, only the owner can
+	// move the folder to the top level
+	if req.ParentID == nil && folder.OwnerID() != user.ID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the owner can move a folder to the top level")
+		return
+	}
 	if req.ParentID != nil {
-		var parentFolder models.Folder
-		if err := database.GetDB().Where("id = ? AND user_id = ?", *req.ParentID, user.ID).First(&parentFolder).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "Destination folder not found or access denied")
+		if _, err := fc.authorizationService.AuthorizeDestination(user.ID, *req.ParentID, folder.OwnerID()); err != nil {
+			destinationErrorResponse(c, err)
 			return
 		}
 
@@ -541,7 +558,7 @@ func (fc *FolderController) MoveFolder(c *gin.Context) {
 
 	// Check if folder with same name already exists in destination
 	var existingFolder models.Folder
-	query := database.GetDB().Where("user_id = ? AND name = ? AND id != ?", user.ID, folder.Name, folder.ID)
+	query := database.GetDB().Where("name = ? AND id != ?", folder.Name, folder.ID).Scopes(models.OwnedBy(folder.OwnerID()))
 	if req.ParentID != nil {
 		query = query.Where("parent_id = ?", *req.ParentID)
 	} else {
@@ -560,6 +577,8 @@ func (fc *FolderController) MoveFolder(c *gin.Context) {
 		utils.InternalServerErrorResponse(c, "Failed to move folder")
 		return
 	}
+	// Access inherited from the old and new parent folders changes with the move
+	services.InvalidatePermissions()
 
 	fc.auditService.LogEvent(&user.ID, models.ActionFolderMove, models.ResourceFolder, &folder.ID,
 		fmt.Sprintf("Folder moved: %s", folder.Name), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
@@ -578,6 +597,7 @@ func (fc *FolderController) MoveFolder(c *gin.Context) {
 // @Success 200 {object} utils.APIResponse "Folder deleted successfully"
 // @Failure 400 {object} utils.APIResponse "Invalid folder ID"
 // @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Only the owner can delete a folder"
 // @Failure 404 {object} utils.APIResponse "Folder not found"
 // @Router /folders/{id} [delete]
 func (fc *FolderController) DeleteFolder(c *gin.Context) {
@@ -593,14 +613,19 @@ func (fc *FolderController) DeleteFolder(c *gin.Context) {
 		return
 	}
 
+	ownerID := resourceOwner(c, user)
 	var folder models.Folder
-	if err := database.GetDB().Where("id = ? AND user_id = ?", folderID, user.ID).First(&folder).Error; err != nil {
+	if err := database.GetDB().Where("id = ?", folderID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
 		return
 	}
+	if services.IsDriveRoot(folder.ID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, services.ErrDriveRootFolder.Error())
+		return
+	}
 
 	// Move folder and all its contents to trash instead of permanently deleting
-	if err := fc.trashService.MoveFolderToTrash(user.ID, folderID); err != nil {
+	if err := fc.trashService.MoveFolderToTrash(ownerID, folderID); err != nil {
 		fc.logger.Error("Failed to move folder to trash", "error", err, "folder_id", folder.ID)
 		utils.InternalServerErrorResponse(c, "Failed to move folder to trash")
 		return
@@ -626,7 +651,7 @@ func (fc *FolderController) GetCollaborators(c *gin.Context) {
 		return
 	}
 
-	collaborators, err := fc.collaboratorService.GetCollaborators(user.ID, folderID, false)
+	collaborators, err := fc.collaboratorService.GetCollaborators(resourceOwner(c, user), folderID, false)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -640,7 +665,8 @@ func (fc *FolderController) GetCollaborators(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Collaborators fetched successfully", responses)
 }
 
-// AddCollaborator adds a collaborator to a folder with a specific role
+// AddCollaborator adds a collaborator to a folder with a specific role, email addresses without
+// an account are sent an invite
 func (fc *FolderController) AddCollaborator(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
@@ -659,7 +685,11 @@ func (fc *FolderController) AddCollaborator(c *gin.Context) {
 		return
 	}
 
-	collaborator, err := fc.collaboratorService.AddCollaborator(user.ID, folderID, req, false)
+	collaborator, err := fc.collaboratorService.AddCollaborator(resourceOwner(c, user), folderID, req, false)
+	if errors.Is(err, services.ErrCollaboratorNotRegistered) {
+		createInvite(c, fc.inviteService, fc.auditService, user, folderID, req, false)
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -693,7 +723,7 @@ func (fc *FolderController) UpdateCollaborator(c *gin.Context) {
 		return
 	}
 
-	collaborator, err := fc.collaboratorService.UpdateCollaborator(user.ID, folderID, collaboratorID, req, false)
+	collaborator, err := fc.collaboratorService.UpdateCollaborator(resourceOwner(c, user), folderID, collaboratorID, req, false)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -722,10 +752,61 @@ func (fc *FolderController) RemoveCollaborator(c *gin.Context) {
 		return
 	}
 
-	if err := fc.collaboratorService.RemoveCollaborator(user.ID, folderID, collaboratorID, false); err != nil {
+	if err := fc.collaboratorService.RemoveCollaborator(resourceOwner(c, user), folderID, collaboratorID, false); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Collaborator removed", nil)
 }
+
+// DownloadFolder godoc
+// @Summary Download a folder as a zip
+// @Description Stream a zip archive of the folder and all of its subfolders
+// @Tags folders
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Success 200 {file} binary "Zip archive"
+// @Failure 400 {object} utils.APIResponse "Invalid folder ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Access denied"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Router /folders/{id}/download [get]
+func (fc *FolderController) DownloadFolder(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	var folder models.Folder
+	if err := database.GetDB().Where("id = ? AND is_trashed = ?", folderID, false).First(&folder).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
+		return
+	}
+	if err := fc.authorizationService.RequireFolder(user.ID, &folder, models.RoleViewer); err != nil {
+		accessErrorResponse(c, err, "Folder")
+		return
+	}
+
+	items, err := fc.zipService.CollectFolder(&folder)
+	if err != nil {
+		fc.logger.Error("Failed to collect folder contents", "error", err, "folder_id", folder.ID)
+		utils.InternalServerErrorResponse(c, "Failed to collect folder contents")
+		return
+	}
+
+	fc.auditService.LogEvent(&user.ID, models.ActionFolderDownload, models.ResourceFolder, &folder.ID,
+		fmt.Sprintf("Folder downloaded: %s", folder.Name), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	streamZip(c, fc.zipService, folder.Name+".zip", items, func(file *models.File) {
+		fc.fileAccessService.LogFileAccess(user.ID, file.ID, models.ActionDownload)
+	})
+}
@@ -0,0 +1,567 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// GroupController serves the group routes for users and for admins. Both sets of handlers share
+// their implementation, the admin ones act on any group and create admin-managed groups.
+type GroupController struct {
+	groupService *services.GroupService
+	auditService *services.AuditService
+}
+
+func NewGroupController() *GroupController {
+	return &GroupController{
+		groupService: services.NewGroupService(),
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetGroups godoc
+// @Summary Get my groups
+// @Description Get the groups you are a member of, with your role in each
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param search query string false "Search term for group names"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Groups retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /groups [get]
+func (gc *GroupController) GetGroups(c *gin.Context) {
+	gc.listGroups(c, false)
+}
+
+// CreateGroup godoc
+// @Summary Create a group
+// @Description Create a group that files and folders can be shared with. You become its first manager.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.GroupCreateRequest true "Group name and description"
+// @Success 201 {object} utils.APIResponse "Group created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /groups [post]
+func (gc *GroupController) CreateGroup(c *gin.Context) {
+	gc.createGroup(c, false)
+}
+
+// GetGroup godoc
+// @Summary Get a group
+// @Description Get a group you are a member of, with its members
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} utils.APIResponse "Group retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /groups/{id} [get]
+func (gc *GroupController) GetGroup(c *gin.Context) {
+	gc.getGroup(c, false)
+}
+
+// UpdateGroup godoc
+// @Summary Update a group
+// @Description Rename a group or change its description. Only managers can change a group, admin-managed groups can only be changed by admins.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.GroupUpdateRequest true "New name or description"
+// @Success 200 {object} utils.APIResponse "Group updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager of the group"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /groups/{id} [put]
+func (gc *GroupController) UpdateGroup(c *gin.Context) {
+	gc.updateGroup(c, false)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Delete a group. Everything shared with the group stops being shared with its members.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} utils.APIResponse "Group deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager of the group"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /groups/{id} [delete]
+func (gc *GroupController) DeleteGroup(c *gin.Context) {
+	gc.deleteGroup(c, false)
+}
+
+// AddGroupMember godoc
+// @Summary Add a group member
+// @Description Add a user to a group you manage, as a member unless another role is given
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.AddGroupMemberRequest true "User and role"
+// @Success 201 {object} utils.APIResponse "Group member added successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or user"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager of the group"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Failure 409 {object} utils.APIResponse "User is already a member"
+// @Router /groups/{id}/members [post]
+func (gc *GroupController) AddGroupMember(c *gin.Context) {
+	gc.addMember(c, false)
+}
+
+// UpdateGroupMember godoc
+// @Summary Change a member's role
+// @Description Make a member of a group you manage a manager, or a manager a member again
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Param request body models.UpdateGroupMemberRequest true "New role"
+// @Success 200 {object} utils.APIResponse "Group member updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager of the group"
+// @Failure 404 {object} utils.APIResponse "Group or member not found"
+// @Failure 409 {object} utils.APIResponse "The group would be left without a manager"
+// @Router /groups/{id}/members/{userId} [put]
+func (gc *GroupController) UpdateGroupMember(c *gin.Context) {
+	gc.updateMember(c, false)
+}
+
+// RemoveGroupMember godoc
+// @Summary Remove a group member
+// @Description Remove a member from a group you manage, or leave a group by removing yourself
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} utils.APIResponse "Group member removed successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group or user ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager of the group"
+// @Failure 404 {object} utils.APIResponse "Group or member not found"
+// @Failure 409 {object} utils.APIResponse "The group would be left without a manager"
+// @Router /groups/{id}/members/{userId} [delete]
+func (gc *GroupController) RemoveGroupMember(c *gin.Context) {
+	gc.removeMember(c, false)
+}
+
+// AdminGetGroups godoc
+// @Summary Get all groups
+// @Description Get every group, admin-managed or not
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param search query string false "Search term for group names"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Groups retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Router /admin/groups [get]
+func (gc *GroupController) AdminGetGroups(c *gin.Context) {
+	gc.listGroups(c, true)
+}
+
+// AdminCreateGroup godoc
+// @Summary Create an admin-managed group
+// @Description Create a group that only admins can change. It starts without members.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.GroupCreateRequest true "Group name and description"
+// @Success 201 {object} utils.APIResponse "Group created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Router /admin/groups [post]
+func (gc *GroupController) AdminCreateGroup(c *gin.Context) {
+	gc.createGroup(c, true)
+}
+
+// AdminGetGroup godoc
+// @Summary Get any group
+// @Description Get a group with its members
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} utils.APIResponse "Group retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /admin/groups/{id} [get]
+func (gc *GroupController) AdminGetGroup(c *gin.Context) {
+	gc.getGroup(c, true)
+}
+
+// AdminUpdateGroup godoc
+// @Summary Update any group
+// @Description Rename a group or change its description
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.GroupUpdateRequest true "New name or description"
+// @Success 200 {object} utils.APIResponse "Group updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /admin/groups/{id} [put]
+func (gc *GroupController) AdminUpdateGroup(c *gin.Context) {
+	gc.updateGroup(c, true)
+}
+
+// AdminDeleteGroup godoc
+// @Summary Delete any group
+// @Description Delete a group together with everything shared with it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Success 200 {object} utils.APIResponse "Group deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Router /admin/groups/{id} [delete]
+func (gc *GroupController) AdminDeleteGroup(c *gin.Context) {
+	gc.deleteGroup(c, true)
+}
+
+// AdminAddGroupMember godoc
+// @Summary Add a member to any group
+// @Description Add a user to a group. Admin-managed groups only have members.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param request body models.AddGroupMemberRequest true "User and role"
+// @Success 201 {object} utils.APIResponse "Group member added successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or user"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group not found"
+// @Failure 409 {object} utils.APIResponse "User is already a member"
+// @Router /admin/groups/{id}/members [post]
+func (gc *GroupController) AdminAddGroupMember(c *gin.Context) {
+	gc.addMember(c, true)
+}
+
+// AdminUpdateGroupMember godoc
+// @Summary Change a member's role in any group
+// @Description Change the role of a member of a group run by users
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Param request body models.UpdateGroupMemberRequest true "New role"
+// @Success 200 {object} utils.APIResponse "Group member updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group or member not found"
+// @Failure 409 {object} utils.APIResponse "The group would be left without a manager"
+// @Router /admin/groups/{id}/members/{userId} [put]
+func (gc *GroupController) AdminUpdateGroupMember(c *gin.Context) {
+	gc.updateMember(c, true)
+}
+
+// AdminRemoveGroupMember godoc
+// @Summary Remove a member from any group
+// @Description Remove a user from a group
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} utils.APIResponse "Group member removed successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid group or user ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Group or member not found"
+// @Failure 409 {object} utils.APIResponse "The group would be left without a manager"
+// @Router /admin/groups/{id}/members/{userId} [delete]
+func (gc *GroupController) AdminRemoveGroupMember(c *gin.Context) {
+	gc.removeMember(c, true)
+}
+
+func (gc *GroupController) listGroups(c *gin.Context, asAdmin bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	search := c.Query("search")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	actor := services.GroupActor{UserID: user.ID, IsAdmin: asAdmin}
+	groups, total, err := gc.groupService.ListGroups(actor, search, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve groups")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Groups retrieved successfully", gin.H{
+		"groups":       groups,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
+
+func (gc *GroupController) createGroup(c *gin.Context, asAdmin bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.GroupCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	actor := services.GroupActor{UserID: user.ID, IsAdmin: asAdmin}
+	group, err := gc.groupService.CreateGroup(actor, req)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	gc.logGroupEvent(c, actor, models.ActionGroupCreate, group, fmt.Sprintf("Group %q created", group.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Group created successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) getGroup(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+
+	group, err := gc.groupService.GetGroup(actor, groupID)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group retrieved successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) updateGroup(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+
+	var req models.GroupUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	group, err := gc.groupService.UpdateGroup(actor, groupID, req)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	gc.logGroupEvent(c, actor, models.ActionGroupUpdate, group, fmt.Sprintf("Group %q updated", group.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Group updated successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) deleteGroup(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+
+	group, err := gc.groupService.DeleteGroup(actor, groupID)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	gc.logGroupEvent(c, actor, models.ActionGroupDelete, group, fmt.Sprintf("Group %q deleted", group.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Group deleted successfully", nil)
+}
+
+func (gc *GroupController) addMember(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+
+	var req models.AddGroupMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	group, err := gc.groupService.AddMember(actor, groupID, req)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	gc.logGroupEvent(c, actor, models.ActionGroupMemberAdd, group,
+		fmt.Sprintf("User %s added to group %q", req.UserID, group.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Group member added successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) updateMember(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateGroupMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	group, err := gc.groupService.UpdateMember(actor, groupID, memberID, req)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	gc.logGroupEvent(c, actor, models.ActionGroupMemberUpdate, group,
+		fmt.Sprintf("User %s is now %s of group %q", memberID, req.Role, group.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Group member updated successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) removeMember(c *gin.Context, asAdmin bool) {
+	actor, groupID, ok := groupRequest(c, asAdmin)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	group, err := gc.groupService.RemoveMember(actor, groupID, memberID)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	details := fmt.Sprintf("User %s removed from group %q", memberID, group.Name)
+	if memberID == actor.UserID {
+		details = fmt.Sprintf("Left group %q", group.Name)
+	}
+	gc.logGroupEvent(c, actor, models.ActionGroupMemberRemove, group, details)
+
+	utils.SuccessResponse(c, http.StatusOK, "Group member removed successfully", groupResponse(group, actor))
+}
+
+func (gc *GroupController) logGroupEvent(c *gin.Context, actor services.GroupActor, action string, group *models.Group, details string) {
+	gc.auditService.LogEvent(&actor.UserID, action, models.ResourceGroup, &group.ID, details,
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+}
+
+// groupResponse converts a group loaded with its members, filling in the caller's role
+func groupResponse(group *models.Group, actor services.GroupActor) models.GroupResponse {
+	response := group.ToResponse()
+	for _, member := range group.Members {
+		if member.UserID == actor.UserID {
+			response.MyRole = member.Role
+		}
+	}
+	return response
+}
+
+// groupRequest reads the acting user and the group ID of a request on a single group
+func groupRequest(c *gin.Context, asAdmin bool) (services.GroupActor, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return services.GroupActor{}, uuid.Nil, false
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group ID")
+		return services.GroupActor{}, uuid.Nil, false
+	}
+	return services.GroupActor{UserID: user.ID, IsAdmin: asAdmin}, groupID, true
+}
+
+// groupErrorResponse maps group service errors to HTTP responses
+func groupErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGroupNotFound), errors.Is(err, services.ErrGroupMemberNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrGroupForbidden), errors.Is(err, services.ErrGroupAdminManaged):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrGroupMemberExists), errors.Is(err, services.ErrGroupLastManager):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrGroupInvalidUser), errors.Is(err, services.ErrGroupAdminManagedRole):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process group request")
+	}
+}
@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// InviteController serves the pending collaborator invites of files and folders. Invites are
+// created by adding a collaborator by an email address that has no account.
+type InviteController struct {
+	inviteService *services.InviteService
+	auditService  *services.AuditService
+}
+
+func NewInviteController() *InviteController {
+	return &InviteController{
+		inviteService: services.NewInviteService(),
+		auditService:  services.NewAuditService(),
+	}
+}
+
+// GetFileInvites godoc
+// @Summary Get pending file invites
+// @Description Get the invites to a file that have not been accepted yet, expired ones included
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Success 200 {object} utils.APIResponse "Invites retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Router /files/{id}/invites [get]
+func (ic *InviteController) GetFileInvites(c *gin.Context) {
+	ic.listInvites(c, true)
+}
+
+// ResendFileInvite godoc
+// @Summary Resend a file invite
+// @Description Email a pending invite again. The invite gets a new expiry and the link sent before stops working.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} utils.APIResponse "Invite sent successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File or invite not found"
+// @Failure 409 {object} utils.APIResponse "Invite is no longer pending"
+// @Failure 429 {object} utils.APIResponse "Invite was sent less than a minute ago"
+// @Failure 503 {object} utils.APIResponse "Email delivery is not configured"
+// @Router /files/{id}/invites/{inviteId}/resend [post]
+func (ic *InviteController) ResendFileInvite(c *gin.Context) {
+	ic.resendInvite(c, true)
+}
+
+// RevokeFileInvite godoc
+// @Summary Revoke a file invite
+// @Description Withdraw a pending invite, its link stops working
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} utils.APIResponse "Invite revoked successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File or invite not found"
+// @Failure 409 {object} utils.APIResponse "Invite is no longer pending"
+// @Router /files/{id}/invites/{inviteId} [delete]
+func (ic *InviteController) RevokeFileInvite(c *gin.Context) {
+	ic.revokeInvite(c, true)
+}
+
+// GetFolderInvites godoc
+// @Summary Get pending folder invites
+// @Description Get the invites to a folder that have not been accepted yet, expired ones included
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Success 200 {object} utils.APIResponse "Invites retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid folder ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Router /folders/{id}/invites [get]
+func (ic *InviteController) GetFolderInvites(c *gin.Context) {
+	ic.listInvites(c, false)
+}
+
+// ResendFolderInvite godoc
+// @Summary Resend a folder invite
+// @Description Email a pending invite again. The invite gets a new expiry and the link sent before stops working.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} utils.APIResponse "Invite sent successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Folder or invite not found"
+// @Failure 409 {object} utils.APIResponse "Invite is no longer pending"
+// @Failure 429 {object} utils.APIResponse "Invite was sent less than a minute ago"
+// @Failure 503 {object} utils.APIResponse "Email delivery is not configured"
+// @Router /folders/{id}/invites/{inviteId}/resend [post]
+func (ic *InviteController) ResendFolderInvite(c *gin.Context) {
+	ic.resendInvite(c, false)
+}
+
+// RevokeFolderInvite godoc
+// @Summary Revoke a folder invite
+// @Description Withdraw a pending invite, its link stops working
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} utils.APIResponse "Invite revoked successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Folder or invite not found"
+// @Failure 409 {object} utils.APIResponse "Invite is no longer pending"
+// @Router /folders/{id}/invites/{inviteId} [delete]
+func (ic *InviteController) RevokeFolderInvite(c *gin.Context) {
+	ic.revokeInvite(c, false)
+}
+
+// GetPublicInvite godoc
+// @Summary Get an invite
+// @Description Get what an invite link is for, so the invitee can sign up or sign in with the right address
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param token path string true "Invite token"
+// @Success 200 {object} utils.APIResponse "Invite retrieved successfully"
+// @Failure 404 {object} utils.APIResponse "Invalid invite link"
+// @Router /public/invites/{token} [get]
+func (ic *InviteController) GetPublicInvite(c *gin.Context) {
+	invite, err := ic.inviteService.GetInviteByToken(c.Param("token"))
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invite retrieved successfully", invite.ToPreview())
+}
+
+// AcceptInvite godoc
+// @Summary Accept an invite
+// @Description Accept an invite sent to your email address. Your address is marked verified and every pending invite to it becomes a collaborator entry.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AcceptInviteRequest true "Invite token"
+// @Success 200 {object} utils.APIResponse "Invite accepted successfully"
+// @Failure 400 {object} utils.APIResponse "Invite was sent to a different address"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Invalid invite link"
+// @Failure 409 {object} utils.APIResponse "Invite is no longer pending"
+// @Failure 410 {object} utils.APIResponse "Invite has expired"
+// @Router /invites/accept [post]
+func (ic *InviteController) AcceptInvite(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.AcceptInviteRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	invites, err := ic.inviteService.AcceptInvite(user, req.Token)
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]gin.H, 0, len(invites))
+	for i := range invites {
+		invite := &invites[i]
+		ic.auditService.LogEvent(&user.ID, models.ActionInviteAccept, models.ResourceInvite, &invite.ID,
+			fmt.Sprintf("Invite to %s %q accepted", invite.ItemType(), invite.ItemName()),
+			c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+		responses = append(responses, gin.H{
+			"item_type": invite.ItemType(),
+			"item_id":   invite.ItemID(),
+			"item_name": invite.ItemName(),
+			"role":      invite.Role,
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invite accepted successfully", gin.H{"accepted": responses})
+}
+
+func (ic *InviteController) listInvites(c *gin.Context, isFile bool) {
+	user, resourceID, ok := inviteItemRequest(c, isFile)
+	if !ok {
+		return
+	}
+
+	invites, err := ic.inviteService.ListInvites(user.ID, resourceID, isFile)
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	responses := make([]models.CollaboratorInviteResponse, 0, len(invites))
+	for i := range invites {
+		responses = append(responses, invites[i].ToResponse())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invites retrieved successfully", responses)
+}
+
+func (ic *InviteController) resendInvite(c *gin.Context, isFile bool) {
+	user, resourceID, ok := inviteItemRequest(c, isFile)
+	if !ok {
+		return
+	}
+	inviteID, err := uuid.Parse(c.Param("inviteId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invite ID")
+		return
+	}
+
+	invite, err := ic.inviteService.ResendInvite(*user, resourceID, inviteID, isFile)
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	ic.auditService.LogEvent(&user.ID, models.ActionInviteResend, models.ResourceInvite, &invite.ID,
+		fmt.Sprintf("Invite to %s %q sent again to %s", invite.ItemType(), invite.ItemName(), invite.Email),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Invite sent successfully", invite.ToResponse())
+}
+
+func (ic *InviteController) revokeInvite(c *gin.Context, isFile bool) {
+	user, resourceID, ok := inviteItemRequest(c, isFile)
+	if !ok {
+		return
+	}
+	inviteID, err := uuid.Parse(c.Param("inviteId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invite ID")
+		return
+	}
+
+	invite, err := ic.inviteService.RevokeInvite(user.ID, resourceID, inviteID, isFile)
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	ic.auditService.LogEvent(&user.ID, models.ActionInviteRevoke, models.ResourceInvite, &invite.ID,
+		fmt.Sprintf("Invite of %s to %s %q revoked", invite.Email, invite.ItemType(), invite.ItemName()),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Invite revoked successfully", invite.ToResponse())
+}
+
+// createInvite invites an email address without an account, for AddCollaborator on files and
+// folders
+func createInvite(c *gin.Context, inviteService *services.InviteService, auditService *services.AuditService,
+	owner *models.User, resourceID uuid.UUID, req models.AddCollaboratorRequest, isFile bool) {
+	invite, err := inviteService.CreateInvite(*owner, resourceID, req, isFile)
+	if err != nil {
+		inviteErrorResponse(c, err)
+		return
+	}
+
+	auditService.LogEvent(&owner.ID, models.ActionInviteCreate, models.ResourceInvite, &invite.ID,
+		fmt.Sprintf("%s invited to %s %q as %s", invite.Email, invite.ItemType(), invite.ItemName(), invite.Role),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	message := "Invite sent"
+	if invite.SendCount == 0 {
+		message = "Invite created, but it could not be emailed"
+	}
+	utils.SuccessResponse(c, http.StatusCreated, message, gin.H{"invite": invite.ToResponse()})
+}
+
+// inviteItemRequest reads the user and the file or folder ID of a request on its invites
+func inviteItemRequest(c *gin.Context, isFile bool) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	resourceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		if isFile {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+		}
+		return nil, uuid.Nil, false
+	}
+	return user, resourceID, true
+}
+
+// inviteErrorResponse maps invite service errors to HTTP responses
+func inviteErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInviteNotFound), errors.Is(err, services.ErrInviteItemNotFound),
+		errors.Is(err, services.ErrInviteInvalidToken):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInviteNotPending):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInviteExpired):
+		utils.ErrorResponse(c, http.StatusGone, err.Error())
+	case errors.Is(err, services.ErrInviteEmailMismatch), errors.Is(err, services.ErrInviteSelf):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInviteResendTooSoon):
+		utils.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrMailNotConfigured):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process invite")
+	}
+}
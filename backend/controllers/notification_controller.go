@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+type NotificationController struct {
+	notificationService *services.NotificationService
+	emailService        *services.EmailService
+}
+
+func NewNotificationController() *NotificationController {
+	return &NotificationController{
+		notificationService: services.NewNotificationService(),
+		emailService:        services.NewEmailService(),
+	}
+}
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get the current user's notifications, newest first
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} utils.APIResponse "Notifications retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /notifications [get]
+func (nc *NotificationController) GetNotifications(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	unreadOnly := c.Query("unread") == "true"
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	notifications, total, err := nc.notificationService.GetNotifications(user.ID, unreadOnly, page, limit)
+	if err != nil {
+		config.GetLogger().Error("Failed to get notifications", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve notifications")
+		return
+	}
+
+	unread, err := nc.notificationService.GetUnreadCount(user.ID)
+	if err != nil {
+		config.GetLogger().Error("Failed to count unread notifications", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve notifications")
+		return
+	}
+
+	responses := make([]models.NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		responses = append(responses, notification.ToResponse())
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications retrieved successfully", gin.H{
+		"notifications": responses,
+		"unread_count":  unread,
+		"total":         total,
+		"current_page":  page,
+		"total_pages":   totalPages,
+		"page_size":     limit,
+	})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark notification as read
+// @Description Mark a single notification as read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} utils.APIResponse "Notification marked as read"
+// @Failure 400 {object} utils.APIResponse "Invalid notification ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Notification not found"
+// @Router /notifications/{id}/read [post]
+func (nc *NotificationController) MarkNotificationRead(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := nc.notificationService.MarkAsRead(user.ID, notificationID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification marked as read", nil)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications as read
+// @Description Mark all of the current user's notifications as read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse "Notifications marked as read"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /notifications/read-all [post]
+func (nc *NotificationController) MarkAllNotificationsRead(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	if err := nc.notificationService.MarkAllAsRead(user.ID); err != nil {
+		config.GetLogger().Error("Failed to mark notifications as read", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to mark notifications as read")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications marked as read", nil)
+}
+
+// DeleteNotification godoc
+// @Summary Delete notification
+// @Description Delete a notification
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} utils.APIResponse "Notification deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid notification ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Notification not found"
+// @Router /notifications/{id} [delete]
+func (nc *NotificationController) DeleteNotification(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := nc.notificationService.DeleteNotification(user.ID, notificationID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification deleted successfully", nil)
+}
+
+// GetEmailPreferences godoc
+// @Summary Get email notification preferences
+// @Description Get how you are emailed about each type of notification: instant, in a periodic digest, or off
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse "Email preferences retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /notifications/email-preferences [get]
+func (nc *NotificationController) GetEmailPreferences(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	prefs, err := nc.emailService.GetPreferences(user.ID)
+	if err != nil {
+		config.GetLogger().Error("Failed to get email preferences", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve email preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Email preferences retrieved successfully", gin.H{"preferences": prefs})
+}
+
+// UpdateEmailPreferences godoc
+// @Summary Update email notification preferences
+// @Description Set how you are emailed about some types of notification, the others are kept. Turning a type off drops what was waiting for your next digest.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EmailPreferencesRequest true "Notification types and their delivery"
+// @Success 200 {object} utils.APIResponse "Email preferences updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /notifications/email-preferences [put]
+func (nc *NotificationController) UpdateEmailPreferences(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.EmailPreferencesRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	prefs, err := nc.emailService.UpdatePreferences(user.ID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailEventUnknown) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		config.GetLogger().Error("Failed to update email preferences", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to update email preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Email preferences updated successfully", gin.H{"preferences": prefs})
+}
+
+// AdminGetEmailOutbox godoc
+// @Summary Get the email outbox
+// @Description Get queued, sent and failed notification emails, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param status query string false "Only emails with this status: pending, sent or failed"
+// @Success 200 {object} utils.APIResponse "Email outbox retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Router /admin/email-outbox [get]
+func (nc *NotificationController) AdminGetEmailOutbox(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	messages, total, err := nc.emailService.ListOutbox(status, page, limit)
+	if err != nil {
+		config.GetLogger().Error("Failed to get email outbox", "error", err)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve email outbox")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Email outbox retrieved successfully", gin.H{
+		"emails":       messages,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
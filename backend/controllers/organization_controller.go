@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// OrganizationController serves organizations and their members. Shared drives of an
+// organization are served by SharedDriveController.
+type OrganizationController struct {
+	organizationService *services.OrganizationService
+	auditService        *services.AuditService
+}
+
+func NewOrganizationController() *OrganizationController {
+	return &OrganizationController{
+		organizationService: services.NewOrganizationService(),
+		auditService:        services.NewAuditService(),
+	}
+}
+
+// GetOrganizations godoc
+// @Summary Get my organizations
+// @Description Get the organizations you are a member of, with your role in each
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Organizations retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /organizations [get]
+func (oc *OrganizationController) GetOrganizations(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	organizations, total, err := oc.organizationService.ListOrganizations(user.ID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve organizations")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Organizations retrieved successfully", gin.H{
+		"organizations": organizations,
+		"total":         total,
+		"current_page":  page,
+		"total_pages":   totalPages,
+		"page_size":     limit,
+	})
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization. You become its first owner.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OrganizationCreateRequest true "Organization name and description"
+// @Success 201 {object} utils.APIResponse "Organization created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /organizations [post]
+func (oc *OrganizationController) CreateOrganization(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.OrganizationCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	organization, err := oc.organizationService.CreateOrganization(user.ID, req)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgCreate, organization,
+		fmt.Sprintf("Organization %q created", organization.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Organization created successfully", organizationResponse(organization, user.ID))
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization you are a member of, with its members
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {object} utils.APIResponse "Organization retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid organization ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Organization not found"
+// @Router /organizations/{id} [get]
+func (oc *OrganizationController) GetOrganization(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+
+	organization, err := oc.organizationService.GetOrganization(user.ID, organizationID)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Organization retrieved successfully", organizationResponse(organization, user.ID))
+}
+
+// UpdateOrganization godoc
+// @Summary Update an organization
+// @Description Rename an organization or change its description. Owners and admins only.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.OrganizationUpdateRequest true "New name or description"
+// @Success 200 {object} utils.APIResponse "Organization updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not an owner or admin"
+// @Failure 404 {object} utils.APIResponse "Organization not found"
+// @Router /organizations/{id} [put]
+func (oc *OrganizationController) UpdateOrganization(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.OrganizationUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	organization, err := oc.organizationService.UpdateOrganization(user.ID, organizationID, req)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgUpdate, organization,
+		fmt.Sprintf("Organization %q updated", organization.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Organization updated successfully", organizationResponse(organization, user.ID))
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Delete an organization that has no shared drives left. Owners only.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {object} utils.APIResponse "Organization deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid organization ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not an owner"
+// @Failure 404 {object} utils.APIResponse "Organization not found"
+// @Failure 409 {object} utils.APIResponse "Organization still has shared drives"
+// @Router /organizations/{id} [delete]
+func (oc *OrganizationController) DeleteOrganization(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+
+	organization, err := oc.organizationService.DeleteOrganization(user.ID, organizationID)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgDelete, organization,
+		fmt.Sprintf("Organization %q deleted", organization.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Organization deleted successfully", nil)
+}
+
+// AddOrganizationMember godoc
+// @Summary Add an organization member
+// @Description Add a user to an organization, as a member unless another role is given. Owners and admins only, only owners can add owners.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.AddOrganizationMemberRequest true "User and role"
+// @Success 201 {object} utils.APIResponse "Organization member added successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or user"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to add this member"
+// @Failure 404 {object} utils.APIResponse "Organization not found"
+// @Failure 409 {object} utils.APIResponse "Already a member"
+// @Router /organizations/{id}/members [post]
+func (oc *OrganizationController) AddOrganizationMember(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	organization, err := oc.organizationService.AddMember(user.ID, organizationID, req)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgMemberAdd, organization,
+		fmt.Sprintf("User %s added to organization %q", req.UserID, organization.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Organization member added successfully", organizationResponse(organization, user.ID))
+}
+
+// UpdateOrganizationMember godoc
+// @Summary Change an organization member's role
+// @Description Change the role of a member. Owners and admins only, only owners can change owners or make someone an owner.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param userId path string true "User ID"
+// @Param request body models.UpdateOrganizationMemberRequest true "New role"
+// @Success 200 {object} utils.APIResponse "Organization member updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to change this member"
+// @Failure 404 {object} utils.APIResponse "Organization or member not found"
+// @Failure 409 {object} utils.APIResponse "Last owner"
+// @Router /organizations/{id}/members/{userId} [put]
+func (oc *OrganizationController) UpdateOrganizationMember(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateOrganizationMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	organization, err := oc.organizationService.UpdateMember(user.ID, organizationID, memberID, req)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgMemberUpdate, organization,
+		fmt.Sprintf("User %s is now %s of organization %q", memberID, req.Role, organization.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Organization member updated successfully", organizationResponse(organization, user.ID))
+}
+
+// RemoveOrganizationMember godoc
+// @Summary Remove an organization member
+// @Description Remove a member from an organization and its shared drives, or leave it by removing yourself. What they added to shared drives stays there.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} utils.APIResponse "Organization member removed successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not allowed to remove this member"
+// @Failure 404 {object} utils.APIResponse "Organization or member not found"
+// @Failure 409 {object} utils.APIResponse "Last owner"
+// @Router /organizations/{id}/members/{userId} [delete]
+func (oc *OrganizationController) RemoveOrganizationMember(c *gin.Context) {
+	user, organizationID, ok := organizationRequest(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	organization, err := oc.organizationService.RemoveMember(user.ID, organizationID, memberID)
+	if err != nil {
+		organizationErrorResponse(c, err)
+		return
+	}
+
+	details := fmt.Sprintf("User %s removed from organization %q", memberID, organization.Name)
+	if memberID == user.ID {
+		details = fmt.Sprintf("Left organization %q", organization.Name)
+	}
+	oc.logOrganizationEvent(c, user.ID, models.ActionOrgMemberRemove, organization, details)
+
+	utils.SuccessResponse(c, http.StatusOK, "Organization member removed successfully", organizationResponse(organization, user.ID))
+}
+
+func (oc *OrganizationController) logOrganizationEvent(c *gin.Context, userID uuid.UUID, action string, organization *models.Organization, details string) {
+	oc.auditService.LogEvent(&userID, action, models.ResourceOrganization, &organization.ID, details,
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+}
+
+// organizationResponse converts an organization loaded with its members, filling in the caller's role
+func organizationResponse(organization *models.Organization, userID uuid.UUID) models.OrganizationResponse {
+	response := organization.ToResponse()
+	for _, member := range organization.Members {
+		if member.UserID == userID {
+			response.MyRole = member.Role
+		}
+	}
+	return response
+}
+
+// organizationRequest reads the user and the organization ID of a request on a single organization
+func organizationRequest(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return nil, uuid.Nil, false
+	}
+	return user, organizationID, true
+}
+
+// organizationErrorResponse maps organization service errors to HTTP responses
+func organizationErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound), errors.Is(err, services.ErrOrgMemberNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrOrganizationForbidden), errors.Is(err, services.ErrOrganizationOwnersOnly):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrOrgMemberExists), errors.Is(err, services.ErrOrganizationLastOwner),
+		errors.Is(err, services.ErrOrganizationHasDrives):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrOrganizationInvalidUser):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process organization request")
+	}
+}
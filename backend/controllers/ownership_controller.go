@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+type OwnershipController struct {
+	ownershipService *services.OwnershipService
+	auditService     *services.AuditService
+}
+
+func NewOwnershipController() *OwnershipController {
+	return &OwnershipController{
+		ownershipService: services.NewOwnershipService(),
+		auditService:     services.NewAuditService(),
+	}
+}
+
+// GetOwnershipTransfers godoc
+// @Summary Get ownership transfers
+// @Description Get the ownership transfers you sent or received, newest first
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param direction query string false "incoming, outgoing or empty for both"
+// @Param status query string false "Filter by status (pending, accepted, declined, cancelled)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Ownership transfers retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid filter"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /ownership-transfers [get]
+func (oc *OwnershipController) GetOwnershipTransfers(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	direction := c.Query("direction")
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if direction != "" && direction != "incoming" && direction != "outgoing" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid direction, use incoming or outgoing")
+		return
+	}
+	switch models.OwnershipTransferStatus(status) {
+	case "", models.OwnershipTransferPending, models.OwnershipTransferAccepted,
+		models.OwnershipTransferDeclined, models.OwnershipTransferCancelled:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	transfers, total, err := oc.ownershipService.ListTransfers(user.ID, direction, status, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve ownership transfers")
+		return
+	}
+
+	responses := make([]models.OwnershipTransferResponse, 0, len(transfers))
+	for i := range transfers {
+		responses = append(responses, transfers[i].ToResponse())
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transfers retrieved successfully", gin.H{
+		"transfers":    responses,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
+
+// CreateOwnershipTransfer godoc
+// @Summary Transfer ownership
+// @Description Offer one of your files or folders to another user. The folder is transferred with everything in it once the recipient accepts. Collaborators and share links carry over, and with keep_as_editor you stay on as an editor.
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OwnershipTransferCreateRequest true "File or folder and the new owner"
+// @Success 201 {object} utils.APIResponse "Ownership transfer requested successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or recipient"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File or folder not found"
+// @Failure 409 {object} utils.APIResponse "A transfer of this item is already pending"
+// @Router /ownership-transfers [post]
+func (oc *OwnershipController) CreateOwnershipTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.OwnershipTransferCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	transfer, err := oc.ownershipService.RequestTransfer(user.ID, req)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	oc.auditService.LogEvent(&user.ID, models.ActionOwnershipRequest, models.ResourceOwnership, &transfer.ID,
+		fmt.Sprintf("Ownership of %s %q offered to %s", transfer.ItemType(), transfer.ItemName, transfer.ToUser.Email),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Ownership transfer requested successfully", transfer.ToResponse())
+}
+
+// GetOwnershipTransfer godoc
+// @Summary Get an ownership transfer
+// @Description Get an ownership transfer you sent or received
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ownership transfer ID"
+// @Success 200 {object} utils.APIResponse "Ownership transfer retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Ownership transfer not found"
+// @Router /ownership-transfers/{id} [get]
+func (oc *OwnershipController) GetOwnershipTransfer(c *gin.Context) {
+	user, transferID, ok := ownershipRequest(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := oc.ownershipService.GetTransfer(user.ID, transferID)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transfer retrieved successfully", transfer.ToResponse())
+}
+
+// AcceptOwnershipTransfer godoc
+// @Summary Accept an ownership transfer
+// @Description Become the owner of the file or folder offered to you. It is moved to your top level, names that are already taken get a counter appended.
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ownership transfer ID"
+// @Success 200 {object} utils.APIResponse "Ownership transfer accepted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Transfer not found, or its item no longer exists"
+// @Failure 409 {object} utils.APIResponse "Transfer is no longer pending"
+// @Router /ownership-transfers/{id}/accept [post]
+func (oc *OwnershipController) AcceptOwnershipTransfer(c *gin.Context) {
+	user, transferID, ok := ownershipRequest(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := oc.ownershipService.AcceptTransfer(user.ID, transferID)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	oc.logTransfer(c, user.ID, transfer)
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transfer accepted successfully", transfer.ToResponse())
+}
+
+// DeclineOwnershipTransfer godoc
+// @Summary Decline an ownership transfer
+// @Description Turn down a file or folder offered to you
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ownership transfer ID"
+// @Success 200 {object} utils.APIResponse "Ownership transfer declined successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Ownership transfer not found"
+// @Failure 409 {object} utils.APIResponse "Transfer is no longer pending"
+// @Router /ownership-transfers/{id}/decline [post]
+func (oc *OwnershipController) DeclineOwnershipTransfer(c *gin.Context) {
+	user, transferID, ok := ownershipRequest(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := oc.ownershipService.DeclineTransfer(user.ID, transferID)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	oc.auditService.LogEvent(&user.ID, models.ActionOwnershipRequest, models.ResourceOwnership, &transfer.ID,
+		fmt.Sprintf("Ownership of %s %q declined", transfer.ItemType(), transfer.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transfer declined successfully", transfer.ToResponse())
+}
+
+// CancelOwnershipTransfer godoc
+// @Summary Cancel an ownership transfer
+// @Description Withdraw a transfer you sent before it is accepted
+// @Tags ownership
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Ownership transfer ID"
+// @Success 200 {object} utils.APIResponse "Ownership transfer cancelled successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Ownership transfer not found"
+// @Failure 409 {object} utils.APIResponse "Transfer is no longer pending"
+// @Router /ownership-transfers/{id}/cancel [post]
+func (oc *OwnershipController) CancelOwnershipTransfer(c *gin.Context) {
+	user, transferID, ok := ownershipRequest(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := oc.ownershipService.CancelTransfer(user.ID, transferID)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	oc.auditService.LogEvent(&user.ID, models.ActionOwnershipRequest, models.ResourceOwnership, &transfer.ID,
+		fmt.Sprintf("Ownership transfer of %s %q cancelled", transfer.ItemType(), transfer.ItemName),
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transfer cancelled successfully", transfer.ToResponse())
+}
+
+// ForceOwnershipTransfer godoc
+// @Summary Force an ownership transfer
+// @Description Move a file, a folder tree or, with from_user_id, everything a user owns to another user without asking either of them. Account transfers land in a new "Transferred from" folder in the recipient's top level.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AdminOwnershipTransferRequest true "Item or previous owner, and the new owner"
+// @Success 200 {object} utils.APIResponse "Ownership transferred successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or recipient"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "File, folder or user not found"
+// @Router /admin/ownership-transfers [post]
+func (oc *OwnershipController) ForceOwnershipTransfer(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.AdminOwnershipTransferRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	transfer, err := oc.ownershipService.ForceTransfer(admin.ID, req)
+	if err != nil {
+		ownershipErrorResponse(c, err)
+		return
+	}
+
+	oc.logTransfer(c, admin.ID, transfer)
+
+	utils.SuccessResponse(c, http.StatusOK, "Ownership transferred successfully", transfer.ToResponse())
+}
+
+// logTransfer records a completed transfer against the item that changed hands
+func (oc *OwnershipController) logTransfer(c *gin.Context, actorID uuid.UUID, transfer *models.OwnershipTransfer) {
+	subject := fmt.Sprintf("Ownership of %s %q", transfer.ItemType(), transfer.ItemName)
+	if transfer.ItemType() == models.ResourceUser {
+		subject = "Everything owned by " + transfer.FromUser.Email
+	}
+	details := fmt.Sprintf("%s transferred from %s to %s (%d folders, %d files)", subject,
+		transfer.FromUser.Email, transfer.ToUser.Email, transfer.FolderCount, transfer.FileCount)
+	if transfer.KeepAsEditor {
+		details += ", previous owner kept as editor"
+	}
+	if transfer.Forced {
+		details += ", forced by an administrator"
+	}
+
+	itemID := transfer.ItemID()
+	oc.auditService.LogEvent(&actorID, models.ActionOwnershipTransfer, transfer.ItemType(), &itemID,
+		details, c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+}
+
+// ownershipRequest reads the user and the transfer ID of a request on a single transfer
+func ownershipRequest(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	transferID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transfer ID")
+		return nil, uuid.Nil, false
+	}
+	return user, transferID, true
+}
+
+// ownershipErrorResponse maps ownership service errors to HTTP responses
+func ownershipErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOwnershipTransferNotFound), errors.Is(err, services.ErrOwnershipItemNotFound),
+		errors.Is(err, services.ErrOwnershipUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrOwnershipTransferNotPending), errors.Is(err, services.ErrOwnershipTransferPending):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrOwnershipTarget), errors.Is(err, services.ErrOwnershipInvalidRecipient),
+		errors.Is(err, services.ErrOwnershipNothingToTransfer):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to transfer ownership")
+	}
+}
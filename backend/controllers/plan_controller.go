@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// PlanController lets admins define plans and assign them to users and organizations
+type PlanController struct {
+	planService  *services.PlanService
+	auditService *services.AuditService
+}
+
+func NewPlanController() *PlanController {
+	return &PlanController{
+		planService:  services.NewPlanService(),
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetPlans godoc
+// @Summary Get all plans
+// @Description Get every plan with how many users and organizations it is assigned to
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse "Plans retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Router /admin/plans [get]
+func (pc *PlanController) GetPlans(c *gin.Context) {
+	plans, err := pc.planService.ListPlans()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve plans")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Plans retrieved successfully", gin.H{"plans": plans})
+}
+
+// CreatePlan godoc
+// @Summary Create a plan
+// @Description Create a plan. Limits left empty fall back to the server configuration.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.PlanRequest true "Plan name and limits"
+// @Success 201 {object} utils.APIResponse "Plan created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 409 {object} utils.APIResponse "Plan name already taken"
+// @Router /admin/plans [post]
+func (pc *PlanController) CreatePlan(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.PlanRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	plan, err := pc.planService.CreatePlan(req)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	pc.logPlanEvent(c, admin.ID, models.ActionPlanCreate, models.ResourcePlan, plan.ID, fmt.Sprintf("Plan %q created", plan.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Plan created successfully", plan)
+}
+
+// GetPlan godoc
+// @Summary Get a plan
+// @Description Get a plan with how many users and organizations it is assigned to
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Plan ID"
+// @Success 200 {object} utils.APIResponse "Plan retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid plan ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Plan not found"
+// @Router /admin/plans/{id} [get]
+func (pc *PlanController) GetPlan(c *gin.Context) {
+	planID, ok := planIDParam(c)
+	if !ok {
+		return
+	}
+
+	plan, err := pc.planService.GetPlan(planID)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Plan retrieved successfully", plan)
+}
+
+// UpdatePlan godoc
+// @Summary Update a plan
+// @Description Replace the name and every limit of a plan. The new limits apply to uploads, share links and versions from then on, existing content and links are kept.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Plan ID"
+// @Param request body models.PlanRequest true "Plan name and limits"
+// @Success 200 {object} utils.APIResponse "Plan updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Plan not found"
+// @Failure 409 {object} utils.APIResponse "Plan name already taken"
+// @Router /admin/plans/{id} [put]
+func (pc *PlanController) UpdatePlan(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+	planID, ok := planIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.PlanRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	plan, err := pc.planService.UpdatePlan(planID, req)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	pc.logPlanEvent(c, admin.ID, models.ActionPlanUpdate, models.ResourcePlan, plan.ID, fmt.Sprintf("Plan %q updated", plan.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Plan updated successfully", plan)
+}
+
+// DeletePlan godoc
+// @Summary Delete a plan
+// @Description Delete a plan that is not assigned to any user or organization
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Plan ID"
+// @Success 200 {object} utils.APIResponse "Plan deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid plan ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Plan not found"
+// @Failure 409 {object} utils.APIResponse "Plan is still assigned"
+// @Router /admin/plans/{id} [delete]
+func (pc *PlanController) DeletePlan(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+	planID, ok := planIDParam(c)
+	if !ok {
+		return
+	}
+
+	plan, err := pc.planService.DeletePlan(planID)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	pc.logPlanEvent(c, admin.ID, models.ActionPlanDelete, models.ResourcePlan, plan.ID, fmt.Sprintf("Plan %q deleted", plan.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Plan deleted successfully", nil)
+}
+
+// AssignUserPlan godoc
+// @Summary Assign a plan to a user
+// @Description Assign a plan to a user, or remove it with a null plan_id. Returns the limits now in effect for the user.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.PlanAssignRequest true "Plan to assign"
+// @Success 200 {object} utils.APIResponse "Plan assigned successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "User or plan not found"
+// @Router /admin/users/{id}/plan [put]
+func (pc *PlanController) AssignUserPlan(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.PlanAssignRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	limits, err := pc.planService.AssignToUser(userID, req.PlanID)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	pc.logPlanEvent(c, admin.ID, models.ActionPlanAssign, models.ResourceUser, userID, planAssignDetails(req.PlanID))
+
+	utils.SuccessResponse(c, http.StatusOK, "Plan assigned successfully", limits)
+}
+
+// AssignOrganizationPlan godoc
+// @Summary Assign a plan to an organization
+// @Description Assign a plan to an organization, or remove it with a null plan_id. It applies to the organization's shared drives and to members without a plan of their own.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.PlanAssignRequest true "Plan to assign"
+// @Success 200 {object} utils.APIResponse "Plan assigned successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Organization or plan not found"
+// @Router /admin/organizations/{id}/plan [put]
+func (pc *PlanController) AssignOrganizationPlan(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var req models.PlanAssignRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	org, err := pc.planService.AssignToOrganization(orgID, req.PlanID)
+	if err != nil {
+		planErrorResponse(c, err)
+		return
+	}
+
+	pc.logPlanEvent(c, admin.ID, models.ActionPlanAssign, models.ResourceOrganization, org.ID, planAssignDetails(req.PlanID))
+
+	utils.SuccessResponse(c, http.StatusOK, "Plan assigned successfully", gin.H{
+		"organization_id": org.ID,
+		"plan_id":         org.PlanID,
+	})
+}
+
+func (pc *PlanController) logPlanEvent(c *gin.Context, adminID uuid.UUID, action, resource string, resourceID uuid.UUID, details string) {
+	pc.auditService.LogEvent(&adminID, action, resource, &resourceID, details,
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+}
+
+func planAssignDetails(planID *uuid.UUID) string {
+	if planID == nil {
+		return "Plan removed"
+	}
+	return fmt.Sprintf("Plan %s assigned", *planID)
+}
+
+// planIDParam reads the plan ID of a request on a single plan
+func planIDParam(c *gin.Context) (uuid.UUID, bool) {
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid plan ID")
+		return uuid.Nil, false
+	}
+	return planID, true
+}
+
+// planErrorResponse maps plan service errors to HTTP responses
+func planErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPlanNotFound), errors.Is(err, services.ErrPlanUserNotFound),
+		errors.Is(err, services.ErrPlanOrgNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrPlanFileSizeTooLarge):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPlanNameTaken), errors.Is(err, services.ErrPlanInUse):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process plan request")
+	}
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// QuotaController serves storage usage and quotas. Users see their own, admins see and override
+// anyone's, including the quota of a shared drive.
+type QuotaController struct {
+	quotaService *services.QuotaService
+	auditService *services.AuditService
+}
+
+func NewQuotaController() *QuotaController {
+	return &QuotaController{
+		quotaService: services.NewQuotaService(),
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetMyQuota godoc
+// @Summary Get my storage quota
+// @Description Get your storage usage and quota. Usage counts every version of your files and files in the trash until they are permanently deleted.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse "Storage quota retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /auth/quota [get]
+func (qc *QuotaController) GetMyQuota(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	quota, err := qc.quotaService.GetQuota(user.ID)
+	if err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Storage quota retrieved successfully", quota)
+}
+
+// AdminGetUserQuota godoc
+// @Summary Get a user's storage quota
+// @Description Get the storage usage and quota of any user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} utils.APIResponse "Storage quota retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid user ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "User not found"
+// @Router /admin/users/{id}/quota [get]
+func (qc *QuotaController) AdminGetUserQuota(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	quota, err := qc.quotaService.GetQuota(userID)
+	if err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Storage quota retrieved successfully", quota)
+}
+
+// AdminUpdateUserQuota godoc
+// @Summary Set a user's storage quota
+// @Description Override the storage quota of a user in bytes, 0 for unlimited or null to go back to the default. Stored content is kept when the quota is lowered below the usage.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.QuotaUpdateRequest true "New quota"
+// @Success 200 {object} utils.APIResponse "Storage quota updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "User not found"
+// @Router /admin/users/{id}/quota [put]
+func (qc *QuotaController) AdminUpdateUserQuota(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	qc.updateQuota(c, qc.quotaService.SetQuota, models.ResourceUser, userID)
+}
+
+// AdminGetDriveQuota godoc
+// @Summary Get a shared drive's storage quota
+// @Description Get the storage usage and quota of a shared drive
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Success 200 {object} utils.APIResponse "Storage quota retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid shared drive ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Router /admin/drives/{id}/quota [get]
+func (qc *QuotaController) AdminGetDriveQuota(c *gin.Context) {
+	driveID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid shared drive ID")
+		return
+	}
+
+	quota, err := qc.quotaService.GetDriveQuota(driveID)
+	if err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Storage quota retrieved successfully", quota)
+}
+
+// AdminUpdateDriveQuota godoc
+// @Summary Set a shared drive's storage quota
+// @Description Override the storage quota of a shared drive in bytes, 0 for unlimited or null to go back to the default
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param request body models.QuotaUpdateRequest true "New quota"
+// @Success 200 {object} utils.APIResponse "Storage quota updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Admin access required"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Router /admin/drives/{id}/quota [put]
+func (qc *QuotaController) AdminUpdateDriveQuota(c *gin.Context) {
+	driveID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid shared drive ID")
+		return
+	}
+
+	qc.updateQuota(c, qc.quotaService.SetDriveQuota, models.ResourceDrive, driveID)
+}
+
+// updateQuota sets the quota of a user or drive with set and audits it against the resource
+func (qc *QuotaController) updateQuota(c *gin.Context, set func(uuid.UUID, *int64) (*models.StorageQuotaResponse, error),
+	resource string, resourceID uuid.UUID) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	var req models.QuotaUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	quota, err := set(resourceID, req.Quota)
+	if err != nil {
+		quotaErrorResponse(c, err)
+		return
+	}
+
+	details := "Storage quota reset to the default"
+	if req.Quota != nil {
+		details = fmt.Sprintf("Storage quota set to %d bytes", *req.Quota)
+	}
+	qc.auditService.LogEvent(&admin.ID, models.ActionQuotaUpdate, resource, &resourceID, details,
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Storage quota updated successfully", quota)
+}
+
+// quotaErrorResponse maps quota service errors to HTTP responses
+func quotaErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrQuotaExceeded):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Storage quota exceeded")
+	case errors.Is(err, services.ErrQuotaUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrQuotaDriveNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Shared drive not found")
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process storage quota")
+	}
+}
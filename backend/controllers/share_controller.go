@@ -1,33 +1,58 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/geoip"
 	"github.com/manjurulhoque/swift-share/backend/middleware"
 	"github.com/manjurulhoque/swift-share/backend/models"
 	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/storage"
 	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
 )
 
 type ShareController struct {
-	shareService *services.ShareService
-	auditService *services.AuditService
+	shareService        *services.ShareService
+	analyticsService    *services.ShareAnalyticsService
+	auditService        *services.AuditService
+	notificationService *services.NotificationService
+	zipService          *services.ZipService
+	watermarkService    *services.WatermarkService
+	planService         *services.PlanService
 }
 
 func NewShareController() *ShareController {
 	return &ShareController{
-		shareService: services.NewShareService(),
-		auditService: services.NewAuditService(),
+		shareService:        services.NewShareService(),
+		analyticsService:    services.NewShareAnalyticsService(),
+		auditService:        services.NewAuditService(),
+		notificationService: services.NewNotificationService(),
+		zipService:          services.NewZipService(),
+		watermarkService:    services.NewWatermarkService(),
+		planService:         services.NewPlanService(),
 	}
 }
 
+// maxShareUploadFiles limits how many files one anonymous upload request may contain
+const maxShareUploadFiles = 20
+
 // CreateShareLink godoc
 // @Summary Create a new share link
-// @Description Create a public share link for a file or folder
+// @Description Create a public share link for a file, a folder, or a bundle of files and folders (file_ids and folder_ids)
 // @Tags sharing
 // @Accept json
 // @Produce json
@@ -267,6 +292,71 @@ func (sc *ShareController) DeleteShareLink(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Share link deleted successfully", nil)
 }
 
+// DisableFileShareLinks godoc
+// @Summary Disable all share links of a file
+// @Description Disable every active share link of the file, including bundle links that contain it. Disabled links can be enabled again with is_active on the update endpoint.
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "File ID"
+// @Success 200 {object} utils.APIResponse "Share links disabled successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "File not found"
+// @Router /files/{id}/share-links/disable [post]
+func (sc *ShareController) DisableFileShareLinks(c *gin.Context) {
+	sc.disableItemShareLinks(c, models.ResourceFile)
+}
+
+// DisableFolderShareLinks godoc
+// @Summary Disable all share links of a folder
+// @Description Disable every active share link of the folder, including bundle links that contain it. Disabled links can be enabled again with is_active on the update endpoint.
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Folder ID"
+// @Success 200 {object} utils.APIResponse "Share links disabled successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid folder ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Folder not found"
+// @Router /folders/{id}/share-links/disable [post]
+func (sc *ShareController) DisableFolderShareLinks(c *gin.Context) {
+	sc.disableItemShareLinks(c, models.ResourceFolder)
+}
+
+// disableItemShareLinks disables the links sharing the file or folder in the :id path parameter
+func (sc *ShareController) disableItemShareLinks(c *gin.Context, resource string) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+resource+" ID")
+		return
+	}
+
+	var disabled int64
+	if resource == models.ResourceFolder {
+		disabled, err = sc.shareService.DisableItemShareLinks(user.ID, nil, &itemID)
+	} else {
+		disabled, err = sc.shareService.DisableItemShareLinks(user.ID, &itemID, nil)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	sc.auditService.LogEvent(&user.ID, "share_disable", resource, &itemID,
+		fmt.Sprintf("Disabled %d share link(s)", disabled), c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Share links disabled successfully", gin.H{"disabled": disabled})
+}
+
 // AccessPublicShare godoc
 // @Summary Access public share
 // @Description Access a public share link (no authentication required)
@@ -274,11 +364,12 @@ func (sc *ShareController) DeleteShareLink(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param token path string true "Share Token"
-// @Param request body models.ShareLinkAccessRequest false "Password if required"
+// @Param request body models.ShareLinkAccessRequest false "Password, email and verification code if required"
 // @Success 200 {object} utils.APIResponse "Share accessed successfully"
 // @Failure 400 {object} utils.APIResponse "Invalid token or password"
 // @Failure 404 {object} utils.APIResponse "Share not found"
-// @Failure 410 {object} utils.APIResponse "Share expired"
+// @Failure 403 {object} utils.APIResponse "Share disabled, not active yet or not available in the visitor's region"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
 // @Router /public/share/{token} [post]
 func (sc *ShareController) AccessPublicShare(c *gin.Context) {
 	token := c.Param("token")
@@ -290,13 +381,17 @@ func (sc *ShareController) AccessPublicShare(c *gin.Context) {
 	var req models.ShareLinkAccessRequest
 	c.ShouldBindJSON(&req) // Password is optional
 
-	shareLink, err := sc.shareService.AccessShareLink(token, req.Password)
+	shareLink, err := sc.shareService.AccessShareLink(token, req, shareVisitor(c))
 	if err != nil {
-		if err.Error() == "share link has expired" {
-			utils.ErrorResponse(c, http.StatusGone, "Share link has expired")
+		if shareUnavailableResponse(c, err, nil) {
 			return
 		}
-		if err.Error() == "password required" || err.Error() == "invalid password" {
+		if errors.Is(err, services.ErrShareLinkGeoBlocked) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link is not available in your region")
+			return
+		}
+		if err.Error() == "password required" || err.Error() == "invalid password" ||
+			errors.Is(err, services.ErrShareEmailRequired) || errors.Is(err, services.ErrShareCodeInvalid) {
 			utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
 			return
 		}
@@ -304,7 +399,67 @@ func (sc *ShareController) AccessPublicShare(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Share accessed successfully", shareLink.ToPublicInfo())
+	email := ""
+	if shareLink.RequiresEmail() {
+		email = utils.NormalizeEmail(req.Email)
+	}
+	accessToken, expiresAt, err := middleware.GenerateShareAccessToken(*shareLink, email)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to issue share access token")
+		return
+	}
+
+	info := shareLink.ToPublicInfo()
+	info.AccessToken = accessToken
+	info.AccessTokenExpiresAt = &expiresAt
+
+	utils.SuccessResponse(c, http.StatusOK, "Share accessed successfully", info)
+}
+
+// RequestShareAccessCode godoc
+// @Summary Request a share verification code
+// @Description Email a one-time verification code for a share link restricted to specific email addresses or domains. The response is the same whether or not the address is allowed.
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param request body models.ShareLinkOTPRequest true "Visitor email address"
+// @Success 200 {object} utils.APIResponse "Verification code sent"
+// @Failure 400 {object} utils.APIResponse "Invalid request or link not email restricted"
+// @Failure 404 {object} utils.APIResponse "Share not found"
+// @Failure 403 {object} utils.APIResponse "Share disabled, not active yet or not available in the visitor's region"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
+// @Failure 429 {object} utils.APIResponse "Too many codes requested"
+// @Failure 503 {object} utils.APIResponse "Email delivery not configured"
+// @Router /public/share/{token}/otp [post]
+func (sc *ShareController) RequestShareAccessCode(c *gin.Context) {
+	var req models.ShareLinkOTPRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	err := sc.shareService.RequestAccessCode(c.Param("token"), req.Email, shareVisitor(c))
+	if err != nil {
+		switch {
+		case err.Error() == "share link not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "Share not found")
+		case shareUnavailableResponse(c, err, nil):
+		case errors.Is(err, services.ErrShareLinkGeoBlocked):
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link is not available in your region")
+		case errors.Is(err, services.ErrShareEmailNotRequired):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrShareCodeRateLimited):
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many verification codes requested, try again later")
+		case errors.Is(err, services.ErrMailNotConfigured):
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Email delivery is not configured")
+		default:
+			config.GetLogger().Error("Failed to send share verification code", "error", err, "token", c.Param("token"))
+			utils.InternalServerErrorResponse(c, "Failed to send verification code")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "If the address is allowed, a verification code has been sent", nil)
 }
 
 // GetPublicShareInfo godoc
@@ -315,8 +470,9 @@ func (sc *ShareController) AccessPublicShare(c *gin.Context) {
 // @Produce json
 // @Param token path string true "Share Token"
 // @Success 200 {object} utils.APIResponse "Share info retrieved successfully"
+// @Failure 403 {object} utils.APIResponse "Share disabled or not active yet"
 // @Failure 404 {object} utils.APIResponse "Share not found"
-// @Failure 410 {object} utils.APIResponse "Share expired"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
 // @Router /public/share/{token} [get]
 func (sc *ShareController) GetPublicShareInfo(c *gin.Context) {
 	token := c.Param("token")
@@ -331,14 +487,41 @@ func (sc *ShareController) GetPublicShareInfo(c *gin.Context) {
 		return
 	}
 
-	if shareLink.IsExpired() {
-		utils.ErrorResponse(c, http.StatusGone, "Share link has expired")
+	if shareUnavailableResponse(c, services.CheckShareAvailable(shareLink), shareLink) {
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Share info retrieved successfully", shareLink.ToPublicInfo())
 }
 
+// shareUnavailableResponse responds when err says the link is disabled, not active yet, expired or
+// used up and reports whether it did. The link, when known, adds its activation time to the
+// response of a scheduled link.
+func shareUnavailableResponse(c *gin.Context, err error, shareLink *models.ShareLink) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrShareLinkDisabled):
+		utils.ErrorResponseWithData(c, http.StatusForbidden, "Share link has been disabled",
+			gin.H{"status": models.ShareStatusDisabled})
+	case errors.Is(err, services.ErrShareLinkScheduled):
+		data := gin.H{"status": models.ShareStatusScheduled}
+		if shareLink != nil {
+			data["starts_at"] = shareLink.StartsAt
+		}
+		utils.ErrorResponseWithData(c, http.StatusForbidden, "Share link is not active yet", data)
+	case errors.Is(err, services.ErrShareLinkExpired):
+		utils.ErrorResponseWithData(c, http.StatusGone, "Share link has expired",
+			gin.H{"status": models.ShareStatusExpired})
+	case errors.Is(err, services.ErrShareLinkExhausted):
+		utils.ErrorResponseWithData(c, http.StatusGone, "Share link is no longer available",
+			gin.H{"status": models.ShareStatusExhausted})
+	default:
+		return false
+	}
+	return true
+}
+
 // GetShareStats godoc
 // @Summary Get sharing statistics
 // @Description Get sharing statistics for the current user
@@ -365,3 +548,701 @@ func (sc *ShareController) GetShareStats(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Share statistics retrieved successfully", stats)
 }
+
+// GetPublicShareContents godoc
+// @Summary Browse a shared folder
+// @Description List the subfolders and files of a folder inside a shared folder tree. Without folder_id a bundle link lists its items.
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param folder_id query string false "Folder inside the share (defaults to the shared folder)"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Success 200 {object} utils.APIResponse "Share contents retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Share is not a folder"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 404 {object} utils.APIResponse "Folder not found in share"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
+// @Router /public/share/{token}/contents [get]
+func (sc *ShareController) GetPublicShareContents(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	if shareLink.FileID != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Share is not a folder")
+		return
+	}
+
+	folderIDStr := c.Query("folder_id")
+	if folderIDStr == "" && shareLink.IsBundle() {
+		utils.SuccessResponse(c, http.StatusOK, "Share contents retrieved successfully", bundleContents(shareLink))
+		return
+	}
+
+	var folderID uuid.UUID
+	if folderIDStr != "" {
+		parsed, err := uuid.Parse(folderIDStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+			return
+		}
+		folderID = parsed
+	} else {
+		folderID = *shareLink.FolderID
+	}
+
+	folder, err := sc.shareService.GetSharedFolder(shareLink, folderID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found in share")
+		return
+	}
+
+	folders, files, err := sc.shareService.GetSharedFolderContents(folder)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve share contents")
+		return
+	}
+
+	breadcrumbs, err := folder.GetBreadcrumbs(database.GetDB())
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve share contents")
+		return
+	}
+	// Do not reveal anything above the shared folder
+	sharedFolderIDs := shareLink.SharedFolderIDs()
+	for i, crumb := range breadcrumbs {
+		if slices.Contains(sharedFolderIDs, crumb.ID) {
+			breadcrumbs = breadcrumbs[i:]
+			break
+		}
+	}
+
+	folderResponse := folder.ToResponse()
+	contents := models.PublicShareContents{
+		Folder:      &folderResponse,
+		Breadcrumbs: breadcrumbs,
+		Folders:     make([]models.FolderResponse, 0, len(folders)),
+		Files:       make([]models.FileResponse, 0, len(files)),
+	}
+	for _, f := range folders {
+		contents.Folders = append(contents.Folders, f.ToResponse())
+	}
+	for _, f := range files {
+		contents.Files = append(contents.Files, f.ToResponse())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Share contents retrieved successfully", contents)
+}
+
+// bundleContents lists the top level items of a bundle link
+func bundleContents(shareLink *models.ShareLink) models.PublicShareContents {
+	folders, files := shareLink.BundleItems()
+	contents := models.PublicShareContents{
+		Breadcrumbs: []models.Breadcrumb{},
+		Folders:     make([]models.FolderResponse, 0, len(folders)),
+		Files:       make([]models.FileResponse, 0, len(files)),
+	}
+	for _, f := range folders {
+		contents.Folders = append(contents.Folders, f.ToResponse())
+	}
+	for _, f := range files {
+		contents.Files = append(contents.Files, f.ToResponse())
+	}
+	return contents
+}
+
+// DownloadPublicShare godoc
+// @Summary Download a shared file or folder
+// @Description Download the shared file, or the whole shared folder or bundle as a zip. Links with watermark enabled stamp images and PDFs with the viewer's identity and refuse other files.
+// @Tags public
+// @Produce octet-stream
+// @Param token path string true "Share Token"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Success 200 {file} binary "File content"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Downloads are disabled for this share"
+// @Failure 404 {object} utils.APIResponse "Share not found"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
+// @Failure 413 {object} utils.APIResponse "File too large to be watermarked"
+// @Failure 415 {object} utils.APIResponse "File is not an image or PDF that can be watermarked"
+// @Failure 422 {object} utils.APIResponse "File could not be watermarked"
+// @Router /public/share/{token}/download [get]
+func (sc *ShareController) DownloadPublicShare(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	if !shareLink.AllowDownload {
+		utils.ErrorResponse(c, http.StatusForbidden, "Downloads are disabled for this share")
+		return
+	}
+
+	if shareLink.FileID != nil {
+		sc.downloadSharedFile(c, shareLink, *shareLink.FileID)
+		return
+	}
+	if shareLink.IsBundle() {
+		sc.downloadSharedBundle(c, shareLink)
+		return
+	}
+	sc.downloadSharedFolder(c, shareLink, *shareLink.FolderID)
+}
+
+// DownloadPublicShareFile godoc
+// @Summary Download a file from a shared folder
+// @Description Download a single file of a bundle or inside a shared folder tree. Links with watermark enabled stamp images and PDFs with the viewer's identity and refuse other files.
+// @Tags public
+// @Produce octet-stream
+// @Param token path string true "Share Token"
+// @Param fileId path string true "File ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Success 200 {file} binary "File content"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Downloads are disabled for this share"
+// @Failure 404 {object} utils.APIResponse "File not found in share"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
+// @Failure 413 {object} utils.APIResponse "File too large to be watermarked"
+// @Failure 415 {object} utils.APIResponse "File is not an image or PDF that can be watermarked"
+// @Failure 422 {object} utils.APIResponse "File could not be watermarked"
+// @Router /public/share/{token}/files/{fileId}/download [get]
+func (sc *ShareController) DownloadPublicShareFile(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("fileId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	if !shareLink.AllowDownload {
+		utils.ErrorResponse(c, http.StatusForbidden, "Downloads are disabled for this share")
+		return
+	}
+
+	sc.downloadSharedFile(c, shareLink, fileID)
+}
+
+// DownloadPublicShareFolder godoc
+// @Summary Download a folder from a shared folder as a zip
+// @Description Download a folder inside a shared folder tree as a zip archive. Links with watermark enabled stamp images and PDFs with the viewer's identity and refuse other files.
+// @Tags public
+// @Produce application/zip
+// @Param token path string true "Share Token"
+// @Param folderId path string true "Folder ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Success 200 {file} binary "Zip archive"
+// @Failure 400 {object} utils.APIResponse "Invalid folder ID"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Downloads are disabled for this share"
+// @Failure 404 {object} utils.APIResponse "Folder not found in share"
+// @Failure 410 {object} utils.APIResponse "Share expired or used up"
+// @Failure 413 {object} utils.APIResponse "File too large to be watermarked"
+// @Failure 415 {object} utils.APIResponse "File is not an image or PDF that can be watermarked"
+// @Router /public/share/{token}/folders/{folderId}/download [get]
+func (sc *ShareController) DownloadPublicShareFolder(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("folderId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	if !shareLink.AllowDownload {
+		utils.ErrorResponse(c, http.StatusForbidden, "Downloads are disabled for this share")
+		return
+	}
+
+	sc.downloadSharedFolder(c, shareLink, folderID)
+}
+
+func (sc *ShareController) downloadSharedFile(c *gin.Context, shareLink *models.ShareLink, fileID uuid.UUID) {
+	file, err := sc.shareService.GetSharedFile(shareLink, fileID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found in share")
+		return
+	}
+
+	if shareLink.Watermark {
+		sc.downloadWatermarkedFile(c, shareLink, file)
+		return
+	}
+
+	if !sc.recordShareDownload(c, shareLink, &file.ID, fmt.Sprintf("File downloaded via share link: %s", file.OriginalName)) {
+		return
+	}
+
+	streamStoredFile(c, file)
+}
+
+// downloadWatermarkedFile renders the file for this viewer before counting the download, so a
+// file that cannot be watermarked is never handed out unmarked
+func (sc *ShareController) downloadWatermarkedFile(c *gin.Context, shareLink *models.ShareLink, file *models.File) {
+	c.Header("Cache-Control", "no-store")
+	rc, err := storage.GetStorage().OpenFile(c.Request.Context(), file.ObjectKey())
+	if err != nil {
+		appLogger.Error("Failed to open stored file", "file_id", file.ID, "error", err)
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found in storage")
+		return
+	}
+	defer rc.Close()
+
+	var rendition bytes.Buffer
+	mark := services.NewWatermark(shareLink, shareVisitor(c), time.Now())
+	if err := sc.watermarkService.Render(&rendition, rc, file, mark); err != nil {
+		watermarkErrorResponse(c, file, err)
+		return
+	}
+
+	if !sc.recordShareDownload(c, shareLink, &file.ID, fmt.Sprintf("Watermarked file downloaded via share link: %s", file.OriginalName)) {
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.OriginalName))
+	c.Data(http.StatusOK, http.DetectContentType(rendition.Bytes()), rendition.Bytes())
+}
+
+// watermarkZipItems makes the zip entries of a watermarked link render through the watermark.
+// It responds and returns false when a file cannot be watermarked or is too large to be.
+func (sc *ShareController) watermarkZipItems(c *gin.Context, shareLink *models.ShareLink, items []services.ZipItem) bool {
+	if !shareLink.Watermark {
+		return true
+	}
+
+	mark := services.NewWatermark(shareLink, shareVisitor(c), time.Now())
+	for i := range items {
+		file := items[i].File
+		if file == nil {
+			continue
+		}
+		if err := sc.watermarkService.Check(file); err != nil {
+			watermarkErrorResponse(c, file, err)
+			return false
+		}
+		items[i].Render = func(w io.Writer, r io.Reader) error {
+			return sc.watermarkService.Render(w, r, file, mark)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	return true
+}
+
+// watermarkErrorResponse maps watermark service errors to HTTP responses
+func watermarkErrorResponse(c *gin.Context, file *models.File, err error) {
+	switch {
+	case errors.Is(err, services.ErrWatermarkTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s is too large to be watermarked", file.OriginalName))
+	case errors.Is(err, services.ErrWatermarkUnsupported):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, fmt.Sprintf("%s is not an image or PDF that can be watermarked", file.OriginalName))
+	default:
+		appLogger.Error("Failed to watermark file", "file_id", file.ID, "error", err)
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, fmt.Sprintf("%s could not be watermarked", file.OriginalName))
+	}
+}
+
+func (sc *ShareController) downloadSharedFolder(c *gin.Context, shareLink *models.ShareLink, folderID uuid.UUID) {
+	folder, err := sc.shareService.GetSharedFolder(shareLink, folderID)
+	if err != nil {
+		if errors.Is(err, services.ErrShareItemNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Folder not found in share")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to load shared folder")
+		return
+	}
+
+	items, err := sc.zipService.CollectFolder(folder)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to collect folder contents")
+		return
+	}
+
+	if !sc.watermarkZipItems(c, shareLink, items) {
+		return
+	}
+
+	if !sc.recordShareDownload(c, shareLink, nil, fmt.Sprintf("Folder downloaded via share link: %s", folder.Name)) {
+		return
+	}
+
+	visitor := shareVisitor(c)
+	streamZip(c, sc.zipService, folder.Name+".zip", items, func(file *models.File) {
+		database.GetDB().Model(file).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+		sc.analyticsService.RecordFileDownload(shareLink, file.ID, visitor)
+	})
+}
+
+// downloadSharedBundle streams every item of a bundle link as one zip
+func (sc *ShareController) downloadSharedBundle(c *gin.Context, shareLink *models.ShareLink) {
+	folders, files := shareLink.BundleItems()
+	if len(folders) == 0 && len(files) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Share has no items left")
+		return
+	}
+
+	var items []services.ZipItem
+	for i := range folders {
+		folderItems, err := sc.zipService.CollectFolder(&folders[i])
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to collect folder contents")
+			return
+		}
+		items = append(items, folderItems...)
+	}
+	for i := range files {
+		items = append(items, services.ZipItem{Path: files[i].OriginalName, File: &files[i]})
+	}
+
+	if !sc.watermarkZipItems(c, shareLink, items) {
+		return
+	}
+
+	if !sc.recordShareDownload(c, shareLink, nil, fmt.Sprintf("Bundle of %d folders and %d files downloaded via share link", len(folders), len(files))) {
+		return
+	}
+
+	visitor := shareVisitor(c)
+	archiveName := fmt.Sprintf("swift-share-%s.zip", time.Now().Format("20060102-150405"))
+	streamZip(c, sc.zipService, archiveName, items, func(file *models.File) {
+		database.GetDB().Model(file).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+		sc.analyticsService.RecordFileDownload(shareLink, file.ID, visitor)
+	})
+}
+
+// UploadToPublicShare godoc
+// @Summary Upload files to a shared folder
+// @Description Upload files anonymously into the folder of a share link with edit permission. Files belong to the link owner, who is notified.
+// @Tags public
+// @Accept multipart/form-data
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param files formData file true "Files to upload"
+// @Param uploader_name formData string false "Uploader name"
+// @Param uploader_email formData string false "Uploader email"
+// @Success 201 {object} utils.APIResponse "Files uploaded successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow uploads"
+// @Failure 413 {object} utils.APIResponse "File too large or the owner's storage is full"
+// @Failure 415 {object} utils.APIResponse "File type not allowed"
+// @Router /public/share/{token}/upload [post]
+func (sc *ShareController) UploadToPublicShare(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	sc.uploadToShare(c, shareLink, nil)
+}
+
+// UploadToPublicShareFolder godoc
+// @Summary Upload files to a folder in a shared folder
+// @Description Upload files anonymously into a folder inside the tree of a share link with edit permission
+// @Tags public
+// @Accept multipart/form-data
+// @Produce json
+// @Param token path string true "Share Token"
+// @Param folderId path string true "Folder ID"
+// @Param X-Share-Access-Token header string true "Share access token from POST /public/share/{token}"
+// @Param files formData file true "Files to upload"
+// @Param uploader_name formData string false "Uploader name"
+// @Param uploader_email formData string false "Uploader email"
+// @Success 201 {object} utils.APIResponse "Files uploaded successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Share access token required"
+// @Failure 403 {object} utils.APIResponse "Share does not allow uploads"
+// @Failure 404 {object} utils.APIResponse "Folder not found in share"
+// @Failure 413 {object} utils.APIResponse "File too large or the owner's storage is full"
+// @Failure 415 {object} utils.APIResponse "File type not allowed"
+// @Router /public/share/{token}/folders/{folderId}/upload [post]
+func (sc *ShareController) UploadToPublicShareFolder(c *gin.Context) {
+	shareLink, exists := middleware.GetShareLinkFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "Share access token required")
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("folderId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	sc.uploadToShare(c, shareLink, &folderID)
+}
+
+// uploadToShare stores the uploaded files in a folder of an edit share link, then writes an
+// audit entry per file and notifies the owner
+func (sc *ShareController) uploadToShare(c *gin.Context, shareLink *models.ShareLink, folderID *uuid.UUID) {
+	folder, err := sc.shareService.GetUploadFolder(shareLink, folderID)
+	if err != nil {
+		if errors.Is(err, services.ErrShareUploadNotAllowed) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Share does not allow uploads")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusNotFound, "Folder not found in share")
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+
+	files := c.Request.MultipartForm.File["files"]
+	if len(files) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No files provided")
+		return
+	}
+	if len(files) > maxShareUploadFiles {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d files can be uploaded at once", maxShareUploadFiles))
+		return
+	}
+
+	uploader, err := readUploader(c, middleware.GetShareEmailFromContext(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limits, ok := planLimits(c, sc.planService, shareLink.UserID)
+	if !ok || !checkUploadPolicy(c, files, limits.MaxFileSize) {
+		return
+	}
+
+	visitor := shareVisitor(c)
+	uploadedBy := describeUploader(uploader)
+
+	var uploaded []models.FileResponse
+	var names []string
+	var uploadErrors []string
+	overQuota := 0
+	for _, header := range files {
+		file, err := sc.shareService.SaveSharedUpload(c.Request.Context(), shareLink, folder, header, uploader, visitor)
+		if err != nil {
+			if errors.Is(err, services.ErrQuotaExceeded) {
+				overQuota++
+				uploadErrors = append(uploadErrors, fmt.Sprintf("%s was not accepted, the owner's storage is full", header.Filename))
+				continue
+			}
+			config.GetLogger().Error("Failed to save share upload", "error", err, "share_link_id", shareLink.ID, "file", header.Filename)
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Failed to upload %s", header.Filename))
+			continue
+		}
+
+		sc.auditService.LogEvent(&shareLink.UserID, models.ActionShareUpload, models.ResourceFile, &file.ID,
+			fmt.Sprintf("File uploaded via share link by %s: %s", uploadedBy, file.OriginalName),
+			c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+		uploaded = append(uploaded, file.ToResponse())
+		names = append(names, file.OriginalName)
+	}
+
+	if len(uploaded) == 0 {
+		if overQuota == len(files) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "The owner's storage is full")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to upload files")
+		return
+	}
+
+	message := fmt.Sprintf("%s uploaded %d file(s) to %q: %s", uploadedBy, len(names), folder.Name, summarizeNames(names, 5))
+	if _, err := sc.notificationService.Notify(shareLink.UserID, models.NotificationShareUpload,
+		"New files in "+folder.Name, message, models.ResourceFolder, &folder.ID); err != nil {
+		config.GetLogger().Error("Failed to notify share owner", "error", err, "share_link_id", shareLink.ID)
+	}
+
+	response := gin.H{
+		"uploaded_files": uploaded,
+		"total_files":    len(files),
+		"success_count":  len(uploaded),
+		"error_count":    len(uploadErrors),
+	}
+	if len(uploadErrors) > 0 {
+		response["errors"] = uploadErrors
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Files uploaded successfully", response)
+}
+
+// recordShareDownload counts a download on the share link and the file and writes an audit entry.
+// It responds and returns false when the link's download limit is used up.
+func (sc *ShareController) recordShareDownload(c *gin.Context, shareLink *models.ShareLink, fileID *uuid.UUID, details string) bool {
+	visitor := shareVisitor(c)
+	if err := sc.shareService.ConsumeDownload(shareLink, fileID, visitor); err != nil {
+		if errors.Is(err, services.ErrShareLinkExhausted) {
+			utils.ErrorResponse(c, http.StatusGone, "Share link download limit reached")
+			return false
+		}
+		if errors.Is(err, services.ErrShareLinkGeoBlocked) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link is not available in your region")
+			return false
+		}
+		config.GetLogger().Error("Failed to count share download", "error", err, "share_link_id", shareLink.ID)
+		utils.InternalServerErrorResponse(c, "Failed to download share")
+		return false
+	}
+
+	resource, resourceID := models.ResourceFolder, shareLink.FolderID
+	if fileID != nil {
+		resource, resourceID = models.ResourceFile, fileID
+		database.GetDB().Model(&models.File{}).Where("id = ?", *fileID).
+			UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+		sc.analyticsService.RecordFileDownload(shareLink, *fileID, visitor)
+	}
+
+	sc.auditService.LogEvent(nil, models.ActionShareDownload, resource, resourceID,
+		details, c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+	return true
+}
+
+// GetShareAnalytics godoc
+// @Summary Get share link analytics
+// @Description Get a daily time series, unique visitors, top referrers and a browser/OS/device breakdown for a share link
+// @Tags sharing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Share Link ID"
+// @Param days query int false "Number of days to include (default: 30, max: 365)"
+// @Success 200 {object} utils.APIResponse "Share analytics retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid share link ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Share link not found"
+// @Router /share/{id}/analytics [get]
+func (sc *ShareController) GetShareAnalytics(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+
+	if _, err := sc.shareService.GetShareLinkByID(user.ID, linkID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	from, to := analyticsPeriod(c)
+	analytics, err := sc.analyticsService.GetAnalytics(linkID, from, to)
+	if err != nil {
+		config.GetLogger().Error("Failed to get share analytics", "error", err, "link_id", linkID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve share analytics")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Share analytics retrieved successfully", analytics)
+}
+
+// ExportShareAnalytics godoc
+// @Summary Export share link access events
+// @Description Download the access events of a share link as CSV
+// @Tags sharing
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path string true "Share Link ID"
+// @Param days query int false "Number of days to include (default: 30, max: 365)"
+// @Success 200 {file} binary "CSV export"
+// @Failure 400 {object} utils.APIResponse "Invalid share link ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Share link not found"
+// @Router /share/{id}/analytics/export [get]
+func (sc *ShareController) ExportShareAnalytics(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+
+	if _, err := sc.shareService.GetShareLinkByID(user.ID, linkID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	from, to := analyticsPeriod(c)
+	events, err := sc.analyticsService.GetAccessEvents(linkID, from, to)
+	if err != nil {
+		config.GetLogger().Error("Failed to export share analytics", "error", err, "link_id", linkID)
+		utils.InternalServerErrorResponse(c, "Failed to export share analytics")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"share-%s-analytics.csv\"", linkID))
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"time", "event", "file_id", "visitor_id", "email", "ip_address", "country", "city", "referrer", "browser", "os", "device_type", "user_agent"})
+	for _, event := range events {
+		fileID := ""
+		if event.FileID != nil {
+			fileID = event.FileID.String()
+		}
+		w.Write([]string{
+			event.CreatedAt.UTC().Format(time.RFC3339), event.EventType, fileID, event.VisitorID, csvSafe(event.Email), event.IPAddress,
+			event.Country, csvSafe(event.City), csvSafe(event.Referrer), event.Browser, event.OS, event.DeviceType, csvSafe(event.UserAgent),
+		})
+	}
+	w.Flush()
+}
+
+// csvSafe stops visitor supplied values from being interpreted as spreadsheet formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// shareVisitor describes the client of a public share request
+func shareVisitor(c *gin.Context) models.ShareVisitor {
+	location := geoip.Lookup(c.ClientIP())
+	return models.ShareVisitor{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Referrer:  c.Request.Referer(),
+		Country:   location.Country,
+		City:      location.City,
+		Email:     middleware.GetShareEmailFromContext(c),
+	}
+}
+
+// analyticsPeriod returns the period selected by the days query parameter
+func analyticsPeriod(c *gin.Context) (time.Time, time.Time) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 365 {
+		days = 30
+	}
+	to := time.Now().UTC()
+	from := to.Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	return from, to
+}
@@ -0,0 +1,446 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// SharedDriveController serves the shared drives of organizations and their members. The content
+// of a drive is browsed and changed through the folder and file routes, starting at the drive's
+// root folder.
+type SharedDriveController struct {
+	driveService *services.SharedDriveService
+	auditService *services.AuditService
+}
+
+func NewSharedDriveController() *SharedDriveController {
+	return &SharedDriveController{
+		driveService: services.NewSharedDriveService(),
+		auditService: services.NewAuditService(),
+	}
+}
+
+// GetDrives godoc
+// @Summary Get my shared drives
+// @Description Get the shared drives you are a member of, and every drive of the organizations you own or administer
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Shared drives retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /drives [get]
+func (sdc *SharedDriveController) GetDrives(c *gin.Context) {
+	sdc.listDrives(c, nil)
+}
+
+// GetOrganizationDrives godoc
+// @Summary Get the shared drives of an organization
+// @Description Get the shared drives of an organization that you can open
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Shared drives retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid organization ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /organizations/{id}/drives [get]
+func (sdc *SharedDriveController) GetOrganizationDrives(c *gin.Context) {
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+	sdc.listDrives(c, &organizationID)
+}
+
+// CreateDrive godoc
+// @Summary Create a shared drive
+// @Description Create a shared drive in an organization. Its content belongs to the organization. Owners and admins only, you become the drive's first manager.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.SharedDriveCreateRequest true "Drive name and description"
+// @Success 201 {object} utils.APIResponse "Shared drive created successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not an owner or admin"
+// @Failure 404 {object} utils.APIResponse "Organization not found"
+// @Router /organizations/{id}/drives [post]
+func (sdc *SharedDriveController) CreateDrive(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var req models.SharedDriveCreateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	drive, err := sdc.driveService.CreateDrive(user.ID, organizationID, req)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveCreate, drive, fmt.Sprintf("Shared drive %q created", drive.Name))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Shared drive created successfully", drive)
+}
+
+// GetDrive godoc
+// @Summary Get a shared drive
+// @Description Get a shared drive with its members and storage usage
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Success 200 {object} utils.APIResponse "Shared drive retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid shared drive ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Router /drives/{id} [get]
+func (sdc *SharedDriveController) GetDrive(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+
+	drive, err := sdc.driveService.GetDrive(user.ID, driveID)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drive retrieved successfully", drive)
+}
+
+// UpdateDrive godoc
+// @Summary Update a shared drive
+// @Description Rename a shared drive or change its description. Managers only.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param request body models.SharedDriveUpdateRequest true "New name or description"
+// @Success 200 {object} utils.APIResponse "Shared drive updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Router /drives/{id} [put]
+func (sdc *SharedDriveController) UpdateDrive(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.SharedDriveUpdateRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	drive, err := sdc.driveService.UpdateDrive(user.ID, driveID, req)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveUpdate, drive, fmt.Sprintf("Shared drive %q updated", drive.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drive updated successfully", drive)
+}
+
+// DeleteDrive godoc
+// @Summary Delete a shared drive
+// @Description Delete an empty shared drive. Owners and admins of the organization only, everything in the drive has to be permanently deleted first.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Success 200 {object} utils.APIResponse "Shared drive deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid shared drive ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not an owner or admin"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Failure 409 {object} utils.APIResponse "Shared drive is not empty"
+// @Router /drives/{id} [delete]
+func (sdc *SharedDriveController) DeleteDrive(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+
+	drive, err := sdc.driveService.DeleteDrive(user.ID, driveID)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveDelete, drive, fmt.Sprintf("Shared drive %q deleted", drive.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drive deleted successfully", nil)
+}
+
+// GetDriveTrash godoc
+// @Summary Get the trash of a shared drive
+// @Description Get the trashed files and folders of a shared drive. Managers only, they restore and delete them through the trash routes.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Trashed items retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid shared drive ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Router /drives/{id}/trash [get]
+func (sdc *SharedDriveController) GetDriveTrash(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	trashedItems, err := sdc.driveService.GetTrash(user.ID, driveID, page, limit)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Trashed items retrieved successfully", trashedItems)
+}
+
+// AddDriveMember godoc
+// @Summary Add a shared drive member
+// @Description Add a member of the organization to a shared drive with a role on all of its content. Managers only.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param request body models.AddDriveMemberRequest true "User and role"
+// @Success 201 {object} utils.APIResponse "Shared drive member added successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request or not an organization member"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager"
+// @Failure 404 {object} utils.APIResponse "Shared drive not found"
+// @Failure 409 {object} utils.APIResponse "Already a member"
+// @Router /drives/{id}/members [post]
+func (sdc *SharedDriveController) AddDriveMember(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddDriveMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	drive, err := sdc.driveService.AddMember(user.ID, driveID, req)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveMemberAdd, drive,
+		fmt.Sprintf("User %s added to shared drive %q as %s", req.UserID, drive.Name, req.Role))
+
+	utils.SuccessResponse(c, http.StatusCreated, "Shared drive member added successfully", drive)
+}
+
+// UpdateDriveMember godoc
+// @Summary Change a shared drive member's role
+// @Description Change the role of a shared drive member. Managers only.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param userId path string true "User ID"
+// @Param request body models.UpdateDriveMemberRequest true "New role"
+// @Success 200 {object} utils.APIResponse "Shared drive member updated successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid request"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager"
+// @Failure 404 {object} utils.APIResponse "Shared drive or member not found"
+// @Router /drives/{id}/members/{userId} [put]
+func (sdc *SharedDriveController) UpdateDriveMember(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateDriveMemberRequest
+	if !utils.BindAndValidate(c, &req) {
+		return
+	}
+
+	drive, err := sdc.driveService.UpdateMember(user.ID, driveID, memberID, req)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveMemberUpdate, drive,
+		fmt.Sprintf("User %s is now %s of shared drive %q", memberID, req.Role, drive.Name))
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drive member updated successfully", drive)
+}
+
+// RemoveDriveMember godoc
+// @Summary Remove a shared drive member
+// @Description Remove a member from a shared drive, or leave it by removing yourself. What they added stays in the drive.
+// @Tags shared-drives
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shared drive ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} utils.APIResponse "Shared drive member removed successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 403 {object} utils.APIResponse "Not a manager"
+// @Failure 404 {object} utils.APIResponse "Shared drive or member not found"
+// @Router /drives/{id}/members/{userId} [delete]
+func (sdc *SharedDriveController) RemoveDriveMember(c *gin.Context) {
+	user, driveID, ok := driveRequest(c)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	drive, err := sdc.driveService.RemoveMember(user.ID, driveID, memberID)
+	if err != nil {
+		driveErrorResponse(c, err)
+		return
+	}
+
+	details := fmt.Sprintf("User %s removed from shared drive %q", memberID, drive.Name)
+	if memberID == user.ID {
+		details = fmt.Sprintf("Left shared drive %q", drive.Name)
+	}
+	sdc.logDriveEvent(c, user.ID, models.ActionDriveMemberRemove, drive, details)
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drive member removed successfully", drive)
+}
+
+func (sdc *SharedDriveController) listDrives(c *gin.Context, organizationID *uuid.UUID) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	drives, total, err := sdc.driveService.ListDrives(user.ID, organizationID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to retrieve shared drives")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Shared drives retrieved successfully", gin.H{
+		"drives":       drives,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
+
+func (sdc *SharedDriveController) logDriveEvent(c *gin.Context, userID uuid.UUID, action string, drive *models.SharedDriveResponse, details string) {
+	sdc.auditService.LogEvent(&userID, action, models.ResourceDrive, &drive.ID, details,
+		c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+}
+
+// driveRequest reads the user and the drive ID of a request on a single shared drive
+func driveRequest(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return nil, uuid.Nil, false
+	}
+
+	driveID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid shared drive ID")
+		return nil, uuid.Nil, false
+	}
+	return user, driveID, true
+}
+
+// driveErrorResponse maps shared drive service errors to HTTP responses
+func driveErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDriveNotFound), errors.Is(err, services.ErrDriveMemberNotFound),
+		errors.Is(err, services.ErrOrganizationNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDriveForbidden), errors.Is(err, services.ErrOrganizationForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrDriveMemberExists), errors.Is(err, services.ErrDriveNotEmpty):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrDriveNotOrgMember):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, "Failed to process shared drive request")
+	}
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+type TransferController struct {
+	transferService     *services.TransferService
+	auditService        *services.AuditService
+	notificationService *services.NotificationService
+	zipService          *services.ZipService
+}
+
+func NewTransferController() *TransferController {
+	return &TransferController{
+		transferService:     services.NewTransferService(),
+		auditService:        services.NewAuditService(),
+		notificationService: services.NewNotificationService(),
+		zipService:          services.NewZipService(),
+	}
+}
+
+// GetTransfers godoc
+// @Summary Get transfers
+// @Description Get the current user's transfers with their recipients and download counts
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} utils.APIResponse "Transfers retrieved successfully"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Router /transfers [get]
+func (tc *TransferController) GetTransfers(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	transfers, total, err := tc.transferService.GetTransfers(user.ID, page, limit)
+	if err != nil {
+		config.GetLogger().Error("Failed to get transfers", "error", err, "user_id", user.ID)
+		utils.InternalServerErrorResponse(c, "Failed to retrieve transfers")
+		return
+	}
+
+	baseURL := requestBaseURL(c)
+	responses := make([]models.TransferResponse, 0, len(transfers))
+	for _, transfer := range transfers {
+		responses = append(responses, transfer.ToResponse(baseURL))
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Transfers retrieved successfully", gin.H{
+		"transfers":    responses,
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"page_size":    limit,
+	})
+}
+
+// GetTransfer godoc
+// @Summary Get a transfer
+// @Description Get a transfer with its files and the download status of each recipient
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} utils.APIResponse "Transfer retrieved successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Transfer not found"
+// @Router /transfers/{id} [get]
+func (tc *TransferController) GetTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	transferID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transfer ID")
+		return
+	}
+
+	transfer, err := tc.transferService.GetTransfer(user.ID, transferID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Transfer retrieved successfully", transfer.ToResponse(requestBaseURL(c)))
+}
+
+// DeleteTransfer godoc
+// @Summary Delete a transfer
+// @Description Cancel a transfer. Its download links stop working and its files are moved to the trash.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} utils.APIResponse "Transfer deleted successfully"
+// @Failure 400 {object} utils.APIResponse "Invalid transfer ID"
+// @Failure 401 {object} utils.APIResponse "Unauthorized"
+// @Failure 404 {object} utils.APIResponse "Transfer not found"
+// @Router /transfers/{id} [delete]
+func (tc *TransferController) DeleteTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not found in context")
+		return
+	}
+
+	transferID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transfer ID")
+		return
+	}
+
+	if err := tc.transferService.DeleteTransfer(user.ID, transferID); err != nil {
+		if errors.Is(err, services.ErrTransferNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		config.GetLogger().Error("Failed to delete transfer", "error", err, "transfer_id", transferID)
+		utils.InternalServerErrorResponse(c, "Failed to delete transfer")
+		return
+	}
+
+	tc.auditService.LogEvent(&user.ID, models.ActionTransferDelete, models.ResourceTransfer, &transferID,
+		"Transfer deleted", c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+
+	utils.SuccessResponse(c, http.StatusOK, "Transfer deleted successfully", nil)
+}
+
+// GetPublicTransfer godoc
+// @Summary Get a transfer by its download link
+// @Description Get the sender, message and file list of a transfer
+// @Tags public
+// @Produce json
+// @Param token path string true "Transfer Token"
+// @Success 200 {object} utils.APIResponse "Transfer retrieved successfully"
+// @Failure 404 {object} utils.APIResponse "Transfer not found"
+// @Failure 410 {object} utils.APIResponse "Transfer has expired"
+// @Router /public/transfers/{token} [get]
+func (tc *TransferController) GetPublicTransfer(c *gin.Context) {
+	transfer, _, ok := tc.loadPublicTransfer(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Transfer retrieved successfully", transfer.ToPublicInfo())
+}
+
+// DownloadPublicTransfer godoc
+// @Summary Download a transfer as a zip
+// @Description Download all files of a transfer as a zip archive. The sender is notified of the download.
+// @Tags public
+// @Produce application/zip
+// @Param token path string true "Transfer Token"
+// @Success 200 {file} binary "Zip archive"
+// @Failure 404 {object} utils.APIResponse "Transfer not found"
+// @Failure 410 {object} utils.APIResponse "Transfer has expired"
+// @Router /public/transfers/{token}/download [get]
+func (tc *TransferController) DownloadPublicTransfer(c *gin.Context) {
+	transfer, recipient, ok := tc.loadPublicTransfer(c)
+	if !ok {
+		return
+	}
+
+	if len(transfer.Files) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Transfer has no files left")
+		return
+	}
+
+	items := make([]services.ZipItem, 0, len(transfer.Files))
+	for i := range transfer.Files {
+		items = append(items, services.ZipItem{Path: transfer.Files[i].OriginalName, File: &transfer.Files[i]})
+	}
+
+	if !tc.recordDownload(c, transfer, recipient, transfer.Files) {
+		return
+	}
+
+	archiveName := "transfer.zip"
+	if transfer.Title != "" {
+		archiveName = transfer.Title + ".zip"
+	}
+	streamZip(c, tc.zipService, archiveName, items, nil)
+}
+
+// DownloadPublicTransferFile godoc
+// @Summary Download a file from a transfer
+// @Description Download a single file of a transfer. The sender is notified of the download.
+// @Tags public
+// @Produce octet-stream
+// @Param token path string true "Transfer Token"
+// @Param fileId path string true "File ID"
+// @Success 200 {file} binary "File content"
+// @Failure 400 {object} utils.APIResponse "Invalid file ID"
+// @Failure 404 {object} utils.APIResponse "File not found in transfer"
+// @Failure 410 {object} utils.APIResponse "Transfer has expired"
+// @Router /public/transfers/{token}/files/{fileId}/download [get]
+func (tc *TransferController) DownloadPublicTransferFile(c *gin.Context) {
+	transfer, recipient, ok := tc.loadPublicTransfer(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("fileId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	file, err := tc.transferService.GetTransferFile(transfer, fileID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "File not found in transfer")
+		return
+	}
+
+	if !tc.recordDownload(c, transfer, recipient, []models.File{*file}) {
+		return
+	}
+
+	streamStoredFile(c, file)
+}
+
+// loadPublicTransfer resolves the token in the path. It responds and returns false when the
+// transfer cannot be downloaded.
+func (tc *TransferController) loadPublicTransfer(c *gin.Context) (*models.Transfer, *models.TransferRecipient, bool) {
+	transfer, recipient, err := tc.transferService.GetTransferByToken(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrTransferExpired) {
+			utils.ErrorResponse(c, http.StatusGone, "Transfer has expired")
+			return nil, nil, false
+		}
+		utils.ErrorResponse(c, http.StatusNotFound, "Transfer not found")
+		return nil, nil, false
+	}
+	return transfer, recipient, true
+}
+
+// recordDownload counts a transfer download and tells the sender who downloaded what
+func (tc *TransferController) recordDownload(c *gin.Context, transfer *models.Transfer, recipient *models.TransferRecipient, files []models.File) bool {
+	if err := tc.transferService.RecordDownload(transfer, recipient, files); err != nil {
+		config.GetLogger().Error("Failed to count transfer download", "error", err, "transfer_id", transfer.ID)
+		utils.InternalServerErrorResponse(c, "Failed to download transfer")
+		return false
+	}
+
+	downloadedBy := "Someone with the transfer link"
+	if recipient != nil {
+		downloadedBy = recipient.Email
+	}
+
+	title := transfer.Title
+	if title == "" {
+		title = "your transfer"
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.OriginalName)
+	}
+
+	message := fmt.Sprintf("%s downloaded %d file(s) from %s: %s", downloadedBy, len(files), title, summarizeNames(names, 5))
+	if _, err := tc.notificationService.Notify(transfer.UserID, models.NotificationTransferDownload,
+		"Transfer downloaded", message, models.ResourceTransfer, &transfer.ID); err != nil {
+		config.GetLogger().Error("Failed to notify transfer sender", "error", err, "transfer_id", transfer.ID)
+	}
+
+	tc.auditService.LogEvent(nil, models.ActionTransferDownload, models.ResourceTransfer, &transfer.ID,
+		message, c.ClientIP(), c.GetHeader("User-Agent"), models.StatusSuccess)
+	return true
+}
@@ -13,14 +13,18 @@ import (
 	"github.com/manjurulhoque/swift-share/backend/utils"
 )
 
+// TrashController serves the trash of the signed in user. Managers of a shared drive handle the
+// drive's trashed items through the same routes.
 type TrashController struct {
 	trashService *services.TrashService
+	driveService *services.SharedDriveService
 	auditService *services.AuditService
 }
 
 func NewTrashController() *TrashController {
 	return &TrashController{
 		trashService: services.NewTrashService(),
+		driveService: services.NewSharedDriveService(),
 		auditService: services.NewAuditService(),
 	}
 }
@@ -90,7 +94,7 @@ func (tc *TrashController) MoveFileToTrash(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.MoveFileToTrash(user.ID, fileID); err != nil {
+	if err := tc.trashService.MoveFileToTrash(tc.driveService.ActingOwner(user.ID, fileID, true), fileID); err != nil {
 		config.GetLogger().Error("Failed to move file to trash", "error", err, "file_id", fileID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to move file to trash")
 		return
@@ -128,7 +132,12 @@ func (tc *TrashController) MoveFolderToTrash(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.MoveFolderToTrash(user.ID, folderID); err != nil {
+	if services.IsDriveRoot(folderID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, services.ErrDriveRootFolder.Error())
+		return
+	}
+
+	if err := tc.trashService.MoveFolderToTrash(tc.driveService.ActingOwner(user.ID, folderID, false), folderID); err != nil {
 		config.GetLogger().Error("Failed to move folder to trash", "error", err, "folder_id", folderID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to move folder to trash")
 		return
@@ -166,7 +175,7 @@ func (tc *TrashController) RestoreFile(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.RestoreFileFromTrash(user.ID, fileID); err != nil {
+	if err := tc.trashService.RestoreFileFromTrash(tc.driveService.ActingOwner(user.ID, fileID, true), fileID); err != nil {
 		config.GetLogger().Error("Failed to restore file from trash", "error", err, "file_id", fileID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to restore file from trash")
 		return
@@ -204,7 +213,7 @@ func (tc *TrashController) RestoreFolder(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.RestoreFolderFromTrash(user.ID, folderID); err != nil {
+	if err := tc.trashService.RestoreFolderFromTrash(tc.driveService.ActingOwner(user.ID, folderID, false), folderID); err != nil {
 		config.GetLogger().Error("Failed to restore folder from trash", "error", err, "folder_id", folderID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to restore folder from trash")
 		return
@@ -242,7 +251,7 @@ func (tc *TrashController) PermanentlyDeleteFile(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.PermanentlyDeleteFile(user.ID, fileID); err != nil {
+	if err := tc.trashService.PermanentlyDeleteFile(tc.driveService.ActingOwner(user.ID, fileID, true), fileID); err != nil {
 		config.GetLogger().Error("Failed to permanently delete file", "error", err, "file_id", fileID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to permanently delete file")
 		return
@@ -280,7 +289,7 @@ func (tc *TrashController) PermanentlyDeleteFolder(c *gin.Context) {
 		return
 	}
 
-	if err := tc.trashService.PermanentlyDeleteFolder(user.ID, folderID); err != nil {
+	if err := tc.trashService.PermanentlyDeleteFolder(tc.driveService.ActingOwner(user.ID, folderID, false), folderID); err != nil {
 		config.GetLogger().Error("Failed to permanently delete folder", "error", err, "folder_id", folderID, "user_id", user.ID)
 		utils.InternalServerErrorResponse(c, "Failed to permanently delete folder")
 		return
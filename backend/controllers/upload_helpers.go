@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// checkUploadPolicy applies the size limit and the configured file type policy to anonymous
+// uploads. It responds and returns false when a file is rejected.
+func checkUploadPolicy(c *gin.Context, files []*multipart.FileHeader, maxSize int64) bool {
+	if !checkFileSizes(c, files, maxSize) {
+		return false
+	}
+	for _, header := range files {
+		if !utils.IsAllowedFileType(header.Filename) {
+			utils.ErrorResponse(c, http.StatusUnsupportedMediaType, fmt.Sprintf("File type of %s is not allowed", header.Filename))
+			return false
+		}
+	}
+	return true
+}
+
+// checkFileSizes responds and returns false when a file is larger than maxSize, usually the
+// MaxFileSize of the owner's plan limits
+func checkFileSizes(c *gin.Context, files []*multipart.FileHeader, maxSize int64) bool {
+	for _, header := range files {
+		if header.Size > maxSize {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("File %s exceeds the %s limit", header.Filename, utils.FormatFileSize(maxSize)))
+			return false
+		}
+	}
+	return true
+}
+
+// planLimits returns the limits in effect for a user, responding when they cannot be resolved
+func planLimits(c *gin.Context, planService *services.PlanService, userID uuid.UUID) (*models.PlanLimits, bool) {
+	limits, err := planService.Limits(userID)
+	if err != nil {
+		appLogger.Error("Failed to resolve plan limits", "error", err, "user_id", userID)
+		utils.InternalServerErrorResponse(c, "Failed to resolve plan limits")
+		return nil, false
+	}
+	return limits, true
+}
+
+// readUploader reads the optional uploader_name and uploader_email form fields of an anonymous
+// upload. An already verified address takes precedence over the form value.
+func readUploader(c *gin.Context, verifiedEmail string) (models.Uploader, error) {
+	uploader := models.Uploader{
+		Name:  strings.TrimSpace(c.PostForm("uploader_name")),
+		Email: utils.NormalizeEmail(c.PostForm("uploader_email")),
+	}
+	if len(uploader.Name) > 100 {
+		return uploader, errors.New("uploader name must be at most 100 characters")
+	}
+	if verifiedEmail != "" {
+		uploader.Email = verifiedEmail
+	} else if uploader.Email != "" {
+		if addr, err := mail.ParseAddress(uploader.Email); err != nil || addr.Address != uploader.Email {
+			return uploader, errors.New("invalid uploader email")
+		}
+	}
+	return uploader, nil
+}
+
+// describeUploader names an anonymous uploader in audit entries and notifications
+func describeUploader(uploader models.Uploader) string {
+	switch {
+	case uploader.Name != "" && uploader.Email != "":
+		return uploader.Name + " <" + uploader.Email + ">"
+	case uploader.Email != "":
+		return uploader.Email
+	case uploader.Name != "":
+		return uploader.Name
+	}
+	return "an anonymous visitor"
+}
+
+// summarizeNames lists up to max names and how many more there are
+func summarizeNames(names []string, max int) string {
+	if len(names) <= max {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:max], ", "), len(names)-max)
+}
+
+// resourceOwner returns the owner of the file or folder of an owner-only route: the user, or the
+// shared drive they manage the item for
+func resourceOwner(c *gin.Context, user *models.User) uuid.UUID {
+	if ownerID, ok := middleware.GetResourceOwnerID(c); ok {
+		return ownerID
+	}
+	return user.ID
+}
+
+// accessErrorResponse maps authorization errors for a file or folder to HTTP responses
+func accessErrorResponse(c *gin.Context, err error, resource string) {
+	switch {
+	case errors.Is(err, services.ErrResourceNotFound):
+		utils.NotFoundResponse(c, resource)
+	case errors.Is(err, services.ErrAccessDenied):
+		utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("You do not have access to this %s", strings.ToLower(resource)))
+	default:
+		appLogger.Error("Failed to check permissions", "error", err)
+		utils.InternalServerErrorResponse(c, "Failed to check permissions")
+	}
+}
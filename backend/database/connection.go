@@ -77,11 +77,35 @@ func Migrate() {
 		&models.Folder{},
 		&models.File{},
 		&models.Collaborator{},
+		&models.FileVersion{},
 		&models.Download{},
 		&models.Upload{},
 		&models.AuditLog{},
 		&models.FileAccess{},
 		&models.ShareLink{},
+		&models.ShareLinkItem{},
+		&models.ArchiveExtraction{},
+		&models.ArchiveExtractionEntry{},
+		&models.ShareLinkAccess{},
+		&models.ShareLinkOTP{},
+		&models.Notification{},
+		&models.FileRequest{},
+		&models.Transfer{},
+		&models.TransferRecipient{},
+		&models.Comment{},
+		&models.CommentMention{},
+		&models.OwnershipTransfer{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.CollaboratorInvite{},
+		&models.AccessRequest{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.SharedDrive{},
+		&models.DriveMember{},
+		&models.Plan{},
+		&models.EmailMessage{},
+		&models.EmailPreference{},
 	)
 
 	if err != nil {
@@ -0,0 +1,166 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the result of a GeoIP lookup. Fields are empty when the address is unknown.
+type Location struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code
+	City    string `json:"city"`
+}
+
+// record matches the layout of MaxMind GeoLite2/GeoIP2 country and city databases
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Resolver looks up IP addresses in a MaxMind-format .mmdb file and reloads it when it changes
+type Resolver struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// NewResolver opens the database at path
+func NewResolver(path string) (*Resolver, error) {
+	r := &Resolver{path: path}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Lookup returns the location of an IP address
+func (r *Resolver) Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.reader == nil {
+		return Location{}
+	}
+
+	var rec record
+	if err := r.reader.Lookup(parsed, &rec); err != nil {
+		return Location{}
+	}
+	return Location{Country: rec.Country.ISOCode, City: rec.City.Names["en"]}
+}
+
+// Watch polls the database file and reloads it when its modification time or size changes
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			if err != nil {
+				continue
+			}
+			r.mu.RLock()
+			changed := !info.ModTime().Equal(r.modTime) || info.Size() != r.size
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.load(); err != nil {
+				// Keep serving from the previous database until a valid file is in place
+				config.GetLogger().Error("Failed to reload GeoIP database", "path", r.path, "error", err)
+				continue
+			}
+			config.GetLogger().Info("GeoIP database reloaded", "path", r.path)
+		}
+	}
+}
+
+// Close releases the database
+func (r *Resolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
+
+func (r *Resolver) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	// Read the whole file instead of memory mapping it, so an update written in place cannot
+	// corrupt the database that is being served
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.reader
+	r.reader = reader
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	r.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+var defaultResolver *Resolver
+
+// InitDefaultResolver opens the configured GeoIP database and starts watching it for changes.
+// GeoIP is optional, nothing is resolved when no database path is configured.
+func InitDefaultResolver(ctx context.Context) error {
+	cfg := config.AppConfig.GeoIP
+	if cfg.DatabasePath == "" {
+		return nil
+	}
+
+	resolver, err := NewResolver(cfg.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defaultResolver = resolver
+
+	go resolver.Watch(ctx, cfg.ReloadInterval)
+	return nil
+}
+
+// Lookup resolves an IP address with the default resolver
+func Lookup(ip string) Location {
+	if defaultResolver == nil {
+		return Location{}
+	}
+	return defaultResolver.Lookup(ip)
+}
@@ -1,52 +1,61 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/manjurulhoque/swift-share/backend/database"
 	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/services"
 	"github.com/manjurulhoque/swift-share/backend/utils"
 )
 
 // FileOwnerMiddleware ensures the authenticated user is the owner of the file in :id
 func FileOwnerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		user, exists := GetUserFromContext(c)
-		if !exists {
-			utils.UnauthorizedResponse(c, "User not found in context")
-			c.Abort()
-			return
-		}
+	return requireRole(true, models.RoleOwner, "Only the owner can perform this action")
+}
 
-		idParam := c.Param("id")
-		fileID, err := uuid.Parse(idParam)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file ID")
-			c.Abort()
-			return
-		}
+// FolderOwnerMiddleware ensures the authenticated user is the owner of the folder in :id
+func FolderOwnerMiddleware() gin.HandlerFunc {
+	return requireRole(false, models.RoleOwner, "Only the owner can perform this action")
+}
 
-		var file models.File
-		if err := database.GetDB().Select("user_id").Where("id = ?", fileID).First(&file).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "File not found")
-			c.Abort()
-			return
-		}
+// FileRoleMiddleware ensures the authenticated user has at least the required role on the file in
+// :id, either directly or through a folder above it
+func FileRoleMiddleware(required models.CollaboratorRole) gin.HandlerFunc {
+	return requireRole(true, required, fmt.Sprintf("You need %s access to perform this action", required))
+}
 
-		if file.UserID != user.ID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Only the owner can perform this action")
-			c.Abort()
-			return
-		}
+// FolderRoleMiddleware ensures the authenticated user has at least the required role on the
+// folder in :id, either directly or through a folder above it
+func FolderRoleMiddleware(required models.CollaboratorRole) gin.HandlerFunc {
+	return requireRole(false, required, fmt.Sprintf("You need %s access to perform this action", required))
+}
+
+// resourceOwnerKey is the context key of the owner of the file or folder in :id, set once the
+// user's role on it has been checked
+const resourceOwnerKey = "resource_owner_id"
 
-		c.Next()
+// GetResourceOwnerID returns the owner of the file or folder in :id. Behind FileOwnerMiddleware and
+// FolderOwnerMiddleware it is the user, or the shared drive they manage the item for.
+func GetResourceOwnerID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get(resourceOwnerKey)
+	if !exists {
+		return uuid.Nil, false
 	}
+	ownerID, ok := value.(uuid.UUID)
+	return ownerID, ok
 }
 
-// FolderOwnerMiddleware ensures the authenticated user is the owner of the folder in :id
-func FolderOwnerMiddleware() gin.HandlerFunc {
+// requireRole checks the user's effective role on the file or folder in :id
+func requireRole(isFile bool, required models.CollaboratorRole, deniedMessage string) gin.HandlerFunc {
+	resource := "folder"
+	if isFile {
+		resource = "file"
+	}
+
 	return func(c *gin.Context) {
 		user, exists := GetUserFromContext(c)
 		if !exists {
@@ -55,27 +64,44 @@ func FolderOwnerMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		idParam := c.Param("id")
-		folderID, err := uuid.Parse(idParam)
+		resourceID, err := uuid.Parse(c.Param("id"))
 		if err != nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid folder ID")
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+resource+" ID")
 			c.Abort()
 			return
 		}
 
-		var folder models.Folder
-		if err := database.GetDB().Select("user_id").Where("id = ?", folderID).First(&folder).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
-			c.Abort()
-			return
+		authorizationService := services.NewAuthorizationService()
+		var ownerID uuid.UUID
+		if isFile {
+			var file *models.File
+			if file, err = authorizationService.AuthorizeFile(user.ID, resourceID, required); err == nil {
+				ownerID = file.OwnerID()
+			}
+		} else {
+			var folder *models.Folder
+			if folder, err = authorizationService.AuthorizeFolder(user.ID, resourceID, required); err == nil {
+				ownerID = folder.OwnerID()
+			}
 		}
 
-		if folder.UserID != user.ID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Only the owner can perform this action")
+		switch {
+		case err == nil:
+			c.Set(resourceOwnerKey, ownerID)
+			c.Next()
+		case errors.Is(err, services.ErrResourceNotFound):
+			if isFile {
+				utils.ErrorResponse(c, http.StatusNotFound, "File not found")
+			} else {
+				utils.ErrorResponse(c, http.StatusNotFound, "Folder not found")
+			}
+			c.Abort()
+		case errors.Is(err, services.ErrAccessDenied):
+			utils.ErrorResponse(c, http.StatusForbidden, deniedMessage)
+			c.Abort()
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to check permissions")
 			c.Abort()
-			return
 		}
-
-		c.Next()
 	}
 }
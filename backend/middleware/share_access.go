@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/geoip"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+)
+
+// ShareAccessTokenTTL is how long a share access token stays valid after the link was unlocked
+const ShareAccessTokenTTL = 15 * time.Minute
+
+// shareAccessAudience keeps share access tokens from being accepted as user tokens and vice versa
+const shareAccessAudience = "share-access"
+
+// ShareAccessHeader is the header carrying the share access token. Browser downloads can pass it
+// as the access_token query parameter instead.
+const ShareAccessHeader = "X-Share-Access-Token"
+
+type ShareAccessClaims struct {
+	ShareLinkID uuid.UUID `json:"share_link_id"`
+	Email       string    `json:"email,omitempty"` // verified address for email restricted links
+	View        int       `json:"view"`            // view count of the link after the visit that unlocked it
+	jwt.RegisteredClaims
+}
+
+// GenerateShareAccessToken issues a short-lived token proving the share link was unlocked, email
+// is the address the visitor verified or empty
+func GenerateShareAccessToken(shareLink models.ShareLink, email string) (string, time.Time, error) {
+	expirationTime := time.Now().Add(ShareAccessTokenTTL)
+	if shareLink.ExpiresAt != nil && shareLink.ExpiresAt.Before(expirationTime) {
+		expirationTime = *shareLink.ExpiresAt
+	}
+
+	claims := &ShareAccessClaims{
+		ShareLinkID: shareLink.ID,
+		Email:       email,
+		View:        shareLink.ViewCount,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "swift-share",
+			Audience:  jwt.ClaimStrings{shareAccessAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(config.AppConfig.JWT.Secret))
+	return signed, expirationTime, err
+}
+
+// ShareAccessMiddleware validates the share access token for the share link in the :token path
+// parameter and stores the link in the context
+func ShareAccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.GetHeader(ShareAccessHeader)
+		if tokenString == "" {
+			tokenString = c.Query("access_token")
+		}
+		tokenString = strings.TrimSpace(tokenString)
+		if tokenString == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Share access token required")
+			c.Abort()
+			return
+		}
+
+		claims := &ShareAccessClaims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(config.AppConfig.JWT.Secret), nil
+		}, jwt.WithAudience(shareAccessAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+		if err != nil || !token.Valid {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired share access token")
+			c.Abort()
+			return
+		}
+
+		var shareLink models.ShareLink
+		if err := database.GetDB().Preload("User").Preload("File").Preload("Folder").
+			Preload("Items.File").Preload("Items.Folder").
+			Where("token = ?", c.Param("token")).First(&shareLink).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Share not found")
+			c.Abort()
+			return
+		}
+
+		// The token is bound to a single link
+		if shareLink.ID != claims.ShareLinkID {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired share access token")
+			c.Abort()
+			return
+		}
+
+		// Tokens stop working as soon as the link is disabled, expires or is used up. A used up
+		// link still serves the visit that took its last view, so the visitor who opened a one-time
+		// link can download it once.
+		switch shareLink.Status() {
+		case models.ShareStatusDisabled:
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link has been disabled")
+			c.Abort()
+			return
+		case models.ShareStatusScheduled:
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link is not active yet")
+			c.Abort()
+			return
+		case models.ShareStatusExpired:
+			utils.ErrorResponse(c, http.StatusGone, "Share link has expired")
+			c.Abort()
+			return
+		case models.ShareStatusExhausted:
+			if claims.View != shareLink.ViewCount || (shareLink.OneTime && shareLink.DownloadsExhausted()) {
+				utils.ErrorResponse(c, http.StatusGone, "Share link is no longer available")
+				c.Abort()
+				return
+			}
+		}
+
+		if !shareLink.AllowsCountry(geoip.Lookup(c.ClientIP()).Country) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Share link is not available in your region")
+			c.Abort()
+			return
+		}
+
+		// Re-check the address so tokens stop working when it is removed from the allow list
+		if shareLink.RequiresEmail() && !shareLink.AllowsEmail(claims.Email) {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Email verification required")
+			c.Abort()
+			return
+		}
+
+		c.Set("share_link", shareLink)
+		c.Set("share_email", claims.Email)
+		c.Next()
+	}
+}
+
+// GetShareLinkFromContext retrieves the share link unlocked by ShareAccessMiddleware
+func GetShareLinkFromContext(c *gin.Context) (*models.ShareLink, bool) {
+	shareLink, exists := c.Get("share_link")
+	if !exists {
+		return nil, false
+	}
+
+	link, ok := shareLink.(models.ShareLink)
+	if !ok {
+		return nil, false
+	}
+
+	return &link, true
+}
+
+// GetShareEmailFromContext returns the email address verified for the share access token, empty
+// when the link is not email restricted
+func GetShareEmailFromContext(c *gin.Context) string {
+	return c.GetString("share_email")
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccessRequestStatus string
+
+const (
+	AccessRequestPending   AccessRequestStatus = "pending"
+	AccessRequestApproved  AccessRequestStatus = "approved"
+	AccessRequestDenied    AccessRequestStatus = "denied"
+	AccessRequestCancelled AccessRequestStatus = "cancelled"
+)
+
+// AccessRequest asks the owner of a file or folder for a role on it. Requests go to whoever owns
+// the item when they are answered, so they follow ownership transfers.
+type AccessRequest struct {
+	ID              uuid.UUID           `json:"id" gorm:"type:uuid;primary_key"`
+	FileID          *uuid.UUID          `json:"file_id" gorm:"type:uuid;index"`
+	FolderID        *uuid.UUID          `json:"folder_id" gorm:"type:uuid;index"`
+	ItemName        string              `json:"item_name" gorm:"size:255"` // name when the request was made
+	RequesterID     uuid.UUID           `json:"requester_id" gorm:"type:uuid;not null;index"`
+	Role            CollaboratorRole    `json:"role" gorm:"size:20;not null"`
+	Message         string              `json:"message" gorm:"size:1000"`
+	Status          AccessRequestStatus `json:"status" gorm:"size:20;not null;index"`
+	GrantedRole     CollaboratorRole    `json:"granted_role" gorm:"size:20"` // can differ from the requested role
+	ResponseMessage string              `json:"response_message" gorm:"size:1000"`
+	RespondedByID   *uuid.UUID          `json:"responded_by_id" gorm:"type:uuid"`
+	RespondedAt     *time.Time          `json:"responded_at"`
+	CreatedAt       time.Time           `json:"created_at" gorm:"index"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+
+	// Relationships
+	Requester   User  `json:"requester,omitempty" gorm:"foreignKey:RequesterID"`
+	RespondedBy *User `json:"responded_by,omitempty" gorm:"foreignKey:RespondedByID"`
+}
+
+type AccessRequestCreateRequest struct {
+	Role    CollaboratorRole `json:"role" validate:"required,oneof=viewer commenter editor"`
+	Message string           `json:"message" validate:"max=1000"`
+}
+
+// ApproveAccessRequest grants the requested role unless the owner picks another one
+type ApproveAccessRequest struct {
+	Role      CollaboratorRole `json:"role" validate:"omitempty,oneof=viewer commenter editor"`
+	ExpiresAt *time.Time       `json:"expires_at"`
+	Message   string           `json:"message" validate:"max=1000"`
+}
+
+type DenyAccessRequest struct {
+	Message string `json:"message" validate:"max=1000"`
+}
+
+type AccessRequestResponse struct {
+	ID              uuid.UUID           `json:"id"`
+	ItemType        string              `json:"item_type"` // file or folder
+	ItemID          uuid.UUID           `json:"item_id"`
+	ItemName        string              `json:"item_name"`
+	Requester       UserResponse        `json:"requester"`
+	Role            CollaboratorRole    `json:"role"`
+	Message         string              `json:"message"`
+	Status          AccessRequestStatus `json:"status"`
+	GrantedRole     CollaboratorRole    `json:"granted_role,omitempty"`
+	ResponseMessage string              `json:"response_message,omitempty"`
+	RespondedAt     *time.Time          `json:"responded_at"`
+	CreatedAt       time.Time           `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (ar *AccessRequest) BeforeCreate(tx *gorm.DB) error {
+	if ar.ID == uuid.Nil {
+		ar.ID = uuid.New()
+	}
+	return nil
+}
+
+// ItemType returns whether access is requested to a file or a folder
+func (ar *AccessRequest) ItemType() string {
+	if ar.FileID != nil {
+		return ResourceFile
+	}
+	return ResourceFolder
+}
+
+// ItemID returns the ID of the file or folder access is requested to
+func (ar *AccessRequest) ItemID() uuid.UUID {
+	if ar.FileID != nil {
+		return *ar.FileID
+	}
+	return *ar.FolderID
+}
+
+// ToResponse converts AccessRequest to AccessRequestResponse
+func (ar *AccessRequest) ToResponse() AccessRequestResponse {
+	return AccessRequestResponse{
+		ID:              ar.ID,
+		ItemType:        ar.ItemType(),
+		ItemID:          ar.ItemID(),
+		ItemName:        ar.ItemName,
+		Requester:       ar.Requester.ToResponse(),
+		Role:            ar.Role,
+		Message:         ar.Message,
+		Status:          ar.Status,
+		GrantedRole:     ar.GrantedRole,
+		ResponseMessage: ar.ResponseMessage,
+		RespondedAt:     ar.RespondedAt,
+		CreatedAt:       ar.CreatedAt,
+	}
+}
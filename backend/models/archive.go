@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Supported archive formats
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTar   = "tar"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+// ArchiveEntry describes a single entry inside an uploaded archive
+type ArchiveEntry struct {
+	Path           string    `json:"path"`
+	Name           string    `json:"name"`
+	IsDir          bool      `json:"is_dir"`
+	Size           int64     `json:"size"`
+	CompressedSize int64     `json:"compressed_size,omitempty"`
+	ModifiedAt     time.Time `json:"modified_at"`
+}
+
+// ArchiveListing is the table of contents of an archive
+type ArchiveListing struct {
+	Format         string         `json:"format"`
+	Entries        []ArchiveEntry `json:"entries"`
+	TotalEntries   int            `json:"total_entries"`
+	TotalSize      int64          `json:"total_size"`
+	SkippedEntries int            `json:"skipped_entries"`
+	File           FileResponse   `json:"file"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ArchiveExtraction is a background job that unpacks an archive file into a folder tree
+type ArchiveExtraction struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	FileID         uuid.UUID  `json:"file_id" gorm:"type:uuid;not null;index"`
+	TargetFolderID *uuid.UUID `json:"target_folder_id" gorm:"type:uuid;index"` // null for the root
+	OnConflict     string     `json:"on_conflict" gorm:"size:20;not null"`
+	Status         string     `json:"status" gorm:"size:20;not null" validate:"required,oneof=pending processing completed failed"`
+	ErrorMessage   string     `json:"error_message" gorm:"size:500"`
+	TotalEntries   int        `json:"total_entries" gorm:"default:0"`
+	FilesCreated   int        `json:"files_created" gorm:"default:0"`
+	FoldersCreated int        `json:"folders_created" gorm:"default:0"`
+	SkippedEntries int        `json:"skipped_entries" gorm:"default:0"`
+	FailedEntries  int        `json:"failed_entries" gorm:"default:0"`
+	StartedAt      *time.Time `json:"started_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	User    User                     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	File    File                     `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Entries []ArchiveExtractionEntry `json:"entries,omitempty" gorm:"foreignKey:ExtractionID"`
+}
+
+// ArchiveExtractionEntry records the outcome of extracting a single archive entry
+type ArchiveExtractionEntry struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	ExtractionID uuid.UUID  `json:"extraction_id" gorm:"type:uuid;not null;index"`
+	Path         string     `json:"path" gorm:"size:1000;not null"`
+	IsDir        bool       `json:"is_dir" gorm:"default:false"`
+	Status       string     `json:"status" gorm:"size:20;not null"`
+	FileID       *uuid.UUID `json:"file_id" gorm:"type:uuid"`
+	FolderID     *uuid.UUID `json:"folder_id" gorm:"type:uuid"`
+	Message      string     `json:"message" gorm:"size:500"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Extraction job statuses
+const (
+	ExtractionStatusPending    = "pending"
+	ExtractionStatusProcessing = "processing"
+	ExtractionStatusCompleted  = "completed"
+	ExtractionStatusFailed     = "failed"
+)
+
+// Extraction entry statuses
+const (
+	ExtractionEntryCreated = "created"
+	ExtractionEntryExists  = "exists"
+	ExtractionEntrySkipped = "skipped"
+	ExtractionEntryRenamed = "renamed"
+	ExtractionEntryFailed  = "failed"
+)
+
+// Name collision rules for extracted files
+const (
+	ConflictSkip   = "skip"
+	ConflictRename = "rename"
+)
+
+type ArchiveExtractRequest struct {
+	FolderID   *uuid.UUID `json:"folder_id"`
+	OnConflict string     `json:"on_conflict" validate:"omitempty,oneof=skip rename"`
+}
+
+type ArchiveExtractionResponse struct {
+	ID             uuid.UUID                `json:"id"`
+	FileID         uuid.UUID                `json:"file_id"`
+	TargetFolderID *uuid.UUID               `json:"target_folder_id"`
+	OnConflict     string                   `json:"on_conflict"`
+	Status         string                   `json:"status"`
+	ErrorMessage   string                   `json:"error_message,omitempty"`
+	TotalEntries   int                      `json:"total_entries"`
+	FilesCreated   int                      `json:"files_created"`
+	FoldersCreated int                      `json:"folders_created"`
+	SkippedEntries int                      `json:"skipped_entries"`
+	FailedEntries  int                      `json:"failed_entries"`
+	StartedAt      *time.Time               `json:"started_at"`
+	CompletedAt    *time.Time               `json:"completed_at"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Entries        []ArchiveExtractionEntry `json:"entries,omitempty"`
+}
+
+// BeforeCreate hook to set UUID
+func (e *ArchiveExtraction) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (e *ArchiveExtractionEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts ArchiveExtraction to ArchiveExtractionResponse
+func (e *ArchiveExtraction) ToResponse() ArchiveExtractionResponse {
+	return ArchiveExtractionResponse{
+		ID:             e.ID,
+		FileID:         e.FileID,
+		TargetFolderID: e.TargetFolderID,
+		OnConflict:     e.OnConflict,
+		Status:         e.Status,
+		ErrorMessage:   e.ErrorMessage,
+		TotalEntries:   e.TotalEntries,
+		FilesCreated:   e.FilesCreated,
+		FoldersCreated: e.FoldersCreated,
+		SkippedEntries: e.SkippedEntries,
+		FailedEntries:  e.FailedEntries,
+		StartedAt:      e.StartedAt,
+		CompletedAt:    e.CompletedAt,
+		CreatedAt:      e.CreatedAt,
+		Entries:        e.Entries,
+	}
+}
+
+// IsFinished returns true once the job has completed or failed
+func (e *ArchiveExtraction) IsFinished() bool {
+	return e.Status == ExtractionStatusCompleted || e.Status == ExtractionStatusFailed
+}
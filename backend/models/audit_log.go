@@ -15,6 +15,8 @@ type AuditLog struct {
 	ResourceID *uuid.UUID `json:"resource_id" gorm:"type:uuid;index"`
 	Details    string     `json:"details" gorm:"type:text"`
 	IPAddress  string     `json:"ip_address" gorm:"size:45"`
+	Country    string     `json:"country" gorm:"size:2"`
+	City       string     `json:"city" gorm:"size:100"`
 	UserAgent  string     `json:"user_agent" gorm:"size:500"`
 	Status     string     `json:"status" gorm:"size:20;not null" validate:"required,oneof=success failure"`
 	CreatedAt  time.Time  `json:"created_at"`
@@ -30,6 +32,8 @@ type AuditLogResponse struct {
 	ResourceID *uuid.UUID    `json:"resource_id"`
 	Details    string        `json:"details"`
 	IPAddress  string        `json:"ip_address"`
+	Country    string        `json:"country"`
+	City       string        `json:"city"`
 	UserAgent  string        `json:"user_agent"`
 	Status     string        `json:"status"`
 	CreatedAt  time.Time     `json:"created_at"`
@@ -46,36 +50,95 @@ type AuditLogCreateRequest struct {
 
 // Common audit actions
 const (
-	ActionLogin          = "login"
-	ActionLogout         = "logout"
-	ActionRegister       = "register"
-	ActionTokenRefresh   = "token_refresh"
-	ActionFileUpload     = "file_upload"
-	ActionFileDownload   = "file_download"
-	ActionFileDelete     = "file_delete"
-	ActionFileUpdate     = "file_update"
-	ActionFileMove       = "file_move"
-	ActionFolderCreate   = "folder_create"
-	ActionFolderUpdate   = "folder_update"
-	ActionFolderDelete   = "folder_delete"
-	ActionFolderMove     = "folder_move"
-	ActionShareCreate    = "share_create"
-	ActionShareAccess    = "share_access"
-	ActionShareUpdate    = "share_update"
-	ActionShareDelete    = "share_delete"
-	ActionUserUpdate     = "user_update"
-	ActionUserDelete     = "user_delete"
-	ActionPasswordChange = "password_change"
+	ActionLogin             = "login"
+	ActionLogout            = "logout"
+	ActionRegister          = "register"
+	ActionTokenRefresh      = "token_refresh"
+	ActionFileUpload        = "file_upload"
+	ActionFileDownload      = "file_download"
+	ActionFileDelete        = "file_delete"
+	ActionFileUpdate        = "file_update"
+	ActionFileMove          = "file_move"
+	ActionFileExtract       = "file_extract"
+	ActionFolderCreate      = "folder_create"
+	ActionFolderUpdate      = "folder_update"
+	ActionFolderDelete      = "folder_delete"
+	ActionFolderMove        = "folder_move"
+	ActionFolderDownload    = "folder_download"
+	ActionFileRequestCreate = "file_request_create"
+	ActionFileRequestUpdate = "file_request_update"
+	ActionFileRequestDelete = "file_request_delete"
+	ActionFileRequestUpload = "file_request_upload"
+	ActionTransferCreate    = "transfer_create"
+	ActionTransferDownload  = "transfer_download"
+	ActionTransferDelete    = "transfer_delete"
+	ActionCommentCreate     = "comment_create"
+	ActionCommentUpdate     = "comment_update"
+	ActionCommentDelete     = "comment_delete"
+	ActionCommentResolve    = "comment_resolve"
+	ActionShareCreate       = "share_create"
+	ActionShareAccess       = "share_access"
+	ActionShareDownload     = "share_download"
+	ActionShareUpload       = "share_upload"
+	ActionShareUpdate       = "share_update"
+	ActionShareDelete       = "share_delete"
+	ActionOwnershipTransfer = "ownership_transfer"
+	ActionOwnershipRequest  = "ownership_transfer_request"
+	ActionGroupCreate       = "group_create"
+	ActionGroupUpdate       = "group_update"
+	ActionGroupDelete       = "group_delete"
+	ActionGroupMemberAdd    = "group_member_add"
+	ActionGroupMemberUpdate = "group_member_update"
+	ActionGroupMemberRemove = "group_member_remove"
+	ActionInviteCreate      = "invite_create"
+	ActionInviteResend      = "invite_resend"
+	ActionInviteRevoke      = "invite_revoke"
+	ActionInviteAccept      = "invite_accept"
+	ActionAccessRequest     = "access_request"
+	ActionAccessApprove     = "access_request_approve"
+	ActionAccessDeny        = "access_request_deny"
+	ActionAccessCancel      = "access_request_cancel"
+	ActionOrgCreate         = "organization_create"
+	ActionOrgUpdate         = "organization_update"
+	ActionOrgDelete         = "organization_delete"
+	ActionOrgMemberAdd      = "organization_member_add"
+	ActionOrgMemberUpdate   = "organization_member_update"
+	ActionOrgMemberRemove   = "organization_member_remove"
+	ActionDriveCreate       = "drive_create"
+	ActionDriveUpdate       = "drive_update"
+	ActionDriveDelete       = "drive_delete"
+	ActionDriveMemberAdd    = "drive_member_add"
+	ActionDriveMemberUpdate = "drive_member_update"
+	ActionDriveMemberRemove = "drive_member_remove"
+	ActionQuotaUpdate       = "quota_update"
+	ActionPlanCreate        = "plan_create"
+	ActionPlanUpdate        = "plan_update"
+	ActionPlanDelete        = "plan_delete"
+	ActionPlanAssign        = "plan_assign"
+	ActionUserUpdate        = "user_update"
+	ActionUserDelete        = "user_delete"
+	ActionPasswordChange    = "password_change"
 )
 
 // Common audit resources
 const (
-	ResourceUser         = "user"
-	ResourceFile         = "file"
-	ResourceFolder       = "folder"
-	ResourceCollaborator = "collaborator"
-	ResourceAuth         = "auth"
-	ResourceSystem       = "system"
+	ResourceUser          = "user"
+	ResourceFile          = "file"
+	ResourceFolder        = "folder"
+	ResourceShareLink     = "share_link"
+	ResourceCollaborator  = "collaborator"
+	ResourceFileRequest   = "file_request"
+	ResourceTransfer      = "transfer"
+	ResourceComment       = "comment"
+	ResourceOwnership     = "ownership_transfer"
+	ResourceGroup         = "group"
+	ResourceInvite        = "collaborator_invite"
+	ResourceAccessRequest = "access_request"
+	ResourceOrganization  = "organization"
+	ResourceDrive         = "shared_drive"
+	ResourcePlan          = "plan"
+	ResourceAuth          = "auth"
+	ResourceSystem        = "system"
 )
 
 // Common audit statuses
@@ -101,6 +164,8 @@ func (a *AuditLog) ToResponse() AuditLogResponse {
 		ResourceID: a.ResourceID,
 		Details:    a.Details,
 		IPAddress:  a.IPAddress,
+		Country:    a.Country,
+		City:       a.City,
 		UserAgent:  a.UserAgent,
 		Status:     a.Status,
 		CreatedAt:  a.CreatedAt,
@@ -11,16 +11,39 @@ import (
 type CollaboratorRole string
 
 const (
-	RoleViewer    CollaboratorRole = "viewer"    // can view/download
-	RoleCommenter CollaboratorRole = "commenter" // reserved for future: can comment
-	RoleEditor    CollaboratorRole = "editor"    // can edit file metadata/delete
+	RoleViewer    CollaboratorRole = "viewer"    // can view/download and read comments
+	RoleCommenter CollaboratorRole = "commenter" // can view and comment
+	RoleEditor    CollaboratorRole = "editor"    // can also rename, edit, move, upload and add versions
+	RoleOwner     CollaboratorRole = "owner"     // effective role of the owner, never stored on a collaborator
 )
 
+// roleRanks orders roles from least to most privileged
+var roleRanks = map[CollaboratorRole]int{
+	RoleViewer:    1,
+	RoleCommenter: 2,
+	RoleEditor:    3,
+	RoleOwner:     4,
+}
+
+// Includes checks if the role grants at least the access of required. The empty role grants nothing.
+func (r CollaboratorRole) Includes(required CollaboratorRole) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[required]
+}
+
+// Max returns the more privileged of two roles
+func (r CollaboratorRole) Max(other CollaboratorRole) CollaboratorRole {
+	if roleRanks[other] > roleRanks[r] {
+		return other
+	}
+	return r
+}
+
 type Collaborator struct {
 	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primary_key"`
 	FileID    *uuid.UUID       `json:"file_id" gorm:"type:uuid;index"`   // nullable for folder collaborations
 	FolderID  *uuid.UUID       `json:"folder_id" gorm:"type:uuid;index"` // nullable for file collaborations
-	UserID    uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index"`
+	UserID    *uuid.UUID       `json:"user_id" gorm:"type:uuid;index"`   // null for group collaborations
+	GroupID   *uuid.UUID       `json:"group_id" gorm:"type:uuid;index"`  // every member of the group gets the role
 	Role      CollaboratorRole `json:"role" gorm:"size:20;not null"`
 	ExpiresAt *time.Time       `json:"expires_at"`
 	CreatedAt time.Time        `json:"created_at"`
@@ -29,7 +52,8 @@ type Collaborator struct {
 	// Relationships
 	File   *File   `json:"file,omitempty" gorm:"foreignKey:FileID"`
 	Folder *Folder `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
-	User   User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Group  *Group  `json:"group,omitempty" gorm:"foreignKey:GroupID"`
 }
 
 func (c *Collaborator) BeforeCreate(tx *gorm.DB) error {
@@ -56,23 +80,36 @@ type CollaboratorResponse struct {
 	ExpiresAt *time.Time       `json:"expires_at"`
 	CreatedAt time.Time        `json:"created_at"`
 	UpdatedAt time.Time        `json:"updated_at"`
-	User      UserResponse     `json:"user"`
+	User      *UserResponse    `json:"user,omitempty"`
+	Group     *GroupSummary    `json:"group,omitempty"`
 }
 
 func (c *Collaborator) ToResponse() CollaboratorResponse {
-	return CollaboratorResponse{
+	response := CollaboratorResponse{
 		ID:        c.ID,
 		Role:      c.Role,
 		ExpiresAt: c.ExpiresAt,
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
-		User:      c.User.ToResponse(),
 	}
+	if c.User != nil {
+		user := c.User.ToResponse()
+		response.User = &user
+	}
+	if c.Group != nil {
+		group := c.Group.ToSummary()
+		response.Group = &group
+	}
+	return response
 }
 
 // Requests
+// AddCollaboratorRequest shares with a user, a group the owner belongs to or an email address.
+// Addresses without an account are sent an invite.
 type AddCollaboratorRequest struct {
-	UserID    uuid.UUID        `json:"user_id" validate:"required"`
+	UserID    *uuid.UUID       `json:"user_id"`
+	GroupID   *uuid.UUID       `json:"group_id"`
+	Email     string           `json:"email" validate:"omitempty,email"`
 	Role      CollaboratorRole `json:"role" validate:"required,oneof=viewer commenter editor"`
 	ExpiresAt *time.Time       `json:"expires_at"`
 }
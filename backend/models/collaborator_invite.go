@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CollaboratorInviteStatus string
+
+const (
+	InvitePending  CollaboratorInviteStatus = "pending"
+	InviteAccepted CollaboratorInviteStatus = "accepted"
+	InviteRevoked  CollaboratorInviteStatus = "revoked"
+	InviteExpired  CollaboratorInviteStatus = "expired" // reported for pending invites past ExpiresAt, never stored
+)
+
+// CollaboratorInvite shares a file or folder with an email address that has no account yet. The
+// invite email carries a signed token, and the invite becomes a collaborator entry once the
+// address registers with that token or a signed in user with that address accepts it.
+type CollaboratorInvite struct {
+	ID              uuid.UUID                `json:"id" gorm:"type:uuid;primary_key"`
+	FileID          *uuid.UUID               `json:"file_id" gorm:"type:uuid;index"`
+	FolderID        *uuid.UUID               `json:"folder_id" gorm:"type:uuid;index"`
+	Email           string                   `json:"email" gorm:"size:255;not null;index"` // lowercased
+	Role            CollaboratorRole         `json:"role" gorm:"size:20;not null"`
+	AccessExpiresAt *time.Time               `json:"access_expires_at"` // expiry of the collaborator entry the invite turns into
+	ExpiresAt       time.Time                `json:"expires_at" gorm:"not null"`
+	InvitedByID     uuid.UUID                `json:"invited_by_id" gorm:"type:uuid;not null;index"`
+	Status          CollaboratorInviteStatus `json:"status" gorm:"size:20;not null;index"`
+	AcceptedByID    *uuid.UUID               `json:"accepted_by_id" gorm:"type:uuid"`
+	AcceptedAt      *time.Time               `json:"accepted_at"`
+	SendCount       int                      `json:"send_count" gorm:"default:0"`
+	LastSentAt      *time.Time               `json:"last_sent_at"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+
+	// Relationships
+	File      *File   `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Folder    *Folder `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+	InvitedBy User    `json:"invited_by,omitempty" gorm:"foreignKey:InvitedByID"`
+}
+
+type AcceptInviteRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type CollaboratorInviteResponse struct {
+	ID              uuid.UUID                `json:"id"`
+	Email           string                   `json:"email"`
+	Role            CollaboratorRole         `json:"role"`
+	Status          CollaboratorInviteStatus `json:"status"`
+	AccessExpiresAt *time.Time               `json:"access_expires_at"`
+	ExpiresAt       time.Time                `json:"expires_at"`
+	InvitedBy       UserResponse             `json:"invited_by"`
+	SendCount       int                      `json:"send_count"`
+	LastSentAt      *time.Time               `json:"last_sent_at"`
+	AcceptedAt      *time.Time               `json:"accepted_at,omitempty"`
+	CreatedAt       time.Time                `json:"created_at"`
+}
+
+// InvitePreview is what the holder of an invite token sees before signing up or accepting
+type InvitePreview struct {
+	Email     string                   `json:"email"`
+	Role      CollaboratorRole         `json:"role"`
+	Status    CollaboratorInviteStatus `json:"status"`
+	ItemType  string                   `json:"item_type"`
+	ItemName  string                   `json:"item_name"`
+	InvitedBy string                   `json:"invited_by"`
+	ExpiresAt time.Time                `json:"expires_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (ci *CollaboratorInvite) BeforeCreate(tx *gorm.DB) error {
+	if ci.ID == uuid.Nil {
+		ci.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsExpired checks if a pending invite can no longer be accepted
+func (ci *CollaboratorInvite) IsExpired() bool {
+	return ci.Status == InvitePending && time.Now().After(ci.ExpiresAt)
+}
+
+// CurrentStatus returns the stored status, or expired for pending invites past their expiry
+func (ci *CollaboratorInvite) CurrentStatus() CollaboratorInviteStatus {
+	if ci.IsExpired() {
+		return InviteExpired
+	}
+	return ci.Status
+}
+
+// ItemName returns the name of the file or folder the invite is for, when it was loaded
+func (ci *CollaboratorInvite) ItemName() string {
+	switch {
+	case ci.File != nil:
+		return ci.File.OriginalName
+	case ci.Folder != nil:
+		return ci.Folder.Name
+	default:
+		return ""
+	}
+}
+
+// ItemType returns whether the invite is for a file or a folder
+func (ci *CollaboratorInvite) ItemType() string {
+	if ci.FileID != nil {
+		return ResourceFile
+	}
+	return ResourceFolder
+}
+
+// ItemID returns the ID of the file or folder the invite is for
+func (ci *CollaboratorInvite) ItemID() uuid.UUID {
+	if ci.FileID != nil {
+		return *ci.FileID
+	}
+	return *ci.FolderID
+}
+
+// ToResponse converts CollaboratorInvite to CollaboratorInviteResponse
+func (ci *CollaboratorInvite) ToResponse() CollaboratorInviteResponse {
+	return CollaboratorInviteResponse{
+		ID:              ci.ID,
+		Email:           ci.Email,
+		Role:            ci.Role,
+		Status:          ci.CurrentStatus(),
+		AccessExpiresAt: ci.AccessExpiresAt,
+		ExpiresAt:       ci.ExpiresAt,
+		InvitedBy:       ci.InvitedBy.ToResponse(),
+		SendCount:       ci.SendCount,
+		LastSentAt:      ci.LastSentAt,
+		AcceptedAt:      ci.AcceptedAt,
+		CreatedAt:       ci.CreatedAt,
+	}
+}
+
+// ToPreview converts CollaboratorInvite to InvitePreview
+func (ci *CollaboratorInvite) ToPreview() InvitePreview {
+	return InvitePreview{
+		Email:     ci.Email,
+		Role:      ci.Role,
+		Status:    ci.CurrentStatus(),
+		ItemType:  ci.ItemType(),
+		ItemName:  ci.ItemName(),
+		InvitedBy: ci.InvitedBy.GetFullName(),
+		ExpiresAt: ci.ExpiresAt,
+	}
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Comment is a comment on a file or folder. Threads are one level deep: a comment without a
+// parent starts a thread and every reply points at the thread's first comment.
+type Comment struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	FileID      *uuid.UUID     `json:"file_id" gorm:"type:uuid;index"`
+	FolderID    *uuid.UUID     `json:"folder_id" gorm:"type:uuid;index"`
+	ParentID    *uuid.UUID     `json:"parent_id" gorm:"type:uuid;index"`
+	UserID      *uuid.UUID     `json:"user_id" gorm:"type:uuid;index"`       // null for share link visitors
+	ShareLinkID *uuid.UUID     `json:"share_link_id" gorm:"type:uuid;index"` // set for share link visitors
+	AuthorName  string         `json:"author_name" gorm:"size:100"`          // share link visitors only
+	AuthorEmail string         `json:"-" gorm:"size:255"`                    // verified address of share link visitors
+	Body        string         `json:"body" gorm:"type:text;not null"`
+	Anchor      CommentAnchor  `json:"anchor" gorm:"embedded;embeddedPrefix:anchor_"`
+	ResolvedAt  *time.Time     `json:"resolved_at"`
+	ResolvedBy  *uuid.UUID     `json:"resolved_by" gorm:"type:uuid"`
+	EditedAt    *time.Time     `json:"edited_at"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User     *User            `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Replies  []Comment        `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+	Mentions []CommentMention `json:"mentions,omitempty" gorm:"foreignKey:CommentID"`
+}
+
+// CommentAnchor optionally pins a thread to part of a file: a page of a document and/or a region
+// of a page or image. Region coordinates are fractions of the width and height, from the top left.
+type CommentAnchor struct {
+	Page   *int     `json:"page,omitempty"`
+	X      *float64 `json:"x,omitempty"`
+	Y      *float64 `json:"y,omitempty"`
+	Width  *float64 `json:"width,omitempty"`
+	Height *float64 `json:"height,omitempty"`
+}
+
+// CommentMention is a user @mentioned in a comment
+type CommentMention struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	CommentID uuid.UUID `json:"comment_id" gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type CommentCreateRequest struct {
+	Body       string         `json:"body" validate:"required,max=10000"`       // mention users as @email
+	ParentID   *uuid.UUID     `json:"parent_id"`                                // reply to a thread
+	Anchor     *CommentAnchor `json:"anchor"`                                   // threads only
+	AuthorName string         `json:"author_name" validate:"omitempty,max=100"` // share link visitors only
+}
+
+type CommentUpdateRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type CommentAuthor struct {
+	ID        *uuid.UUID `json:"id"`
+	Name      string     `json:"name"`
+	IsVisitor bool       `json:"is_visitor"` // commented through a share link
+}
+
+type CommentResponse struct {
+	ID         uuid.UUID         `json:"id"`
+	FileID     *uuid.UUID        `json:"file_id"`
+	FolderID   *uuid.UUID        `json:"folder_id"`
+	ParentID   *uuid.UUID        `json:"parent_id"`
+	Author     CommentAuthor     `json:"author"`
+	Body       string            `json:"body"`
+	Anchor     *CommentAnchor    `json:"anchor,omitempty"`
+	IsResolved bool              `json:"is_resolved"`
+	ResolvedAt *time.Time        `json:"resolved_at"`
+	ResolvedBy *uuid.UUID        `json:"resolved_by"`
+	EditedAt   *time.Time        `json:"edited_at"`
+	Mentions   []UserResponse    `json:"mentions"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Replies    []CommentResponse `json:"replies,omitempty"`
+}
+
+// BeforeCreate hook to set UUID
+func (c *Comment) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (cm *CommentMention) BeforeCreate(tx *gorm.DB) error {
+	if cm.ID == uuid.Nil {
+		cm.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsEmpty checks if no part of the anchor is set
+func (a CommentAnchor) IsEmpty() bool {
+	return a.Page == nil && a.X == nil && a.Y == nil && a.Width == nil && a.Height == nil
+}
+
+// IsAuthor checks if the user wrote the comment
+func (c *Comment) IsAuthor(userID uuid.UUID) bool {
+	return c.UserID != nil && *c.UserID == userID
+}
+
+// ToResponse converts Comment to CommentResponse, with its replies when they are loaded
+func (c *Comment) ToResponse() CommentResponse {
+	response := CommentResponse{
+		ID:         c.ID,
+		FileID:     c.FileID,
+		FolderID:   c.FolderID,
+		ParentID:   c.ParentID,
+		Body:       c.Body,
+		IsResolved: c.ResolvedAt != nil,
+		ResolvedAt: c.ResolvedAt,
+		ResolvedBy: c.ResolvedBy,
+		EditedAt:   c.EditedAt,
+		Mentions:   make([]UserResponse, 0, len(c.Mentions)),
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+	}
+
+	if c.User != nil && c.User.ID != uuid.Nil {
+		response.Author = CommentAuthor{ID: &c.User.ID, Name: c.User.GetFullName()}
+	} else {
+		response.Author = CommentAuthor{Name: c.AuthorName, IsVisitor: true}
+		if response.Author.Name == "" {
+			response.Author.Name = "Guest"
+		}
+	}
+
+	if !c.Anchor.IsEmpty() {
+		anchor := c.Anchor
+		response.Anchor = &anchor
+	}
+
+	for _, mention := range c.Mentions {
+		response.Mentions = append(response.Mentions, mention.User.ToResponse())
+	}
+
+	for i := range c.Replies {
+		response.Replies = append(response.Replies, c.Replies[i].ToResponse())
+	}
+
+	return response
+}
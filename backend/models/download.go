@@ -8,15 +8,16 @@ import (
 )
 
 type Download struct {
-	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
-	UserID    *uuid.UUID `json:"user_id" gorm:"type:uuid;index"` // Nullable for anonymous downloads
-	FileID    uuid.UUID  `json:"file_id" gorm:"type:uuid;not null;index"`
-	IPAddress string     `json:"ip_address" gorm:"size:45"`
-	UserAgent string     `json:"user_agent" gorm:"size:500"`
-	Referrer  string     `json:"referrer" gorm:"size:500"`
-	Country   string     `json:"country" gorm:"size:2"`
-	City      string     `json:"city" gorm:"size:100"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID      *uuid.UUID `json:"user_id" gorm:"type:uuid;index"` // Nullable for anonymous downloads
+	FileID      uuid.UUID  `json:"file_id" gorm:"type:uuid;not null;index"`
+	ShareLinkID *uuid.UUID `json:"share_link_id" gorm:"type:uuid;index"` // set for downloads through a share link
+	IPAddress   string     `json:"ip_address" gorm:"size:45"`
+	UserAgent   string     `json:"user_agent" gorm:"size:500"`
+	Referrer    string     `json:"referrer" gorm:"size:500"`
+	Country     string     `json:"country" gorm:"size:2"`
+	City        string     `json:"city" gorm:"size:100"`
+	CreatedAt   time.Time  `json:"created_at"`
 
 	// Relationships
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailStatus string
+
+const (
+	EmailPending EmailStatus = "pending"
+	EmailSent    EmailStatus = "sent"
+	EmailFailed  EmailStatus = "failed" // gave up after the configured number of attempts
+)
+
+type EmailDelivery string
+
+const (
+	EmailInstant EmailDelivery = "instant"
+	EmailDigest  EmailDelivery = "digest"
+	EmailOff     EmailDelivery = "off"
+)
+
+// EmailEventDefaults lists the notification types that can be emailed and how they are delivered
+// to users who have not set a preference. Downloads of share links are frequent, so they are
+// batched into a digest.
+var EmailEventDefaults = map[string]EmailDelivery{
+	NotificationCollaboratorAdded: EmailInstant,
+	NotificationShareDownload:     EmailDigest,
+	NotificationShareExpiring:     EmailInstant,
+	NotificationComment:           EmailInstant,
+	NotificationCommentReply:      EmailInstant,
+	NotificationCommentMention:    EmailInstant,
+	NotificationQuotaWarning:      EmailInstant,
+}
+
+// EmailMessage is an email in the outbox. It is retried with a growing delay until it is sent or
+// runs out of attempts, so a mail server outage does not lose notifications.
+type EmailMessage struct {
+	ID            uuid.UUID   `json:"id" gorm:"type:uuid;primary_key"`
+	UserID        *uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	ToAddress     string      `json:"to_address" gorm:"size:255;not null"`
+	Subject       string      `json:"subject" gorm:"size:255;not null"`
+	TextBody      string      `json:"-" gorm:"type:text"`
+	HTMLBody      string      `json:"-" gorm:"type:text"`
+	Kind          string      `json:"kind" gorm:"size:50;index"` // notification type, or digest
+	Status        EmailStatus `json:"status" gorm:"size:20;not null;index"`
+	Attempts      int         `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time   `json:"next_attempt_at" gorm:"index"`
+	LastError     string      `json:"last_error" gorm:"size:1000"`
+	SentAt        *time.Time  `json:"sent_at"`
+	CreatedAt     time.Time   `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// EmailPreference is how a user wants to be emailed about one type of notification
+type EmailPreference struct {
+	ID        uuid.UUID     `json:"-" gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID     `json:"-" gorm:"type:uuid;not null;uniqueIndex:idx_email_preference_user_event"`
+	EventType string        `json:"event_type" gorm:"size:50;not null;uniqueIndex:idx_email_preference_user_event"`
+	Delivery  EmailDelivery `json:"delivery" gorm:"size:20;not null"`
+	UpdatedAt time.Time     `json:"-"`
+}
+
+// EmailPreferenceResponse is the delivery in effect for one type of notification
+type EmailPreferenceResponse struct {
+	EventType string        `json:"event_type"`
+	Delivery  EmailDelivery `json:"delivery"`
+	IsDefault bool          `json:"is_default"`
+}
+
+// EmailPreferencesRequest changes the delivery of some notification types, the others are kept
+type EmailPreferencesRequest struct {
+	Preferences []EmailPreferenceUpdate `json:"preferences" validate:"required,min=1,dive"`
+}
+
+type EmailPreferenceUpdate struct {
+	EventType string        `json:"event_type" validate:"required"`
+	Delivery  EmailDelivery `json:"delivery" validate:"required,oneof=instant digest off"`
+}
+
+// BeforeCreate hook to set UUID
+func (m *EmailMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (p *EmailPreference) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
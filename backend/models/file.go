@@ -1,6 +1,7 @@
 package models
 
 import (
+	"path/filepath"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,25 +9,35 @@ import (
 )
 
 type File struct {
-	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
-	UserID        uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
-	FolderID      *uuid.UUID     `json:"folder_id" gorm:"type:uuid;index"` // null for root files
-	FileName      string         `json:"file_name" gorm:"size:255;not null" validate:"required"`
-	OriginalName  string         `json:"original_name" gorm:"size:255;not null" validate:"required"`
-	FilePath      string         `json:"file_path" gorm:"size:500;not null" validate:"required"`
-	FileSize      int64          `json:"file_size" gorm:"not null" validate:"required,min=1"`
-	MimeType      string         `json:"mime_type" gorm:"size:100;not null" validate:"required"`
-	FileExtension string         `json:"file_extension" gorm:"size:10;not null" validate:"required"`
-	IsPublic      bool           `json:"is_public" gorm:"default:false"`
-	IsStarred     bool           `json:"is_starred" gorm:"default:false"`
-	IsTrashed     bool           `json:"is_trashed" gorm:"default:false;index"`
-	TrashedAt     *time.Time     `json:"trashed_at,omitempty"`
-	DownloadCount int            `json:"download_count" gorm:"default:0"`
-	Description   string         `json:"description" gorm:"size:500"`
-	Tags          string         `json:"tags" gorm:"size:255"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	DriveID       *uuid.UUID `json:"drive_id" gorm:"type:uuid;index"`  // shared drive that owns the file, UserID is then who added it
+	FolderID      *uuid.UUID `json:"folder_id" gorm:"type:uuid;index"` // null for root files
+	FileName      string     `json:"file_name" gorm:"size:255;not null" validate:"required"`
+	OriginalName  string     `json:"original_name" gorm:"size:255;not null" validate:"required"`
+	FilePath      string     `json:"file_path" gorm:"size:500;not null" validate:"required"`
+	FileSize      int64      `json:"file_size" gorm:"not null" validate:"required,min=1"`
+	MimeType      string     `json:"mime_type" gorm:"size:100;not null" validate:"required"`
+	FileExtension string     `json:"file_extension" gorm:"size:10;not null" validate:"required"`
+	IsPublic      bool       `json:"is_public" gorm:"default:false"`
+	IsStarred     bool       `json:"is_starred" gorm:"default:false"`
+	IsTrashed     bool       `json:"is_trashed" gorm:"default:false;index"`
+	TrashedAt     *time.Time `json:"trashed_at,omitempty"`
+	DownloadCount int        `json:"download_count" gorm:"default:0"`
+	Description   string     `json:"description" gorm:"size:500"`
+	Tags          string     `json:"tags" gorm:"size:255"`
+	// Object key of a file whose ownership was transferred, the content stays where the previous
+	// owner stored it. Empty for files stored under their owner, see ObjectKey.
+	StorageKey string `json:"-" gorm:"size:500"`
+	// Set for files uploaded anonymously through an edit share link or a file request, or by an
+	// editor into a folder shared with them. UserID is the folder owner in all of these cases.
+	UploadShareLinkID *uuid.UUID     `json:"upload_share_link_id" gorm:"type:uuid;index"`
+	FileRequestID     *uuid.UUID     `json:"file_request_id" gorm:"type:uuid;index"`
+	UploaderName      string         `json:"uploader_name" gorm:"size:100"`
+	UploaderEmail     string         `json:"uploader_email" gorm:"size:255"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	User          User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -35,6 +46,13 @@ type File struct {
 	Downloads     []Download     `json:"downloads,omitempty" gorm:"foreignKey:FileID"`
 }
 
+// Uploader is the optional identity an anonymous visitor gives when uploading through a share
+// link or file request
+type Uploader struct {
+	Name  string
+	Email string
+}
+
 type FileUploadRequest struct {
 	Description string     `json:"description" validate:"omitempty,max=500"`
 	Tags        string     `json:"tags" validate:"omitempty,max=255"`
@@ -43,27 +61,33 @@ type FileUploadRequest struct {
 }
 
 type FileResponse struct {
-	ID            uuid.UUID       `json:"id"`
-	FolderID      *uuid.UUID      `json:"folder_id"`
-	FileName      string          `json:"file_name"`
-	OriginalName  string          `json:"original_name"`
-	FileSize      int64           `json:"file_size"`
-	MimeType      string          `json:"mime_type"`
-	FileExtension string          `json:"file_extension"`
-	IsPublic      bool            `json:"is_public"`
-	IsStarred     bool            `json:"is_starred"`
-	IsTrashed     bool            `json:"is_trashed"`
-	TrashedAt     *time.Time      `json:"trashed_at,omitempty"`
-	DownloadCount int             `json:"download_count"`
-	Description   string          `json:"description"`
-	Tags          string          `json:"tags"`
-	CreatedAt     time.Time       `json:"created_at"`
-	UpdatedAt     time.Time       `json:"updated_at"`
-	User          UserResponse    `json:"user,omitempty"`
-	Folder        *FolderResponse `json:"folder,omitempty"`
+	ID                uuid.UUID       `json:"id"`
+	DriveID           *uuid.UUID      `json:"drive_id,omitempty"`
+	FolderID          *uuid.UUID      `json:"folder_id"`
+	FileName          string          `json:"file_name"`
+	OriginalName      string          `json:"original_name"`
+	FileSize          int64           `json:"file_size"`
+	MimeType          string          `json:"mime_type"`
+	FileExtension     string          `json:"file_extension"`
+	IsPublic          bool            `json:"is_public"`
+	IsStarred         bool            `json:"is_starred"`
+	IsTrashed         bool            `json:"is_trashed"`
+	TrashedAt         *time.Time      `json:"trashed_at,omitempty"`
+	DownloadCount     int             `json:"download_count"`
+	Description       string          `json:"description"`
+	Tags              string          `json:"tags"`
+	UploadShareLinkID *uuid.UUID      `json:"upload_share_link_id,omitempty"`
+	FileRequestID     *uuid.UUID      `json:"file_request_id,omitempty"`
+	UploaderName      string          `json:"uploader_name,omitempty"`
+	UploaderEmail     string          `json:"uploader_email,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	User              UserResponse    `json:"user,omitempty"`
+	Folder            *FolderResponse `json:"folder,omitempty"`
 }
 
 type FileUpdateRequest struct {
+	Name        string `json:"name" validate:"omitempty,max=255"` // renames the file when set
 	Description string `json:"description" validate:"omitempty,max=500"`
 	Tags        string `json:"tags" validate:"omitempty,max=255"`
 	IsPublic    bool   `json:"is_public"`
@@ -74,6 +98,11 @@ type FileMoveRequest struct {
 	FolderID *uuid.UUID `json:"folder_id" validate:"omitempty"`
 }
 
+type FileDownloadZipRequest struct {
+	FileIDs   []uuid.UUID `json:"file_ids" validate:"omitempty,max=1000"`
+	FolderIDs []uuid.UUID `json:"folder_ids" validate:"omitempty,max=100"`
+}
+
 // BeforeCreate hook to set UUID
 func (f *File) BeforeCreate(tx *gorm.DB) error {
 	if f.ID == uuid.Nil {
@@ -85,22 +114,27 @@ func (f *File) BeforeCreate(tx *gorm.DB) error {
 // ToResponse converts File to FileResponse
 func (f *File) ToResponse() FileResponse {
 	response := FileResponse{
-		ID:            f.ID,
-		FolderID:      f.FolderID,
-		FileName:      f.FileName,
-		OriginalName:  f.OriginalName,
-		FileSize:      f.FileSize,
-		MimeType:      f.MimeType,
-		FileExtension: f.FileExtension,
-		IsPublic:      f.IsPublic,
-		IsStarred:     f.IsStarred,
-		IsTrashed:     f.IsTrashed,
-		TrashedAt:     f.TrashedAt,
-		DownloadCount: f.DownloadCount,
-		Description:   f.Description,
-		Tags:          f.Tags,
-		CreatedAt:     f.CreatedAt,
-		UpdatedAt:     f.UpdatedAt,
+		ID:                f.ID,
+		DriveID:           f.DriveID,
+		FolderID:          f.FolderID,
+		FileName:          f.FileName,
+		OriginalName:      f.OriginalName,
+		FileSize:          f.FileSize,
+		MimeType:          f.MimeType,
+		FileExtension:     f.FileExtension,
+		IsPublic:          f.IsPublic,
+		IsStarred:         f.IsStarred,
+		IsTrashed:         f.IsTrashed,
+		TrashedAt:         f.TrashedAt,
+		DownloadCount:     f.DownloadCount,
+		Description:       f.Description,
+		Tags:              f.Tags,
+		UploadShareLinkID: f.UploadShareLinkID,
+		FileRequestID:     f.FileRequestID,
+		UploaderName:      f.UploaderName,
+		UploaderEmail:     f.UploaderEmail,
+		CreatedAt:         f.CreatedAt,
+		UpdatedAt:         f.UpdatedAt,
 	}
 
 	if f.User.ID != uuid.Nil {
@@ -115,6 +149,30 @@ func (f *File) ToResponse() FileResponse {
 	return response
 }
 
+// OwnerID returns who owns the file: its shared drive, or the user
+func (f *File) OwnerID() uuid.UUID {
+	if f.DriveID != nil {
+		return *f.DriveID
+	}
+	return f.UserID
+}
+
+// ObjectKey returns the key the file content is stored under in the storage backend
+func (f *File) ObjectKey() string {
+	if f.StorageKey != "" {
+		return f.StorageKey
+	}
+	return filepath.Join(f.OwnerID().String(), f.FileName)
+}
+
+// OwnedBy scopes a query on files or folders to the content of an owner: the content of a shared
+// drive when ownerID is a drive, the user's own content otherwise
+func OwnedBy(ownerID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(drive_id = ? OR (drive_id IS NULL AND user_id = ?))", ownerID, ownerID)
+	}
+}
+
 // GetFormattedFileSize returns human-readable file size
 func (f *File) GetFormattedFileSize() string {
 	const unit = 1024
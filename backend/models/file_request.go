@@ -0,0 +1,171 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FileRequest is an upload-only link that collects files into one of the owner's folders
+type FileRequest struct {
+	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	FolderID     uuid.UUID      `json:"folder_id" gorm:"type:uuid;not null;index"`
+	Token        string         `json:"token" gorm:"size:64;not null;unique;index"`
+	Title        string         `json:"title" gorm:"size:255;not null"`
+	Instructions string         `json:"instructions" gorm:"size:2000"`
+	Deadline     *time.Time     `json:"deadline"`
+	MaxFiles     *int           `json:"max_files"`     // total files the request accepts, null for unlimited
+	MaxFileSize  *int64         `json:"max_file_size"` // bytes per file, null for the upload policy limit
+	RequireEmail bool           `json:"require_email" gorm:"default:false"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	UploadCount  int            `json:"upload_count" gorm:"default:0"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User   User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Folder Folder `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+}
+
+type FileRequestCreateRequest struct {
+	FolderID     uuid.UUID  `json:"folder_id" validate:"required"`
+	Title        string     `json:"title" validate:"required,min=1,max=255"`
+	Instructions string     `json:"instructions" validate:"omitempty,max=2000"`
+	Deadline     *time.Time `json:"deadline"`
+	MaxFiles     *int       `json:"max_files" validate:"omitempty,min=1"`
+	MaxFileSize  *int64     `json:"max_file_size" validate:"omitempty,min=1"`
+	RequireEmail bool       `json:"require_email"`
+}
+
+type FileRequestUpdateRequest struct {
+	Title         *string    `json:"title" validate:"omitempty,min=1,max=255"`
+	Instructions  *string    `json:"instructions" validate:"omitempty,max=2000"`
+	Deadline      *time.Time `json:"deadline"`
+	ClearDeadline bool       `json:"clear_deadline"`
+	MaxFiles      *int       `json:"max_files" validate:"omitempty,min=0"`     // 0 removes the limit
+	MaxFileSize   *int64     `json:"max_file_size" validate:"omitempty,min=0"` // 0 removes the limit
+	RequireEmail  *bool      `json:"require_email"`
+	IsActive      *bool      `json:"is_active"`
+}
+
+type FileRequestResponse struct {
+	ID           uuid.UUID       `json:"id"`
+	FolderID     uuid.UUID       `json:"folder_id"`
+	Token        string          `json:"token"`
+	Title        string          `json:"title"`
+	Instructions string          `json:"instructions"`
+	Deadline     *time.Time      `json:"deadline"`
+	MaxFiles     *int            `json:"max_files"`
+	MaxFileSize  *int64          `json:"max_file_size"`
+	RequireEmail bool            `json:"require_email"`
+	IsActive     bool            `json:"is_active"`
+	IsOpen       bool            `json:"is_open"`
+	UploadCount  int             `json:"upload_count"`
+	RequestURL   string          `json:"request_url"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	Folder       *FolderResponse `json:"folder,omitempty"`
+}
+
+// PublicFileRequestInfo is what uploaders see, it never includes the folder contents
+type PublicFileRequestInfo struct {
+	Title          string       `json:"title"`
+	Instructions   string       `json:"instructions"`
+	Deadline       *time.Time   `json:"deadline"`
+	MaxFileSize    int64        `json:"max_file_size"`
+	RemainingFiles *int         `json:"remaining_files"` // null for unlimited
+	RequireEmail   bool         `json:"require_email"`
+	IsOpen         bool         `json:"is_open"`
+	Owner          UserResponse `json:"owner"`
+}
+
+// FileRequestUploadResult describes a file accepted by a file request without exposing its ID
+type FileRequestUploadResult struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// BeforeCreate hook to set UUID
+func (fr *FileRequest) BeforeCreate(tx *gorm.DB) error {
+	if fr.ID == uuid.Nil {
+		fr.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsPastDeadline checks if the deadline has passed
+func (fr *FileRequest) IsPastDeadline() bool {
+	return fr.Deadline != nil && time.Now().After(*fr.Deadline)
+}
+
+// IsFull checks if the request has received its maximum number of files
+func (fr *FileRequest) IsFull() bool {
+	return fr.MaxFiles != nil && fr.UploadCount >= *fr.MaxFiles
+}
+
+// IsOpen checks if the request still accepts uploads
+func (fr *FileRequest) IsOpen() bool {
+	return fr.IsActive && !fr.IsPastDeadline() && !fr.IsFull()
+}
+
+// EffectiveMaxFileSize returns the per-file limit, never above the given policy limit
+func (fr *FileRequest) EffectiveMaxFileSize(policyLimit int64) int64 {
+	if fr.MaxFileSize != nil && *fr.MaxFileSize < policyLimit {
+		return *fr.MaxFileSize
+	}
+	return policyLimit
+}
+
+// ToResponse converts FileRequest to FileRequestResponse
+func (fr *FileRequest) ToResponse(baseURL string) FileRequestResponse {
+	response := FileRequestResponse{
+		ID:           fr.ID,
+		FolderID:     fr.FolderID,
+		Token:        fr.Token,
+		Title:        fr.Title,
+		Instructions: fr.Instructions,
+		Deadline:     fr.Deadline,
+		MaxFiles:     fr.MaxFiles,
+		MaxFileSize:  fr.MaxFileSize,
+		RequireEmail: fr.RequireEmail,
+		IsActive:     fr.IsActive,
+		IsOpen:       fr.IsOpen(),
+		UploadCount:  fr.UploadCount,
+		RequestURL:   baseURL + "/request/" + fr.Token,
+		CreatedAt:    fr.CreatedAt,
+		UpdatedAt:    fr.UpdatedAt,
+	}
+
+	if fr.Folder.ID != uuid.Nil {
+		folderResponse := fr.Folder.ToResponse()
+		response.Folder = &folderResponse
+	}
+
+	return response
+}
+
+// ToPublicInfo converts FileRequest to PublicFileRequestInfo
+func (fr *FileRequest) ToPublicInfo(policyLimit int64) PublicFileRequestInfo {
+	info := PublicFileRequestInfo{
+		Title:        fr.Title,
+		Instructions: fr.Instructions,
+		Deadline:     fr.Deadline,
+		MaxFileSize:  fr.EffectiveMaxFileSize(policyLimit),
+		RequireEmail: fr.RequireEmail,
+		IsOpen:       fr.IsOpen(),
+		Owner:        fr.User.ToResponse(),
+	}
+
+	if fr.MaxFiles != nil {
+		remaining := *fr.MaxFiles - fr.UploadCount
+		if remaining < 0 {
+			remaining = 0
+		}
+		info.RemainingFiles = &remaining
+	}
+
+	return info
+}
@@ -29,11 +29,6 @@ type FileVersion struct {
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
-type FileVersionCreateRequest struct {
-	Comment    string `json:"comment" validate:"omitempty,max=500"`
-	IsAutoSave bool   `json:"is_auto_save"`
-}
-
 type FileVersionResponse struct {
 	ID            uuid.UUID    `json:"id"`
 	VersionNumber int          `json:"version_number"`
@@ -55,10 +50,6 @@ type FileVersionsResponse struct {
 	VersionCount int                   `json:"version_count"`
 }
 
-type RestoreVersionRequest struct {
-	Comment string `json:"comment" validate:"omitempty,max=500"`
-}
-
 // BeforeCreate hook to set UUID
 func (fv *FileVersion) BeforeCreate(tx *gorm.DB) error {
 	if fv.ID == uuid.Nil {
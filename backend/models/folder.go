@@ -10,6 +10,7 @@ import (
 type Folder struct {
 	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
 	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	DriveID   *uuid.UUID     `json:"drive_id" gorm:"type:uuid;index"`  // shared drive that owns the folder, UserID is then who added it
 	ParentID  *uuid.UUID     `json:"parent_id" gorm:"type:uuid;index"` // null for root folders
 	Name      string         `json:"name" gorm:"size:255;not null" validate:"required,max=255"`
 	Path      string         `json:"path" gorm:"size:1000;not null;index"` // full path for efficient querying
@@ -65,6 +66,7 @@ func (f *Folder) BeforeUpdate(tx *gorm.DB) error {
 
 type FolderResponse struct {
 	ID             uuid.UUID    `json:"id"`
+	DriveID        *uuid.UUID   `json:"drive_id,omitempty"`
 	Name           string       `json:"name"`
 	Path           string       `json:"path"`
 	ParentID       *uuid.UUID   `json:"parent_id"`
@@ -98,6 +100,7 @@ type FolderMoveRequest struct {
 func (f *Folder) ToResponse() FolderResponse {
 	response := FolderResponse{
 		ID:        f.ID,
+		DriveID:   f.DriveID,
 		Name:      f.Name,
 		Path:      f.Path,
 		ParentID:  f.ParentID,
@@ -116,6 +119,23 @@ func (f *Folder) ToResponse() FolderResponse {
 	return response
 }
 
+// OwnerID returns who owns the folder: its shared drive, or the user
+func (f *Folder) OwnerID() uuid.UUID {
+	if f.DriveID != nil {
+		return *f.DriveID
+	}
+	return f.UserID
+}
+
+// ContentOwner returns the user and shared drive of content userID adds to the folder: the folder's
+// owner for a personal folder, userID in the folder's drive otherwise
+func (f *Folder) ContentOwner(userID uuid.UUID) (uuid.UUID, *uuid.UUID) {
+	if f.DriveID != nil {
+		return userID, f.DriveID
+	}
+	return f.UserID, nil
+}
+
 // IsRoot returns true if this is a root folder
 func (f *Folder) IsRoot() bool {
 	return f.ParentID == nil
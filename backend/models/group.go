@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GroupRole string
+
+const (
+	GroupRoleManager GroupRole = "manager" // can rename the group and change its members
+	GroupRoleMember  GroupRole = "member"
+)
+
+// Group is a set of users that files and folders can be shared with in one collaborator entry.
+// Members get the access of the group's grants for as long as they are in the group. Groups
+// created by users are run by their managers, admin-managed groups can only be changed by admins.
+type Group struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Name         string    `json:"name" gorm:"size:100;not null"`
+	Description  string    `json:"description" gorm:"size:500"`
+	AdminManaged bool      `json:"admin_managed" gorm:"default:false;index"`
+	CreatedByID  uuid.UUID `json:"created_by_id" gorm:"type:uuid;not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Relationships
+	CreatedBy User          `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+	Members   []GroupMember `json:"members,omitempty" gorm:"foreignKey:GroupID"`
+}
+
+type GroupMember struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	GroupID   uuid.UUID `json:"group_id" gorm:"type:uuid;not null;uniqueIndex:idx_group_member"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_group_member;index"`
+	Role      GroupRole `json:"role" gorm:"size:20;not null"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type GroupCreateRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type GroupUpdateRequest struct {
+	Name        string  `json:"name" validate:"omitempty,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type AddGroupMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Role   GroupRole `json:"role" validate:"omitempty,oneof=manager member"` // member by default
+}
+
+type UpdateGroupMemberRequest struct {
+	Role GroupRole `json:"role" validate:"required,oneof=manager member"`
+}
+
+type GroupMemberResponse struct {
+	User      UserResponse `json:"user"`
+	Role      GroupRole    `json:"role"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// GroupSummary identifies a group where it appears as a collaborator
+type GroupSummary struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	AdminManaged bool      `json:"admin_managed"`
+}
+
+type GroupResponse struct {
+	ID           uuid.UUID             `json:"id"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	AdminManaged bool                  `json:"admin_managed"`
+	MemberCount  int64                 `json:"member_count"`
+	MyRole       GroupRole             `json:"my_role,omitempty"` // empty when the caller is not a member
+	CreatedAt    time.Time             `json:"created_at"`
+	Members      []GroupMemberResponse `json:"members,omitempty"`
+}
+
+// BeforeCreate hook to set UUID
+func (g *Group) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (gm *GroupMember) BeforeCreate(tx *gorm.DB) error {
+	if gm.ID == uuid.Nil {
+		gm.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts Group to GroupResponse, listing the members when they were loaded
+func (g *Group) ToResponse() GroupResponse {
+	response := GroupResponse{
+		ID:           g.ID,
+		Name:         g.Name,
+		Description:  g.Description,
+		AdminManaged: g.AdminManaged,
+		MemberCount:  int64(len(g.Members)),
+		CreatedAt:    g.CreatedAt,
+	}
+	for _, member := range g.Members {
+		response.Members = append(response.Members, GroupMemberResponse{
+			User:      member.User.ToResponse(),
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	return response
+}
+
+// ToSummary converts Group to GroupSummary
+func (g *Group) ToSummary() GroupSummary {
+	return GroupSummary{ID: g.ID, Name: g.Name, AdminManaged: g.AdminManaged}
+}
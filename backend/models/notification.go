@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification is an in-app message for a user about activity on their content
+type Notification struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type       string     `json:"type" gorm:"size:50;not null;index"`
+	Title      string     `json:"title" gorm:"size:255;not null"`
+	Message    string     `json:"message" gorm:"size:1000"`
+	Resource   string     `json:"resource" gorm:"size:50"`
+	ResourceID *uuid.UUID `json:"resource_id" gorm:"type:uuid"`
+	ReadAt     *time.Time `json:"read_at" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+	// Waiting to be emailed in the user's next digest, see EmailService
+	EmailDigestPending bool `json:"-" gorm:"default:false;index"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// Notification types
+const (
+	NotificationShareUpload       = "share_upload"
+	NotificationFileRequestUpload = "file_request_upload"
+	NotificationTransferDownload  = "transfer_download"
+	NotificationComment           = "comment"
+	NotificationCommentReply      = "comment_reply"
+	NotificationCommentMention    = "comment_mention"
+	NotificationOwnershipTransfer = "ownership_transfer"
+	NotificationGroupAdded        = "group_added"
+	NotificationInviteAccepted    = "invite_accepted"
+	NotificationAccessRequest     = "access_request"
+	NotificationOrganizationAdded = "organization_added"
+	NotificationDriveAdded        = "drive_added"
+	NotificationQuotaWarning      = "quota_warning"
+	NotificationCollaboratorAdded = "collaborator_added"
+	NotificationShareDownload     = "share_download"
+	NotificationShareExpiring     = "share_expiring"
+)
+
+type NotificationResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Message    string     `json:"message"`
+	Resource   string     `json:"resource"`
+	ResourceID *uuid.UUID `json:"resource_id"`
+	IsRead     bool       `json:"is_read"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts Notification to NotificationResponse
+func (n *Notification) ToResponse() NotificationResponse {
+	return NotificationResponse{
+		ID:         n.ID,
+		Type:       n.Type,
+		Title:      n.Title,
+		Message:    n.Message,
+		Resource:   n.Resource,
+		ResourceID: n.ResourceID,
+		IsRead:     n.ReadAt != nil,
+		ReadAt:     n.ReadAt,
+		CreatedAt:  n.CreatedAt,
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "owner"  // can do everything, including managing other owners
+	OrganizationRoleAdmin  OrganizationRole = "admin"  // manages members and shared drives, and manages every drive
+	OrganizationRoleMember OrganizationRole = "member" // sees the drives they are added to
+)
+
+// organizationRoleRanks orders organization roles from least to most privileged
+var organizationRoleRanks = map[OrganizationRole]int{
+	OrganizationRoleMember: 1,
+	OrganizationRoleAdmin:  2,
+	OrganizationRoleOwner:  3,
+}
+
+// Includes reports whether the role grants at least the permissions of the required role
+func (r OrganizationRole) Includes(required OrganizationRole) bool {
+	return organizationRoleRanks[r] >= organizationRoleRanks[required]
+}
+
+// Organization is a company or team whose members share drives. Content in its shared drives
+// belongs to the organization, not to the member who added it.
+type Organization struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	Name        string         `json:"name" gorm:"size:100;not null"`
+	Description string         `json:"description" gorm:"size:500"`
+	CreatedByID uuid.UUID      `json:"created_by_id" gorm:"type:uuid;not null;index"`
+	PlanID      *uuid.UUID     `json:"plan_id" gorm:"type:uuid;index"` // limits of members without a plan of their own and of the drives
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	CreatedBy User                 `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+	Members   []OrganizationMember `json:"members,omitempty" gorm:"foreignKey:OrganizationID"`
+	Drives    []SharedDrive        `json:"drives,omitempty" gorm:"foreignKey:OrganizationID"`
+}
+
+type OrganizationMember struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID        `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_organization_member"`
+	UserID         uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_organization_member;index"`
+	Role           OrganizationRole `json:"role" gorm:"size:20;not null"`
+	CreatedAt      time.Time        `json:"created_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type OrganizationCreateRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type OrganizationUpdateRequest struct {
+	Name        string  `json:"name" validate:"omitempty,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type AddOrganizationMemberRequest struct {
+	UserID uuid.UUID        `json:"user_id" validate:"required"`
+	Role   OrganizationRole `json:"role" validate:"omitempty,oneof=owner admin member"` // member by default
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role OrganizationRole `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type OrganizationMemberResponse struct {
+	User      UserResponse     `json:"user"`
+	Role      OrganizationRole `json:"role"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type OrganizationResponse struct {
+	ID          uuid.UUID                    `json:"id"`
+	Name        string                       `json:"name"`
+	Description string                       `json:"description"`
+	MemberCount int64                        `json:"member_count"`
+	DriveCount  int64                        `json:"drive_count"`
+	MyRole      OrganizationRole             `json:"my_role"`
+	PlanID      *uuid.UUID                   `json:"plan_id"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	Members     []OrganizationMemberResponse `json:"members,omitempty"`
+}
+
+// BeforeCreate hook to set UUID
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (om *OrganizationMember) BeforeCreate(tx *gorm.DB) error {
+	if om.ID == uuid.Nil {
+		om.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts Organization to OrganizationResponse, listing the members and counting the
+// drives when they were loaded
+func (o *Organization) ToResponse() OrganizationResponse {
+	response := OrganizationResponse{
+		ID:          o.ID,
+		Name:        o.Name,
+		Description: o.Description,
+		MemberCount: int64(len(o.Members)),
+		DriveCount:  int64(len(o.Drives)),
+		PlanID:      o.PlanID,
+		CreatedAt:   o.CreatedAt,
+	}
+	for _, member := range o.Members {
+		response.Members = append(response.Members, OrganizationMemberResponse{
+			User:      member.User.ToResponse(),
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	return response
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OwnershipTransferStatus string
+
+const (
+	OwnershipTransferPending   OwnershipTransferStatus = "pending"
+	OwnershipTransferAccepted  OwnershipTransferStatus = "accepted"
+	OwnershipTransferDeclined  OwnershipTransferStatus = "declined"
+	OwnershipTransferCancelled OwnershipTransferStatus = "cancelled"
+)
+
+// OwnershipTransfer hands a file or a folder tree over to another user. Transfers started by the
+// owner wait for the recipient to accept them. Transfers forced by an admin complete at once and
+// can also move everything a user owns, FileID and FolderID are both null in that case.
+type OwnershipTransfer struct {
+	ID                uuid.UUID               `json:"id" gorm:"type:uuid;primary_key"`
+	FileID            *uuid.UUID              `json:"file_id" gorm:"type:uuid;index"`
+	FolderID          *uuid.UUID              `json:"folder_id" gorm:"type:uuid;index"`
+	ItemName          string                  `json:"item_name" gorm:"size:255"` // name when the transfer was created
+	FromUserID        uuid.UUID               `json:"from_user_id" gorm:"type:uuid;not null;index"`
+	ToUserID          uuid.UUID               `json:"to_user_id" gorm:"type:uuid;not null;index"`
+	InitiatedByID     uuid.UUID               `json:"initiated_by_id" gorm:"type:uuid;not null"` // the owner, or the admin who forced it
+	Forced            bool                    `json:"forced" gorm:"default:false"`
+	KeepAsEditor      bool                    `json:"keep_as_editor" gorm:"default:false"` // previous owner stays on as an editor
+	Message           string                  `json:"message" gorm:"size:1000"`
+	Status            OwnershipTransferStatus `json:"status" gorm:"size:20;not null;index"`
+	FolderCount       int                     `json:"folder_count" gorm:"default:0"`        // folders moved, set on completion
+	FileCount         int                     `json:"file_count" gorm:"default:0"`          // files moved, set on completion
+	ContainerFolderID *uuid.UUID              `json:"container_folder_id" gorm:"type:uuid"` // folder account transfers land in
+	RespondedAt       *time.Time              `json:"responded_at"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+
+	// Relationships
+	FromUser    User `json:"from_user,omitempty" gorm:"foreignKey:FromUserID"`
+	ToUser      User `json:"to_user,omitempty" gorm:"foreignKey:ToUserID"`
+	InitiatedBy User `json:"initiated_by,omitempty" gorm:"foreignKey:InitiatedByID"`
+}
+
+type OwnershipTransferCreateRequest struct {
+	FileID       *uuid.UUID `json:"file_id"`
+	FolderID     *uuid.UUID `json:"folder_id"`
+	ToUserID     uuid.UUID  `json:"to_user_id" validate:"required"`
+	KeepAsEditor bool       `json:"keep_as_editor"`
+	Message      string     `json:"message" validate:"max=1000"`
+}
+
+// AdminOwnershipTransferRequest moves one file or folder tree, or with from_user_id everything
+// that user owns
+type AdminOwnershipTransferRequest struct {
+	FileID       *uuid.UUID `json:"file_id"`
+	FolderID     *uuid.UUID `json:"folder_id"`
+	FromUserID   *uuid.UUID `json:"from_user_id"`
+	ToUserID     uuid.UUID  `json:"to_user_id" validate:"required"`
+	KeepAsEditor bool       `json:"keep_as_editor"`
+	Message      string     `json:"message" validate:"max=1000"`
+}
+
+type OwnershipTransferResponse struct {
+	ID                uuid.UUID               `json:"id"`
+	ItemType          string                  `json:"item_type"` // file, folder or user
+	ItemID            uuid.UUID               `json:"item_id"`
+	ItemName          string                  `json:"item_name"`
+	FromUser          UserResponse            `json:"from_user"`
+	ToUser            UserResponse            `json:"to_user"`
+	InitiatedBy       UserResponse            `json:"initiated_by"`
+	Forced            bool                    `json:"forced"`
+	KeepAsEditor      bool                    `json:"keep_as_editor"`
+	Message           string                  `json:"message"`
+	Status            OwnershipTransferStatus `json:"status"`
+	FolderCount       int                     `json:"folder_count"`
+	FileCount         int                     `json:"file_count"`
+	ContainerFolderID *uuid.UUID              `json:"container_folder_id,omitempty"`
+	RespondedAt       *time.Time              `json:"responded_at"`
+	CreatedAt         time.Time               `json:"created_at"`
+}
+
+// BeforeCreate hook to set UUID
+func (ot *OwnershipTransfer) BeforeCreate(tx *gorm.DB) error {
+	if ot.ID == uuid.Nil {
+		ot.ID = uuid.New()
+	}
+	return nil
+}
+
+// ItemType returns what the transfer moves: a file, a folder tree or a whole account
+func (ot *OwnershipTransfer) ItemType() string {
+	switch {
+	case ot.FileID != nil:
+		return ResourceFile
+	case ot.FolderID != nil:
+		return ResourceFolder
+	default:
+		return ResourceUser
+	}
+}
+
+// ItemID returns the ID of the file, folder or user the transfer moves
+func (ot *OwnershipTransfer) ItemID() uuid.UUID {
+	switch {
+	case ot.FileID != nil:
+		return *ot.FileID
+	case ot.FolderID != nil:
+		return *ot.FolderID
+	default:
+		return ot.FromUserID
+	}
+}
+
+// ToResponse converts OwnershipTransfer to OwnershipTransferResponse
+func (ot *OwnershipTransfer) ToResponse() OwnershipTransferResponse {
+	return OwnershipTransferResponse{
+		ID:                ot.ID,
+		ItemType:          ot.ItemType(),
+		ItemID:            ot.ItemID(),
+		ItemName:          ot.ItemName,
+		FromUser:          ot.FromUser.ToResponse(),
+		ToUser:            ot.ToUser.ToResponse(),
+		InitiatedBy:       ot.InitiatedBy.ToResponse(),
+		Forced:            ot.Forced,
+		KeepAsEditor:      ot.KeepAsEditor,
+		Message:           ot.Message,
+		Status:            ot.Status,
+		FolderCount:       ot.FolderCount,
+		FileCount:         ot.FileCount,
+		ContainerFolderID: ot.ContainerFolderID,
+		RespondedAt:       ot.RespondedAt,
+		CreatedAt:         ot.CreatedAt,
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Plan bundles the limits admins give to users and organizations. A limit left empty falls back
+// to the server configuration.
+type Plan struct {
+	ID                   uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Name                 string    `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Description          string    `json:"description" gorm:"size:500"`
+	MaxFileSize          *int64    `json:"max_file_size"`       // bytes per uploaded file
+	StorageQuota         *int64    `json:"storage_quota"`       // bytes, 0 is unlimited
+	MaxShareLinkDays     *int      `json:"max_share_link_days"` // share links must expire within this many days
+	RequireSharePassword bool      `json:"require_share_password" gorm:"default:false"`
+	VersionRetention     *int      `json:"version_retention"` // versions kept per file, older ones are deleted
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// PlanRequest creates a plan or replaces all of its fields
+type PlanRequest struct {
+	Name                 string `json:"name" validate:"required,max=100"`
+	Description          string `json:"description" validate:"max=500"`
+	MaxFileSize          *int64 `json:"max_file_size" validate:"omitempty,min=1"`
+	StorageQuota         *int64 `json:"storage_quota" validate:"omitempty,min=0"`
+	MaxShareLinkDays     *int   `json:"max_share_link_days" validate:"omitempty,min=1,max=3650"`
+	RequireSharePassword bool   `json:"require_share_password"`
+	VersionRetention     *int   `json:"version_retention" validate:"omitempty,min=1,max=1000"`
+}
+
+// PlanAssignRequest assigns a plan to a user or organization, null removes it
+type PlanAssignRequest struct {
+	PlanID *uuid.UUID `json:"plan_id"`
+}
+
+type PlanResponse struct {
+	Plan
+	UserCount         int64 `json:"user_count"`
+	OrganizationCount int64 `json:"organization_count"`
+}
+
+// PlanLimits are the limits in effect for a user, from their plan or the server configuration
+type PlanLimits struct {
+	PlanID               *uuid.UUID `json:"plan_id"`
+	PlanName             string     `json:"plan_name,omitempty"`
+	MaxFileSize          int64      `json:"max_file_size"`
+	StorageQuota         int64      `json:"storage_quota"`       // 0 is unlimited
+	MaxShareLinkDays     int        `json:"max_share_link_days"` // 0 lets share links never expire
+	RequireSharePassword bool       `json:"require_share_password"`
+	VersionRetention     int        `json:"version_retention"` // 0 keeps every version
+}
+
+// BeforeCreate hook to set UUID
+func (p *Plan) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import "github.com/google/uuid"
+
+// StorageQuotaResponse is a user's storage usage against their quota. Usage counts every stored
+// object once: each version of a file, and files in the trash.
+type StorageQuotaResponse struct {
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	DriveID     *uuid.UUID `json:"drive_id,omitempty"` // set instead of UserID for the quota of a shared drive
+	Used        int64      `json:"used"`
+	Quota       int64      `json:"quota"` // 0 when unlimited
+	Unlimited   bool       `json:"unlimited"`
+	Remaining   int64      `json:"remaining"` // 0 when unlimited or over quota
+	UsedPercent float64    `json:"used_percent"`
+	IsDefault   bool       `json:"is_default"` // the plan or configured default applies, no admin override
+}
+
+// QuotaUpdateRequest sets a user's quota in bytes, 0 for unlimited. A null quota goes back to the
+// quota of the user's plan or the configured default.
+type QuotaUpdateRequest struct {
+	Quota *int64 `json:"quota" validate:"omitempty,min=0"`
+}
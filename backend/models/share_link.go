@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,65 +30,133 @@ type ShareLink struct {
 	AllowDownload bool                `json:"allow_download" gorm:"default:true"`
 	ViewCount     int                 `json:"view_count" gorm:"default:0"`
 	DownloadCount int                 `json:"download_count" gorm:"default:0"`
-	ExpiresAt     *time.Time          `json:"expires_at"`
-	CreatedAt     time.Time           `json:"created_at"`
-	UpdatedAt     time.Time           `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt      `json:"-" gorm:"index"`
+	MaxViews      *int                `json:"max_views"`                     // null for unlimited
+	MaxDownloads  *int                `json:"max_downloads"`                 // null for unlimited
+	OneTime       bool                `json:"one_time" gorm:"default:false"` // burn after the first view and download
+	BurnedAt      *time.Time          `json:"burned_at"`                     // set when a one-time link was used
+	// Comma-separated ISO 3166-1 alpha-2 codes, empty for no restriction
+	AllowedCountries string `json:"-" gorm:"size:500"`
+	BlockedCountries string `json:"-" gorm:"size:500"`
+	// Comma-separated email addresses and @domains, visitors must verify one of them by passcode
+	AllowedEmails string         `json:"-" gorm:"size:2000"`
+	IsActive      bool           `json:"is_active" gorm:"default:true"`  // owners can pause a link without losing its token
+	Watermark     bool           `json:"watermark" gorm:"default:false"` // stamp downloaded images and PDFs with the viewer's identity
+	WatermarkText string         `json:"watermark_text" gorm:"size:200"` // custom line added to the watermark
+	StartsAt      *time.Time     `json:"starts_at"`                      // null for active immediately
+	ExpiresAt     *time.Time     `json:"expires_at"`
+	ExpiryNotice  *time.Time     `json:"-"` // when the owner was told the link is about to expire
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	User   User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	File   *File   `json:"file,omitempty" gorm:"foreignKey:FileID"`
-	Folder *Folder `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+	User   User            `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	File   *File           `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Folder *Folder         `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+	Items  []ShareLinkItem `json:"items,omitempty" gorm:"foreignKey:ShareLinkID"` // bundle links only
 }
 
 type ShareLinkCreateRequest struct {
-	FileID        *uuid.UUID          `json:"file_id"`
-	FolderID      *uuid.UUID          `json:"folder_id"`
-	Permission    ShareLinkPermission `json:"permission" validate:"required,oneof=view comment edit"`
-	Password      string              `json:"password" validate:"omitempty,min=6"`
-	ExpiresAt     *time.Time          `json:"expires_at"`
-	AllowDownload bool                `json:"allow_download"`
+	FileID           *uuid.UUID          `json:"file_id"`
+	FolderID         *uuid.UUID          `json:"folder_id"`
+	FileIDs          []uuid.UUID         `json:"file_ids"`   // with folder_ids, shares a bundle of items under one link
+	FolderIDs        []uuid.UUID         `json:"folder_ids"` // with file_ids, shares a bundle of items under one link
+	Permission       ShareLinkPermission `json:"permission" validate:"required,oneof=view comment edit"`
+	Password         string              `json:"password" validate:"omitempty,min=6"`
+	StartsAt         *time.Time          `json:"starts_at"`
+	ExpiresAt        *time.Time          `json:"expires_at"`
+	AllowDownload    bool                `json:"allow_download"`
+	MaxViews         *int                `json:"max_views" validate:"omitempty,min=1"`
+	MaxDownloads     *int                `json:"max_downloads" validate:"omitempty,min=1"`
+	OneTime          bool                `json:"one_time"`
+	AllowedCountries []string            `json:"allowed_countries" validate:"omitempty,dive,len=2,alpha"`
+	BlockedCountries []string            `json:"blocked_countries" validate:"omitempty,dive,len=2,alpha"`
+	AllowedEmails    []string            `json:"allowed_emails"` // addresses or domains such as "example.com"
+	Watermark        bool                `json:"watermark"`
+	WatermarkText    string              `json:"watermark_text" validate:"max=200"`
 }
 
 type ShareLinkUpdateRequest struct {
-	Permission    ShareLinkPermission `json:"permission" validate:"omitempty,oneof=view comment edit"`
-	Password      string              `json:"password" validate:"omitempty,min=6"`
-	ExpiresAt     *time.Time          `json:"expires_at"`
-	AllowDownload bool                `json:"allow_download"`
-	IsActive      bool                `json:"is_active"`
+	Permission       ShareLinkPermission `json:"permission" validate:"omitempty,oneof=view comment edit"`
+	Password         string              `json:"password" validate:"omitempty,min=6"`
+	StartsAt         *time.Time          `json:"starts_at"`
+	ClearStartsAt    bool                `json:"clear_starts_at"`
+	ExpiresAt        *time.Time          `json:"expires_at"`
+	AllowDownload    bool                `json:"allow_download"`
+	IsActive         *bool               `json:"is_active"`
+	MaxViews         *int                `json:"max_views" validate:"omitempty,min=0"`                    // 0 removes the limit
+	MaxDownloads     *int                `json:"max_downloads" validate:"omitempty,min=0"`                // 0 removes the limit
+	OneTime          *bool               `json:"one_time"`                                                // false reopens a used one-time link
+	AllowedCountries []string            `json:"allowed_countries" validate:"omitempty,dive,len=2,alpha"` // an empty list removes the restriction
+	BlockedCountries []string            `json:"blocked_countries" validate:"omitempty,dive,len=2,alpha"` // an empty list removes the restriction
+	AllowedEmails    []string            `json:"allowed_emails"`                                          // an empty list removes the restriction
+	Watermark        *bool               `json:"watermark"`
+	WatermarkText    *string             `json:"watermark_text" validate:"omitempty,max=200"`
 }
 
 type ShareLinkResponse struct {
-	ID            uuid.UUID           `json:"id"`
-	Token         string              `json:"token"`
-	Permission    ShareLinkPermission `json:"permission"`
-	HasPassword   bool                `json:"has_password"`
-	IsPublic      bool                `json:"is_public"`
-	AllowDownload bool                `json:"allow_download"`
-	ViewCount     int                 `json:"view_count"`
-	DownloadCount int                 `json:"download_count"`
-	ExpiresAt     *time.Time          `json:"expires_at"`
-	CreatedAt     time.Time           `json:"created_at"`
-	UpdatedAt     time.Time           `json:"updated_at"`
-	ShareURL      string              `json:"share_url"`
-	File          *FileResponse       `json:"file,omitempty"`
-	Folder        *FolderResponse     `json:"folder,omitempty"`
-	User          UserResponse        `json:"user"`
+	ID               uuid.UUID           `json:"id"`
+	Token            string              `json:"token"`
+	Permission       ShareLinkPermission `json:"permission"`
+	HasPassword      bool                `json:"has_password"`
+	IsPublic         bool                `json:"is_public"`
+	AllowDownload    bool                `json:"allow_download"`
+	ViewCount        int                 `json:"view_count"`
+	DownloadCount    int                 `json:"download_count"`
+	MaxViews         *int                `json:"max_views"`
+	MaxDownloads     *int                `json:"max_downloads"`
+	OneTime          bool                `json:"one_time"`
+	BurnedAt         *time.Time          `json:"burned_at"`
+	IsExhausted      bool                `json:"is_exhausted"`
+	IsActive         bool                `json:"is_active"`
+	Status           string              `json:"status"`
+	StartsAt         *time.Time          `json:"starts_at"`
+	AllowedCountries []string            `json:"allowed_countries"`
+	BlockedCountries []string            `json:"blocked_countries"`
+	AllowedEmails    []string            `json:"allowed_emails"`
+	Watermark        bool                `json:"watermark"`
+	WatermarkText    string              `json:"watermark_text"`
+	ExpiresAt        *time.Time          `json:"expires_at"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+	ShareURL         string              `json:"share_url"`
+	File             *FileResponse       `json:"file,omitempty"`
+	Folder           *FolderResponse     `json:"folder,omitempty"`
+	Bundle           *ShareBundle        `json:"bundle,omitempty"`
+	User             UserResponse        `json:"user"`
 }
 
 type ShareLinkAccessRequest struct {
 	Password string `json:"password"`
+	Email    string `json:"email"` // required with code for email restricted links
+	Code     string `json:"code"`  // passcode sent to the email address
 }
 
 type PublicShareInfo struct {
-	ID            uuid.UUID           `json:"id"`
-	Permission    ShareLinkPermission `json:"permission"`
-	AllowDownload bool                `json:"allow_download"`
-	ExpiresAt     *time.Time          `json:"expires_at"`
-	HasPassword   bool                `json:"has_password"`
-	File          *FileResponse       `json:"file,omitempty"`
-	Folder        *FolderResponse     `json:"folder,omitempty"`
-	Owner         UserResponse        `json:"owner"`
+	ID                   uuid.UUID           `json:"id"`
+	Permission           ShareLinkPermission `json:"permission"`
+	AllowDownload        bool                `json:"allow_download"`
+	StartsAt             *time.Time          `json:"starts_at"`
+	ExpiresAt            *time.Time          `json:"expires_at"`
+	HasPassword          bool                `json:"has_password"`
+	OneTime              bool                `json:"one_time"`
+	RequiresEmail        bool                `json:"requires_email"`
+	Watermark            bool                `json:"watermark"` // downloads are stamped with the viewer's identity
+	IsBundle             bool                `json:"is_bundle"`
+	ItemCount            int                 `json:"item_count,omitempty"` // bundle links only, the items are listed after access
+	File                 *FileResponse       `json:"file,omitempty"`
+	Folder               *FolderResponse     `json:"folder,omitempty"`
+	Owner                UserResponse        `json:"owner"`
+	AccessToken          string              `json:"access_token,omitempty"`
+	AccessTokenExpiresAt *time.Time          `json:"access_token_expires_at,omitempty"`
+}
+
+// PublicShareContents lists one folder of a shared folder tree, or the top level items of a bundle
+type PublicShareContents struct {
+	Folder      *FolderResponse  `json:"folder"` // null at the top level of a bundle
+	Breadcrumbs []Breadcrumb     `json:"breadcrumbs"`
+	Folders     []FolderResponse `json:"folders"`
+	Files       []FileResponse   `json:"files"`
 }
 
 // BeforeCreate hook to set UUID and generate token
@@ -100,6 +170,36 @@ func (sl *ShareLink) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Share link states, in the order they are checked
+const (
+	ShareStatusDisabled  = "disabled"
+	ShareStatusScheduled = "scheduled"
+	ShareStatusExpired   = "expired"
+	ShareStatusExhausted = "exhausted"
+	ShareStatusActive    = "active"
+)
+
+// IsScheduled checks if the link's activation time has not been reached yet
+func (sl *ShareLink) IsScheduled() bool {
+	return sl.StartsAt != nil && time.Now().Before(*sl.StartsAt)
+}
+
+// Status reports why a link cannot be opened, or ShareStatusActive when it can
+func (sl *ShareLink) Status() string {
+	switch {
+	case !sl.IsActive:
+		return ShareStatusDisabled
+	case sl.IsScheduled():
+		return ShareStatusScheduled
+	case sl.IsExpired():
+		return ShareStatusExpired
+	case sl.IsExhausted():
+		return ShareStatusExhausted
+	default:
+		return ShareStatusActive
+	}
+}
+
 // IsExpired checks if the share link has expired
 func (sl *ShareLink) IsExpired() bool {
 	if sl.ExpiresAt == nil {
@@ -108,26 +208,154 @@ func (sl *ShareLink) IsExpired() bool {
 	return time.Now().After(*sl.ExpiresAt)
 }
 
+// IsExhausted checks if the share link has been burned or used up its views
+func (sl *ShareLink) IsExhausted() bool {
+	if sl.BurnedAt != nil {
+		return true
+	}
+	return sl.MaxViews != nil && sl.ViewCount >= *sl.MaxViews
+}
+
+// DownloadsExhausted checks if no more downloads are allowed through the share link
+func (sl *ShareLink) DownloadsExhausted() bool {
+	if sl.OneTime && sl.DownloadCount >= 1 {
+		return true
+	}
+	return sl.MaxDownloads != nil && sl.DownloadCount >= *sl.MaxDownloads
+}
+
+// IsBundle checks if the link shares a collection of items rather than a single file or folder
+func (sl *ShareLink) IsBundle() bool {
+	return sl.FileID == nil && sl.FolderID == nil
+}
+
+// BundleItems returns the files and folders of a bundle link that are still outside the trash, in
+// the order they were shared
+func (sl *ShareLink) BundleItems() ([]Folder, []File) {
+	items := slices.Clone(sl.Items)
+	slices.SortFunc(items, func(a, b ShareLinkItem) int { return a.Position - b.Position })
+
+	var folders []Folder
+	var files []File
+	for _, item := range items {
+		if item.Folder != nil && item.Folder.ID != uuid.Nil && !item.Folder.IsTrashed {
+			folders = append(folders, *item.Folder)
+		}
+		if item.File != nil && item.File.ID != uuid.Nil && !item.File.IsTrashed {
+			files = append(files, *item.File)
+		}
+	}
+	return folders, files
+}
+
+// SharedFolderIDs returns the folders whose subtrees the link shares
+func (sl *ShareLink) SharedFolderIDs() []uuid.UUID {
+	if sl.FolderID != nil {
+		return []uuid.UUID{*sl.FolderID}
+	}
+	var ids []uuid.UUID
+	for _, item := range sl.Items {
+		if item.FolderID != nil {
+			ids = append(ids, *item.FolderID)
+		}
+	}
+	return ids
+}
+
+// HasBundleFile checks if a file is one of the top level items of a bundle link
+func (sl *ShareLink) HasBundleFile(fileID uuid.UUID) bool {
+	for _, item := range sl.Items {
+		if item.FileID != nil && *item.FileID == fileID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCountry checks the link's country restrictions against a visitor's country. When an
+// allow list is set, visitors whose country cannot be resolved are refused.
+func (sl *ShareLink) AllowsCountry(country string) bool {
+	country = strings.ToUpper(country)
+	if country != "" && containsCountry(sl.BlockedCountries, country) {
+		return false
+	}
+	if sl.AllowedCountries != "" {
+		return country != "" && containsCountry(sl.AllowedCountries, country)
+	}
+	return true
+}
+
+// RequiresEmail checks if visitors must verify their email address
+func (sl *ShareLink) RequiresEmail() bool {
+	return sl.AllowedEmails != ""
+}
+
+// AllowsEmail checks an email address against the link's allowed addresses and domains
+func (sl *ShareLink) AllowsEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return false
+	}
+	domain := email[at:]
+	for _, entry := range strings.Split(sl.AllowedEmails, ",") {
+		if entry == email || entry == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList converts a stored comma-separated list into its entries
+func splitList(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+	return strings.Split(list, ",")
+}
+
+func containsCountry(list, country string) bool {
+	for _, code := range strings.Split(list, ",") {
+		if code == country {
+			return true
+		}
+	}
+	return false
+}
+
 // CanAccess checks if the share link can be accessed
 func (sl *ShareLink) CanAccess() bool {
-	return !sl.IsExpired() && sl.DeletedAt.Time.IsZero()
+	return sl.Status() == ShareStatusActive && sl.DeletedAt.Time.IsZero()
 }
 
 // ToResponse converts ShareLink to ShareLinkResponse
 func (sl *ShareLink) ToResponse(baseURL string) ShareLinkResponse {
 	response := ShareLinkResponse{
-		ID:            sl.ID,
-		Token:         sl.Token,
-		Permission:    sl.Permission,
-		HasPassword:   sl.HasPassword,
-		IsPublic:      sl.IsPublic,
-		AllowDownload: sl.AllowDownload,
-		ViewCount:     sl.ViewCount,
-		DownloadCount: sl.DownloadCount,
-		ExpiresAt:     sl.ExpiresAt,
-		CreatedAt:     sl.CreatedAt,
-		UpdatedAt:     sl.UpdatedAt,
-		ShareURL:      baseURL + "/share/" + sl.Token,
+		ID:               sl.ID,
+		Token:            sl.Token,
+		Permission:       sl.Permission,
+		HasPassword:      sl.HasPassword,
+		IsPublic:         sl.IsPublic,
+		AllowDownload:    sl.AllowDownload,
+		ViewCount:        sl.ViewCount,
+		DownloadCount:    sl.DownloadCount,
+		MaxViews:         sl.MaxViews,
+		MaxDownloads:     sl.MaxDownloads,
+		OneTime:          sl.OneTime,
+		BurnedAt:         sl.BurnedAt,
+		IsExhausted:      sl.IsExhausted(),
+		IsActive:         sl.IsActive,
+		Status:           sl.Status(),
+		StartsAt:         sl.StartsAt,
+		AllowedCountries: splitList(sl.AllowedCountries),
+		BlockedCountries: splitList(sl.BlockedCountries),
+		AllowedEmails:    splitList(sl.AllowedEmails),
+		Watermark:        sl.Watermark,
+		WatermarkText:    sl.WatermarkText,
+		ExpiresAt:        sl.ExpiresAt,
+		CreatedAt:        sl.CreatedAt,
+		UpdatedAt:        sl.UpdatedAt,
+		ShareURL:         baseURL + "/share/" + sl.Token,
 	}
 
 	if sl.User.ID != uuid.Nil {
@@ -144,6 +372,21 @@ func (sl *ShareLink) ToResponse(baseURL string) ShareLinkResponse {
 		response.Folder = &folderResponse
 	}
 
+	if sl.IsBundle() {
+		folders, files := sl.BundleItems()
+		bundle := &ShareBundle{
+			Folders: make([]FolderResponse, 0, len(folders)),
+			Files:   make([]FileResponse, 0, len(files)),
+		}
+		for _, folder := range folders {
+			bundle.Folders = append(bundle.Folders, folder.ToResponse())
+		}
+		for _, file := range files {
+			bundle.Files = append(bundle.Files, file.ToResponse())
+		}
+		response.Bundle = bundle
+	}
+
 	return response
 }
 
@@ -153,11 +396,21 @@ func (sl *ShareLink) ToPublicInfo() PublicShareInfo {
 		ID:            sl.ID,
 		Permission:    sl.Permission,
 		AllowDownload: sl.AllowDownload,
+		StartsAt:      sl.StartsAt,
 		ExpiresAt:     sl.ExpiresAt,
 		HasPassword:   sl.HasPassword,
+		OneTime:       sl.OneTime,
+		RequiresEmail: sl.RequiresEmail(),
+		Watermark:     sl.Watermark,
+		IsBundle:      sl.IsBundle(),
 		Owner:         sl.User.ToResponse(),
 	}
 
+	if sl.IsBundle() {
+		folders, files := sl.BundleItems()
+		info.ItemCount = len(folders) + len(files)
+	}
+
 	if sl.File != nil && sl.File.ID != uuid.Nil {
 		fileResponse := sl.File.ToResponse()
 		info.File = &fileResponse
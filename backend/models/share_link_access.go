@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShareLinkAccess is a single public view or download of a share link
+type ShareLinkAccess struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	ShareLinkID uuid.UUID  `json:"share_link_id" gorm:"type:uuid;not null;index"`
+	FileID      *uuid.UUID `json:"file_id" gorm:"type:uuid;index"` // null for views and folder downloads
+	EventType   string     `json:"event_type" gorm:"size:20;not null;index"`
+	VisitorID   string     `json:"visitor_id" gorm:"size:32;index"` // hash of IP and user agent
+	Email       string     `json:"email" gorm:"size:255;index"`     // verified address on email restricted links
+	IPAddress   string     `json:"ip_address" gorm:"size:45"`
+	Country     string     `json:"country" gorm:"size:2;index"`
+	City        string     `json:"city" gorm:"size:100"`
+	UserAgent   string     `json:"user_agent" gorm:"size:500"`
+	Referrer    string     `json:"referrer" gorm:"size:500"`
+	Browser     string     `json:"browser" gorm:"size:50"`
+	OS          string     `json:"os" gorm:"size:50"`
+	DeviceType  string     `json:"device_type" gorm:"size:20"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`
+}
+
+// Share link access event types
+const (
+	ShareEventView     = "view"
+	ShareEventDownload = "download"
+	ShareEventUpload   = "upload"
+)
+
+// ShareVisitor identifies the anonymous client behind a public share request
+type ShareVisitor struct {
+	IPAddress string
+	UserAgent string
+	Referrer  string
+	Country   string // resolved from IPAddress, empty when GeoIP is disabled or the address is unknown
+	City      string
+	Email     string // verified email address, empty unless the link is email restricted
+}
+
+type ShareCountStat struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ShareDailyStats struct {
+	Date      string `json:"date"`
+	Views     int    `json:"views"`
+	Downloads int    `json:"downloads"`
+}
+
+// ShareLinkAnalytics summarizes the access events of a share link
+type ShareLinkAnalytics struct {
+	ShareLinkID      uuid.UUID         `json:"share_link_id"`
+	From             time.Time         `json:"from"`
+	To               time.Time         `json:"to"`
+	TotalViews       int               `json:"total_views"`
+	TotalDownloads   int               `json:"total_downloads"`
+	UniqueVisitors   int               `json:"unique_visitors"`
+	Daily            []ShareDailyStats `json:"daily"`
+	TopReferrers     []ShareCountStat  `json:"top_referrers"`
+	Browsers         []ShareCountStat  `json:"browsers"`
+	OperatingSystems []ShareCountStat  `json:"operating_systems"`
+	Devices          []ShareCountStat  `json:"devices"`
+	Countries        []ShareCountStat  `json:"countries"`
+	Cities           []ShareCountStat  `json:"cities"`
+}
+
+// BeforeCreate hook to set UUID
+func (sla *ShareLinkAccess) BeforeCreate(tx *gorm.DB) error {
+	if sla.ID == uuid.Nil {
+		sla.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShareLinkItem is one file or folder of a bundle share link. Folders are shared with their
+// whole subtree, exactly like a folder link.
+type ShareLinkItem struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	ShareLinkID uuid.UUID  `json:"share_link_id" gorm:"type:uuid;not null;index"`
+	FileID      *uuid.UUID `json:"file_id" gorm:"type:uuid;index"`
+	FolderID    *uuid.UUID `json:"folder_id" gorm:"type:uuid;index"`
+	Position    int        `json:"position" gorm:"not null;default:0"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relationships
+	File   *File   `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Folder *Folder `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+}
+
+// ShareBundle lists the top level items of a bundle share link
+type ShareBundle struct {
+	Folders []FolderResponse `json:"folders"`
+	Files   []FileResponse   `json:"files"`
+}
+
+// BeforeCreate hook to set UUID
+func (sli *ShareLinkItem) BeforeCreate(tx *gorm.DB) error {
+	if sli.ID == uuid.Nil {
+		sli.ID = uuid.New()
+	}
+	return nil
+}
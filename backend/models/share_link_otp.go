@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShareLinkOTP is a one-time passcode emailed to a visitor of an email restricted share link
+type ShareLinkOTP struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	ShareLinkID uuid.UUID  `json:"share_link_id" gorm:"type:uuid;not null;index"`
+	Email       string     `json:"email" gorm:"size:255;not null;index"`
+	CodeHash    string     `json:"-" gorm:"size:255;not null"`
+	Attempts    int        `json:"attempts" gorm:"default:0"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	ConsumedAt  *time.Time `json:"consumed_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`
+}
+
+// ShareLinkOTPRequest asks for a passcode to be sent to the visitor's email address
+type ShareLinkOTPRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// BeforeCreate hook to set UUID
+func (o *ShareLinkOTP) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUsable checks if the passcode can still be redeemed
+func (o *ShareLinkOTP) IsUsable(maxAttempts int) bool {
+	return o.ConsumedAt == nil && o.Attempts < maxAttempts && time.Now().Before(o.ExpiresAt)
+}
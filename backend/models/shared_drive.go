@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DriveRole string
+
+const (
+	DriveRoleManager   DriveRole = "manager"   // manages the drive and its members, owner of its content
+	DriveRoleEditor    DriveRole = "editor"    // adds, edits, moves and renames content
+	DriveRoleCommenter DriveRole = "commenter" // views and comments
+	DriveRoleViewer    DriveRole = "viewer"    // views and downloads
+)
+
+// ContentRole returns the role a drive member has on every file and folder of the drive
+func (r DriveRole) ContentRole() CollaboratorRole {
+	switch r {
+	case DriveRoleManager:
+		return RoleOwner
+	case DriveRoleEditor:
+		return RoleEditor
+	case DriveRoleCommenter:
+		return RoleCommenter
+	case DriveRoleViewer:
+		return RoleViewer
+	default:
+		return ""
+	}
+}
+
+// SharedDrive is a drive of an organization. Its files and folders carry the drive's ID as their
+// owner, so they stay in the drive when the members who added them leave. Members reach the
+// content through the drive's root folder.
+type SharedDrive struct {
+	ID                 uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID     uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Name               string    `json:"name" gorm:"size:100;not null"`
+	Description        string    `json:"description" gorm:"size:500"`
+	RootFolderID       uuid.UUID `json:"root_folder_id" gorm:"type:uuid;not null"`
+	CreatedByID        uuid.UUID `json:"created_by_id" gorm:"type:uuid;not null;index"`
+	StorageUsed        int64     `json:"-" gorm:"not null;default:0"` // bytes stored for the drive's files, versions and trash, see QuotaService
+	StorageQuota       *int64    `json:"-"`                           // set by admins, nil uses the organization's plan or configured default and 0 is unlimited
+	QuotaWarnedPercent int       `json:"-" gorm:"not null;default:0"` // highest warning threshold the managers were notified of
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization Organization  `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+	Members      []DriveMember `json:"members,omitempty" gorm:"foreignKey:DriveID"`
+}
+
+type DriveMember struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	DriveID   uuid.UUID `json:"drive_id" gorm:"type:uuid;not null;uniqueIndex:idx_drive_member"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_drive_member;index"`
+	Role      DriveRole `json:"role" gorm:"size:20;not null"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type SharedDriveCreateRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type SharedDriveUpdateRequest struct {
+	Name        string  `json:"name" validate:"omitempty,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type AddDriveMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Role   DriveRole `json:"role" validate:"required,oneof=manager editor commenter viewer"`
+}
+
+type UpdateDriveMemberRequest struct {
+	Role DriveRole `json:"role" validate:"required,oneof=manager editor commenter viewer"`
+}
+
+// DriveUsage is the storage a shared drive uses, trashed files are counted until they are
+// permanently deleted
+type DriveUsage struct {
+	FileCount   int64 `json:"file_count"`
+	FolderCount int64 `json:"folder_count"`
+	StorageUsed int64 `json:"storage_used"` // bytes of files not in the trash
+	TrashSize   int64 `json:"trash_size"`   // bytes of trashed files
+	QuotaUsed   int64 `json:"quota_used"`   // bytes counted against the quota, including versions and trash
+	Quota       int64 `json:"quota"`        // 0 when unlimited
+}
+
+type DriveMemberResponse struct {
+	User      UserResponse `json:"user"`
+	Role      DriveRole    `json:"role"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type SharedDriveResponse struct {
+	ID             uuid.UUID             `json:"id"`
+	OrganizationID uuid.UUID             `json:"organization_id"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	RootFolderID   uuid.UUID             `json:"root_folder_id"`
+	MemberCount    int64                 `json:"member_count"`
+	MyRole         DriveRole             `json:"my_role"`
+	Usage          *DriveUsage           `json:"usage,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	Members        []DriveMemberResponse `json:"members,omitempty"`
+}
+
+// BeforeCreate hook to set UUID
+func (sd *SharedDrive) BeforeCreate(tx *gorm.DB) error {
+	if sd.ID == uuid.Nil {
+		sd.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (dm *DriveMember) BeforeCreate(tx *gorm.DB) error {
+	if dm.ID == uuid.Nil {
+		dm.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts SharedDrive to SharedDriveResponse, listing the members when they were loaded
+func (sd *SharedDrive) ToResponse() SharedDriveResponse {
+	response := SharedDriveResponse{
+		ID:             sd.ID,
+		OrganizationID: sd.OrganizationID,
+		Name:           sd.Name,
+		Description:    sd.Description,
+		RootFolderID:   sd.RootFolderID,
+		MemberCount:    int64(len(sd.Members)),
+		CreatedAt:      sd.CreatedAt,
+	}
+	for _, member := range sd.Members {
+		response.Members = append(response.Members, DriveMemberResponse{
+			User:      member.User.ToResponse(),
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	return response
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Transfer is a bundle of uploaded files sent to a list of recipients by email. The files are
+// moved to the owner's trash when the transfer expires.
+type Transfer struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	UserID        uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Token         string         `json:"token" gorm:"size:64;not null;unique;index"`
+	Title         string         `json:"title" gorm:"size:255"`
+	Message       string         `json:"message" gorm:"size:2000"`
+	ExpiresAt     time.Time      `json:"expires_at" gorm:"not null;index"`
+	ExpiredAt     *time.Time     `json:"expired_at" gorm:"index"` // set once the files have been trashed
+	DownloadCount int            `json:"download_count" gorm:"default:0"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User       User                `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Files      []File              `json:"files,omitempty" gorm:"many2many:transfer_files"`
+	Recipients []TransferRecipient `json:"recipients,omitempty" gorm:"foreignKey:TransferID"`
+}
+
+// TransferRecipient is an email address a transfer was sent to. Each recipient gets their own
+// download token so the sender can see who downloaded the files.
+type TransferRecipient struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	TransferID       uuid.UUID  `json:"transfer_id" gorm:"type:uuid;not null;index"`
+	Email            string     `json:"email" gorm:"size:255;not null"`
+	Token            string     `json:"-" gorm:"size:64;not null;unique;index"`
+	NotifiedAt       *time.Time `json:"notified_at"`
+	DownloadCount    int        `json:"download_count" gorm:"default:0"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// TransferCreateRequest holds the transfer fields of a multi-file upload
+type TransferCreateRequest struct {
+	Title      string
+	Message    string
+	Recipients []string
+	ExpiryDays int
+}
+
+type TransferRecipientResponse struct {
+	Email            string     `json:"email"`
+	NotifiedAt       *time.Time `json:"notified_at"`
+	DownloadCount    int        `json:"download_count"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at"`
+}
+
+type TransferResponse struct {
+	ID            uuid.UUID                   `json:"id"`
+	Title         string                      `json:"title"`
+	Message       string                      `json:"message"`
+	ExpiresAt     time.Time                   `json:"expires_at"`
+	ExpiredAt     *time.Time                  `json:"expired_at"`
+	IsExpired     bool                        `json:"is_expired"`
+	DownloadCount int                         `json:"download_count"`
+	DownloadURL   string                      `json:"download_url"`
+	FileCount     int                         `json:"file_count"`
+	TotalSize     int64                       `json:"total_size"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	Files         []FileResponse              `json:"files"`
+	Recipients    []TransferRecipientResponse `json:"recipients"`
+}
+
+// TransferFileInfo describes a file of a transfer to its recipients
+type TransferFileInfo struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	MimeType string    `json:"mime_type"`
+}
+
+// PublicTransferInfo is what recipients see before downloading
+type PublicTransferInfo struct {
+	Title     string             `json:"title"`
+	Message   string             `json:"message"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	TotalSize int64              `json:"total_size"`
+	Files     []TransferFileInfo `json:"files"`
+	Sender    UserResponse       `json:"sender"`
+}
+
+// BeforeCreate hook to set UUID
+func (t *Transfer) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (tr *TransferRecipient) BeforeCreate(tx *gorm.DB) error {
+	if tr.ID == uuid.Nil {
+		tr.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsExpired checks if the transfer can no longer be downloaded
+func (t *Transfer) IsExpired() bool {
+	return t.ExpiredAt != nil || !time.Now().Before(t.ExpiresAt)
+}
+
+// TotalSize returns the combined size of the transfer's files
+func (t *Transfer) TotalSize() int64 {
+	var total int64
+	for _, file := range t.Files {
+		total += file.FileSize
+	}
+	return total
+}
+
+// ToResponse converts Transfer to TransferResponse
+func (t *Transfer) ToResponse(baseURL string) TransferResponse {
+	response := TransferResponse{
+		ID:            t.ID,
+		Title:         t.Title,
+		Message:       t.Message,
+		ExpiresAt:     t.ExpiresAt,
+		ExpiredAt:     t.ExpiredAt,
+		IsExpired:     t.IsExpired(),
+		DownloadCount: t.DownloadCount,
+		DownloadURL:   baseURL + "/transfer/" + t.Token,
+		FileCount:     len(t.Files),
+		TotalSize:     t.TotalSize(),
+		CreatedAt:     t.CreatedAt,
+		Files:         make([]FileResponse, 0, len(t.Files)),
+		Recipients:    make([]TransferRecipientResponse, 0, len(t.Recipients)),
+	}
+
+	for _, file := range t.Files {
+		response.Files = append(response.Files, file.ToResponse())
+	}
+	for _, recipient := range t.Recipients {
+		response.Recipients = append(response.Recipients, TransferRecipientResponse{
+			Email:            recipient.Email,
+			NotifiedAt:       recipient.NotifiedAt,
+			DownloadCount:    recipient.DownloadCount,
+			LastDownloadedAt: recipient.LastDownloadedAt,
+		})
+	}
+
+	return response
+}
+
+// ToPublicInfo converts Transfer to PublicTransferInfo
+func (t *Transfer) ToPublicInfo() PublicTransferInfo {
+	info := PublicTransferInfo{
+		Title:     t.Title,
+		Message:   t.Message,
+		ExpiresAt: t.ExpiresAt,
+		TotalSize: t.TotalSize(),
+		Files:     make([]TransferFileInfo, 0, len(t.Files)),
+		Sender:    t.User.ToResponse(),
+	}
+
+	for _, file := range t.Files {
+		info.Files = append(info.Files, TransferFileInfo{
+			ID:       file.ID,
+			Name:     file.OriginalName,
+			Size:     file.FileSize,
+			MimeType: file.MimeType,
+		})
+	}
+
+	return info
+}
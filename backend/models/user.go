@@ -9,18 +9,22 @@ import (
 )
 
 type User struct {
-	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
-	FirstName     string         `json:"first_name" gorm:"size:50;not null" validate:"required,min=2,max=50"`
-	LastName      string         `json:"last_name" gorm:"size:50;not null" validate:"required,min=2,max=50"`
-	Email         string         `json:"email" gorm:"uniqueIndex;size:255;not null" validate:"required,email"`
-	Password      string         `json:"-" gorm:"size:255;not null" validate:"required,min=6"`
-	IsActive      bool           `json:"is_active" gorm:"default:true"`
-	IsAdmin       bool           `json:"is_admin" gorm:"default:false"`
-	EmailVerified bool           `json:"email_verified" gorm:"default:false"`
-	LastLoginAt   *time.Time     `json:"last_login_at"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	FirstName          string         `json:"first_name" gorm:"size:50;not null" validate:"required,min=2,max=50"`
+	LastName           string         `json:"last_name" gorm:"size:50;not null" validate:"required,min=2,max=50"`
+	Email              string         `json:"email" gorm:"uniqueIndex;size:255;not null" validate:"required,email"`
+	Password           string         `json:"-" gorm:"size:255;not null" validate:"required,min=6"`
+	IsActive           bool           `json:"is_active" gorm:"default:true"`
+	IsAdmin            bool           `json:"is_admin" gorm:"default:false"`
+	EmailVerified      bool           `json:"email_verified" gorm:"default:false"`
+	StorageUsed        int64          `json:"-" gorm:"not null;default:0"` // bytes stored for the user's files, versions and trash, see QuotaService
+	StorageQuota       *int64         `json:"-"`                           // set by admins, nil uses the plan or configured default and 0 is unlimited
+	QuotaWarnedPercent int            `json:"-" gorm:"not null;default:0"` // highest warning threshold the user was notified of
+	PlanID             *uuid.UUID     `json:"-" gorm:"type:uuid;index"`    // set by admins, see PlanService for how limits are resolved
+	LastLoginAt        *time.Time     `json:"last_login_at"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Files     []File     `json:"files,omitempty" gorm:"foreignKey:UserID"`
@@ -34,6 +38,8 @@ type UserRegisterRequest struct {
 	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
 	Email     string `json:"email" validate:"required,email"`
 	Password  string `json:"password" validate:"required,min=6"`
+	// InviteToken from a collaborator invite sent to this email, verifies the address on sign up
+	InviteToken string `json:"invite_token"`
 }
 
 type UserLoginRequest struct {
@@ -59,6 +65,12 @@ type UserResponse struct {
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
+// ProfileResponse is the signed in user's own profile with the limits in effect for them
+type ProfileResponse struct {
+	UserResponse
+	Limits PlanLimits `json:"limits"`
+}
+
 type UserUpdateRequest struct {
 	FirstName string `json:"first_name" validate:"omitempty,min=2,max=50"`
 	LastName  string `json:"last_name" validate:"omitempty,min=2,max=50"`
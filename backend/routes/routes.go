@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/manjurulhoque/swift-share/backend/controllers"
 	"github.com/manjurulhoque/swift-share/backend/middleware"
+	"github.com/manjurulhoque/swift-share/backend/models"
 )
 
 func SetupRoutes(router *gin.Engine) {
@@ -12,8 +13,20 @@ func SetupRoutes(router *gin.Engine) {
 	fileController := controllers.NewFileController()
 	folderController := controllers.NewFolderController()
 	trashController := controllers.NewTrashController()
+	notificationController := controllers.NewNotificationController()
+	fileRequestController := controllers.NewFileRequestController()
+	transferController := controllers.NewTransferController()
+	commentController := controllers.NewCommentController()
 	shareController := controllers.NewShareController()
 	adminController := controllers.NewAdminController()
+	ownershipController := controllers.NewOwnershipController()
+	groupController := controllers.NewGroupController()
+	inviteController := controllers.NewInviteController()
+	accessRequestController := controllers.NewAccessRequestController()
+	organizationController := controllers.NewOrganizationController()
+	sharedDriveController := controllers.NewSharedDriveController()
+	quotaController := controllers.NewQuotaController()
+	planController := controllers.NewPlanController()
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
@@ -31,6 +44,34 @@ func SetupRoutes(router *gin.Engine) {
 		{
 			public.GET("/share/:token", shareController.GetPublicShareInfo)
 			public.POST("/share/:token", shareController.AccessPublicShare)
+			public.POST("/share/:token/otp", shareController.RequestShareAccessCode)
+
+			// File request uploads, upload-only
+			public.GET("/requests/:token", fileRequestController.GetPublicFileRequest)
+			public.POST("/requests/:token/upload", fileRequestController.UploadToFileRequest)
+
+			// Transfer downloads, by the transfer or a recipient token
+			public.GET("/transfers/:token", transferController.GetPublicTransfer)
+			public.GET("/transfers/:token/download", transferController.DownloadPublicTransfer)
+			public.GET("/transfers/:token/files/:fileId/download", transferController.DownloadPublicTransferFile)
+
+			// Collaborator invites, signing up with the token accepts them
+			public.GET("/invites/:token", inviteController.GetPublicInvite)
+
+			// Routes below require the access token issued by AccessPublicShare
+			sharedContent := public.Group("/share/:token", middleware.ShareAccessMiddleware())
+			{
+				sharedContent.GET("/contents", shareController.GetPublicShareContents)
+				sharedContent.GET("/download", shareController.DownloadPublicShare)
+				sharedContent.GET("/files/:fileId/download", shareController.DownloadPublicShareFile)
+				sharedContent.GET("/folders/:folderId/download", shareController.DownloadPublicShareFolder)
+				sharedContent.POST("/upload", shareController.UploadToPublicShare)
+				sharedContent.POST("/folders/:folderId/upload", shareController.UploadToPublicShareFolder)
+				sharedContent.GET("/files/:fileId/comments", commentController.GetPublicShareFileComments)
+				sharedContent.POST("/files/:fileId/comments", commentController.CreatePublicShareFileComment)
+				sharedContent.GET("/folders/:folderId/comments", commentController.GetPublicShareFolderComments)
+				sharedContent.POST("/folders/:folderId/comments", commentController.CreatePublicShareFolderComment)
+			}
 		}
 
 		// Protected routes (authentication required)
@@ -44,6 +85,7 @@ func SetupRoutes(router *gin.Engine) {
 				authProtected.PUT("/profile", authController.UpdateProfile)
 				authProtected.POST("/logout", authController.Logout)
 				authProtected.GET("/dashboard-stats", authController.GetDashboardStats)
+				authProtected.GET("/quota", quotaController.GetMyQuota)
 			}
 
 			// User search routes
@@ -60,20 +102,38 @@ func SetupRoutes(router *gin.Engine) {
 				files.GET("/recent", fileController.GetRecentFiles)
 				files.POST("/upload", fileController.UploadFile)
 				files.POST("/upload-multiple", fileController.UploadMultipleFiles)
+				files.POST("/download-zip", fileController.DownloadZip)
 				files.GET("/:id", fileController.GetFile)
 				files.GET("/:id/history", fileController.GetFileAccessHistory)
+				// Editor operations
+				files.PUT("/:id", middleware.FileRoleMiddleware(models.RoleEditor), fileController.UpdateFile)
+				files.POST("/:id/move", middleware.FileRoleMiddleware(models.RoleEditor), fileController.MoveFile)
+				files.GET("/:id/versions", fileController.GetFileVersions)
+				files.POST("/:id/versions", middleware.FileRoleMiddleware(models.RoleEditor), fileController.UploadFileVersion)
 				// Owner-only operations
-				files.PUT("/:id", middleware.FileOwnerMiddleware(), fileController.UpdateFile)
 				files.DELETE("/:id", middleware.FileOwnerMiddleware(), fileController.DeleteFile)
-				files.POST("/:id/move", middleware.FileOwnerMiddleware(), fileController.MoveFile)
 				// Download/presign allow collaborators; keep standard auth only
 				files.GET("/:id/download", fileController.DownloadFile)
 				files.POST("/:id/presigned-url", fileController.GeneratePresignedURL)
+				files.GET("/:id/archive", fileController.GetArchiveEntries)
+				files.GET("/:id/archive/entry", fileController.DownloadArchiveEntry)
+				files.POST("/:id/extract", fileController.ExtractArchive)
+				files.GET("/:id/extract/:jobId", fileController.GetExtraction)
 				// Collaborators
 				files.GET("/:id/collaborators", middleware.FileOwnerMiddleware(), fileController.GetCollaborators)
 				files.POST("/:id/collaborators", middleware.FileOwnerMiddleware(), fileController.AddCollaborator)
 				files.PUT("/:id/collaborators/:collaboratorId", middleware.FileOwnerMiddleware(), fileController.UpdateCollaborator)
 				files.DELETE("/:id/collaborators/:collaboratorId", middleware.FileOwnerMiddleware(), fileController.RemoveCollaborator)
+				files.GET("/:id/invites", middleware.FileOwnerMiddleware(), inviteController.GetFileInvites)
+				files.POST("/:id/invites/:inviteId/resend", middleware.FileOwnerMiddleware(), inviteController.ResendFileInvite)
+				files.DELETE("/:id/invites/:inviteId", middleware.FileOwnerMiddleware(), inviteController.RevokeFileInvite)
+				// Anyone signed in can ask the owner for access
+				files.POST("/:id/access-requests", accessRequestController.RequestFileAccess)
+				// Share links
+				files.POST("/:id/share-links/disable", shareController.DisableFileShareLinks)
+				// Comments
+				files.GET("/:id/comments", commentController.GetFileComments)
+				files.POST("/:id/comments", commentController.CreateFileComment)
 			}
 
 			// Folder management routes
@@ -83,14 +143,25 @@ func SetupRoutes(router *gin.Engine) {
 				folders.GET("/shared-with-me", folderController.GetSharedWithMeFolders)
 				folders.POST("/", folderController.CreateFolder)
 				folders.GET("/:id", folderController.GetFolder)
-				folders.PUT("/:id", folderController.UpdateFolder)
-				folders.DELETE("/:id", folderController.DeleteFolder)
-				folders.POST("/:id/move", folderController.MoveFolder)
+				folders.PUT("/:id", middleware.FolderRoleMiddleware(models.RoleEditor), folderController.UpdateFolder)
+				folders.DELETE("/:id", middleware.FolderOwnerMiddleware(), folderController.DeleteFolder)
+				folders.POST("/:id/move", middleware.FolderRoleMiddleware(models.RoleEditor), folderController.MoveFolder)
+				folders.GET("/:id/download", folderController.DownloadFolder)
 				// Collaborators
 				folders.GET("/:id/collaborators", middleware.FolderOwnerMiddleware(), folderController.GetCollaborators)
 				folders.POST("/:id/collaborators", middleware.FolderOwnerMiddleware(), folderController.AddCollaborator)
 				folders.PUT("/:id/collaborators/:collaboratorId", middleware.FolderOwnerMiddleware(), folderController.UpdateCollaborator)
 				folders.DELETE("/:id/collaborators/:collaboratorId", middleware.FolderOwnerMiddleware(), folderController.RemoveCollaborator)
+				folders.GET("/:id/invites", middleware.FolderOwnerMiddleware(), inviteController.GetFolderInvites)
+				folders.POST("/:id/invites/:inviteId/resend", middleware.FolderOwnerMiddleware(), inviteController.ResendFolderInvite)
+				folders.DELETE("/:id/invites/:inviteId", middleware.FolderOwnerMiddleware(), inviteController.RevokeFolderInvite)
+				// Anyone signed in can ask the owner for access
+				folders.POST("/:id/access-requests", accessRequestController.RequestFolderAccess)
+				// Share links
+				folders.POST("/:id/share-links/disable", shareController.DisableFolderShareLinks)
+				// Comments
+				folders.GET("/:id/comments", commentController.GetFolderComments)
+				folders.POST("/:id/comments", commentController.CreateFolderComment)
 			}
 
 			// Trash management routes
@@ -106,6 +177,110 @@ func SetupRoutes(router *gin.Engine) {
 				trash.DELETE("/empty", trashController.EmptyTrash)
 			}
 
+			// File request routes
+			fileRequests := protected.Group("/file-requests")
+			{
+				fileRequests.GET("/", fileRequestController.GetFileRequests)
+				fileRequests.POST("/", fileRequestController.CreateFileRequest)
+				fileRequests.GET("/:id", fileRequestController.GetFileRequest)
+				fileRequests.PUT("/:id", fileRequestController.UpdateFileRequest)
+				fileRequests.DELETE("/:id", fileRequestController.DeleteFileRequest)
+				fileRequests.GET("/:id/uploads", fileRequestController.GetFileRequestUploads)
+			}
+
+			// Transfer routes, transfers are created by uploading files with recipients
+			transfers := protected.Group("/transfers")
+			{
+				transfers.GET("/", transferController.GetTransfers)
+				transfers.GET("/:id", transferController.GetTransfer)
+				transfers.DELETE("/:id", transferController.DeleteTransfer)
+			}
+
+			// Comment routes, comments are created and listed under their file or folder
+			comments := protected.Group("/comments")
+			{
+				comments.PUT("/:id", commentController.UpdateComment)
+				comments.DELETE("/:id", commentController.DeleteComment)
+				comments.POST("/:id/resolve", commentController.ResolveComment)
+				comments.POST("/:id/unresolve", commentController.UnresolveComment)
+			}
+
+			// Ownership transfer routes, the recipient accepts or declines and the sender can cancel
+			ownership := protected.Group("/ownership-transfers")
+			{
+				ownership.GET("/", ownershipController.GetOwnershipTransfers)
+				ownership.POST("/", ownershipController.CreateOwnershipTransfer)
+				ownership.GET("/:id", ownershipController.GetOwnershipTransfer)
+				ownership.POST("/:id/accept", ownershipController.AcceptOwnershipTransfer)
+				ownership.POST("/:id/decline", ownershipController.DeclineOwnershipTransfer)
+				ownership.POST("/:id/cancel", ownershipController.CancelOwnershipTransfer)
+			}
+
+			// Access request routes, the owner of the item approves or denies and the requester can cancel
+			accessRequests := protected.Group("/access-requests")
+			{
+				accessRequests.GET("/", accessRequestController.GetAccessRequests)
+				accessRequests.GET("/:id", accessRequestController.GetAccessRequest)
+				accessRequests.POST("/:id/approve", accessRequestController.ApproveAccessRequest)
+				accessRequests.POST("/:id/deny", accessRequestController.DenyAccessRequest)
+				accessRequests.POST("/:id/cancel", accessRequestController.CancelAccessRequest)
+			}
+
+			// Collaborator invites sent to the signed in user's address
+			protected.POST("/invites/accept", inviteController.AcceptInvite)
+
+			// Group routes, managers change the group and members can leave by removing themselves
+			groups := protected.Group("/groups")
+			{
+				groups.GET("/", groupController.GetGroups)
+				groups.POST("/", groupController.CreateGroup)
+				groups.GET("/:id", groupController.GetGroup)
+				groups.PUT("/:id", groupController.UpdateGroup)
+				groups.DELETE("/:id", groupController.DeleteGroup)
+				groups.POST("/:id/members", groupController.AddGroupMember)
+				groups.PUT("/:id/members/:userId", groupController.UpdateGroupMember)
+				groups.DELETE("/:id/members/:userId", groupController.RemoveGroupMember)
+			}
+
+			// Organization routes, owners and admins change the organization and members can leave
+			organizations := protected.Group("/organizations")
+			{
+				organizations.GET("/", organizationController.GetOrganizations)
+				organizations.POST("/", organizationController.CreateOrganization)
+				organizations.GET("/:id", organizationController.GetOrganization)
+				organizations.PUT("/:id", organizationController.UpdateOrganization)
+				organizations.DELETE("/:id", organizationController.DeleteOrganization)
+				organizations.POST("/:id/members", organizationController.AddOrganizationMember)
+				organizations.PUT("/:id/members/:userId", organizationController.UpdateOrganizationMember)
+				organizations.DELETE("/:id/members/:userId", organizationController.RemoveOrganizationMember)
+				organizations.GET("/:id/drives", sharedDriveController.GetOrganizationDrives)
+				organizations.POST("/:id/drives", sharedDriveController.CreateDrive)
+			}
+
+			// Shared drive routes, the content itself goes through the folder and file routes
+			drives := protected.Group("/drives")
+			{
+				drives.GET("/", sharedDriveController.GetDrives)
+				drives.GET("/:id", sharedDriveController.GetDrive)
+				drives.PUT("/:id", sharedDriveController.UpdateDrive)
+				drives.DELETE("/:id", sharedDriveController.DeleteDrive)
+				drives.GET("/:id/trash", sharedDriveController.GetDriveTrash)
+				drives.POST("/:id/members", sharedDriveController.AddDriveMember)
+				drives.PUT("/:id/members/:userId", sharedDriveController.UpdateDriveMember)
+				drives.DELETE("/:id/members/:userId", sharedDriveController.RemoveDriveMember)
+			}
+
+			// Notification routes
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/", notificationController.GetNotifications)
+				notifications.GET("/email-preferences", notificationController.GetEmailPreferences)
+				notifications.PUT("/email-preferences", notificationController.UpdateEmailPreferences)
+				notifications.POST("/read-all", notificationController.MarkAllNotificationsRead)
+				notifications.POST("/:id/read", notificationController.MarkNotificationRead)
+				notifications.DELETE("/:id", notificationController.DeleteNotification)
+			}
+
 			// Share management routes
 			share := protected.Group("/share")
 			{
@@ -113,6 +288,8 @@ func SetupRoutes(router *gin.Engine) {
 				share.POST("/", shareController.CreateShareLink)
 				share.GET("/stats", shareController.GetShareStats)
 				share.GET("/:id", shareController.GetShareLink)
+				share.GET("/:id/analytics", shareController.GetShareAnalytics)
+				share.GET("/:id/analytics/export", shareController.ExportShareAnalytics)
 				share.PUT("/:id", shareController.UpdateShareLink)
 				share.DELETE("/:id", shareController.DeleteShareLink)
 			}
@@ -126,6 +303,27 @@ func SetupRoutes(router *gin.Engine) {
 		{
 			admin.GET("/users", adminController.GetUsers)
 			admin.GET("/stats", adminController.GetSystemStats)
+			admin.GET("/users/:id/quota", quotaController.AdminGetUserQuota)
+			admin.PUT("/users/:id/quota", quotaController.AdminUpdateUserQuota)
+			admin.GET("/drives/:id/quota", quotaController.AdminGetDriveQuota)
+			admin.PUT("/drives/:id/quota", quotaController.AdminUpdateDriveQuota)
+			admin.GET("/plans", planController.GetPlans)
+			admin.POST("/plans", planController.CreatePlan)
+			admin.GET("/plans/:id", planController.GetPlan)
+			admin.PUT("/plans/:id", planController.UpdatePlan)
+			admin.DELETE("/plans/:id", planController.DeletePlan)
+			admin.PUT("/users/:id/plan", planController.AssignUserPlan)
+			admin.PUT("/organizations/:id/plan", planController.AssignOrganizationPlan)
+			admin.GET("/email-outbox", notificationController.AdminGetEmailOutbox)
+			admin.POST("/ownership-transfers", ownershipController.ForceOwnershipTransfer)
+			admin.GET("/groups", groupController.AdminGetGroups)
+			admin.POST("/groups", groupController.AdminCreateGroup)
+			admin.GET("/groups/:id", groupController.AdminGetGroup)
+			admin.PUT("/groups/:id", groupController.AdminUpdateGroup)
+			admin.DELETE("/groups/:id", groupController.AdminDeleteGroup)
+			admin.POST("/groups/:id/members", groupController.AdminAddGroupMember)
+			admin.PUT("/groups/:id/members/:userId", groupController.AdminUpdateGroupMember)
+			admin.DELETE("/groups/:id/members/:userId", groupController.AdminRemoveGroupMember)
 		}
 
 	}
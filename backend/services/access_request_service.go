@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAccessRequestNotFound     = errors.New("access request not found")
+	ErrAccessRequestItemNotFound = errors.New("file or folder not found")
+	ErrAccessRequestOwner        = errors.New("you own this item")
+	ErrAccessRequestHasAccess    = errors.New("you already have this access")
+	ErrAccessRequestPending      = errors.New("you already requested access to this item")
+	ErrAccessRequestNotPending   = errors.New("access request is no longer pending")
+	ErrAccessRequestRateLimited  = errors.New("too many access requests, try again later")
+	ErrAccessRequestDriveItem    = errors.New("access to shared drive content is given through drive membership")
+)
+
+type AccessRequestService struct {
+	db                   *gorm.DB
+	authorizationService *AuthorizationService
+	notificationService  *NotificationService
+}
+
+func NewAccessRequestService() *AccessRequestService {
+	return &AccessRequestService{
+		db:                   database.GetDB(),
+		authorizationService: NewAuthorizationService(),
+		notificationService:  NewNotificationService(),
+	}
+}
+
+// RequestAccess asks the owner of a file or folder for a role on it and notifies them. Each
+// requester can only make a limited number of requests an hour.
+func (ars *AccessRequestService) RequestAccess(requester *models.User, resourceID uuid.UUID, isFile bool, req models.AccessRequestCreateRequest) (*models.AccessRequest, error) {
+	var itemName string
+	var ownerID uuid.UUID
+	var driveID *uuid.UUID
+	var role models.CollaboratorRole
+	var err error
+	if isFile {
+		var file models.File
+		if err := ars.db.Where("id = ? AND is_trashed = false", resourceID).First(&file).Error; err != nil {
+			return nil, ErrAccessRequestItemNotFound
+		}
+		itemName, ownerID, driveID = file.OriginalName, file.UserID, file.DriveID
+		role, err = ars.authorizationService.FileRole(requester.ID, &file)
+	} else {
+		var folder models.Folder
+		if err := ars.db.Where("id = ? AND is_trashed = false", resourceID).First(&folder).Error; err != nil {
+			return nil, ErrAccessRequestItemNotFound
+		}
+		itemName, ownerID, driveID = folder.Name, folder.UserID, folder.DriveID
+		role, err = ars.authorizationService.FolderRole(requester.ID, &folder)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if driveID != nil {
+		return nil, ErrAccessRequestDriveItem
+	}
+	if ownerID == requester.ID {
+		return nil, ErrAccessRequestOwner
+	}
+	if role.Includes(req.Role) {
+		return nil, ErrAccessRequestHasAccess
+	}
+
+	var pending int64
+	ars.itemScope(resourceID, isFile).Model(&models.AccessRequest{}).
+		Where("requester_id = ? AND status = ?", requester.ID, models.AccessRequestPending).Count(&pending)
+	if pending > 0 {
+		return nil, ErrAccessRequestPending
+	}
+
+	var recent int64
+	ars.db.Model(&models.AccessRequest{}).
+		Where("requester_id = ? AND created_at > ?", requester.ID, time.Now().Add(-time.Hour)).Count(&recent)
+	if recent >= int64(config.AppConfig.AccessRequest.MaxPerHour) {
+		return nil, ErrAccessRequestRateLimited
+	}
+
+	request := &models.AccessRequest{
+		ItemName:    itemName,
+		RequesterID: requester.ID,
+		Role:        req.Role,
+		Message:     req.Message,
+		Status:      models.AccessRequestPending,
+	}
+	if isFile {
+		request.FileID = &resourceID
+	} else {
+		request.FolderID = &resourceID
+	}
+	if err := ars.db.Create(request).Error; err != nil {
+		return nil, err
+	}
+	request.Requester = *requester
+
+	ars.notify(request, ownerID, "Access requested",
+		fmt.Sprintf("%s asked for %s access to %q", requester.GetFullName(), req.Role, itemName))
+	return request, nil
+}
+
+// ListRequests returns the access requests the user made, or received for items they own,
+// newest first
+func (ars *AccessRequestService) ListRequests(userID uuid.UUID, direction, status string, page, limit int) ([]models.AccessRequest, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		switch direction {
+		case "incoming":
+			db = db.Where(ars.ownedBy(userID))
+		case "outgoing":
+			db = db.Where("requester_id = ?", userID)
+		default:
+			db = db.Where(ars.db.Where("requester_id = ?", userID).Or(ars.ownedBy(userID)))
+		}
+		if status != "" {
+			db = db.Where("status = ?", status)
+		}
+		return db
+	}
+
+	var total int64
+	if err := ars.db.Model(&models.AccessRequest{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []models.AccessRequest
+	offset := (page - 1) * limit
+	err := ars.db.Scopes(filter).Preload("Requester").
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&requests).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+// GetRequest returns an access request the user made or can answer
+func (ars *AccessRequestService) GetRequest(userID, requestID uuid.UUID) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	err := ars.db.Preload("Requester").Where("id = ?", requestID).
+		Where(ars.db.Where("requester_id = ?", userID).Or(ars.ownedBy(userID))).
+		First(&request).Error
+	if err != nil {
+		return nil, ErrAccessRequestNotFound
+	}
+	return &request, nil
+}
+
+// ApproveRequest gives the requester the role they asked for, or the one the owner picked. A role
+// the requester already has on the item is only ever raised.
+func (ars *AccessRequestService) ApproveRequest(ownerID, requestID uuid.UUID, req models.ApproveAccessRequest) (*models.AccessRequest, error) {
+	request, err := ars.answerable(ownerID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = request.Role
+	}
+	err = ars.db.Transaction(func(tx *gorm.DB) error {
+		err := ars.setStatus(tx, request, ownerID, models.AccessRequestApproved, map[string]interface{}{
+			"granted_role":     role,
+			"response_message": req.Message,
+		})
+		if err != nil {
+			return err
+		}
+		target := models.Collaborator{FileID: request.FileID, FolderID: request.FolderID, UserID: &request.RequesterID}
+		return grantAtLeast(tx, target, role, req.ExpiresAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	request.GrantedRole = role
+	request.ResponseMessage = req.Message
+
+	ars.notify(request, request.RequesterID, "Access request approved",
+		fmt.Sprintf("You now have %s access to %q", role, request.ItemName))
+	return request, nil
+}
+
+// DenyRequest turns an access request down
+func (ars *AccessRequestService) DenyRequest(ownerID, requestID uuid.UUID, req models.DenyAccessRequest) (*models.AccessRequest, error) {
+	request, err := ars.answerable(ownerID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ars.setStatus(ars.db, request, ownerID, models.AccessRequestDenied, map[string]interface{}{
+		"response_message": req.Message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.ResponseMessage = req.Message
+
+	ars.notify(request, request.RequesterID, "Access request denied",
+		fmt.Sprintf("Your request for access to %q was denied", request.ItemName))
+	return request, nil
+}
+
+// CancelRequest withdraws a pending request the user made
+func (ars *AccessRequestService) CancelRequest(requesterID, requestID uuid.UUID) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	if err := ars.db.Preload("Requester").Where("id = ? AND requester_id = ?", requestID, requesterID).First(&request).Error; err != nil {
+		return nil, ErrAccessRequestNotFound
+	}
+	if err := ars.setStatus(ars.db, &request, requesterID, models.AccessRequestCancelled, nil); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// answerable loads a pending request on an item the user owns
+func (ars *AccessRequestService) answerable(ownerID, requestID uuid.UUID) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	err := ars.db.Preload("Requester").Where("id = ?", requestID).Where(ars.ownedBy(ownerID)).First(&request).Error
+	if err != nil {
+		return nil, ErrAccessRequestNotFound
+	}
+	if request.Status != models.AccessRequestPending {
+		return nil, ErrAccessRequestNotPending
+	}
+	return &request, nil
+}
+
+// ownedBy matches requests for files and folders the user currently owns
+func (ars *AccessRequestService) ownedBy(userID uuid.UUID) *gorm.DB {
+	return ars.db.Where("file_id IN (?)", ars.db.Model(&models.File{}).Select("id").Scopes(models.OwnedBy(userID))).
+		Or("folder_id IN (?)", ars.db.Model(&models.Folder{}).Select("id").Scopes(models.OwnedBy(userID)))
+}
+
+func (ars *AccessRequestService) itemScope(resourceID uuid.UUID, isFile bool) *gorm.DB {
+	if isFile {
+		return ars.db.Where("file_id = ?", resourceID)
+	}
+	return ars.db.Where("folder_id = ?", resourceID)
+}
+
+// setStatus moves a pending request to its final status, failing when it was answered meanwhile
+func (ars *AccessRequestService) setStatus(db *gorm.DB, request *models.AccessRequest, userID uuid.UUID, status models.AccessRequestStatus, updates map[string]interface{}) error {
+	now := time.Now()
+	if updates == nil {
+		updates = make(map[string]interface{})
+	}
+	updates["status"] = status
+	updates["responded_by_id"] = userID
+	updates["responded_at"] = now
+
+	result := db.Model(&models.AccessRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.AccessRequestPending).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccessRequestNotPending
+	}
+	request.Status = status
+	request.RespondedByID = &userID
+	request.RespondedAt = &now
+	return nil
+}
+
+func (ars *AccessRequestService) notify(request *models.AccessRequest, userID uuid.UUID, title, message string) {
+	if _, err := ars.notificationService.Notify(userID, models.NotificationAccessRequest, title, message,
+		models.ResourceAccessRequest, &request.ID); err != nil {
+		config.GetLogger().Error("Failed to send access request notification", "error", err, "request_id", request.ID)
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
+)
+
+const (
+	// maxArchiveEntries caps how many entries are read from a single archive
+	maxArchiveEntries = 10000
+	// maxArchiveEntrySize caps the uncompressed size of a single entry
+	maxArchiveEntrySize = 1 << 30 // 1GB
+	// maxArchiveScanSize caps how many bytes are decompressed while walking a compressed tar stream
+	maxArchiveScanSize = 10 << 30 // 10GB
+	// maxArchiveCompressionRatio rejects entries that expand suspiciously well (zip bombs)
+	maxArchiveCompressionRatio = 200
+	// archiveRatioThreshold is the size below which the compression ratio is not checked
+	archiveRatioThreshold = 1 << 20 // 1MB
+)
+
+var (
+	ErrArchiveUnsupported    = errors.New("file is not a supported archive")
+	ErrArchiveCorrupt        = errors.New("archive is corrupt or unreadable")
+	ErrArchiveEntryNotFound  = errors.New("archive entry not found")
+	ErrArchiveTooManyEntries = errors.New("archive contains too many entries")
+	ErrArchiveUnsafe         = errors.New("archive entry exceeds the allowed size or compression ratio")
+	ErrArchiveInvalidPath    = errors.New("invalid archive entry path")
+
+	errStopWalk = errors.New("stop archive walk")
+)
+
+// archiveVisitor is called for every safe entry of an archive. open returns a reader over the
+// entry content and is only valid until the visitor returns.
+type archiveVisitor func(entry models.ArchiveEntry, open func() (io.ReadCloser, error)) error
+
+type ArchiveService struct {
+	storage storage.StorageService
+}
+
+func NewArchiveService() *ArchiveService {
+	return &ArchiveService{
+		storage: storage.GetStorage(),
+	}
+}
+
+// ArchiveFormat returns the archive format for a file name, or an empty string if it is not a supported archive
+func ArchiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return models.ArchiveFormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return models.ArchiveFormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return models.ArchiveFormatTar
+	}
+	return ""
+}
+
+// ListEntries returns the table of contents of an archive file
+func (as *ArchiveService) ListEntries(ctx context.Context, file *models.File) (*models.ArchiveListing, error) {
+	listing := &models.ArchiveListing{
+		Format:  ArchiveFormat(file.OriginalName),
+		Entries: []models.ArchiveEntry{},
+		File:    file.ToResponse(),
+	}
+
+	skipped, err := as.walk(ctx, file, func(entry models.ArchiveEntry, _ func() (io.ReadCloser, error)) error {
+		listing.Entries = append(listing.Entries, entry)
+		if !entry.IsDir {
+			listing.TotalSize += entry.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listing.TotalEntries = len(listing.Entries)
+	listing.SkippedEntries = skipped
+	return listing, nil
+}
+
+// OpenEntry returns a reader over a single file entry of an archive. The caller must close it.
+func (as *ArchiveService) OpenEntry(ctx context.Context, file *models.File, entryPath string) (io.ReadCloser, *models.ArchiveEntry, error) {
+	target, err := sanitizeArchivePath(entryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found *models.ArchiveEntry
+	var reader io.ReadCloser
+	visit := func(entry models.ArchiveEntry, open func() (io.ReadCloser, error)) error {
+		if entry.Path != target || entry.IsDir {
+			return nil
+		}
+		rc, err := open()
+		if err != nil {
+			return err
+		}
+		found, reader = &entry, rc
+		return errStopWalk
+	}
+
+	switch ArchiveFormat(file.OriginalName) {
+	case models.ArchiveFormatZip:
+		if _, err := as.walkZip(ctx, file, visit); err != nil {
+			return nil, nil, err
+		}
+		if reader == nil {
+			return nil, nil, ErrArchiveEntryNotFound
+		}
+		return reader, found, nil
+	case models.ArchiveFormatTar, models.ArchiveFormatTarGz:
+		tr, closer, err := as.openTar(ctx, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The tar stream must stay open while the caller reads the entry
+		if _, err := walkTar(tr, visit); err != nil || reader == nil {
+			closer.Close()
+			if err == nil {
+				err = ErrArchiveEntryNotFound
+			}
+			return nil, nil, err
+		}
+		return &archiveEntryReader{Reader: reader, closer: closer}, found, nil
+	default:
+		return nil, nil, ErrArchiveUnsupported
+	}
+}
+
+// walk calls visit for every safe entry of the archive and returns the number of skipped entries
+func (as *ArchiveService) walk(ctx context.Context, file *models.File, visit archiveVisitor) (int, error) {
+	switch ArchiveFormat(file.OriginalName) {
+	case models.ArchiveFormatZip:
+		return as.walkZip(ctx, file, visit)
+	case models.ArchiveFormatTar, models.ArchiveFormatTarGz:
+		tr, closer, err := as.openTar(ctx, file)
+		if err != nil {
+			return 0, err
+		}
+		defer closer.Close()
+		return walkTar(tr, visit)
+	default:
+		return 0, ErrArchiveUnsupported
+	}
+}
+
+func (as *ArchiveService) walkZip(ctx context.Context, file *models.File, visit archiveVisitor) (int, error) {
+	key := file.ObjectKey()
+	size, err := as.storage.GetFileSize(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// The central directory is read through ranged requests, so only the parts we need are fetched
+	zr, err := zip.NewReader(storage.NewReaderAt(ctx, as.storage, key, size), size)
+	if err != nil {
+		return 0, ErrArchiveCorrupt
+	}
+
+	if len(zr.File) > maxArchiveEntries {
+		return 0, ErrArchiveTooManyEntries
+	}
+
+	skipped := 0
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		if mode&fs.ModeSymlink != 0 || (!mode.IsRegular() && !mode.IsDir()) {
+			skipped++
+			continue
+		}
+
+		entryPath, err := sanitizeArchivePath(zf.Name)
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		entry := models.ArchiveEntry{
+			Path:           entryPath,
+			Name:           path.Base(entryPath),
+			IsDir:          mode.IsDir(),
+			Size:           int64(zf.UncompressedSize64),
+			CompressedSize: int64(zf.CompressedSize64),
+			ModifiedAt:     zf.Modified,
+		}
+
+		zf := zf
+		open := func() (io.ReadCloser, error) {
+			if err := checkArchiveEntry(entry); err != nil {
+				return nil, err
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, ErrArchiveCorrupt
+			}
+			return &archiveEntryReader{Reader: &boundedReader{r: rc, remaining: entry.Size}, closer: rc}, nil
+		}
+
+		if err := visit(entry, open); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return skipped, nil
+			}
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
+
+// openTar opens a tar or tar.gz archive. Plain tar archives are read through ranged requests so
+// file data can be skipped by seeking; compressed archives have to be streamed.
+func (as *ArchiveService) openTar(ctx context.Context, file *models.File) (*tar.Reader, io.Closer, error) {
+	key := file.ObjectKey()
+
+	if ArchiveFormat(file.OriginalName) == models.ArchiveFormatTar {
+		size, err := as.storage.GetFileSize(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		section := io.NewSectionReader(storage.NewReaderAt(ctx, as.storage, key, size), 0, size)
+		return tar.NewReader(section), io.NopCloser(nil), nil
+	}
+
+	rc, err := as.storage.OpenFile(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, nil, ErrArchiveCorrupt
+	}
+
+	closer := closerFunc(func() error {
+		gz.Close()
+		return rc.Close()
+	})
+	return tar.NewReader(&boundedReader{r: gz, remaining: maxArchiveScanSize}), closer, nil
+}
+
+func walkTar(tr *tar.Reader, visit archiveVisitor) (int, error) {
+	skipped, seen := 0, 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return skipped, nil
+		}
+		if err != nil {
+			if errors.Is(err, ErrArchiveUnsafe) {
+				return skipped, err
+			}
+			return skipped, ErrArchiveCorrupt
+		}
+
+		seen++
+		if seen > maxArchiveEntries {
+			return skipped, ErrArchiveTooManyEntries
+		}
+
+		// Symlinks, hard links and devices are never exposed
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+			skipped++
+			continue
+		}
+
+		entryPath, err := sanitizeArchivePath(hdr.Name)
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		entry := models.ArchiveEntry{
+			Path:       entryPath,
+			Name:       path.Base(entryPath),
+			IsDir:      hdr.Typeflag == tar.TypeDir,
+			Size:       hdr.Size,
+			ModifiedAt: hdr.ModTime,
+		}
+
+		open := func() (io.ReadCloser, error) {
+			if err := checkArchiveEntry(entry); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(&boundedReader{r: tr, remaining: entry.Size}), nil
+		}
+
+		if err := visit(entry, open); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return skipped, nil
+			}
+			return skipped, err
+		}
+	}
+}
+
+// checkArchiveEntry guards against zip bombs before an entry is decompressed
+func checkArchiveEntry(entry models.ArchiveEntry) error {
+	if entry.Size > maxArchiveEntrySize {
+		return ErrArchiveUnsafe
+	}
+	if entry.CompressedSize > 0 && entry.Size > archiveRatioThreshold &&
+		entry.Size/entry.CompressedSize > maxArchiveCompressionRatio {
+		return ErrArchiveUnsafe
+	}
+	return nil
+}
+
+// sanitizeArchivePath normalizes an entry name and rejects absolute paths and path traversal
+func sanitizeArchivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.ContainsRune(name, 0) || strings.HasPrefix(name, "/") ||
+		(len(name) > 1 && name[1] == ':') {
+		return "", ErrArchiveInvalidPath
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", ErrArchiveInvalidPath
+		}
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == "" {
+		return "", ErrArchiveInvalidPath
+	}
+	return cleaned, nil
+}
+
+// boundedReader fails once more than remaining bytes are produced, so entries cannot expand past
+// their declared size
+type boundedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (b *boundedReader) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Allow a clean EOF but refuse any extra data
+		var probe [1]byte
+		if n, _ := b.r.Read(probe[:]); n > 0 {
+			return 0, ErrArchiveUnsafe
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+type archiveEntryReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *archiveEntryReader) Close() error {
+	return r.closer.Close()
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"github.com/google/uuid"
 	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/geoip"
 	"github.com/manjurulhoque/swift-share/backend/models"
 	"gorm.io/gorm"
 )
@@ -19,6 +20,7 @@ func NewAuditService() *AuditService {
 
 // LogEvent creates a new audit log entry
 func (as *AuditService) LogEvent(userID *uuid.UUID, action, resource string, resourceID *uuid.UUID, details, ipAddress, userAgent, status string) error {
+	location := geoip.Lookup(ipAddress)
 	auditLog := models.AuditLog{
 		UserID:     userID,
 		Action:     action,
@@ -26,6 +28,8 @@ func (as *AuditService) LogEvent(userID *uuid.UUID, action, resource string, res
 		ResourceID: resourceID,
 		Details:    details,
 		IPAddress:  ipAddress,
+		Country:    location.Country,
+		City:       location.City,
 		UserAgent:  userAgent,
 		Status:     status,
 	}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// maxFolderDepth guards ancestor walks against cycles in corrupted parent chains
+const maxFolderDepth = 256
+
+// maxPermissionCacheEntries bounds the cache, it is emptied when it grows past this
+const maxPermissionCacheEntries = 50000
+
+var (
+	ErrResourceNotFound = errors.New("resource not found")
+	ErrAccessDenied     = errors.New("access denied")
+)
+
+// AuthorizationService resolves a user's effective role on files and folders. The role is the
+// most privileged of:
+//   - owner, when the user owns the item, which nobody does personally in a shared drive
+//   - editor, when the user owns a folder the item is in
+//   - every unexpired collaborator grant on the item or any folder above it, made to the user or
+//     to a group they are a member of
+//   - the user's drive role, when a shared drive owns the item
+type AuthorizationService struct {
+	db    *gorm.DB
+	cache *permissionCache
+}
+
+func NewAuthorizationService() *AuthorizationService {
+	return &AuthorizationService{
+		db:    database.GetDB(),
+		cache: getPermissionCache(),
+	}
+}
+
+// InvalidatePermissions drops all cached roles. It is called after anything that changes who can
+// access what: collaborator changes, group membership changes, moves and ownership changes.
+func InvalidatePermissions() {
+	getPermissionCache().clear()
+}
+
+// FileRole returns the user's effective role on the file, empty when they have no access
+func (as *AuthorizationService) FileRole(userID uuid.UUID, file *models.File) (models.CollaboratorRole, error) {
+	if file.DriveID == nil && file.UserID == userID {
+		return models.RoleOwner, nil
+	}
+
+	key := permissionKey{userID: userID, resourceID: file.ID, isFile: true}
+	if resolved, ok := as.cache.get(key); ok {
+		return resolved.role, nil
+	}
+	generation := as.cache.generation()
+
+	var grants []models.Collaborator
+	if err := as.activeGrants(userID).Where("file_id = ?", file.ID).Find(&grants).Error; err != nil {
+		return "", err
+	}
+	resolved := resolveGrants(grants)
+
+	driveRole, err := driveContentRole(as.db, userID, file.DriveID)
+	if err != nil {
+		return "", err
+	}
+	resolved.role = resolved.role.Max(driveRole)
+
+	if file.FolderID != nil {
+		folderRole, err := as.folderRole(userID, *file.FolderID, true)
+		if err != nil {
+			return "", err
+		}
+		resolved.role = resolved.role.Max(folderRole.role)
+		resolved.validUntil = earliest(resolved.validUntil, folderRole.validUntil)
+	}
+
+	as.cache.set(key, resolved, generation)
+	return resolved.role, nil
+}
+
+// FolderRole returns the user's effective role on the folder, empty when they have no access
+func (as *AuthorizationService) FolderRole(userID uuid.UUID, folder *models.Folder) (models.CollaboratorRole, error) {
+	if folder.DriveID == nil && folder.UserID == userID {
+		return models.RoleOwner, nil
+	}
+	resolved, err := as.folderRole(userID, folder.ID, false)
+	if err != nil {
+		return "", err
+	}
+	return resolved.role, nil
+}
+
+// AuthorizeFile loads the file and checks that the user has at least the required role on it
+func (as *AuthorizationService) AuthorizeFile(userID, fileID uuid.UUID, required models.CollaboratorRole) (*models.File, error) {
+	var file models.File
+	if err := as.db.Where("id = ?", fileID).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, err
+	}
+	if err := as.RequireFile(userID, &file, required); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// AuthorizeFolder loads the folder and checks that the user has at least the required role on it
+func (as *AuthorizationService) AuthorizeFolder(userID, folderID uuid.UUID, required models.CollaboratorRole) (*models.Folder, error) {
+	var folder models.Folder
+	if err := as.db.Where("id = ?", folderID).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, err
+	}
+	if err := as.RequireFolder(userID, &folder, required); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// AuthorizeDestination loads the folder an item owned by ownerID is moved into. The user needs
+// editor access on it and it must belong to ownerID, so every folder tree keeps a single owner.
+func (as *AuthorizationService) AuthorizeDestination(userID, folderID, ownerID uuid.UUID) (*models.Folder, error) {
+	folder, err := as.AuthorizeFolder(userID, folderID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if folder.IsTrashed {
+		return nil, ErrResourceNotFound
+	}
+	if folder.OwnerID() != ownerID {
+		return nil, ErrAccessDenied
+	}
+	return folder, nil
+}
+
+// RequireFile returns ErrAccessDenied unless the user has at least the required role on the file
+func (as *AuthorizationService) RequireFile(userID uuid.UUID, file *models.File, required models.CollaboratorRole) error {
+	role, err := as.FileRole(userID, file)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(required) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// RequireFolder returns ErrAccessDenied unless the user has at least the required role on the folder
+func (as *AuthorizationService) RequireFolder(userID uuid.UUID, folder *models.Folder, required models.CollaboratorRole) error {
+	role, err := as.FolderRole(userID, folder)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(required) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// folderRole resolves the role on a folder from its ancestor chain. When inherited is set the
+// folder is a container of the item being checked, so owning it counts as editor rather than owner.
+func (as *AuthorizationService) folderRole(userID, folderID uuid.UUID, inherited bool) (resolvedRole, error) {
+	key := permissionKey{userID: userID, resourceID: folderID}
+	resolved, ok := as.cache.get(key)
+	if !ok {
+		generation := as.cache.generation()
+		var err error
+		resolved, err = as.resolveFolder(userID, folderID)
+		if err != nil {
+			return resolvedRole{}, err
+		}
+		as.cache.set(key, resolved, generation)
+	}
+
+	if inherited && resolved.role == models.RoleOwner {
+		resolved.role = models.RoleEditor
+	}
+	return resolved, nil
+}
+
+// resolveFolder walks from the folder up to its root, then combines the grants on the whole chain
+func (as *AuthorizationService) resolveFolder(userID, folderID uuid.UUID) (resolvedRole, error) {
+	chain := make([]uuid.UUID, 0, 8)
+	visited := make(map[uuid.UUID]bool)
+	var resolved resolvedRole
+	var driveID *uuid.UUID
+
+	next := &folderID
+	for next != nil && !visited[*next] && len(chain) < maxFolderDepth {
+		var folder models.Folder
+		if err := as.db.Select("id", "parent_id", "user_id", "drive_id").Where("id = ?", *next).First(&folder).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return resolvedRole{}, err
+		}
+		visited[folder.ID] = true
+		chain = append(chain, folder.ID)
+
+		if folder.DriveID == nil && folder.UserID == userID {
+			if folder.ID == folderID {
+				return resolvedRole{role: models.RoleOwner}, nil
+			}
+			resolved.role = resolved.role.Max(models.RoleEditor)
+		}
+		if folder.ID == folderID {
+			driveID = folder.DriveID
+		}
+		next = folder.ParentID
+	}
+	if len(chain) == 0 {
+		return resolved, nil
+	}
+
+	driveRole, err := driveContentRole(as.db, userID, driveID)
+	if err != nil {
+		return resolvedRole{}, err
+	}
+	resolved.role = resolved.role.Max(driveRole)
+
+	var grants []models.Collaborator
+	if err := as.activeGrants(userID).Where("folder_id IN ?", chain).Find(&grants).Error; err != nil {
+		return resolvedRole{}, err
+	}
+	fromGrants := resolveGrants(grants)
+	resolved.role = resolved.role.Max(fromGrants.role)
+	resolved.validUntil = fromGrants.validUntil
+	return resolved, nil
+}
+
+// GrantedFolderIDs returns the folders the user reaches through unexpired grants on the folder or
+// on a folder above it, made to them or to a group they are a member of. Listings use it, so they
+// show what the resolver allows without resolving a role per item.
+func (as *AuthorizationService) GrantedFolderIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var level []uuid.UUID
+	if err := as.grantsOf(userID).Where("folder_id IS NOT NULL").Distinct().Pluck("folder_id", &level).Error; err != nil {
+		return nil, err
+	}
+
+	var folderIDs []uuid.UUID
+	visited := make(map[uuid.UUID]bool)
+	for depth := 0; len(level) > 0 && depth < maxFolderDepth; depth++ {
+		var unseen []uuid.UUID
+		for _, id := range level {
+			if !visited[id] {
+				visited[id] = true
+				unseen = append(unseen, id)
+			}
+		}
+		if len(unseen) == 0 {
+			break
+		}
+		folderIDs = append(folderIDs, unseen...)
+
+		level = nil
+		if err := as.db.Model(&models.Folder{}).Where("parent_id IN ?", unseen).Pluck("id", &level).Error; err != nil {
+			return nil, err
+		}
+	}
+	return folderIDs, nil
+}
+
+// GrantedFiles returns a subquery of the IDs of the files the user reaches through unexpired
+// grants on the file or on a folder above it, see GrantedFolderIDs
+func (as *AuthorizationService) GrantedFiles(userID uuid.UUID) (*gorm.DB, error) {
+	folderIDs, err := as.GrantedFolderIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	fileGrants := as.grantsOf(userID).Select("file_id").Where("file_id IS NOT NULL")
+	return as.db.Model(&models.File{}).Select("id").Where("id IN (?) OR folder_id IN ?", fileGrants, folderIDs), nil
+}
+
+// grantsOf matches the collaborator rows of the user and of their groups that have not expired
+func (as *AuthorizationService) grantsOf(userID uuid.UUID) *gorm.DB {
+	return as.db.Model(&models.Collaborator{}).
+		Where("(user_id = ? OR group_id IN (?)) AND (expires_at IS NULL OR expires_at > ?)",
+			userID, groupsOf(as.db, userID), time.Now())
+}
+
+// activeGrants selects the role and expiry of the grants of grantsOf
+func (as *AuthorizationService) activeGrants(userID uuid.UUID) *gorm.DB {
+	return as.grantsOf(userID).Select("role", "expires_at")
+}
+
+// resolvedRole is a role together with the moment it may change on its own because a grant expires
+type resolvedRole struct {
+	role       models.CollaboratorRole
+	validUntil *time.Time
+}
+
+// resolveGrants returns the most privileged role of the grants
+func resolveGrants(grants []models.Collaborator) resolvedRole {
+	var resolved resolvedRole
+	for _, grant := range grants {
+		resolved.role = resolved.role.Max(grant.Role)
+		resolved.validUntil = earliest(resolved.validUntil, grant.ExpiresAt)
+	}
+	return resolved
+}
+
+func earliest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.Before(*a)) {
+		return b
+	}
+	return a
+}
+
+type permissionKey struct {
+	userID     uuid.UUID
+	resourceID uuid.UUID
+	isFile     bool
+}
+
+type permissionEntry struct {
+	resolved  resolvedRole
+	expiresAt time.Time
+}
+
+// permissionCache is an in-process cache of resolved roles. Entries expire after the configured
+// TTL or when one of the grants they were resolved from expires, whichever comes first.
+type permissionCache struct {
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	entries map[permissionKey]permissionEntry
+	gen     uint64 // bumped by clear
+}
+
+var (
+	permissionCacheOnce   sync.Once
+	sharedPermissionCache *permissionCache
+)
+
+func getPermissionCache() *permissionCache {
+	permissionCacheOnce.Do(func() {
+		var ttl time.Duration
+		if config.AppConfig != nil {
+			ttl = config.AppConfig.Permission.CacheTTL
+		}
+		sharedPermissionCache = &permissionCache{ttl: ttl, entries: make(map[permissionKey]permissionEntry)}
+	})
+	return sharedPermissionCache
+}
+
+func (pc *permissionCache) get(key permissionKey) (resolvedRole, bool) {
+	if pc.ttl <= 0 {
+		return resolvedRole{}, false
+	}
+	pc.mu.RLock()
+	entry, ok := pc.entries[key]
+	pc.mu.RUnlock()
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return resolvedRole{}, false
+	}
+	return entry.resolved, true
+}
+
+// generation is read before resolving a role and passed to set, so a role resolved from data
+// that changed in the meantime is not stored
+func (pc *permissionCache) generation() uint64 {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.gen
+}
+
+func (pc *permissionCache) set(key permissionKey, resolved resolvedRole, generation uint64) {
+	if pc.ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(pc.ttl)
+	if resolved.validUntil != nil && resolved.validUntil.Before(expiresAt) {
+		expiresAt = *resolved.validUntil
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if generation != pc.gen {
+		return
+	}
+	if len(pc.entries) >= maxPermissionCacheEntries {
+		pc.entries = make(map[permissionKey]permissionEntry)
+	}
+	pc.entries[key] = permissionEntry{resolved: resolved, expiresAt: expiresAt}
+}
+
+func (pc *permissionCache) clear() {
+	pc.mu.Lock()
+	pc.entries = make(map[permissionKey]permissionEntry)
+	pc.gen++
+	pc.mu.Unlock()
+}
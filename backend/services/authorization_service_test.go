@@ -0,0 +1,233 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+)
+
+// grantFile gives the user a role on the file, expiresAt may be nil
+func grantFile(t *testing.T, user *models.User, file *models.File, role models.CollaboratorRole, expiresAt *time.Time) *models.Collaborator {
+	t.Helper()
+	grant := &models.Collaborator{FileID: &file.ID, UserID: &user.ID, Role: role, ExpiresAt: expiresAt}
+	if err := database.GetDB().Create(grant).Error; err != nil {
+		t.Fatalf("create grant: %v", err)
+	}
+	return grant
+}
+
+// createTestDrive creates an organization with a shared drive in which the user has the role
+func createTestDrive(t *testing.T, member *models.User, role models.DriveRole) *models.SharedDrive {
+	t.Helper()
+	db := database.GetDB()
+	creator := createTestUser(t, "orgowner")
+	organization := &models.Organization{Name: "Acme", CreatedByID: creator.ID}
+	if err := db.Create(organization).Error; err != nil {
+		t.Fatalf("create organization: %v", err)
+	}
+	memberships := []models.OrganizationMember{
+		{OrganizationID: organization.ID, UserID: creator.ID, Role: models.OrganizationRoleOwner},
+		{OrganizationID: organization.ID, UserID: member.ID, Role: models.OrganizationRoleMember},
+	}
+	if err := db.Create(&memberships).Error; err != nil {
+		t.Fatalf("create organization members: %v", err)
+	}
+
+	// The root folder is created first and carries the drive's ID, as CreateDrive does
+	drive := &models.SharedDrive{ID: uuid.New(), OrganizationID: organization.ID, Name: "Eng", CreatedByID: creator.ID}
+	root := &models.Folder{UserID: creator.ID, DriveID: &drive.ID, Name: drive.Name}
+	if err := db.Create(root).Error; err != nil {
+		t.Fatalf("create drive root: %v", err)
+	}
+	drive.RootFolderID = root.ID
+	if err := db.Create(drive).Error; err != nil {
+		t.Fatalf("create drive: %v", err)
+	}
+	if err := db.Create(&models.DriveMember{DriveID: drive.ID, UserID: member.ID, Role: role}).Error; err != nil {
+		t.Fatalf("create drive member: %v", err)
+	}
+	return drive
+}
+
+func TestFileRole(t *testing.T) {
+	as := NewAuthorizationService()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		// setup returns the user to check and the file to check them on
+		setup func(t *testing.T) (*models.User, *models.File)
+		want  models.CollaboratorRole
+	}{
+		{
+			name: "owner",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				owner := createTestUser(t, "owner")
+				return owner, createTestFile(t, owner, nil, "a.txt", "a")
+			},
+			want: models.RoleOwner,
+		},
+		{
+			name: "direct file grant",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+				file := createTestFile(t, owner, nil, "a.txt", "a")
+				grantFile(t, user, file, models.RoleCommenter, nil)
+				return user, file
+			},
+			want: models.RoleCommenter,
+		},
+		{
+			name: "folder grant inherited through ancestors",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+				top := createTestFolder(t, owner, nil, "top")
+				middle := createTestFolder(t, owner, top, "middle")
+				file := createTestFile(t, owner, createTestFolder(t, owner, middle, "bottom"), "a.txt", "a")
+				grant := &models.Collaborator{FolderID: &top.ID, UserID: &user.ID, Role: models.RoleEditor}
+				if err := database.GetDB().Create(grant).Error; err != nil {
+					t.Fatalf("create grant: %v", err)
+				}
+				return user, file
+			},
+			want: models.RoleEditor,
+		},
+		{
+			name: "expired grant",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+				file := createTestFile(t, owner, nil, "a.txt", "a")
+				grantFile(t, user, file, models.RoleEditor, &past)
+				return user, file
+			},
+			want: "",
+		},
+		{
+			name: "group grant",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				db := database.GetDB()
+				owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+				file := createTestFile(t, owner, nil, "a.txt", "a")
+				group := &models.Group{Name: "Team", CreatedByID: owner.ID}
+				if err := db.Create(group).Error; err != nil {
+					t.Fatalf("create group: %v", err)
+				}
+				if err := db.Create(&models.GroupMember{GroupID: group.ID, UserID: user.ID, Role: models.GroupRoleMember}).Error; err != nil {
+					t.Fatalf("create group member: %v", err)
+				}
+				grant := &models.Collaborator{FileID: &file.ID, GroupID: &group.ID, Role: models.RoleViewer}
+				if err := db.Create(grant).Error; err != nil {
+					t.Fatalf("create grant: %v", err)
+				}
+				return user, file
+			},
+			want: models.RoleViewer,
+		},
+		{
+			name: "shared drive role",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				member, adder := createTestUser(t, "member"), createTestUser(t, "adder")
+				drive := createTestDrive(t, member, models.DriveRoleEditor)
+				file := &models.File{UserID: adder.ID, DriveID: &drive.ID, FolderID: &drive.RootFolderID,
+					OriginalName: "a.txt", FileName: "a.txt", FileSize: 1}
+				if err := database.GetDB().Create(file).Error; err != nil {
+					t.Fatalf("create file: %v", err)
+				}
+				return member, file
+			},
+			want: models.RoleEditor,
+		},
+		{
+			name: "stranger",
+			setup: func(t *testing.T) (*models.User, *models.File) {
+				owner, stranger := createTestUser(t, "owner"), createTestUser(t, "stranger")
+				return stranger, createTestFile(t, owner, nil, "a.txt", "a")
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, file := tt.setup(t)
+			role, err := as.FileRole(user.ID, file)
+			if err != nil {
+				t.Fatalf("FileRole: %v", err)
+			}
+			if role != tt.want {
+				t.Errorf("role = %q, want %q", role, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileRoleCacheInvalidation(t *testing.T) {
+	as := NewAuthorizationService()
+	owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+	file := createTestFile(t, owner, nil, "a.txt", "a")
+	grant := grantFile(t, user, file, models.RoleViewer, nil)
+
+	if role, err := as.FileRole(user.ID, file); err != nil || role != models.RoleViewer {
+		t.Fatalf("FileRole = %q, %v, want %q", role, err, models.RoleViewer)
+	}
+
+	// Revoking the grant behind the service's back leaves the cached role in place
+	if err := database.GetDB().Delete(grant).Error; err != nil {
+		t.Fatalf("delete grant: %v", err)
+	}
+	if role, _ := as.FileRole(user.ID, file); role != models.RoleViewer {
+		t.Fatalf("cached role = %q, want %q", role, models.RoleViewer)
+	}
+
+	InvalidatePermissions()
+	role, err := as.FileRole(user.ID, file)
+	if err != nil {
+		t.Fatalf("FileRole: %v", err)
+	}
+	if role != "" {
+		t.Errorf("role after invalidation = %q, want none", role)
+	}
+}
+
+func TestGrantedFiles(t *testing.T) {
+	as := NewAuthorizationService()
+	db := database.GetDB()
+	owner, user := createTestUser(t, "owner"), createTestUser(t, "user")
+	top := createTestFolder(t, owner, nil, "top")
+	bottom := createTestFolder(t, owner, top, "bottom")
+	inherited := createTestFile(t, owner, bottom, "inherited.txt", "a")
+	direct := createTestFile(t, owner, nil, "direct.txt", "a")
+	expired := createTestFile(t, owner, nil, "expired.txt", "a")
+	createTestFile(t, owner, nil, "private.txt", "a")
+
+	past := time.Now().Add(-time.Hour)
+	if err := db.Create(&models.Collaborator{FolderID: &top.ID, UserID: &user.ID, Role: models.RoleViewer}).Error; err != nil {
+		t.Fatalf("create grant: %v", err)
+	}
+	grantFile(t, user, direct, models.RoleViewer, nil)
+	grantFile(t, user, expired, models.RoleViewer, &past)
+
+	folderIDs, err := as.GrantedFolderIDs(user.ID)
+	if err != nil {
+		t.Fatalf("GrantedFolderIDs: %v", err)
+	}
+	if len(folderIDs) != 2 {
+		t.Errorf("granted folders = %v, want %s and %s", folderIDs, top.ID, bottom.ID)
+	}
+
+	granted, err := as.GrantedFiles(user.ID)
+	if err != nil {
+		t.Fatalf("GrantedFiles: %v", err)
+	}
+	var names []string
+	if err := db.Model(&models.File{}).Where("id IN (?)", granted).Order("original_name").Pluck("original_name", &names).Error; err != nil {
+		t.Fatalf("load granted files: %v", err)
+	}
+	if strings.Join(names, ",") != direct.OriginalName+","+inherited.OriginalName {
+		t.Errorf("granted files = %v, want %s and %s", names, direct.OriginalName, inherited.OriginalName)
+	}
+}
@@ -2,53 +2,90 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
 	"github.com/manjurulhoque/swift-share/backend/database"
 	"github.com/manjurulhoque/swift-share/backend/models"
 	"gorm.io/gorm"
 )
 
+// ErrCollaboratorNotRegistered is returned when a collaborator is added by an email address that
+// has no account, the caller invites the address instead
+var ErrCollaboratorNotRegistered = errors.New("no user is registered with this email")
+
 type CollaboratorService struct {
-	db *gorm.DB
+	db                  *gorm.DB
+	notificationService *NotificationService
 }
 
 func NewCollaboratorService() *CollaboratorService {
 	return &CollaboratorService{
-		db: database.GetDB(),
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
 	}
 }
 
 // AddCollaborator adds a collaborator to a file or folder
 func (cs *CollaboratorService) AddCollaborator(ownerID, resourceID uuid.UUID, req models.AddCollaboratorRequest, isFile bool) (*models.Collaborator, error) {
 	// Verify ownership
+	var itemName string
 	if isFile {
 		var file models.File
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&file).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
 			return nil, errors.New("file not found or access denied")
 		}
+		itemName = file.OriginalName
 	} else {
 		var folder models.Folder
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&folder).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
 			return nil, errors.New("folder not found or access denied")
 		}
+		itemName = folder.Name
 	}
 
-	// Check if user exists
-	var user models.User
-	if err := cs.db.Where("id = ?", req.UserID).First(&user).Error; err != nil {
-		return nil, errors.New("user not found")
+	// Collaborators are either a user other than the owner or a group the owner belongs to, users
+	// can also be given by email address
+	principals := 0
+	for _, set := range []bool{req.UserID != nil, req.GroupID != nil, req.Email != ""} {
+		if set {
+			principals++
+		}
 	}
-
-	// Check if user is trying to add themselves
-	if req.UserID == ownerID {
-		return nil, errors.New("cannot add yourself as a collaborator")
+	if principals != 1 {
+		return nil, errors.New("specify either a user, a group or an email address")
+	}
+	if req.Email != "" {
+		var user models.User
+		if err := cs.db.Where("LOWER(email) = ?", strings.ToLower(req.Email)).First(&user).Error; err != nil {
+			return nil, ErrCollaboratorNotRegistered
+		}
+		req.UserID = &user.ID
+	}
+	var principal *gorm.DB
+	if req.UserID != nil {
+		var user models.User
+		if err := cs.db.Where("id = ?", *req.UserID).First(&user).Error; err != nil {
+			return nil, errors.New("user not found")
+		}
+		if *req.UserID == ownerID {
+			return nil, errors.New("cannot add yourself as a collaborator")
+		}
+		principal = cs.db.Where("user_id = ?", *req.UserID)
+	} else {
+		var membership models.GroupMember
+		if err := cs.db.Where("group_id = ? AND user_id = ?", *req.GroupID, ownerID).First(&membership).Error; err != nil {
+			return nil, errors.New("group not found")
+		}
+		principal = cs.db.Where("group_id = ?", *req.GroupID)
 	}
 
 	// Check if collaborator already exists
 	var existingCollaborator models.Collaborator
-	query := cs.db.Where("user_id = ?", req.UserID)
+	query := principal
 	if isFile {
 		query = query.Where("file_id = ?", resourceID)
 	} else {
@@ -62,6 +99,7 @@ func (cs *CollaboratorService) AddCollaborator(ownerID, resourceID uuid.UUID, re
 		if err := cs.db.Save(&existingCollaborator).Error; err != nil {
 			return nil, err
 		}
+		InvalidatePermissions()
 		cs.loadCollaboratorRelations(&existingCollaborator)
 		return &existingCollaborator, nil
 	}
@@ -69,6 +107,7 @@ func (cs *CollaboratorService) AddCollaborator(ownerID, resourceID uuid.UUID, re
 	// Create new collaborator
 	collaborator := &models.Collaborator{
 		UserID:    req.UserID,
+		GroupID:   req.GroupID,
 		Role:      req.Role,
 		ExpiresAt: req.ExpiresAt,
 	}
@@ -82,28 +121,60 @@ func (cs *CollaboratorService) AddCollaborator(ownerID, resourceID uuid.UUID, re
 	if err := cs.db.Create(collaborator).Error; err != nil {
 		return nil, err
 	}
+	InvalidatePermissions()
+
+	cs.notifyAdded(ownerID, collaborator, itemName, isFile, resourceID)
 
 	cs.loadCollaboratorRelations(collaborator)
 	return collaborator, nil
 }
 
+// notifyAdded tells a new collaborator, or every other member of a new group collaborator, that
+// the item was shared with them
+func (cs *CollaboratorService) notifyAdded(ownerID uuid.UUID, collaborator *models.Collaborator, itemName string, isFile bool, resourceID uuid.UUID) {
+	var recipients []uuid.UUID
+	if collaborator.UserID != nil {
+		recipients = []uuid.UUID{*collaborator.UserID}
+	} else if err := cs.db.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id <> ?", *collaborator.GroupID, ownerID).
+		Pluck("user_id", &recipients).Error; err != nil {
+		config.GetLogger().Error("Failed to load group members to notify", "group_id", *collaborator.GroupID, "error", err)
+		return
+	}
+
+	var owner models.User
+	cs.db.Where("id = ?", ownerID).First(&owner)
+	resource := models.ResourceFolder
+	if isFile {
+		resource = models.ResourceFile
+	}
+	message := fmt.Sprintf("%s %s shared %q with you as %s", owner.FirstName, owner.LastName, itemName, collaborator.Role)
+
+	for _, userID := range recipients {
+		if _, err := cs.notificationService.Notify(userID, models.NotificationCollaboratorAdded,
+			"Shared with you", message, resource, &resourceID); err != nil {
+			config.GetLogger().Error("Failed to create collaborator notification", "user_id", userID, "error", err)
+		}
+	}
+}
+
 // GetCollaborators returns all collaborators for a file or folder
 func (cs *CollaboratorService) GetCollaborators(ownerID, resourceID uuid.UUID, isFile bool) ([]models.Collaborator, error) {
 	// Verify ownership
 	if isFile {
 		var file models.File
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&file).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
 			return nil, errors.New("file not found or access denied")
 		}
 	} else {
 		var folder models.Folder
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&folder).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
 			return nil, errors.New("folder not found or access denied")
 		}
 	}
 
 	var collaborators []models.Collaborator
-	query := cs.db.Preload("User")
+	query := cs.db.Preload("User").Preload("Group")
 	if isFile {
 		query = query.Where("file_id = ?", resourceID)
 	} else {
@@ -122,18 +193,18 @@ func (cs *CollaboratorService) UpdateCollaborator(ownerID, resourceID, collabora
 	// Verify ownership
 	if isFile {
 		var file models.File
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&file).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
 			return nil, errors.New("file not found or access denied")
 		}
 	} else {
 		var folder models.Folder
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&folder).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
 			return nil, errors.New("folder not found or access denied")
 		}
 	}
 
 	var collaborator models.Collaborator
-	query := cs.db.Where("id = ? AND user_id = ?", collaboratorID, collaboratorID)
+	query := cs.db.Where("id = ?", collaboratorID)
 	if isFile {
 		query = query.Where("file_id = ?", resourceID)
 	} else {
@@ -155,6 +226,7 @@ func (cs *CollaboratorService) UpdateCollaborator(ownerID, resourceID, collabora
 	if err := cs.db.Save(&collaborator).Error; err != nil {
 		return nil, err
 	}
+	InvalidatePermissions()
 
 	cs.loadCollaboratorRelations(&collaborator)
 	return &collaborator, nil
@@ -165,17 +237,17 @@ func (cs *CollaboratorService) RemoveCollaborator(ownerID, resourceID, collabora
 	// Verify ownership
 	if isFile {
 		var file models.File
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&file).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
 			return errors.New("file not found or access denied")
 		}
 	} else {
 		var folder models.Folder
-		if err := cs.db.Where("id = ? AND user_id = ? AND is_trashed = false", resourceID, ownerID).First(&folder).Error; err != nil {
+		if err := cs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
 			return errors.New("folder not found or access denied")
 		}
 	}
 
-	query := cs.db.Where("id = ? AND user_id = ?", collaboratorID, collaboratorID)
+	query := cs.db.Where("id = ?", collaboratorID)
 	if isFile {
 		query = query.Where("file_id = ?", resourceID)
 	} else {
@@ -186,40 +258,19 @@ func (cs *CollaboratorService) RemoveCollaborator(ownerID, resourceID, collabora
 		return err
 	}
 
+	InvalidatePermissions()
 	return nil
 }
 
-// CheckCollaboratorAccess checks if a user has access to a file or folder as a collaborator
-func (cs *CollaboratorService) CheckCollaboratorAccess(userID, resourceID uuid.UUID, isFile bool) (*models.Collaborator, error) {
-	var collaborator models.Collaborator
-	query := cs.db.Where("user_id = ?", userID)
-	if isFile {
-		query = query.Where("file_id = ?", resourceID)
-	} else {
-		query = query.Where("folder_id = ?", resourceID)
-	}
-
-	if err := query.First(&collaborator).Error; err != nil {
-		return nil, errors.New("access denied")
-	}
-
-	// Check if expired
-	if collaborator.IsExpired() {
-		return nil, errors.New("collaborator access has expired")
-	}
-
-	cs.loadCollaboratorRelations(&collaborator)
-	return &collaborator, nil
-}
-
-// GetUserCollaborations returns all files/folders where the user is a collaborator
+// GetUserCollaborations returns all files/folders where the user is a collaborator, directly or
+// through one of their groups
 func (cs *CollaboratorService) GetUserCollaborations(userID uuid.UUID, isFile bool) ([]models.Collaborator, error) {
 	var collaborators []models.Collaborator
-	query := cs.db.Preload("User")
+	query := cs.db.Preload("User").Preload("Group").Where("user_id = ? OR group_id IN (?)", userID, groupsOf(cs.db, userID))
 	if isFile {
-		query = query.Preload("File").Where("user_id = ? AND file_id IS NOT NULL", userID)
+		query = query.Preload("File").Where("file_id IS NOT NULL")
 	} else {
-		query = query.Preload("Folder").Where("user_id = ? AND folder_id IS NOT NULL", userID)
+		query = query.Preload("Folder").Where("folder_id IS NOT NULL")
 	}
 
 	if err := query.Find(&collaborators).Error; err != nil {
@@ -236,6 +287,6 @@ func (cs *CollaboratorService) CleanupExpiredCollaborators() error {
 
 // Helper function to load relationships
 func (cs *CollaboratorService) loadCollaboratorRelations(collaborator *models.Collaborator) {
-	cs.db.Preload("User").Preload("File").Preload("Folder").
+	cs.db.Preload("User").Preload("Group").Preload("File").Preload("Folder").
 		Where("id = ?", collaborator.ID).First(collaborator)
 }
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCommentLength   = 10000
+	maxCommentMentions = 20
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("you do not have permission to do this")
+	ErrCommentTarget    = errors.New("file or folder not found")
+)
+
+// mentionPattern matches @mentions written as an email address, e.g. "@jane@example.com"
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([\w.%+\-]+@[\w\-]+(?:\.[\w\-]+)+)`)
+
+// CommentActor is who reads or writes comments: a signed-in user, or a visitor of a share link
+// whose scope the caller has already checked
+type CommentActor struct {
+	UserID    *uuid.UUID
+	Email     string // verified address of share link visitors, if any
+	ShareLink *models.ShareLink
+}
+
+// CommentTarget is the file or folder comments are attached to
+type CommentTarget struct {
+	FileID   *uuid.UUID
+	FolderID *uuid.UUID
+	OwnerID  uuid.UUID
+	Name     string
+
+	file   *models.File
+	folder *models.Folder
+}
+
+// commentAccess is what an actor may do with the comments of a target
+type commentAccess struct {
+	read     bool // list comments
+	write    bool // start threads and reply
+	moderate bool // resolve any thread
+	owner    bool // also delete any comment
+}
+
+type CommentService struct {
+	db                   *gorm.DB
+	notificationService  *NotificationService
+	authorizationService *AuthorizationService
+}
+
+func NewCommentService() *CommentService {
+	return &CommentService{
+		db:                   database.GetDB(),
+		notificationService:  NewNotificationService(),
+		authorizationService: NewAuthorizationService(),
+	}
+}
+
+// FileTarget returns the comment target of a file outside the trash
+func (cs *CommentService) FileTarget(fileID uuid.UUID) (*CommentTarget, error) {
+	var file models.File
+	if err := cs.db.Where("id = ? AND is_trashed = ?", fileID, false).First(&file).Error; err != nil {
+		return nil, ErrCommentTarget
+	}
+	return &CommentTarget{FileID: &file.ID, OwnerID: file.UserID, Name: file.OriginalName, file: &file}, nil
+}
+
+// FolderTarget returns the comment target of a folder outside the trash
+func (cs *CommentService) FolderTarget(folderID uuid.UUID) (*CommentTarget, error) {
+	var folder models.Folder
+	if err := cs.db.Where("id = ? AND is_trashed = ?", folderID, false).First(&folder).Error; err != nil {
+		return nil, ErrCommentTarget
+	}
+	return &CommentTarget{FolderID: &folder.ID, OwnerID: folder.UserID, Name: folder.Name, folder: &folder}, nil
+}
+
+// ListComments returns the target's threads, oldest first, each with its replies
+func (cs *CommentService) ListComments(actor CommentActor, target *CommentTarget, includeResolved bool, page, limit int) ([]models.Comment, int64, error) {
+	if !cs.access(actor, target).read {
+		return nil, 0, ErrCommentForbidden
+	}
+
+	threads := func() *gorm.DB {
+		query := cs.targetQuery(target).Where("parent_id IS NULL")
+		if !includeResolved {
+			query = query.Where("resolved_at IS NULL")
+		}
+		return query
+	}
+
+	var total int64
+	if err := threads().Model(&models.Comment{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []models.Comment
+	offset := (page - 1) * limit
+	if err := cs.preloadComment(threads()).
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Replies.User").Preload("Replies.Mentions.User").
+		Order("created_at ASC").Offset(offset).Limit(limit).
+		Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// CreateComment starts a thread on the target, or replies to one when ParentID is set. Mentioned
+// users and the people following the thread are notified.
+func (cs *CommentService) CreateComment(actor CommentActor, target *CommentTarget, req models.CommentCreateRequest) (*models.Comment, error) {
+	if !cs.access(actor, target).write {
+		return nil, ErrCommentForbidden
+	}
+
+	body, err := normalizeCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		FileID:   target.FileID,
+		FolderID: target.FolderID,
+		UserID:   actor.UserID,
+		Body:     body,
+	}
+
+	if actor.UserID == nil {
+		comment.ShareLinkID = &actor.ShareLink.ID
+		comment.AuthorEmail = actor.Email
+		comment.AuthorName = strings.Join(strings.Fields(req.AuthorName), " ")
+		if comment.AuthorName == "" {
+			comment.AuthorName = actor.Email
+		}
+		if len(comment.AuthorName) > 100 {
+			return nil, errors.New("author name must be at most 100 characters")
+		}
+	}
+
+	var parent *models.Comment
+	if req.ParentID != nil {
+		parent = &models.Comment{}
+		if err := cs.targetQuery(target).Where("id = ? AND parent_id IS NULL", *req.ParentID).
+			First(parent).Error; err != nil {
+			return nil, errors.New("thread not found")
+		}
+		if req.Anchor != nil && !req.Anchor.IsEmpty() {
+			return nil, errors.New("replies cannot have an anchor")
+		}
+		comment.ParentID = &parent.ID
+	} else if req.Anchor != nil && !req.Anchor.IsEmpty() {
+		if target.FileID == nil {
+			return nil, errors.New("anchors are only supported on file comments")
+		}
+		if err := validateCommentAnchor(*req.Anchor); err != nil {
+			return nil, err
+		}
+		comment.Anchor = *req.Anchor
+	}
+
+	mentioned, err := cs.mentionedUsers(target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return cs.saveMentions(tx, comment.ID, mentioned)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.preloadComment(cs.db).First(comment, "id = ?", comment.ID).Error; err != nil {
+		return nil, err
+	}
+
+	cs.notifyComment(actor, target, comment, parent, mentioned)
+	return comment, nil
+}
+
+// UpdateComment changes the text of the user's own comment. Users mentioned for the first time
+// are notified.
+func (cs *CommentService) UpdateComment(userID, commentID uuid.UUID, req models.CommentUpdateRequest) (*models.Comment, error) {
+	comment, target, err := cs.loadComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	actor := CommentActor{UserID: &userID}
+	if !comment.IsAuthor(userID) || !cs.access(actor, target).read {
+		return nil, ErrCommentForbidden
+	}
+
+	body, err := normalizeCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mentioned, err := cs.mentionedUsers(target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	alreadyMentioned := make(map[uuid.UUID]bool, len(comment.Mentions))
+	for _, mention := range comment.Mentions {
+		alreadyMentioned[mention.UserID] = true
+	}
+	var newlyMentioned []models.User
+	for _, user := range mentioned {
+		if !alreadyMentioned[user.ID] {
+			newlyMentioned = append(newlyMentioned, user)
+		}
+	}
+
+	now := time.Now()
+	err = cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(comment).Updates(map[string]interface{}{"body": body, "edited_at": now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return cs.saveMentions(tx, comment.ID, mentioned)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.preloadComment(cs.db).First(comment, "id = ?", comment.ID).Error; err != nil {
+		return nil, err
+	}
+
+	cs.notifyComment(actor, target, comment, nil, newlyMentioned)
+	return comment, nil
+}
+
+// DeleteComment deletes a comment written by the user, or any comment on an item the user owns.
+// Deleting the first comment of a thread deletes the whole thread.
+func (cs *CommentService) DeleteComment(userID, commentID uuid.UUID) error {
+	comment, target, err := cs.loadComment(commentID)
+	if err != nil {
+		return err
+	}
+	if !comment.IsAuthor(userID) && !cs.access(CommentActor{UserID: &userID}, target).owner {
+		return ErrCommentForbidden
+	}
+
+	return cs.db.Transaction(func(tx *gorm.DB) error {
+		if comment.ParentID == nil {
+			if err := tx.Where("parent_id = ?", comment.ID).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(comment).Error
+	})
+}
+
+// SetResolved resolves or reopens a thread. The owner, editors and the user who started the
+// thread may do so.
+func (cs *CommentService) SetResolved(userID, commentID uuid.UUID, resolved bool) (*models.Comment, error) {
+	comment, target, err := cs.loadComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		return nil, errors.New("only threads can be resolved, not replies")
+	}
+	access := cs.access(CommentActor{UserID: &userID}, target)
+	if !access.moderate && !(comment.IsAuthor(userID) && access.read) {
+		return nil, ErrCommentForbidden
+	}
+
+	updates := map[string]interface{}{"resolved_at": nil, "resolved_by": nil}
+	if resolved {
+		updates = map[string]interface{}{"resolved_at": time.Now(), "resolved_by": userID}
+	}
+	if err := cs.db.Model(comment).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if err := cs.preloadComment(cs.db).
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Replies.User").Preload("Replies.Mentions.User").
+		First(comment, "id = ?", comment.ID).Error; err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// access works out what the actor may do. Share link visitors get the link's permission, users
+// get their effective role on the item, which includes roles inherited from parent folders.
+func (cs *CommentService) access(actor CommentActor, target *CommentTarget) commentAccess {
+	if actor.UserID == nil {
+		if actor.ShareLink == nil {
+			return commentAccess{}
+		}
+		switch actor.ShareLink.Permission {
+		case models.PermissionComment, models.PermissionEdit:
+			return commentAccess{read: true, write: true}
+		}
+		return commentAccess{}
+	}
+
+	var role models.CollaboratorRole
+	var err error
+	if target.file != nil {
+		role, err = cs.authorizationService.FileRole(*actor.UserID, target.file)
+	} else {
+		role, err = cs.authorizationService.FolderRole(*actor.UserID, target.folder)
+	}
+	if err != nil {
+		return commentAccess{}
+	}
+
+	switch role {
+	case models.RoleOwner:
+		return commentAccess{read: true, write: true, moderate: true, owner: true}
+	case models.RoleEditor:
+		return commentAccess{read: true, write: true, moderate: true}
+	case models.RoleCommenter:
+		return commentAccess{read: true, write: true}
+	case models.RoleViewer:
+		return commentAccess{read: true}
+	}
+	return commentAccess{}
+}
+
+// mentionedUsers returns the users @mentioned in the body that can see the target. Other
+// addresses are left as plain text.
+func (cs *CommentService) mentionedUsers(target *CommentTarget, body string) ([]models.User, error) {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := utils.NormalizeEmail(strings.TrimRight(match[1], "."))
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	if len(emails) > maxCommentMentions {
+		return nil, fmt.Errorf("a comment can mention at most %d people", maxCommentMentions)
+	}
+
+	var users []models.User
+	if err := cs.db.Where("email IN ? AND is_active = ?", emails, true).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	mentioned := make([]models.User, 0, len(users))
+	for _, user := range users {
+		if cs.access(CommentActor{UserID: &user.ID}, target).read {
+			mentioned = append(mentioned, user)
+		}
+	}
+	return mentioned, nil
+}
+
+func (cs *CommentService) saveMentions(tx *gorm.DB, commentID uuid.UUID, users []models.User) error {
+	for _, user := range users {
+		if err := tx.Create(&models.CommentMention{CommentID: commentID, UserID: user.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyComment tells mentioned users, the author of the thread being replied to and the item's
+// owner about a comment. Nobody is notified twice or about their own comment.
+func (cs *CommentService) notifyComment(actor CommentActor, target *CommentTarget, comment *models.Comment, parent *models.Comment, mentioned []models.User) {
+	authorName := comment.ToResponse().Author.Name
+	resource, resourceID := models.ResourceFolder, target.FolderID
+	if target.FileID != nil {
+		resource, resourceID = models.ResourceFile, target.FileID
+	}
+
+	notified := make(map[uuid.UUID]bool)
+	if actor.UserID != nil {
+		notified[*actor.UserID] = true
+	}
+	notify := func(userID uuid.UUID, notificationType, title, message string) {
+		if notified[userID] {
+			return
+		}
+		notified[userID] = true
+		if _, err := cs.notificationService.Notify(userID, notificationType, title, message, resource, resourceID); err != nil {
+			config.GetLogger().Error("Failed to send comment notification", "error", err, "comment_id", comment.ID)
+		}
+	}
+
+	for _, user := range mentioned {
+		notify(user.ID, models.NotificationCommentMention, "You were mentioned",
+			fmt.Sprintf("%s mentioned you in a comment on %s", authorName, target.Name))
+	}
+	if parent != nil && parent.UserID != nil {
+		notify(*parent.UserID, models.NotificationCommentReply, "New reply",
+			fmt.Sprintf("%s replied to your comment on %s", authorName, target.Name))
+	}
+	if comment.EditedAt == nil {
+		notify(target.OwnerID, models.NotificationComment, "New comment",
+			fmt.Sprintf("%s commented on %s", authorName, target.Name))
+	}
+}
+
+// loadComment returns a comment with its mentions and the item it belongs to
+func (cs *CommentService) loadComment(commentID uuid.UUID) (*models.Comment, *CommentTarget, error) {
+	var comment models.Comment
+	if err := cs.preloadComment(cs.db).Where("id = ?", commentID).First(&comment).Error; err != nil {
+		return nil, nil, ErrCommentNotFound
+	}
+
+	var target *CommentTarget
+	var err error
+	if comment.FileID != nil {
+		target, err = cs.FileTarget(*comment.FileID)
+	} else {
+		target, err = cs.FolderTarget(*comment.FolderID)
+	}
+	if err != nil {
+		return nil, nil, ErrCommentNotFound
+	}
+	return &comment, target, nil
+}
+
+func (cs *CommentService) targetQuery(target *CommentTarget) *gorm.DB {
+	if target.FileID != nil {
+		return cs.db.Where("file_id = ?", *target.FileID)
+	}
+	return cs.db.Where("folder_id = ?", *target.FolderID)
+}
+
+func (cs *CommentService) preloadComment(query *gorm.DB) *gorm.DB {
+	return query.Preload("User").Preload("Mentions.User")
+}
+
+// normalizeCommentBody trims the comment text and checks its length
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment cannot be empty")
+	}
+	if len([]rune(body)) > maxCommentLength {
+		return "", fmt.Errorf("comment must be at most %d characters", maxCommentLength)
+	}
+	return body, nil
+}
+
+// validateCommentAnchor checks the page number and that a region lies within the page
+func validateCommentAnchor(anchor models.CommentAnchor) error {
+	if anchor.Page != nil && *anchor.Page < 1 {
+		return errors.New("anchor page must be at least 1")
+	}
+
+	region := []*float64{anchor.X, anchor.Y, anchor.Width, anchor.Height}
+	set := 0
+	for _, value := range region {
+		if value != nil {
+			set++
+		}
+	}
+	if set == 0 {
+		return nil
+	}
+	if set != len(region) {
+		return errors.New("an anchor region needs x, y, width and height")
+	}
+
+	// Allow for rounding in coordinates computed by clients
+	const epsilon = 1e-9
+	x, y, width, height := *anchor.X, *anchor.Y, *anchor.Width, *anchor.Height
+	if x < 0 || y < 0 || width <= 0 || height <= 0 || x+width > 1+epsilon || y+height > 1+epsilon {
+		return errors.New("an anchor region must lie within the page, with coordinates between 0 and 1")
+	}
+	return nil
+}
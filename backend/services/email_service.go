@@ -0,0 +1,431 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var ErrEmailEventUnknown = errors.New("notification type cannot be emailed")
+
+// errDigestClaimed rolls back a digest whose notifications another worker is already sending
+var errDigestClaimed = errors.New("digest notifications claimed by another worker")
+
+//go:embed email_templates
+var emailTemplateFS embed.FS
+
+var (
+	emailHTMLTemplates = htmltemplate.Must(htmltemplate.ParseFS(emailTemplateFS, "email_templates/*.html"))
+	emailTextTemplates = texttemplate.Must(texttemplate.ParseFS(emailTemplateFS, "email_templates/*.txt"))
+)
+
+// digestMaxItems caps how many notifications a single digest lists, the rest go in the next one
+const digestMaxItems = 100
+
+// EmailService emails notifications to users. Each notification type in EmailEventDefaults is
+// sent right away, batched into a periodic digest or not emailed at all, as the user prefers.
+// Emails go through an outbox table: they are tried once when queued and then retried by
+// RunWorker with a growing delay until they are sent or run out of attempts.
+type EmailService struct {
+	db          *gorm.DB
+	mailService *MailService
+	cfg         config.NotificationConfig
+}
+
+func NewEmailService() *EmailService {
+	return &EmailService{
+		db:          database.GetDB(),
+		mailService: NewMailService(),
+		cfg:         config.AppConfig.Notification,
+	}
+}
+
+type notificationEmailData struct {
+	Name    string
+	Title   string
+	Message string
+	Link    string
+	AppName string
+}
+
+type digestEmailData struct {
+	Name    string
+	Items   []models.Notification
+	Link    string
+	AppName string
+}
+
+// QueueNotification emails a notification that was just created, or marks it for the user's next
+// digest. Notifications are kept in the app either way, so nothing is queued when mail is not
+// configured.
+func (es *EmailService) QueueNotification(notification *models.Notification) error {
+	if _, ok := models.EmailEventDefaults[notification.Type]; !ok || !es.mailService.Enabled() {
+		return nil
+	}
+
+	var user models.User
+	if err := es.db.Where("id = ?", notification.UserID).First(&user).Error; err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	switch es.delivery(user.ID, notification.Type) {
+	case models.EmailDigest:
+		return es.db.Model(notification).UpdateColumn("email_digest_pending", true).Error
+	case models.EmailInstant:
+		data := notificationEmailData{
+			Name:    user.FirstName,
+			Title:   notification.Title,
+			Message: notification.Message,
+			Link:    es.appLink(),
+			AppName: config.AppConfig.Email.FromName,
+		}
+		textBody, htmlBody, err := renderEmail("notification", data)
+		if err != nil {
+			return err
+		}
+		message, err := es.enqueue(&user, notification.Type, notification.Title, textBody, htmlBody)
+		if err != nil {
+			return err
+		}
+		go es.deliver(message.ID)
+	}
+	return nil
+}
+
+// GetPreferences returns the delivery in effect for every notification type that can be emailed
+func (es *EmailService) GetPreferences(userID uuid.UUID) ([]models.EmailPreferenceResponse, error) {
+	var prefs []models.EmailPreference
+	if err := es.db.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	chosen := make(map[string]models.EmailDelivery, len(prefs))
+	for _, pref := range prefs {
+		chosen[pref.EventType] = pref.Delivery
+	}
+
+	responses := make([]models.EmailPreferenceResponse, 0, len(models.EmailEventDefaults))
+	for eventType, delivery := range models.EmailEventDefaults {
+		response := models.EmailPreferenceResponse{EventType: eventType, Delivery: delivery, IsDefault: true}
+		if pref, ok := chosen[eventType]; ok {
+			response.Delivery = pref
+			response.IsDefault = false
+		}
+		responses = append(responses, response)
+	}
+	sort.Slice(responses, func(i, j int) bool { return responses[i].EventType < responses[j].EventType })
+	return responses, nil
+}
+
+// UpdatePreferences sets how the user is emailed about the given notification types. Turning a
+// type off drops what was waiting for the next digest.
+func (es *EmailService) UpdatePreferences(userID uuid.UUID, req models.EmailPreferencesRequest) ([]models.EmailPreferenceResponse, error) {
+	for _, update := range req.Preferences {
+		if _, ok := models.EmailEventDefaults[update.EventType]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrEmailEventUnknown, update.EventType)
+		}
+	}
+
+	err := es.db.Transaction(func(tx *gorm.DB) error {
+		for _, update := range req.Preferences {
+			var pref models.EmailPreference
+			err := tx.Where("user_id = ? AND event_type = ?", userID, update.EventType).First(&pref).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				pref = models.EmailPreference{UserID: userID, EventType: update.EventType, Delivery: update.Delivery}
+				if err := tx.Create(&pref).Error; err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			default:
+				if err := tx.Model(&pref).Update("delivery", update.Delivery).Error; err != nil {
+					return err
+				}
+			}
+
+			if update.Delivery == models.EmailOff {
+				if err := tx.Model(&models.Notification{}).
+					Where("user_id = ? AND type = ? AND email_digest_pending = ?", userID, update.EventType, true).
+					UpdateColumn("email_digest_pending", false).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return es.GetPreferences(userID)
+}
+
+// ListOutbox returns the emails in the outbox, newest first, optionally only those with a status
+func (es *EmailService) ListOutbox(status string, page, limit int) ([]models.EmailMessage, int64, error) {
+	var messages []models.EmailMessage
+	var total int64
+
+	query := es.db.Model(&models.EmailMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// DeliverDue tries every email that is due, returning how many were sent
+func (es *EmailService) DeliverDue() (int, error) {
+	var ids []uuid.UUID
+	if err := es.db.Model(&models.EmailMessage{}).
+		Where("status = ? AND next_attempt_at <= ?", models.EmailPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(100).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, id := range ids {
+		if es.deliver(id) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// SendDigests queues a digest for every user whose oldest pending notification has waited for
+// the digest interval, returning how many were queued
+func (es *EmailService) SendDigests() (int, error) {
+	if !es.mailService.Enabled() {
+		return 0, nil
+	}
+
+	var userIDs []uuid.UUID
+	if err := es.db.Model(&models.Notification{}).
+		Where("email_digest_pending = ?", true).
+		Group("user_id").
+		Having("MIN(created_at) <= ?", time.Now().Add(-es.cfg.DigestInterval)).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, userID := range userIDs {
+		message, err := es.queueDigest(userID)
+		if err != nil {
+			config.GetLogger().Error("Failed to queue email digest", "user_id", userID, "error", err)
+			continue
+		}
+		if message != nil {
+			queued++
+			es.deliver(message.ID)
+		}
+	}
+	return queued, nil
+}
+
+// RunWorker delivers due emails and queues digests every interval until ctx is cancelled
+func (es *EmailService) RunWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := es.DeliverDue(); err != nil {
+			config.GetLogger().Error("Failed to deliver queued emails", "error", err)
+		} else if sent > 0 {
+			config.GetLogger().Info("Delivered queued emails", "count", sent)
+		}
+		if queued, err := es.SendDigests(); err != nil {
+			config.GetLogger().Error("Failed to send email digests", "error", err)
+		} else if queued > 0 {
+			config.GetLogger().Info("Queued email digests", "count", queued)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// queueDigest renders the user's pending notifications into one email and clears them. It returns
+// nil when another worker claimed any of the notifications first, they are then sent by that
+// worker or go in the next digest.
+func (es *EmailService) queueDigest(userID uuid.UUID) (*models.EmailMessage, error) {
+	var user models.User
+	if err := es.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	var items []models.Notification
+	if err := es.db.Where("user_id = ? AND email_digest_pending = ?", userID, true).
+		Order("created_at ASC").Limit(digestMaxItems).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	data := digestEmailData{
+		Name:    user.FirstName,
+		Items:   items,
+		Link:    es.appLink(),
+		AppName: config.AppConfig.Email.FromName,
+	}
+	textBody, htmlBody, err := renderEmail("digest", data)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	subject := fmt.Sprintf("%s: %d new notifications", data.AppName, len(items))
+	if len(items) == 1 {
+		subject = fmt.Sprintf("%s: 1 new notification", data.AppName)
+	}
+
+	var message *models.EmailMessage
+	err = es.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the notifications only while they are still pending, like deliver claims an attempt
+		claim := tx.Model(&models.Notification{}).Where("id IN ? AND email_digest_pending = ?", ids, true).
+			UpdateColumn("email_digest_pending", false)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected != int64(len(ids)) {
+			return errDigestClaimed
+		}
+		message = &models.EmailMessage{
+			UserID:        &user.ID,
+			ToAddress:     user.Email,
+			Subject:       subject,
+			TextBody:      textBody,
+			HTMLBody:      htmlBody,
+			Kind:          "digest",
+			Status:        models.EmailPending,
+			NextAttemptAt: time.Now(),
+		}
+		return tx.Create(message).Error
+	})
+	if errors.Is(err, errDigestClaimed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (es *EmailService) enqueue(user *models.User, kind, subject, textBody, htmlBody string) (*models.EmailMessage, error) {
+	message := &models.EmailMessage{
+		UserID:        &user.ID,
+		ToAddress:     user.Email,
+		Subject:       subject,
+		TextBody:      textBody,
+		HTMLBody:      htmlBody,
+		Kind:          kind,
+		Status:        models.EmailPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := es.db.Create(message).Error; err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// deliver makes one attempt at sending an email and reports whether it was sent. The attempt is
+// claimed by moving the next attempt time forward, so the worker and the send made right after
+// queueing never try the same email at once.
+func (es *EmailService) deliver(id uuid.UUID) bool {
+	var message models.EmailMessage
+	if err := es.db.Where("id = ?", id).First(&message).Error; err != nil {
+		return false
+	}
+	now := time.Now()
+	if message.Status != models.EmailPending || message.NextAttemptAt.After(now) {
+		return false
+	}
+
+	attempts := message.Attempts + 1
+	claim := es.db.Model(&models.EmailMessage{}).
+		Where("id = ? AND status = ? AND attempts = ?", message.ID, models.EmailPending, message.Attempts).
+		Updates(map[string]interface{}{"attempts": attempts, "next_attempt_at": now.Add(retryDelay(attempts))})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false
+	}
+
+	err := es.mailService.SendHTML(message.ToAddress, message.Subject, message.TextBody, message.HTMLBody)
+	if err == nil {
+		es.db.Model(&message).Updates(map[string]interface{}{"status": models.EmailSent, "sent_at": time.Now(), "last_error": ""})
+		return true
+	}
+
+	updates := map[string]interface{}{"last_error": truncate(err.Error(), 1000)}
+	if attempts >= es.cfg.MaxAttempts {
+		updates["status"] = models.EmailFailed
+		config.GetLogger().Error("Giving up on email", "email_id", message.ID, "attempts", attempts, "error", err)
+	} else {
+		config.GetLogger().Warn("Failed to send email, will retry", "email_id", message.ID, "attempts", attempts, "error", err)
+	}
+	es.db.Model(&message).Updates(updates)
+	return false
+}
+
+// delivery returns how the user wants to be emailed about a notification type
+func (es *EmailService) delivery(userID uuid.UUID, eventType string) models.EmailDelivery {
+	var pref models.EmailPreference
+	if err := es.db.Where("user_id = ? AND event_type = ?", userID, eventType).First(&pref).Error; err == nil {
+		return pref.Delivery
+	}
+	return models.EmailEventDefaults[eventType]
+}
+
+func (es *EmailService) appLink() string {
+	return config.AppConfig.Server.AppURL + "/dashboard"
+}
+
+// retryDelay doubles the wait after every failed attempt, from a minute up to an hour
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// renderEmail renders the text and HTML versions of an email template
+func renderEmail(name string, data interface{}) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := emailTextTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	if err := emailHTMLTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return "", "", err
+	}
+	return text.String(), html.String(), nil
+}
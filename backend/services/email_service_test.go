@@ -0,0 +1,183 @@
+package services
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+)
+
+// createTestNotification creates an in-app notification of the type for the user
+func createTestNotification(t *testing.T, user *models.User, notificationType, title string) *models.Notification {
+	t.Helper()
+	notification := &models.Notification{UserID: user.ID, Type: notificationType, Title: title, Message: title}
+	if err := database.GetDB().Create(notification).Error; err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	return notification
+}
+
+// setEmailPreference sets how the user is emailed about a notification type
+func setEmailPreference(t *testing.T, es *EmailService, user *models.User, eventType string, delivery models.EmailDelivery) {
+	t.Helper()
+	req := models.EmailPreferencesRequest{Preferences: []models.EmailPreferenceUpdate{{EventType: eventType, Delivery: delivery}}}
+	if _, err := es.UpdatePreferences(user.ID, req); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+}
+
+// userEmails returns the outbox emails of the user
+func userEmails(t *testing.T, user *models.User) []models.EmailMessage {
+	t.Helper()
+	var messages []models.EmailMessage
+	if err := database.GetDB().Where("user_id = ?", user.ID).Find(&messages).Error; err != nil {
+		t.Fatalf("load emails: %v", err)
+	}
+	return messages
+}
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	return port
+}
+
+func TestEmailNotifications(t *testing.T) {
+	sink, port := startSMTPSink(t)
+	es := NewEmailService()
+	es.mailService = newTestMailService(port)
+
+	t.Run("instant notification is sent right away", func(t *testing.T) {
+		user := createTestUser(t, "instant")
+		sent := sink.count()
+
+		notification := createTestNotification(t, user, models.NotificationComment, "New comment on report.pdf")
+		if err := es.QueueNotification(notification); err != nil {
+			t.Fatalf("QueueNotification: %v", err)
+		}
+		messages := sink.wait(t, sent+1)
+		if !strings.Contains(messages[len(messages)-1], "New comment on report.pdf") {
+			t.Errorf("email does not mention the notification:\n%s", messages[len(messages)-1])
+		}
+
+		// The send runs in the background, the outbox row is updated right after the sink has the email
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			emails := userEmails(t, user)
+			if len(emails) == 1 && emails[0].Status == models.EmailSent {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("outbox = %+v, want one sent email", emails)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("failed send is retried until it runs out of attempts", func(t *testing.T) {
+		failing := NewEmailService()
+		failing.mailService = newTestMailService(closedPort(t))
+		failing.cfg.MaxAttempts = 3
+		user := createTestUser(t, "retry")
+
+		message, err := failing.enqueue(user, models.NotificationComment, "Unreachable", "body", "")
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		for attempt := 1; attempt <= failing.cfg.MaxAttempts; attempt++ {
+			if failing.deliver(message.ID) {
+				t.Fatalf("attempt %d was sent to a closed port", attempt)
+			}
+
+			var stored models.EmailMessage
+			database.GetDB().First(&stored, "id = ?", message.ID)
+			if stored.Attempts != attempt {
+				t.Fatalf("attempts = %d, want %d", stored.Attempts, attempt)
+			}
+			want := models.EmailPending
+			if attempt == failing.cfg.MaxAttempts {
+				want = models.EmailFailed
+			}
+			if stored.Status != want {
+				t.Fatalf("status after attempt %d = %q, want %q", attempt, stored.Status, want)
+			}
+			if stored.LastError == "" {
+				t.Errorf("attempt %d recorded no error", attempt)
+			}
+
+			// Make the retry due now instead of after the backoff
+			database.GetDB().Model(&stored).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second))
+		}
+
+		if failing.deliver(message.ID) {
+			t.Error("failed email was tried again")
+		}
+	})
+
+	t.Run("digest batches pending notifications", func(t *testing.T) {
+		user := createTestUser(t, "digest")
+		setEmailPreference(t, es, user, models.NotificationComment, models.EmailDigest)
+		titles := []string{"First comment", "Second comment"}
+		for _, title := range titles {
+			if err := es.QueueNotification(createTestNotification(t, user, models.NotificationComment, title)); err != nil {
+				t.Fatalf("QueueNotification: %v", err)
+			}
+		}
+		if emails := userEmails(t, user); len(emails) != 0 {
+			t.Fatalf("got %d emails before the digest, want none", len(emails))
+		}
+
+		es.cfg.DigestInterval = 0
+		if _, err := es.SendDigests(); err != nil {
+			t.Fatalf("SendDigests: %v", err)
+		}
+
+		emails := userEmails(t, user)
+		if len(emails) != 1 || emails[0].Kind != "digest" || emails[0].Status != models.EmailSent {
+			t.Fatalf("outbox = %+v, want one sent digest", emails)
+		}
+		for _, title := range titles {
+			if !strings.Contains(emails[0].TextBody, title) {
+				t.Errorf("digest does not list %q:\n%s", title, emails[0].TextBody)
+			}
+		}
+
+		var pending int64
+		database.GetDB().Model(&models.Notification{}).
+			Where("user_id = ? AND email_digest_pending = ?", user.ID, true).Count(&pending)
+		if pending != 0 {
+			t.Errorf("%d notifications still pending after the digest", pending)
+		}
+	})
+
+	t.Run("preference off sends nothing", func(t *testing.T) {
+		user := createTestUser(t, "off")
+		setEmailPreference(t, es, user, models.NotificationComment, models.EmailOff)
+
+		notification := createTestNotification(t, user, models.NotificationComment, "Muted comment")
+		if err := es.QueueNotification(notification); err != nil {
+			t.Fatalf("QueueNotification: %v", err)
+		}
+		if _, err := es.SendDigests(); err != nil {
+			t.Fatalf("SendDigests: %v", err)
+		}
+
+		if emails := userEmails(t, user); len(emails) != 0 {
+			t.Errorf("got %d emails, want none", len(emails))
+		}
+		var stored models.Notification
+		database.GetDB().First(&stored, "id = ?", notification.ID)
+		if stored.EmailDigestPending {
+			t.Error("muted notification was marked for the digest")
+		}
+	})
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// extractionProgressInterval is how many entries are processed between job counter updates
+const extractionProgressInterval = 50
+
+var ErrExtractionNotFound = errors.New("extraction job not found")
+
+type ExtractionService struct {
+	db             *gorm.DB
+	storage        storage.StorageService
+	archiveService *ArchiveService
+	auditService   *AuditService
+	quotaService   *QuotaService
+}
+
+func NewExtractionService() *ExtractionService {
+	return &ExtractionService{
+		db:             database.GetDB(),
+		storage:        storage.GetStorage(),
+		archiveService: NewArchiveService(),
+		auditService:   NewAuditService(),
+		quotaService:   NewQuotaService(),
+	}
+}
+
+// StartExtraction creates an extraction job for the archive and runs it in the background
+func (es *ExtractionService) StartExtraction(userID uuid.UUID, file *models.File, req models.ArchiveExtractRequest) (*models.ArchiveExtraction, error) {
+	if ArchiveFormat(file.OriginalName) == "" {
+		return nil, ErrArchiveUnsupported
+	}
+
+	onConflict := req.OnConflict
+	if onConflict == "" {
+		onConflict = models.ConflictRename
+	}
+
+	job := &models.ArchiveExtraction{
+		UserID:         userID,
+		FileID:         file.ID,
+		TargetFolderID: req.FolderID,
+		OnConflict:     onConflict,
+		Status:         models.ExtractionStatusPending,
+	}
+	if err := es.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	archive := *file
+	go es.run(job.ID, &archive)
+
+	return job, nil
+}
+
+// GetExtraction returns an extraction job of the user together with its per-entry results
+func (es *ExtractionService) GetExtraction(userID, fileID, jobID uuid.UUID) (*models.ArchiveExtraction, error) {
+	var job models.ArchiveExtraction
+	err := es.db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ? AND file_id = ? AND user_id = ?", jobID, fileID, userID).First(&job).Error
+	if err != nil {
+		return nil, ErrExtractionNotFound
+	}
+	return &job, nil
+}
+
+// FailInterruptedJobs marks jobs left pending or processing by a previous run of the server as
+// failed, so clients polling them get an answer. Call it on startup, before new jobs are started.
+func (es *ExtractionService) FailInterruptedJobs() (int64, error) {
+	result := es.db.Model(&models.ArchiveExtraction{}).
+		Where("status IN ?", []string{models.ExtractionStatusPending, models.ExtractionStatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":        models.ExtractionStatusFailed,
+			"error_message": "extraction was interrupted by a server restart",
+			"completed_at":  time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// extractionRun holds the state of a running extraction job
+type extractionRun struct {
+	job     *models.ArchiveExtraction
+	folders map[string]*uuid.UUID // archive directory path -> folder ID
+}
+
+func (es *ExtractionService) run(jobID uuid.UUID, archive *models.File) {
+	logger := config.GetLogger()
+
+	var job models.ArchiveExtraction
+	if err := es.db.Where("id = ?", jobID).First(&job).Error; err != nil {
+		logger.Error("Failed to load extraction job", "job_id", jobID, "error", err)
+		return
+	}
+
+	// A panic in a decompressor or the storage backend fails the job instead of the server
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Archive extraction panicked", "job_id", job.ID, "file_id", archive.ID, "panic", r)
+			completedAt := time.Now()
+			job.CompletedAt = &completedAt
+			job.Status = models.ExtractionStatusFailed
+			job.ErrorMessage = "extraction stopped unexpectedly"
+			es.saveProgress(&job)
+		}
+	}()
+
+	now := time.Now()
+	job.Status = models.ExtractionStatusProcessing
+	job.StartedAt = &now
+	es.saveProgress(&job)
+
+	run := &extractionRun{
+		job:     &job,
+		folders: map[string]*uuid.UUID{"": job.TargetFolderID},
+	}
+
+	skipped, err := es.archiveService.walk(context.Background(), archive, func(entry models.ArchiveEntry, open func() (io.ReadCloser, error)) error {
+		job.TotalEntries++
+		if entry.IsDir {
+			es.extractDir(run, entry)
+		} else {
+			es.extractFile(run, entry, open)
+		}
+		if job.TotalEntries%extractionProgressInterval == 0 {
+			es.saveProgress(&job)
+		}
+		return nil
+	})
+
+	job.SkippedEntries += skipped
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	if err != nil {
+		job.Status = models.ExtractionStatusFailed
+		job.ErrorMessage = err.Error()
+		logger.Error("Archive extraction failed", "job_id", job.ID, "file_id", archive.ID, "error", err)
+	} else {
+		job.Status = models.ExtractionStatusCompleted
+	}
+	es.saveProgress(&job)
+
+	status := models.StatusSuccess
+	if err != nil {
+		status = models.StatusFailure
+	}
+	es.auditService.LogEvent(&job.UserID, models.ActionFileExtract, models.ResourceFile, &archive.ID,
+		fmt.Sprintf("Archive extracted: %s (%d files, %d folders)", archive.OriginalName, job.FilesCreated, job.FoldersCreated),
+		"", "", status)
+}
+
+func (es *ExtractionService) saveProgress(job *models.ArchiveExtraction) {
+	err := es.db.Model(job).Select("status", "error_message", "total_entries", "files_created", "folders_created",
+		"skipped_entries", "failed_entries", "started_at", "completed_at").Updates(job).Error
+	if err != nil {
+		config.GetLogger().Error("Failed to update extraction job", "job_id", job.ID, "error", err)
+	}
+}
+
+func (es *ExtractionService) extractDir(run *extractionRun, entry models.ArchiveEntry) {
+	folderID, created, err := es.ensureFolder(run, entry.Path)
+	result := models.ArchiveExtractionEntry{Path: entry.Path, IsDir: true, FolderID: folderID}
+	switch {
+	case err != nil:
+		result.Status = models.ExtractionEntryFailed
+		result.Message = err.Error()
+		run.job.FailedEntries++
+	case created:
+		result.Status = models.ExtractionEntryCreated
+	default:
+		result.Status = models.ExtractionEntryExists
+	}
+	es.recordEntry(run, result)
+}
+
+func (es *ExtractionService) extractFile(run *extractionRun, entry models.ArchiveEntry, open func() (io.ReadCloser, error)) {
+	result := models.ArchiveExtractionEntry{Path: entry.Path}
+	fail := func(err error) {
+		result.Status = models.ExtractionEntryFailed
+		result.Message = err.Error()
+		run.job.FailedEntries++
+		es.recordEntry(run, result)
+	}
+
+	if err := utils.ValidateUpload(entry.Name, entry.Size); err != nil {
+		result.Status = models.ExtractionEntrySkipped
+		result.Message = err.Error()
+		run.job.SkippedEntries++
+		es.recordEntry(run, result)
+		return
+	}
+
+	folderID, _, err := es.ensureFolder(run, path.Dir(entry.Path))
+	if err != nil {
+		fail(err)
+		return
+	}
+	result.FolderID = folderID
+
+	name := entry.Name
+	if fileNameTaken(es.db, run.job.UserID, folderID, name) {
+		if run.job.OnConflict == models.ConflictSkip {
+			result.Status = models.ExtractionEntrySkipped
+			result.Message = "file with this name already exists"
+			run.job.SkippedEntries++
+			es.recordEntry(run, result)
+			return
+		}
+		name = uniqueFileName(es.db, run.job.UserID, folderID, name)
+	}
+
+	rc, err := open()
+	if err != nil {
+		fail(err)
+		return
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	fileExtension := filepath.Ext(name)
+	fileModel := models.File{
+		ID:            uuid.New(),
+		UserID:        run.job.UserID,
+		OriginalName:  name,
+		FileSize:      int64(len(data)),
+		MimeType:      utils.GetMimeType(name),
+		FileExtension: fileExtension,
+		FolderID:      folderID,
+	}
+	fileModel.FileName = fileModel.ID.String() + fileExtension
+
+	if err := es.quotaService.Reserve(fileModel.UserID, fileModel.FileSize); err != nil {
+		fail(err)
+		return
+	}
+
+	ctx := context.Background()
+	objectKey := fileModel.ObjectKey()
+	urlOrPath, err := es.storage.UploadFile(ctx, objectKey, data, fileModel.MimeType)
+	if err != nil {
+		es.quotaService.Release(fileModel.UserID, fileModel.FileSize)
+		fail(err)
+		return
+	}
+	fileModel.FilePath = urlOrPath
+
+	if err := es.db.Create(&fileModel).Error; err != nil {
+		es.storage.DeleteFile(ctx, objectKey)
+		es.quotaService.Release(fileModel.UserID, fileModel.FileSize)
+		fail(err)
+		return
+	}
+	if err := es.storage.SetObjectPublic(ctx, objectKey, false); err != nil {
+		config.GetLogger().Error("Failed to set object ACL", "key", objectKey, "error", err)
+	}
+
+	run.job.FilesCreated++
+	result.FileID = &fileModel.ID
+	result.Status = models.ExtractionEntryCreated
+	if name != entry.Name {
+		result.Status = models.ExtractionEntryRenamed
+		result.Message = fmt.Sprintf("stored as %s", name)
+	}
+	es.recordEntry(run, result)
+}
+
+func (es *ExtractionService) recordEntry(run *extractionRun, result models.ArchiveExtractionEntry) {
+	result.ExtractionID = run.job.ID
+	if err := es.db.Create(&result).Error; err != nil {
+		config.GetLogger().Error("Failed to record extraction entry", "job_id", run.job.ID, "path", result.Path, "error", err)
+	}
+}
+
+// ensureFolder returns the folder for an archive directory, creating missing folders along the way.
+// Existing folders with the same name are reused so archives can be extracted on top of a tree.
+func (es *ExtractionService) ensureFolder(run *extractionRun, dir string) (*uuid.UUID, bool, error) {
+	if dir == "." {
+		dir = ""
+	}
+	if id, ok := run.folders[dir]; ok {
+		return id, false, nil
+	}
+
+	parentDir := path.Dir(dir)
+	parentID, _, err := es.ensureFolder(run, parentDir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	name := path.Base(dir)
+	var folder models.Folder
+	query := es.db.Where("name = ? AND is_trashed = ?", name, false).Scopes(models.OwnedBy(run.job.UserID))
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	if err := query.First(&folder).Error; err == nil {
+		run.folders[dir] = &folder.ID
+		return &folder.ID, false, nil
+	}
+
+	// The BeforeCreate hook derives Path from the parent folder
+	folder = models.Folder{
+		UserID:   run.job.UserID,
+		ParentID: parentID,
+		Name:     name,
+	}
+	if err := es.db.Create(&folder).Error; err != nil {
+		return nil, false, err
+	}
+
+	run.job.FoldersCreated++
+	run.folders[dir] = &folder.ID
+	return &folder.ID, true, nil
+}
+
+func fileNameTaken(db *gorm.DB, userID uuid.UUID, folderID *uuid.UUID, name string) bool {
+	var count int64
+	query := db.Model(&models.File{}).Where("original_name = ? AND is_trashed = ?", name, false).Scopes(models.OwnedBy(userID))
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("folder_id IS NULL")
+	}
+	query.Count(&count)
+	return count > 0
+}
+
+// uniqueFileName appends a counter to the name until it no longer collides, e.g. "report (2).pdf"
+func uniqueFileName(db *gorm.DB, userID uuid.UUID, folderID *uuid.UUID, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !fileNameTaken(db, userID, folderID, candidate) {
+			return candidate
+		}
+	}
+}
+
+func folderNameTaken(db *gorm.DB, userID uuid.UUID, parentID *uuid.UUID, name string) bool {
+	var count int64
+	query := db.Model(&models.Folder{}).Where("name = ? AND is_trashed = ?", name, false).Scopes(models.OwnedBy(userID))
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	query.Count(&count)
+	return count > 0
+}
+
+// uniqueFolderName appends a counter to the name until it no longer collides, e.g. "Reports (2)"
+func uniqueFolderName(db *gorm.DB, userID uuid.UUID, parentID *uuid.UUID, name string) string {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !folderNameTaken(db, userID, parentID, candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFileRequestNotFound = errors.New("file request not found")
+	ErrFileRequestClosed   = errors.New("file request is no longer accepting files")
+)
+
+type FileRequestService struct {
+	db *gorm.DB
+}
+
+func NewFileRequestService() *FileRequestService {
+	return &FileRequestService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateFileRequest creates a file request that collects uploads into one of the user's folders
+func (frs *FileRequestService) CreateFileRequest(userID uuid.UUID, req models.FileRequestCreateRequest) (*models.FileRequest, error) {
+	var folder models.Folder
+	if err := frs.db.Where("id = ? AND is_trashed = ?", req.FolderID, false).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
+		return nil, errors.New("folder not found or access denied")
+	}
+
+	if req.Deadline != nil && req.Deadline.Before(time.Now()) {
+		return nil, errors.New("deadline must be in the future")
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	fileRequest := &models.FileRequest{
+		UserID:       userID,
+		FolderID:     folder.ID,
+		Token:        token,
+		Title:        req.Title,
+		Instructions: req.Instructions,
+		Deadline:     req.Deadline,
+		MaxFiles:     req.MaxFiles,
+		MaxFileSize:  req.MaxFileSize,
+		RequireEmail: req.RequireEmail,
+		IsActive:     true,
+	}
+
+	if err := frs.db.Create(fileRequest).Error; err != nil {
+		return nil, err
+	}
+
+	fileRequest.Folder = folder
+	return fileRequest, nil
+}
+
+// GetFileRequests returns the user's file requests, newest first
+func (frs *FileRequestService) GetFileRequests(userID uuid.UUID, page, limit int) ([]models.FileRequest, int64, error) {
+	var fileRequests []models.FileRequest
+	var total int64
+
+	if err := frs.db.Model(&models.FileRequest{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := frs.db.Preload("Folder").Where("user_id = ?", userID).
+		Order("created_at DESC").Offset(offset).Limit(limit).
+		Find(&fileRequests).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return fileRequests, total, nil
+}
+
+// GetFileRequest returns a file request owned by the user
+func (frs *FileRequestService) GetFileRequest(userID, requestID uuid.UUID) (*models.FileRequest, error) {
+	var fileRequest models.FileRequest
+	if err := frs.db.Preload("Folder").Where("id = ? AND user_id = ?", requestID, userID).
+		First(&fileRequest).Error; err != nil {
+		return nil, ErrFileRequestNotFound
+	}
+	return &fileRequest, nil
+}
+
+// GetFileRequestByToken returns a file request by its public token
+func (frs *FileRequestService) GetFileRequestByToken(token string) (*models.FileRequest, error) {
+	var fileRequest models.FileRequest
+	if err := frs.db.Preload("User").Preload("Folder").Where("token = ?", token).
+		First(&fileRequest).Error; err != nil {
+		return nil, ErrFileRequestNotFound
+	}
+	return &fileRequest, nil
+}
+
+// UpdateFileRequest updates a file request
+func (frs *FileRequestService) UpdateFileRequest(userID, requestID uuid.UUID, req models.FileRequestUpdateRequest) (*models.FileRequest, error) {
+	fileRequest, err := frs.GetFileRequest(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Instructions != nil {
+		updates["instructions"] = *req.Instructions
+	}
+	if req.ClearDeadline {
+		updates["deadline"] = nil
+	} else if req.Deadline != nil {
+		updates["deadline"] = req.Deadline
+	}
+
+	// A zero limit removes it
+	if req.MaxFiles != nil {
+		updates["max_files"] = nilIfZero(*req.MaxFiles)
+	}
+	if req.MaxFileSize != nil {
+		if *req.MaxFileSize == 0 {
+			updates["max_file_size"] = nil
+		} else {
+			updates["max_file_size"] = *req.MaxFileSize
+		}
+	}
+	if req.RequireEmail != nil {
+		updates["require_email"] = *req.RequireEmail
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) > 0 {
+		if err := frs.db.Model(fileRequest).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return frs.GetFileRequest(userID, requestID)
+}
+
+// DeleteFileRequest deletes a file request. Files it collected stay in the target folder.
+func (frs *FileRequestService) DeleteFileRequest(userID, requestID uuid.UUID) error {
+	result := frs.db.Where("id = ? AND user_id = ?", requestID, userID).Delete(&models.FileRequest{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFileRequestNotFound
+	}
+	return nil
+}
+
+// GetFileRequestUploads returns the files collected by a file request, newest first
+func (frs *FileRequestService) GetFileRequestUploads(userID, requestID uuid.UUID, page, limit int) ([]models.File, int64, error) {
+	if _, err := frs.GetFileRequest(userID, requestID); err != nil {
+		return nil, 0, err
+	}
+
+	var files []models.File
+	var total int64
+
+	query := frs.db.Model(&models.File{}).Where("file_request_id = ?", requestID).Scopes(models.OwnedBy(userID))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&files).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return files, total, nil
+}
+
+// SaveUpload stores a file uploaded to a file request in its target folder. A slot is reserved
+// before the file is stored so concurrent uploads cannot exceed max_files or beat the deadline.
+func (frs *FileRequestService) SaveUpload(ctx context.Context, fileRequest *models.FileRequest, header *multipart.FileHeader, uploader models.Uploader) (*models.File, error) {
+	result := frs.db.Model(&models.FileRequest{}).
+		Where("id = ? AND is_active = ? AND (deadline IS NULL OR deadline > ?) AND (max_files IS NULL OR upload_count < max_files)",
+			fileRequest.ID, true, time.Now()).
+		UpdateColumn("upload_count", gorm.Expr("upload_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrFileRequestClosed
+	}
+
+	// The target folder must still exist, uploads never go anywhere else
+	var folder models.Folder
+	if err := frs.db.Where("id = ? AND is_trashed = ?", fileRequest.FolderID, false).Scopes(models.OwnedBy(fileRequest.UserID)).
+		First(&folder).Error; err != nil {
+		frs.releaseUpload(fileRequest)
+		return nil, ErrFileRequestClosed
+	}
+
+	file, err := storeUpload(ctx, frs.db, fileRequest.UserID, folder.ID, header, uploader, func(file *models.File) {
+		file.FileRequestID = &fileRequest.ID
+	})
+	if err != nil {
+		frs.releaseUpload(fileRequest)
+		return nil, err
+	}
+
+	fileRequest.UploadCount++
+	return file, nil
+}
+
+// releaseUpload gives back a slot reserved for an upload that failed
+func (frs *FileRequestService) releaseUpload(fileRequest *models.FileRequest) {
+	frs.db.Model(&models.FileRequest{}).Where("id = ? AND upload_count > 0", fileRequest.ID).
+		UpdateColumn("upload_count", gorm.Expr("upload_count - 1"))
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGroupNotFound         = errors.New("group not found")
+	ErrGroupForbidden        = errors.New("only group managers can do this")
+	ErrGroupAdminManaged     = errors.New("this group is managed by administrators")
+	ErrGroupMemberNotFound   = errors.New("member not found")
+	ErrGroupMemberExists     = errors.New("user is already a member of this group")
+	ErrGroupLastManager      = errors.New("a group needs at least one manager")
+	ErrGroupInvalidUser      = errors.New("user not found or inactive")
+	ErrGroupAdminManagedRole = errors.New("admin-managed groups have no managers")
+)
+
+// GroupActor is who manages a group. IsAdmin is only set on admin routes, admins using the regular
+// routes act as any other user.
+type GroupActor struct {
+	UserID  uuid.UUID
+	IsAdmin bool
+}
+
+type GroupService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewGroupService() *GroupService {
+	return &GroupService{
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// groupsOf selects the IDs of the groups the user is a member of, for use as a subquery
+func groupsOf(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.GroupMember{}).Select("group_id").Where("user_id = ?", userID)
+}
+
+// CreateGroup creates a group. Groups created by users have them as their first manager, groups
+// created by admins are admin-managed and start empty.
+func (gs *GroupService) CreateGroup(actor GroupActor, req models.GroupCreateRequest) (*models.Group, error) {
+	group := &models.Group{
+		Name:         req.Name,
+		Description:  req.Description,
+		AdminManaged: actor.IsAdmin,
+		CreatedByID:  actor.UserID,
+	}
+	err := gs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		if actor.IsAdmin {
+			return nil
+		}
+		return tx.Create(&models.GroupMember{GroupID: group.ID, UserID: actor.UserID, Role: models.GroupRoleManager}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gs.loadGroup(group.ID)
+}
+
+// ListGroups returns the groups the user is a member of, or every group for admins
+func (gs *GroupService) ListGroups(actor GroupActor, search string, page, limit int) ([]models.GroupResponse, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if !actor.IsAdmin {
+			db = db.Where("id IN (?)", groupsOf(gs.db, actor.UserID))
+		}
+		if search != "" {
+			db = db.Where("name LIKE ?", "%"+search+"%")
+		}
+		return db
+	}
+
+	var total int64
+	if err := gs.db.Model(&models.Group{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var groups []models.Group
+	offset := (page - 1) * limit
+	if err := gs.db.Scopes(filter).Order("name ASC").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, len(groups))
+	for i := range groups {
+		ids[i] = groups[i].ID
+	}
+	var counts []struct {
+		GroupID uuid.UUID
+		Count   int64
+	}
+	gs.db.Model(&models.GroupMember{}).Select("group_id, COUNT(*) as count").
+		Where("group_id IN ?", ids).Group("group_id").Scan(&counts)
+	var memberships []models.GroupMember
+	gs.db.Where("group_id IN ? AND user_id = ?", ids, actor.UserID).Find(&memberships)
+
+	memberCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		memberCounts[count.GroupID] = count.Count
+	}
+	roles := make(map[uuid.UUID]models.GroupRole, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.GroupID] = membership.Role
+	}
+
+	responses := make([]models.GroupResponse, 0, len(groups))
+	for i := range groups {
+		response := groups[i].ToResponse()
+		response.MemberCount = memberCounts[groups[i].ID]
+		response.MyRole = roles[groups[i].ID]
+		responses = append(responses, response)
+	}
+	return responses, total, nil
+}
+
+// GetGroup returns a group with its members. Only members and admins can see a group.
+func (gs *GroupService) GetGroup(actor GroupActor, groupID uuid.UUID) (*models.Group, error) {
+	group, err := gs.loadGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if memberRole(group, actor.UserID) == "" && !actor.IsAdmin {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// UpdateGroup renames a group or changes its description
+func (gs *GroupService) UpdateGroup(actor GroupActor, groupID uuid.UUID, req models.GroupUpdateRequest) (*models.Group, error) {
+	group, err := gs.manageableGroup(actor, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		group.Name = req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	if err := gs.db.Model(group).Updates(map[string]interface{}{"name": group.Name, "description": group.Description}).Error; err != nil {
+		return nil, err
+	}
+	return gs.loadGroup(group.ID)
+}
+
+// DeleteGroup deletes a group together with everything that was shared with it
+func (gs *GroupService) DeleteGroup(actor GroupActor, groupID uuid.UUID) (*models.Group, error) {
+	group, err := gs.manageableGroup(actor, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.Collaborator{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	return group, nil
+}
+
+// AddMember adds a user to a group, as a member unless another role is asked for
+func (gs *GroupService) AddMember(actor GroupActor, groupID uuid.UUID, req models.AddGroupMemberRequest) (*models.Group, error) {
+	group, err := gs.manageableGroup(actor, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.GroupRoleMember
+	}
+	if group.AdminManaged && role == models.GroupRoleManager {
+		return nil, ErrGroupAdminManagedRole
+	}
+	var user models.User
+	if err := gs.db.Where("id = ? AND is_active = ?", req.UserID, true).First(&user).Error; err != nil {
+		return nil, ErrGroupInvalidUser
+	}
+	if memberRole(group, req.UserID) != "" {
+		return nil, ErrGroupMemberExists
+	}
+
+	if err := gs.db.Create(&models.GroupMember{GroupID: group.ID, UserID: req.UserID, Role: role}).Error; err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	if req.UserID != actor.UserID {
+		if _, err := gs.notificationService.Notify(req.UserID, models.NotificationGroupAdded, "Added to a group",
+			fmt.Sprintf("You were added to the group %s", group.Name), models.ResourceGroup, &group.ID); err != nil {
+			config.GetLogger().Error("Failed to send group notification", "error", err, "group_id", group.ID)
+		}
+	}
+	return gs.loadGroup(group.ID)
+}
+
+// UpdateMember changes the role of a member
+func (gs *GroupService) UpdateMember(actor GroupActor, groupID, userID uuid.UUID, req models.UpdateGroupMemberRequest) (*models.Group, error) {
+	group, err := gs.manageableGroup(actor, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := memberRole(group, userID)
+	if current == "" {
+		return nil, ErrGroupMemberNotFound
+	}
+	if group.AdminManaged && req.Role == models.GroupRoleManager {
+		return nil, ErrGroupAdminManagedRole
+	}
+	if current == models.GroupRoleManager && req.Role != models.GroupRoleManager && managerCount(group) == 1 {
+		return nil, ErrGroupLastManager
+	}
+
+	err = gs.db.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, userID).
+		Update("role", req.Role).Error
+	if err != nil {
+		return nil, err
+	}
+	return gs.loadGroup(group.ID)
+}
+
+// RemoveMember takes a user out of a group. Members of groups run by users can also leave on
+// their own, the last manager has to delete the group instead.
+func (gs *GroupService) RemoveMember(actor GroupActor, groupID, userID uuid.UUID) (*models.Group, error) {
+	var group *models.Group
+	var err error
+	if userID == actor.UserID && !actor.IsAdmin {
+		if group, err = gs.GetGroup(actor, groupID); err == nil && group.AdminManaged {
+			err = ErrGroupAdminManaged
+		}
+	} else {
+		group, err = gs.manageableGroup(actor, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := memberRole(group, userID)
+	if role == "" {
+		return nil, ErrGroupMemberNotFound
+	}
+	if role == models.GroupRoleManager && managerCount(group) == 1 {
+		return nil, ErrGroupLastManager
+	}
+
+	if err := gs.db.Where("group_id = ? AND user_id = ?", group.ID, userID).Delete(&models.GroupMember{}).Error; err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	return gs.loadGroup(group.ID)
+}
+
+// manageableGroup loads a group the actor may change: any group for admins, and groups run by
+// users for their managers
+func (gs *GroupService) manageableGroup(actor GroupActor, groupID uuid.UUID) (*models.Group, error) {
+	group, err := gs.loadGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.IsAdmin {
+		return group, nil
+	}
+
+	switch role := memberRole(group, actor.UserID); {
+	case role == "":
+		return nil, ErrGroupNotFound
+	case group.AdminManaged:
+		return nil, ErrGroupAdminManaged
+	case role != models.GroupRoleManager:
+		return nil, ErrGroupForbidden
+	}
+	return group, nil
+}
+
+func (gs *GroupService) loadGroup(groupID uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	err := gs.db.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User").Where("id = ?", groupID).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// memberRole returns the user's role in a group loaded with its members, empty for non-members
+func memberRole(group *models.Group, userID uuid.UUID) models.GroupRole {
+	for _, member := range group.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+func managerCount(group *models.Group) int {
+	count := 0
+	for _, member := range group.Members {
+		if member.Role == models.GroupRoleManager {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// inviteResendInterval is how long an owner has to wait before sending the same invite again
+const inviteResendInterval = time.Minute
+
+var (
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrInviteItemNotFound  = errors.New("file or folder not found or access denied")
+	ErrInviteInvalidToken  = errors.New("invalid invite link")
+	ErrInviteNotPending    = errors.New("invite is no longer pending")
+	ErrInviteExpired       = errors.New("invite has expired")
+	ErrInviteEmailMismatch = errors.New("invite was sent to a different email address")
+	ErrInviteSelf          = errors.New("cannot invite yourself")
+	ErrInviteResendTooSoon = errors.New("invite was sent less than a minute ago")
+)
+
+type InviteService struct {
+	db                  *gorm.DB
+	mailService         *MailService
+	notificationService *NotificationService
+}
+
+func NewInviteService() *InviteService {
+	return &InviteService{
+		db:                  database.GetDB(),
+		mailService:         NewMailService(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// CreateInvite invites an email address without an account to a file or folder the owner has and
+// emails them the invite link. Inviting the same address again updates the pending invite and
+// sends it again.
+func (ivs *InviteService) CreateInvite(owner models.User, resourceID uuid.UUID, req models.AddCollaboratorRequest, isFile bool) (*models.CollaboratorInvite, error) {
+	itemName, err := ivs.ownedItem(owner.ID, resourceID, isFile)
+	if err != nil {
+		return nil, err
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == strings.ToLower(owner.Email) {
+		return nil, ErrInviteSelf
+	}
+
+	var invite models.CollaboratorInvite
+	err = ivs.itemScope(resourceID, isFile).
+		Where("email = ? AND status = ?", email, models.InvitePending).First(&invite).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		invite = models.CollaboratorInvite{
+			Email:       email,
+			InvitedByID: owner.ID,
+			Status:      models.InvitePending,
+		}
+		if isFile {
+			invite.FileID = &resourceID
+		} else {
+			invite.FolderID = &resourceID
+		}
+	case err != nil:
+		return nil, err
+	}
+	invite.Role = req.Role
+	invite.AccessExpiresAt = req.ExpiresAt
+	invite.ExpiresAt = time.Now().AddDate(0, 0, config.AppConfig.Invite.ExpiryDays)
+
+	if err := ivs.db.Save(&invite).Error; err != nil {
+		return nil, err
+	}
+	if err := ivs.send(&invite, owner, itemName); err != nil {
+		config.GetLogger().Warn("Failed to email collaborator invite", "error", err, "invite_id", invite.ID)
+	}
+	return ivs.loadInvite(invite.ID)
+}
+
+// ListInvites returns the pending invites of a file or folder, expired ones included so that
+// they can be resent
+func (ivs *InviteService) ListInvites(ownerID, resourceID uuid.UUID, isFile bool) ([]models.CollaboratorInvite, error) {
+	if _, err := ivs.ownedItem(ownerID, resourceID, isFile); err != nil {
+		return nil, err
+	}
+
+	var invites []models.CollaboratorInvite
+	err := ivs.itemScope(resourceID, isFile).Preload("InvitedBy").
+		Where("status = ?", models.InvitePending).Order("created_at DESC").Find(&invites).Error
+	return invites, err
+}
+
+// ResendInvite emails a pending invite again. The invite gets a new expiry, which also
+// invalidates the link sent before.
+func (ivs *InviteService) ResendInvite(owner models.User, resourceID, inviteID uuid.UUID, isFile bool) (*models.CollaboratorInvite, error) {
+	invite, err := ivs.pendingInvite(owner.ID, resourceID, inviteID, isFile)
+	if err != nil {
+		return nil, err
+	}
+	if invite.LastSentAt != nil && time.Since(*invite.LastSentAt) < inviteResendInterval {
+		return nil, ErrInviteResendTooSoon
+	}
+
+	invite.ExpiresAt = time.Now().AddDate(0, 0, config.AppConfig.Invite.ExpiryDays)
+	if err := ivs.db.Model(invite).Update("expires_at", invite.ExpiresAt).Error; err != nil {
+		return nil, err
+	}
+	if err := ivs.send(invite, owner, invite.ItemName()); err != nil {
+		return nil, err
+	}
+	return ivs.loadInvite(invite.ID)
+}
+
+// RevokeInvite withdraws a pending invite, its link stops working
+func (ivs *InviteService) RevokeInvite(ownerID, resourceID, inviteID uuid.UUID, isFile bool) (*models.CollaboratorInvite, error) {
+	invite, err := ivs.pendingInvite(ownerID, resourceID, inviteID, isFile)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ivs.db.Model(&models.CollaboratorInvite{}).
+		Where("id = ? AND status = ?", invite.ID, models.InvitePending).
+		Update("status", models.InviteRevoked)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInviteNotPending
+	}
+	invite.Status = models.InviteRevoked
+	return invite, nil
+}
+
+// GetInviteByToken returns the invite a token was issued for, whatever its status
+func (ivs *InviteService) GetInviteByToken(token string) (*models.CollaboratorInvite, error) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInviteInvalidToken
+	}
+	inviteID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInviteInvalidToken
+	}
+
+	invite, err := ivs.loadInvite(inviteID)
+	if err != nil {
+		return nil, ErrInviteInvalidToken
+	}
+	expected := signInvite(invite)
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return nil, ErrInviteInvalidToken
+	}
+	return invite, nil
+}
+
+// VerifyToken checks that a token is for a pending invite to the given email address
+func (ivs *InviteService) VerifyToken(token, email string) (*models.CollaboratorInvite, error) {
+	invite, err := ivs.GetInviteByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.Status != models.InvitePending {
+		return nil, ErrInviteNotPending
+	}
+	if invite.IsExpired() {
+		return nil, ErrInviteExpired
+	}
+	if !strings.EqualFold(invite.Email, email) {
+		return nil, ErrInviteEmailMismatch
+	}
+	return invite, nil
+}
+
+// AcceptInvite redeems an invite token for a signed in user. The token proves the user receives
+// mail at their address, so the address is marked verified and every invite sent to it is
+// converted, not only this one.
+func (ivs *InviteService) AcceptInvite(user *models.User, token string) ([]models.CollaboratorInvite, error) {
+	if _, err := ivs.VerifyToken(token, user.Email); err != nil {
+		return nil, err
+	}
+	if !user.EmailVerified {
+		if err := ivs.db.Model(user).Update("email_verified", true).Error; err != nil {
+			return nil, err
+		}
+		user.EmailVerified = true
+	}
+	return ivs.ConvertPendingInvites(user)
+}
+
+// ConvertPendingInvites turns the pending invites sent to a verified user's email address into
+// collaborator entries. A role the user already has on the item is only ever raised.
+func (ivs *InviteService) ConvertPendingInvites(user *models.User) ([]models.CollaboratorInvite, error) {
+	if !user.EmailVerified {
+		return nil, nil
+	}
+
+	var invites []models.CollaboratorInvite
+	err := ivs.db.Preload("File").Preload("Folder").
+		Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), models.InvitePending, time.Now()).
+		Find(&invites).Error
+	if err != nil || len(invites) == 0 {
+		return nil, err
+	}
+
+	var converted []models.CollaboratorInvite
+	err = ivs.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for i := range invites {
+			invite := &invites[i]
+			ownerID, available := inviteItemOwner(invite)
+			if !available {
+				// the file or folder was deleted after the invite was sent
+				if err := tx.Model(invite).Update("status", models.InviteRevoked).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if ownerID != user.ID {
+				target := models.Collaborator{FileID: invite.FileID, FolderID: invite.FolderID, UserID: &user.ID}
+				if err := grantAtLeast(tx, target, invite.Role, invite.AccessExpiresAt); err != nil {
+					return err
+				}
+			}
+			err := tx.Model(invite).Updates(map[string]interface{}{
+				"status":         models.InviteAccepted,
+				"accepted_by_id": user.ID,
+				"accepted_at":    now,
+			}).Error
+			if err != nil {
+				return err
+			}
+			invite.Status = models.InviteAccepted
+			invite.AcceptedByID = &user.ID
+			invite.AcceptedAt = &now
+			converted = append(converted, *invite)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	for i := range converted {
+		invite := &converted[i]
+		itemID := invite.ItemID()
+		if _, err := ivs.notificationService.Notify(invite.InvitedByID, models.NotificationInviteAccepted, "Invite accepted",
+			fmt.Sprintf("%s accepted your invite to %s", user.GetFullName(), invite.ItemName()),
+			invite.ItemType(), &itemID); err != nil {
+			config.GetLogger().Error("Failed to send invite notification", "error", err, "invite_id", invite.ID)
+		}
+	}
+	return converted, nil
+}
+
+// send emails the invite link on the web app and records when it was sent
+func (ivs *InviteService) send(invite *models.CollaboratorInvite, owner models.User, itemName string) error {
+	if !ivs.mailService.Enabled() {
+		return ErrMailNotConfigured
+	}
+
+	subject := fmt.Sprintf("%s shared %q with you", owner.GetFullName(), itemName)
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s (%s) invited you to the %s %q as %s.\n\n", owner.GetFullName(), owner.Email,
+		invite.ItemType(), itemName, invite.Role)
+	fmt.Fprintf(&body, "Create your account or sign in with this email address to open it: %s/invite/%s\n\n",
+		config.AppConfig.Server.AppURL, signInvite(invite))
+	fmt.Fprintf(&body, "The invite expires on %s.\n", invite.ExpiresAt.UTC().Format("2 January 2006 15:04 MST"))
+
+	if err := ivs.mailService.Send(invite.Email, subject, body.String()); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	invite.SendCount++
+	invite.LastSentAt = &now
+	return ivs.db.Model(invite).Updates(map[string]interface{}{"send_count": invite.SendCount, "last_sent_at": now}).Error
+}
+
+// ownedItem checks that the file or folder exists and belongs to the owner
+func (ivs *InviteService) ownedItem(ownerID, resourceID uuid.UUID, isFile bool) (string, error) {
+	if isFile {
+		var file models.File
+		if err := ivs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&file).Error; err != nil {
+			return "", ErrInviteItemNotFound
+		}
+		return file.OriginalName, nil
+	}
+	var folder models.Folder
+	if err := ivs.db.Where("id = ? AND is_trashed = false", resourceID).Scopes(models.OwnedBy(ownerID)).First(&folder).Error; err != nil {
+		return "", ErrInviteItemNotFound
+	}
+	return folder.Name, nil
+}
+
+func (ivs *InviteService) pendingInvite(ownerID, resourceID, inviteID uuid.UUID, isFile bool) (*models.CollaboratorInvite, error) {
+	if _, err := ivs.ownedItem(ownerID, resourceID, isFile); err != nil {
+		return nil, err
+	}
+
+	var invite models.CollaboratorInvite
+	err := ivs.itemScope(resourceID, isFile).Preload("InvitedBy").Preload("File").Preload("Folder").
+		Where("id = ?", inviteID).First(&invite).Error
+	if err != nil {
+		return nil, ErrInviteNotFound
+	}
+	if invite.Status != models.InvitePending {
+		return nil, ErrInviteNotPending
+	}
+	return &invite, nil
+}
+
+func (ivs *InviteService) itemScope(resourceID uuid.UUID, isFile bool) *gorm.DB {
+	if isFile {
+		return ivs.db.Where("file_id = ?", resourceID)
+	}
+	return ivs.db.Where("folder_id = ?", resourceID)
+}
+
+func (ivs *InviteService) loadInvite(inviteID uuid.UUID) (*models.CollaboratorInvite, error) {
+	var invite models.CollaboratorInvite
+	if err := ivs.db.Preload("InvitedBy").Preload("File").Preload("Folder").
+		Where("id = ?", inviteID).First(&invite).Error; err != nil {
+		return nil, ErrInviteNotFound
+	}
+	return &invite, nil
+}
+
+// inviteItemOwner returns the current owner of the invite's file or folder, and false when the
+// item is gone or in the trash. The invite must be loaded with its file or folder.
+func inviteItemOwner(invite *models.CollaboratorInvite) (uuid.UUID, bool) {
+	switch {
+	case invite.File != nil:
+		return invite.File.OwnerID(), !invite.File.IsTrashed
+	case invite.Folder != nil:
+		return invite.Folder.OwnerID(), !invite.Folder.IsTrashed
+	default:
+		return uuid.Nil, false
+	}
+}
+
+// signInvite returns the invite's token: its ID and an HMAC over the ID, the address and the
+// expiry. Changing the expiry, as resending does, invalidates earlier tokens.
+func signInvite(invite *models.CollaboratorInvite) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.Secret))
+	fmt.Fprintf(mac, "collaborator-invite:%s:%s:%d", invite.ID, invite.Email, invite.ExpiresAt.Unix())
+	return invite.ID.String() + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+)
+
+var ErrMailNotConfigured = errors.New("email delivery is not configured")
+
+// MailService sends plain text or HTML email over the SMTP settings in EmailConfig. Authentication is
+// skipped when no username is configured, which is how local sinks such as MailHog are used.
+type MailService struct {
+	cfg config.EmailConfig
+}
+
+func NewMailService() *MailService {
+	return &MailService{
+		cfg: config.AppConfig.Email,
+	}
+}
+
+// Enabled reports whether an SMTP server is configured
+func (ms *MailService) Enabled() bool {
+	return ms.cfg.SMTPHost != "" && ms.cfg.FromEmail != ""
+}
+
+// Send delivers a plain text message to a single recipient
+func (ms *MailService) Send(to, subject, body string) error {
+	return ms.SendHTML(to, subject, body, "")
+}
+
+// SendHTML delivers a message with a plain text and an HTML part to a single recipient. It is
+// sent as plain text when htmlBody is empty.
+func (ms *MailService) SendHTML(to, subject, textBody, htmlBody string) error {
+	if !ms.Enabled() {
+		return ErrMailNotConfigured
+	}
+
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	from := mail.Address{Name: ms.cfg.FromName, Address: ms.cfg.FromEmail}
+	addr := net.JoinHostPort(ms.cfg.SMTPHost, ms.cfg.SMTPPort)
+
+	var auth smtp.Auth
+	if ms.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", ms.cfg.SMTPUsername, ms.cfg.SMTPPassword, ms.cfg.SMTPHost)
+	}
+
+	msg := buildMessage(&from, recipient, subject, textBody, htmlBody)
+	return smtp.SendMail(addr, auth, from.Address, []string{recipient.Address}, msg)
+}
+
+func buildMessage(from *mail.Address, to *mail.Address, subject, textBody, htmlBody string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + to.String() + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Message-ID: <" + uuid.NewString() + "@" + messageIDHost(from.Address) + ">\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if htmlBody == "" {
+		writePart(&buf, "text/plain", textBody)
+		return buf.Bytes()
+	}
+
+	boundary := "swiftshare-" + uuid.NewString()
+	buf.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString("--" + boundary + "\r\n")
+	writePart(&buf, "text/plain", textBody)
+	buf.WriteString("\r\n--" + boundary + "\r\n")
+	writePart(&buf, "text/html", htmlBody)
+	buf.WriteString("\r\n--" + boundary + "--\r\n")
+	return buf.Bytes()
+}
+
+// writePart writes the content headers and CRLF-normalized body of a single message part
+func writePart(buf *bytes.Buffer, contentType, body string) {
+	buf.WriteString("Content-Type: " + contentType + "; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+}
+
+func messageIDHost(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manjurulhoque/swift-share/backend/config"
+)
+
+// smtpSink is a minimal SMTP server that keeps every message it receives, in the spirit of MailHog
+type smtpSink struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// startSMTPSink listens on a free local port until the test ends
+func startSMTPSink(t *testing.T) (*smtpSink, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sink := &smtpSink{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return sink, port
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	var data strings.Builder
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				s.mu.Lock()
+				s.messages = append(s.messages, data.String())
+				s.mu.Unlock()
+				data.Reset()
+				reply("250 queued")
+				continue
+			}
+			data.WriteString(line)
+			continue
+		}
+
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 sink")
+		case command == "DATA":
+			inData = true
+			reply("354 end data with <CR><LF>.<CR><LF>")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// count returns how many messages were received so far
+func (s *smtpSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+// wait returns the received messages once there are at least n, failing the test after a timeout
+func (s *smtpSink) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		messages := append([]string(nil), s.messages...)
+		s.mu.Unlock()
+		if len(messages) >= n {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d emails, want %d", len(messages), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestMailService returns a MailService that delivers to the sink on port
+func newTestMailService(port string) *MailService {
+	return &MailService{cfg: config.EmailConfig{
+		SMTPHost:  "127.0.0.1",
+		SMTPPort:  port,
+		FromEmail: "noreply@swift-share.test",
+		FromName:  "Swift Share",
+	}}
+}
+
+func TestMailServiceSend(t *testing.T) {
+	sink, port := startSMTPSink(t)
+	ms := newTestMailService(port)
+
+	tests := []struct {
+		name     string
+		htmlBody string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "plain text",
+			want:    []string{"Content-Type: text/plain; charset=utf-8", "Hello\r\nthere"},
+			notWant: []string{"multipart/alternative"},
+		},
+		{
+			name:     "text and html",
+			htmlBody: "<p>Hello</p>",
+			want:     []string{"multipart/alternative", "Content-Type: text/plain; charset=utf-8", "Content-Type: text/html; charset=utf-8", "<p>Hello</p>"},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ms.SendHTML("Alice <alice@example.com>", "Greetings", "Hello\nthere", tt.htmlBody); err != nil {
+				t.Fatalf("SendHTML: %v", err)
+			}
+			message := sink.wait(t, i+1)[i]
+			for _, want := range append(tt.want, "To: \"Alice\" <alice@example.com>", "Subject: Greetings") {
+				if !strings.Contains(message, want) {
+					t.Errorf("message does not contain %q:\n%s", want, message)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(message, notWant) {
+					t.Errorf("message contains %q:\n%s", notWant, message)
+				}
+			}
+		})
+	}
+}
+
+func TestMailServiceNotConfigured(t *testing.T) {
+	ms := &MailService{}
+	if err := ms.Send("alice@example.com", "Hi", "body"); err != ErrMailNotConfigured {
+		t.Fatalf("Send = %v, want ErrMailNotConfigured", err)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
+)
+
+// TestMain runs the service tests against a throwaway SQLite database and local storage
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "swift-share-services")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Setenv("DB_DRIVER", "sqlite")
+	os.Setenv("DB_NAME", dir+"/test.db")
+	os.Setenv("STORAGE_DRIVER", "local")
+	os.Setenv("LOCAL_UPLOAD_PATH", dir+"/uploads")
+	os.Setenv("GIN_MODE", "release")
+	os.Setenv("LOG_LEVEL", "error")
+	config.LoadConfig()
+	database.Connect()
+	database.Migrate()
+	if err := storage.InitDefaultStorage(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// createTestUser creates an active user with a unique email address
+func createTestUser(t *testing.T, name string) *models.User {
+	t.Helper()
+	user := &models.User{
+		FirstName: name,
+		LastName:  "Tester",
+		Email:     fmt.Sprintf("%s-%s@example.com", name, uuid.NewString()[:8]),
+		Password:  "password",
+		IsActive:  true,
+	}
+	if err := database.GetDB().Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestFolder creates a folder owned by the user, parent may be nil
+func createTestFolder(t *testing.T, owner *models.User, parent *models.Folder, name string) *models.Folder {
+	t.Helper()
+	folder := &models.Folder{UserID: owner.ID, Name: name}
+	if parent != nil {
+		folder.ParentID = &parent.ID
+	}
+	if err := database.GetDB().Create(folder).Error; err != nil {
+		t.Fatalf("create folder: %v", err)
+	}
+	return folder
+}
+
+// createTestFile creates a stored text file owned by the user, folder may be nil
+func createTestFile(t *testing.T, owner *models.User, folder *models.Folder, name, content string) *models.File {
+	t.Helper()
+	file := &models.File{
+		UserID:       owner.ID,
+		OriginalName: name,
+		FileName:     uuid.NewString() + ".txt",
+		FileSize:     int64(len(content)),
+		MimeType:     "text/plain",
+	}
+	if folder != nil {
+		file.FolderID = &folder.ID
+	}
+	if err := database.GetDB().Create(file).Error; err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	if _, err := storage.GetStorage().UploadFile(context.Background(), file.ObjectKey(), []byte(content), "text/plain"); err != nil {
+		t.Fatalf("store file: %v", err)
+	}
+	return file
+}
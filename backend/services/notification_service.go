@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+type NotificationService struct {
+	db           *gorm.DB
+	emailService *EmailService
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		db:           database.GetDB(),
+		emailService: NewEmailService(),
+	}
+}
+
+// Notify creates a notification for a user and emails it when the user wants it emailed
+func (ns *NotificationService) Notify(userID uuid.UUID, notificationType, title, message, resource string, resourceID *uuid.UUID) (*models.Notification, error) {
+	notification := &models.Notification{
+		UserID:     userID,
+		Type:       notificationType,
+		Title:      title,
+		Message:    message,
+		Resource:   resource,
+		ResourceID: resourceID,
+	}
+
+	if err := ns.db.Create(notification).Error; err != nil {
+		return nil, err
+	}
+	if err := ns.emailService.QueueNotification(notification); err != nil {
+		config.GetLogger().Error("Failed to queue notification email", "notification_id", notification.ID, "error", err)
+	}
+	return notification, nil
+}
+
+// GetNotifications returns a user's notifications, newest first
+func (ns *NotificationService) GetNotifications(userID uuid.UUID, unreadOnly bool, page, limit int) ([]models.Notification, int64, error) {
+	var notifications []models.Notification
+	var total int64
+
+	query := ns.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// GetUnreadCount returns how many notifications the user has not read
+func (ns *NotificationService) GetUnreadCount(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := ns.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkAsRead marks a single notification as read
+func (ns *NotificationService) MarkAsRead(userID, notificationID uuid.UUID) error {
+	var notification models.Notification
+	if err := ns.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		return errors.New("notification not found")
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return ns.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllAsRead marks all of a user's notifications as read
+func (ns *NotificationService) MarkAllAsRead(userID uuid.UUID) error {
+	return ns.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
+
+// DeleteNotification deletes a notification
+func (ns *NotificationService) DeleteNotification(userID, notificationID uuid.UUID) error {
+	result := ns.db.Where("id = ? AND user_id = ?", notificationID, userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("notification not found")
+	}
+	return nil
+}
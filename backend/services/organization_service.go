@@ -0,0 +1,335 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrganizationNotFound    = errors.New("organization not found")
+	ErrOrganizationForbidden   = errors.New("only organization owners and admins can do this")
+	ErrOrganizationOwnersOnly  = errors.New("only organization owners can manage owners")
+	ErrOrganizationHasDrives   = errors.New("delete the organization's shared drives first")
+	ErrOrganizationLastOwner   = errors.New("an organization needs at least one owner")
+	ErrOrgMemberNotFound       = errors.New("member not found")
+	ErrOrgMemberExists         = errors.New("user is already a member of this organization")
+	ErrOrganizationInvalidUser = errors.New("user not found or inactive")
+)
+
+type OrganizationService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewOrganizationService() *OrganizationService {
+	return &OrganizationService{
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// organizationRole returns the user's role in an organization, empty for non-members
+func organizationRole(db *gorm.DB, organizationID, userID uuid.UUID) (models.OrganizationRole, error) {
+	var memberships []models.OrganizationMember
+	err := db.Where("organization_id = ? AND user_id = ?", organizationID, userID).Limit(1).Find(&memberships).Error
+	if err != nil || len(memberships) == 0 {
+		return "", err
+	}
+	return memberships[0].Role, nil
+}
+
+// CreateOrganization creates an organization with the user as its first owner
+func (ogs *OrganizationService) CreateOrganization(userID uuid.UUID, req models.OrganizationCreateRequest) (*models.Organization, error) {
+	organization := &models.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedByID: userID,
+	}
+	err := ogs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: organization.ID,
+			UserID:         userID,
+			Role:           models.OrganizationRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ogs.loadOrganization(organization.ID)
+}
+
+// ListOrganizations returns the organizations the user is a member of
+func (ogs *OrganizationService) ListOrganizations(userID uuid.UUID, page, limit int) ([]models.OrganizationResponse, int64, error) {
+	memberOf := ogs.db.Model(&models.OrganizationMember{}).Select("organization_id").Where("user_id = ?", userID)
+
+	var total int64
+	if err := ogs.db.Model(&models.Organization{}).Where("id IN (?)", memberOf).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var organizations []models.Organization
+	offset := (page - 1) * limit
+	err := ogs.db.Where("id IN (?)", memberOf).Order("name ASC").Offset(offset).Limit(limit).Find(&organizations).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, len(organizations))
+	for i := range organizations {
+		ids[i] = organizations[i].ID
+	}
+	type count struct {
+		OrganizationID uuid.UUID
+		Count          int64
+	}
+	var memberCounts, driveCounts []count
+	ogs.db.Model(&models.OrganizationMember{}).Select("organization_id, COUNT(*) as count").
+		Where("organization_id IN ?", ids).Group("organization_id").Scan(&memberCounts)
+	ogs.db.Model(&models.SharedDrive{}).Select("organization_id, COUNT(*) as count").
+		Where("organization_id IN ?", ids).Group("organization_id").Scan(&driveCounts)
+	var memberships []models.OrganizationMember
+	ogs.db.Where("organization_id IN ? AND user_id = ?", ids, userID).Find(&memberships)
+
+	members := make(map[uuid.UUID]int64, len(memberCounts))
+	for _, c := range memberCounts {
+		members[c.OrganizationID] = c.Count
+	}
+	drives := make(map[uuid.UUID]int64, len(driveCounts))
+	for _, c := range driveCounts {
+		drives[c.OrganizationID] = c.Count
+	}
+	roles := make(map[uuid.UUID]models.OrganizationRole, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.OrganizationID] = membership.Role
+	}
+
+	responses := make([]models.OrganizationResponse, 0, len(organizations))
+	for i := range organizations {
+		response := organizations[i].ToResponse()
+		response.MemberCount = members[organizations[i].ID]
+		response.DriveCount = drives[organizations[i].ID]
+		response.MyRole = roles[organizations[i].ID]
+		responses = append(responses, response)
+	}
+	return responses, total, nil
+}
+
+// GetOrganization returns an organization with its members. Only members can see an organization.
+func (ogs *OrganizationService) GetOrganization(userID, organizationID uuid.UUID) (*models.Organization, error) {
+	organization, err := ogs.loadOrganization(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if organizationMemberRole(organization, userID) == "" {
+		return nil, ErrOrganizationNotFound
+	}
+	return organization, nil
+}
+
+// UpdateOrganization renames an organization or changes its description
+func (ogs *OrganizationService) UpdateOrganization(userID, organizationID uuid.UUID, req models.OrganizationUpdateRequest) (*models.Organization, error) {
+	organization, err := ogs.manageableOrganization(userID, organizationID, models.OrganizationRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		organization.Name = req.Name
+	}
+	if req.Description != nil {
+		organization.Description = *req.Description
+	}
+	err = ogs.db.Model(organization).
+		Updates(map[string]interface{}{"name": organization.Name, "description": organization.Description}).Error
+	if err != nil {
+		return nil, err
+	}
+	return ogs.loadOrganization(organization.ID)
+}
+
+// DeleteOrganization deletes an organization that has no shared drives left
+func (ogs *OrganizationService) DeleteOrganization(userID, organizationID uuid.UUID) (*models.Organization, error) {
+	organization, err := ogs.manageableOrganization(userID, organizationID, models.OrganizationRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if len(organization.Drives) > 0 {
+		return nil, ErrOrganizationHasDrives
+	}
+
+	err = ogs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", organization.ID).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(organization).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+// AddMember adds a user to an organization, as a member unless another role is asked for. Only
+// owners can add owners.
+func (ogs *OrganizationService) AddMember(userID, organizationID uuid.UUID, req models.AddOrganizationMemberRequest) (*models.Organization, error) {
+	organization, err := ogs.manageableOrganization(userID, organizationID, models.OrganizationRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.OrganizationRoleMember
+	}
+	if role == models.OrganizationRoleOwner && organizationMemberRole(organization, userID) != models.OrganizationRoleOwner {
+		return nil, ErrOrganizationOwnersOnly
+	}
+	var user models.User
+	if err := ogs.db.Where("id = ? AND is_active = ?", req.UserID, true).First(&user).Error; err != nil {
+		return nil, ErrOrganizationInvalidUser
+	}
+	if organizationMemberRole(organization, req.UserID) != "" {
+		return nil, ErrOrgMemberExists
+	}
+
+	member := &models.OrganizationMember{OrganizationID: organization.ID, UserID: req.UserID, Role: role}
+	if err := ogs.db.Create(member).Error; err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	if _, err := ogs.notificationService.Notify(req.UserID, models.NotificationOrganizationAdded, "Added to an organization",
+		fmt.Sprintf("You were added to the organization %s", organization.Name), models.ResourceOrganization, &organization.ID); err != nil {
+		config.GetLogger().Error("Failed to send organization notification", "error", err, "organization_id", organization.ID)
+	}
+	return ogs.loadOrganization(organization.ID)
+}
+
+// UpdateMember changes the role of a member. Only owners can change the role of an owner or make
+// someone an owner.
+func (ogs *OrganizationService) UpdateMember(userID, organizationID, memberID uuid.UUID, req models.UpdateOrganizationMemberRequest) (*models.Organization, error) {
+	organization, err := ogs.manageableOrganization(userID, organizationID, models.OrganizationRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	current := organizationMemberRole(organization, memberID)
+	if current == "" {
+		return nil, ErrOrgMemberNotFound
+	}
+	if (current == models.OrganizationRoleOwner || req.Role == models.OrganizationRoleOwner) &&
+		organizationMemberRole(organization, userID) != models.OrganizationRoleOwner {
+		return nil, ErrOrganizationOwnersOnly
+	}
+	if current == models.OrganizationRoleOwner && req.Role != models.OrganizationRoleOwner && ownerCount(organization) == 1 {
+		return nil, ErrOrganizationLastOwner
+	}
+
+	err = ogs.db.Model(&models.OrganizationMember{}).Where("organization_id = ? AND user_id = ?", organization.ID, memberID).
+		Update("role", req.Role).Error
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	return ogs.loadOrganization(organization.ID)
+}
+
+// RemoveMember takes a user out of an organization and all of its shared drives. The content
+// they added stays in the drives. Members can also leave on their own, the last owner cannot.
+func (ogs *OrganizationService) RemoveMember(userID, organizationID, memberID uuid.UUID) (*models.Organization, error) {
+	var organization *models.Organization
+	var err error
+	if memberID == userID {
+		organization, err = ogs.GetOrganization(userID, organizationID)
+	} else {
+		organization, err = ogs.manageableOrganization(userID, organizationID, models.OrganizationRoleAdmin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := organizationMemberRole(organization, memberID)
+	if role == "" {
+		return nil, ErrOrgMemberNotFound
+	}
+	if role == models.OrganizationRoleOwner && memberID != userID &&
+		organizationMemberRole(organization, userID) != models.OrganizationRoleOwner {
+		return nil, ErrOrganizationOwnersOnly
+	}
+	if role == models.OrganizationRoleOwner && ownerCount(organization) == 1 {
+		return nil, ErrOrganizationLastOwner
+	}
+
+	err = ogs.db.Transaction(func(tx *gorm.DB) error {
+		drives := tx.Model(&models.SharedDrive{}).Select("id").Where("organization_id = ?", organization.ID)
+		if err := tx.Where("drive_id IN (?) AND user_id = ?", drives, memberID).Delete(&models.DriveMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ? AND user_id = ?", organization.ID, memberID).Delete(&models.OrganizationMember{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	return ogs.loadOrganization(organization.ID)
+}
+
+// manageableOrganization loads an organization in which the user has at least the required role
+func (ogs *OrganizationService) manageableOrganization(userID, organizationID uuid.UUID, required models.OrganizationRole) (*models.Organization, error) {
+	organization, err := ogs.GetOrganization(userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !organizationMemberRole(organization, userID).Includes(required) {
+		if required == models.OrganizationRoleOwner {
+			return nil, ErrOrganizationOwnersOnly
+		}
+		return nil, ErrOrganizationForbidden
+	}
+	return organization, nil
+}
+
+func (ogs *OrganizationService) loadOrganization(organizationID uuid.UUID) (*models.Organization, error) {
+	var organization models.Organization
+	err := ogs.db.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User").
+		Preload("Drives", func(db *gorm.DB) *gorm.DB { return db.Select("id", "organization_id") }).
+		Where("id = ?", organizationID).First(&organization).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// organizationMemberRole returns the user's role in an organization loaded with its members,
+// empty for non-members
+func organizationMemberRole(organization *models.Organization, userID uuid.UUID) models.OrganizationRole {
+	for _, member := range organization.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+func ownerCount(organization *models.Organization) int {
+	count := 0
+	for _, member := range organization.Members {
+		if member.Role == models.OrganizationRoleOwner {
+			count++
+		}
+	}
+	return count
+}
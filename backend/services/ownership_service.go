@@ -0,0 +1,756 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// ownershipBatchSize bounds the IN lists of bulk updates, account transfers can touch many rows
+const ownershipBatchSize = 500
+
+var (
+	ErrOwnershipTransferNotFound   = errors.New("ownership transfer not found")
+	ErrOwnershipTransferNotPending = errors.New("ownership transfer is no longer pending")
+	ErrOwnershipTransferPending    = errors.New("a transfer of this item is already pending")
+	ErrOwnershipTarget             = errors.New("specify exactly one item to transfer")
+	ErrOwnershipItemNotFound       = errors.New("file or folder not found")
+	ErrOwnershipUserNotFound       = errors.New("user not found")
+	ErrOwnershipInvalidRecipient   = errors.New("recipient must be another active user")
+	ErrOwnershipNothingToTransfer  = errors.New("user owns no files or folders")
+)
+
+// OwnershipService moves files and folder trees from one owner to another. Everything below a
+// transferred folder moves with it, so folder trees keep a single owner. Collaborators and share
+// links carry over, and the stored content stays where it is, see File.StorageKey.
+type OwnershipService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewOwnershipService() *OwnershipService {
+	return &OwnershipService{
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// RequestTransfer offers one of the owner's files or folders to another user, who has to accept it
+func (ows *OwnershipService) RequestTransfer(ownerID uuid.UUID, req models.OwnershipTransferCreateRequest) (*models.OwnershipTransfer, error) {
+	if (req.FileID == nil) == (req.FolderID == nil) {
+		return nil, ErrOwnershipTarget
+	}
+
+	name, itemOwnerID, err := ows.loadItem(req.FileID, req.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	if itemOwnerID != ownerID {
+		return nil, ErrOwnershipItemNotFound
+	}
+	if err := ows.checkRecipient(ownerID, req.ToUserID); err != nil {
+		return nil, err
+	}
+
+	var pending int64
+	query := ows.db.Model(&models.OwnershipTransfer{}).Where("status = ?", models.OwnershipTransferPending)
+	if req.FileID != nil {
+		query = query.Where("file_id = ?", *req.FileID)
+	} else {
+		query = query.Where("folder_id = ?", *req.FolderID)
+	}
+	if err := query.Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, ErrOwnershipTransferPending
+	}
+
+	transfer := &models.OwnershipTransfer{
+		FileID:        req.FileID,
+		FolderID:      req.FolderID,
+		ItemName:      name,
+		FromUserID:    ownerID,
+		ToUserID:      req.ToUserID,
+		InitiatedByID: ownerID,
+		KeepAsEditor:  req.KeepAsEditor,
+		Message:       req.Message,
+		Status:        models.OwnershipTransferPending,
+	}
+	if err := ows.db.Create(transfer).Error; err != nil {
+		return nil, err
+	}
+	ows.loadRelations(transfer)
+
+	ows.notify(transfer, transfer.ToUserID, "Ownership transfer request",
+		fmt.Sprintf("%s wants to make you the owner of %s", transfer.FromUser.GetFullName(), transfer.ItemName))
+	return transfer, nil
+}
+
+// ListTransfers returns the transfers the user sends (outgoing), receives (incoming) or both
+func (ows *OwnershipService) ListTransfers(userID uuid.UUID, direction, status string, page, limit int) ([]models.OwnershipTransfer, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		switch direction {
+		case "incoming":
+			db = db.Where("to_user_id = ?", userID)
+		case "outgoing":
+			db = db.Where("from_user_id = ?", userID)
+		default:
+			db = db.Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+		}
+		if status != "" {
+			db = db.Where("status = ?", status)
+		}
+		return db
+	}
+
+	var total int64
+	if err := ows.db.Model(&models.OwnershipTransfer{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []models.OwnershipTransfer
+	offset := (page - 1) * limit
+	err := ows.db.Scopes(filter).Preload("FromUser").Preload("ToUser").Preload("InitiatedBy").
+		Order("created_at DESC").Offset(offset).Limit(limit).Find(&transfers).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return transfers, total, nil
+}
+
+// GetTransfer returns a transfer the user sends or receives
+func (ows *OwnershipService) GetTransfer(userID, transferID uuid.UUID) (*models.OwnershipTransfer, error) {
+	var transfer models.OwnershipTransfer
+	err := ows.db.Where("id = ? AND (from_user_id = ? OR to_user_id = ?)", transferID, userID, userID).
+		First(&transfer).Error
+	if err != nil {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	ows.loadRelations(&transfer)
+	return &transfer, nil
+}
+
+// AcceptTransfer makes the recipient the owner. A transfer whose item was deleted, trashed or
+// changed hands in the meantime is cancelled instead.
+func (ows *OwnershipService) AcceptTransfer(userID, transferID uuid.UUID) (*models.OwnershipTransfer, error) {
+	transfer, err := ows.pendingTransfer(transferID, "to_user_id", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, itemOwnerID, err := ows.loadItem(transfer.FileID, transfer.FolderID)
+	if err == nil && itemOwnerID != transfer.FromUserID {
+		err = ErrOwnershipItemNotFound
+	}
+	if errors.Is(err, ErrOwnershipItemNotFound) {
+		ows.setStatus(ows.db, transfer, models.OwnershipTransferCancelled)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = ows.db.Transaction(func(tx *gorm.DB) error {
+		if err := ows.setStatus(tx, transfer, models.OwnershipTransferAccepted); err != nil {
+			return err
+		}
+		return ows.reassign(tx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	ows.loadRelations(transfer)
+	ows.notify(transfer, transfer.FromUserID, "Ownership transfer accepted",
+		fmt.Sprintf("%s is now the owner of %s", transfer.ToUser.GetFullName(), transfer.ItemName))
+	return transfer, nil
+}
+
+// DeclineTransfer lets the recipient turn a transfer down
+func (ows *OwnershipService) DeclineTransfer(userID, transferID uuid.UUID) (*models.OwnershipTransfer, error) {
+	transfer, err := ows.pendingTransfer(transferID, "to_user_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ows.setStatus(ows.db, transfer, models.OwnershipTransferDeclined); err != nil {
+		return nil, err
+	}
+
+	ows.loadRelations(transfer)
+	ows.notify(transfer, transfer.FromUserID, "Ownership transfer declined",
+		fmt.Sprintf("%s declined to become the owner of %s", transfer.ToUser.GetFullName(), transfer.ItemName))
+	return transfer, nil
+}
+
+// CancelTransfer lets the owner withdraw a transfer before it is accepted
+func (ows *OwnershipService) CancelTransfer(userID, transferID uuid.UUID) (*models.OwnershipTransfer, error) {
+	transfer, err := ows.pendingTransfer(transferID, "from_user_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ows.setStatus(ows.db, transfer, models.OwnershipTransferCancelled); err != nil {
+		return nil, err
+	}
+
+	ows.loadRelations(transfer)
+	ows.notify(transfer, transfer.ToUserID, "Ownership transfer cancelled",
+		fmt.Sprintf("%s cancelled the transfer of %s", transfer.FromUser.GetFullName(), transfer.ItemName))
+	return transfer, nil
+}
+
+// ForceTransfer moves a file, a folder tree or everything a user owns to another user without
+// asking either of them. Everything from an account lands in a new folder in the recipient's
+// top level. Pending transfers of the moved items are cancelled.
+func (ows *OwnershipService) ForceTransfer(adminID uuid.UUID, req models.AdminOwnershipTransferRequest) (*models.OwnershipTransfer, error) {
+	targets := 0
+	for _, id := range []*uuid.UUID{req.FileID, req.FolderID, req.FromUserID} {
+		if id != nil {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, ErrOwnershipTarget
+	}
+
+	var name string
+	var fromID uuid.UUID
+	if req.FromUserID != nil {
+		var from models.User
+		if err := ows.db.Where("id = ?", *req.FromUserID).First(&from).Error; err != nil {
+			return nil, ErrOwnershipUserNotFound
+		}
+		var owned int64
+		ows.db.Model(&models.Folder{}).Scopes(models.OwnedBy(from.ID)).Count(&owned)
+		if owned == 0 {
+			ows.db.Model(&models.File{}).Scopes(models.OwnedBy(from.ID)).Count(&owned)
+		}
+		if owned == 0 {
+			return nil, ErrOwnershipNothingToTransfer
+		}
+		name, fromID = from.GetFullName(), from.ID
+	} else {
+		var err error
+		name, fromID, err = ows.loadItem(req.FileID, req.FolderID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := ows.checkRecipient(fromID, req.ToUserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	transfer := &models.OwnershipTransfer{
+		FileID:        req.FileID,
+		FolderID:      req.FolderID,
+		ItemName:      name,
+		FromUserID:    fromID,
+		ToUserID:      req.ToUserID,
+		InitiatedByID: adminID,
+		Forced:        true,
+		KeepAsEditor:  req.KeepAsEditor,
+		Message:       req.Message,
+		Status:        models.OwnershipTransferAccepted,
+		RespondedAt:   &now,
+	}
+	err := ows.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+		if err := ows.reassign(tx, transfer); err != nil {
+			return err
+		}
+
+		stale := tx.Model(&models.OwnershipTransfer{}).
+			Where("from_user_id = ? AND status = ?", fromID, models.OwnershipTransferPending)
+		switch {
+		case req.FileID != nil:
+			stale = stale.Where("file_id = ?", *req.FileID)
+		case req.FolderID != nil:
+			stale = stale.Where("folder_id = ?", *req.FolderID)
+		}
+		return stale.Updates(map[string]interface{}{
+			"status":       models.OwnershipTransferCancelled,
+			"responded_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	ows.loadRelations(transfer)
+	ows.notify(transfer, transfer.ToUserID, "Ownership transferred to you",
+		fmt.Sprintf("An administrator made you the owner of %s", transfer.ItemName))
+	ows.notify(transfer, transfer.FromUserID, "Ownership transferred",
+		fmt.Sprintf("An administrator transferred %s to %s", transfer.ItemName, transfer.ToUser.GetFullName()))
+	return transfer, nil
+}
+
+// reassign moves the items of the transfer to the recipient and records how many were moved.
+// The transferred file or folder is moved to the recipient's top level, or for account transfers
+// everything is moved into a new folder there. Names that collide get a counter appended.
+func (ows *OwnershipService) reassign(tx *gorm.DB, transfer *models.OwnershipTransfer) error {
+	fromID, toID := transfer.FromUserID, transfer.ToUserID
+
+	var folders []models.Folder
+	var files []models.File
+	var destination *models.Folder
+	switch {
+	case transfer.FileID != nil:
+		if err := tx.Where("id = ?", *transfer.FileID).Find(&files).Error; err != nil {
+			return err
+		}
+	case transfer.FolderID != nil:
+		var err error
+		if folders, err = ows.subtree(tx, *transfer.FolderID, fromID); err != nil {
+			return err
+		}
+		err = inBatches(folderIDs(folders), func(batch []uuid.UUID) error {
+			var found []models.File
+			err := tx.Where("folder_id IN ?", batch).Scopes(models.OwnedBy(fromID)).Find(&found).Error
+			files = append(files, found...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		if err := tx.Scopes(models.OwnedBy(fromID)).Find(&folders).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(models.OwnedBy(fromID)).Find(&files).Error; err != nil {
+			return err
+		}
+		name := "Transferred from " + transfer.ItemName
+		if folderNameTaken(tx, toID, nil, name) {
+			name = uniqueFolderName(tx, toID, nil, name)
+		}
+		destination = &models.Folder{UserID: toID, Name: name}
+		if err := tx.Create(destination).Error; err != nil {
+			return err
+		}
+		transfer.ContainerFolderID = &destination.ID
+	}
+
+	var destinationID *uuid.UUID
+	destinationPath := ""
+	if destination != nil {
+		destinationID, destinationPath = &destination.ID, destination.Path
+	}
+
+	// Folders are updated parents first so every path can be derived from the parent's new path
+	inScope := make(map[uuid.UUID]bool, len(folders))
+	children := make(map[uuid.UUID][]*models.Folder)
+	for i := range folders {
+		inScope[folders[i].ID] = true
+	}
+	var queue []*models.Folder
+	for i := range folders {
+		folder := &folders[i]
+		if folder.ParentID != nil && inScope[*folder.ParentID] {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder)
+		} else {
+			queue = append(queue, folder)
+		}
+	}
+
+	paths := make(map[uuid.UUID]string, len(folders))
+	for len(queue) > 0 {
+		folder := queue[0]
+		queue = queue[1:]
+
+		columns := map[string]interface{}{"user_id": toID}
+		if folder.ParentID == nil || !inScope[*folder.ParentID] {
+			name := folder.Name
+			if folderNameTaken(tx, toID, destinationID, name) {
+				name = uniqueFolderName(tx, toID, destinationID, name)
+			}
+			columns["parent_id"] = destinationID
+			columns["name"] = name
+			paths[folder.ID] = destinationPath + "/" + name
+		} else {
+			paths[folder.ID] = paths[*folder.ParentID] + "/" + folder.Name
+		}
+		columns["path"] = paths[folder.ID]
+
+		if err := tx.Model(&models.Folder{}).Where("id = ?", folder.ID).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		queue = append(queue, children[folder.ID]...)
+	}
+	// Folders in a broken parent cycle are not reachable from a root, they still change owner
+	for i := range folders {
+		if _, done := paths[folders[i].ID]; !done {
+			if err := tx.Model(&models.Folder{}).Where("id = ?", folders[i].ID).UpdateColumn("user_id", toID).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// The storage the files take moves to the recipient's usage, their quota is not enforced
+	var movedSize int64
+	err := inBatches(fileIDs(files), func(batch []uuid.UUID) error {
+		usage, err := usageByOwner(tx.Where("id IN ?", batch))
+		for _, owner := range usage {
+			movedSize += owner.Total
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := releaseUsage(tx, fromID, movedSize); err != nil {
+		return err
+	}
+	if err := addUsage(tx, toID, movedSize); err != nil {
+		return err
+	}
+
+	for i := range files {
+		file := &files[i]
+		columns := map[string]interface{}{"user_id": toID}
+		if file.StorageKey == "" {
+			// The object stays under the previous owner's prefix
+			columns["storage_key"] = file.ObjectKey()
+		}
+		if file.FolderID == nil || !inScope[*file.FolderID] {
+			name := file.OriginalName
+			if fileNameTaken(tx, toID, destinationID, name) {
+				name = uniqueFileName(tx, toID, destinationID, name)
+			}
+			columns["folder_id"] = destinationID
+			columns["original_name"] = name
+		}
+		if err := tx.Model(&models.File{}).Where("id = ?", file.ID).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := ows.carryOverAccess(tx, transfer, folders, files, destination); err != nil {
+		return err
+	}
+
+	transfer.FolderCount = len(folders)
+	transfer.FileCount = len(files)
+	return tx.Model(transfer).UpdateColumns(map[string]interface{}{
+		"folder_count":        transfer.FolderCount,
+		"file_count":          transfer.FileCount,
+		"container_folder_id": transfer.ContainerFolderID,
+	}).Error
+}
+
+// carryOverAccess keeps everyone's access to the moved items. Grants on the folders the item was
+// taken out of are copied onto it, the recipient's own grants are dropped as they are the owner
+// now, and share links and file requests follow their items to the new owner.
+func (ows *OwnershipService) carryOverAccess(tx *gorm.DB, transfer *models.OwnershipTransfer, folders []models.Folder, files []models.File, destination *models.Folder) error {
+	fromID, toID := transfer.FromUserID, transfer.ToUserID
+	movedFolders, movedFiles := folderIDs(folders), fileIDs(files)
+
+	var grantTarget models.Collaborator
+	var formerParentID *uuid.UUID
+	switch {
+	case transfer.FileID != nil:
+		grantTarget.FileID = transfer.FileID
+		if len(files) > 0 {
+			formerParentID = files[0].FolderID
+		}
+	case transfer.FolderID != nil:
+		grantTarget.FolderID = transfer.FolderID
+		for _, folder := range folders {
+			if folder.ID == *transfer.FolderID {
+				formerParentID = folder.ParentID
+			}
+		}
+	default:
+		grantTarget.FolderID = &destination.ID
+	}
+
+	if formerParentID != nil {
+		if err := ows.copyInheritedGrants(tx, *formerParentID, grantTarget, fromID, toID); err != nil {
+			return err
+		}
+	}
+
+	err := inBatches(movedFolders, func(batch []uuid.UUID) error {
+		return tx.Where("user_id = ? AND folder_id IN ?", toID, batch).Delete(&models.Collaborator{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	err = inBatches(movedFiles, func(batch []uuid.UUID) error {
+		return tx.Where("user_id = ? AND file_id IN ?", toID, batch).Delete(&models.Collaborator{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if transfer.KeepAsEditor {
+		grantTarget.UserID = &fromID
+		if err := grantAtLeast(tx, grantTarget, models.RoleEditor, nil); err != nil {
+			return err
+		}
+	}
+
+	if transfer.FileID == nil && transfer.FolderID == nil {
+		if err := tx.Model(&models.ShareLink{}).Where("user_id = ?", fromID).UpdateColumn("user_id", toID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FileRequest{}).Where("user_id = ?", fromID).UpdateColumn("user_id", toID).Error
+	}
+
+	// Bundle links of the previous owner keep their other items and lose the moved ones
+	err = inBatches(movedFiles, func(batch []uuid.UUID) error {
+		return tx.Model(&models.ShareLink{}).Where("user_id = ? AND file_id IN ?", fromID, batch).
+			UpdateColumn("user_id", toID).Error
+	})
+	if err != nil {
+		return err
+	}
+	return inBatches(movedFolders, func(batch []uuid.UUID) error {
+		if err := tx.Model(&models.ShareLink{}).Where("user_id = ? AND folder_id IN ?", fromID, batch).
+			UpdateColumn("user_id", toID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FileRequest{}).Where("user_id = ? AND folder_id IN ?", fromID, batch).
+			UpdateColumn("user_id", toID).Error
+	})
+}
+
+// copyInheritedGrants gives the collaborators of the folders above the former location of an
+// item the same role on the item itself, so moving it out of the tree does not lock them out
+func (ows *OwnershipService) copyInheritedGrants(tx *gorm.DB, parentID uuid.UUID, target models.Collaborator, fromID, toID uuid.UUID) error {
+	chain := make([]uuid.UUID, 0, 8)
+	visited := make(map[uuid.UUID]bool)
+	next := &parentID
+	for next != nil && !visited[*next] && len(chain) < maxFolderDepth {
+		var folder models.Folder
+		if err := tx.Select("id", "parent_id").Where("id = ?", *next).First(&folder).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return err
+		}
+		visited[folder.ID] = true
+		chain = append(chain, folder.ID)
+		next = folder.ParentID
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+
+	var grants []models.Collaborator
+	err := tx.Where("folder_id IN ? AND (user_id IS NULL OR user_id NOT IN ?) AND (expires_at IS NULL OR expires_at > ?)",
+		chain, []uuid.UUID{fromID, toID}, time.Now()).Find(&grants).Error
+	if err != nil {
+		return err
+	}
+
+	// Keep the most privileged grant per user or group, and of equal ones the longest lasting
+	type principal struct{ userID, groupID uuid.UUID }
+	best := make(map[principal]models.Collaborator)
+	for _, grant := range grants {
+		var key principal
+		if grant.UserID != nil {
+			key.userID = *grant.UserID
+		} else if grant.GroupID != nil {
+			key.groupID = *grant.GroupID
+		}
+		current, ok := best[key]
+		if !ok || current.Role.Max(grant.Role) != current.Role ||
+			(current.Role == grant.Role && current.ExpiresAt != nil && (grant.ExpiresAt == nil || grant.ExpiresAt.After(*current.ExpiresAt))) {
+			best[key] = grant
+		}
+	}
+	for _, grant := range best {
+		target.UserID, target.GroupID = grant.UserID, grant.GroupID
+		if err := grantAtLeast(tx, target, grant.Role, grant.ExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subtree returns the folder and all folders below it that belong to ownerID
+func (ows *OwnershipService) subtree(tx *gorm.DB, folderID, ownerID uuid.UUID) ([]models.Folder, error) {
+	var root models.Folder
+	if err := tx.Where("id = ?", folderID).Scopes(models.OwnedBy(ownerID)).First(&root).Error; err != nil {
+		return nil, ErrOwnershipItemNotFound
+	}
+
+	folders := []models.Folder{root}
+	visited := map[uuid.UUID]bool{root.ID: true}
+	frontier := []uuid.UUID{root.ID}
+	for len(frontier) > 0 {
+		var next []uuid.UUID
+		err := inBatches(frontier, func(batch []uuid.UUID) error {
+			var found []models.Folder
+			if err := tx.Where("parent_id IN ?", batch).Scopes(models.OwnedBy(ownerID)).Find(&found).Error; err != nil {
+				return err
+			}
+			for _, folder := range found {
+				if !visited[folder.ID] {
+					visited[folder.ID] = true
+					folders = append(folders, folder)
+					next = append(next, folder.ID)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		frontier = next
+	}
+	return folders, nil
+}
+
+// loadItem returns the name and owner of the file or folder, which must not be in the trash
+func (ows *OwnershipService) loadItem(fileID, folderID *uuid.UUID) (string, uuid.UUID, error) {
+	if fileID != nil {
+		var file models.File
+		if err := ows.db.Where("id = ? AND is_trashed = ?", *fileID, false).First(&file).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", uuid.Nil, ErrOwnershipItemNotFound
+			}
+			return "", uuid.Nil, err
+		}
+		return file.OriginalName, file.OwnerID(), nil
+	}
+
+	var folder models.Folder
+	if err := ows.db.Where("id = ? AND is_trashed = ?", *folderID, false).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", uuid.Nil, ErrOwnershipItemNotFound
+		}
+		return "", uuid.Nil, err
+	}
+	return folder.Name, folder.OwnerID(), nil
+}
+
+// checkRecipient makes sure the new owner is an active user other than the current owner
+func (ows *OwnershipService) checkRecipient(fromID, toID uuid.UUID) error {
+	if fromID == toID {
+		return ErrOwnershipInvalidRecipient
+	}
+	var recipient models.User
+	if err := ows.db.Where("id = ? AND is_active = ?", toID, true).First(&recipient).Error; err != nil {
+		return ErrOwnershipInvalidRecipient
+	}
+	return nil
+}
+
+// pendingTransfer loads a pending transfer in which the user is on the given side
+func (ows *OwnershipService) pendingTransfer(transferID uuid.UUID, side string, userID uuid.UUID) (*models.OwnershipTransfer, error) {
+	var transfer models.OwnershipTransfer
+	if err := ows.db.Where("id = ? AND "+side+" = ?", transferID, userID).First(&transfer).Error; err != nil {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	if transfer.Status != models.OwnershipTransferPending {
+		return nil, ErrOwnershipTransferNotPending
+	}
+	return &transfer, nil
+}
+
+// setStatus resolves a pending transfer. It fails when someone else resolved it first.
+func (ows *OwnershipService) setStatus(db *gorm.DB, transfer *models.OwnershipTransfer, status models.OwnershipTransferStatus) error {
+	now := time.Now()
+	result := db.Model(&models.OwnershipTransfer{}).
+		Where("id = ? AND status = ?", transfer.ID, models.OwnershipTransferPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOwnershipTransferNotPending
+	}
+	transfer.Status = status
+	transfer.RespondedAt = &now
+	return nil
+}
+
+func (ows *OwnershipService) loadRelations(transfer *models.OwnershipTransfer) {
+	ows.db.Where("id = ?", transfer.FromUserID).First(&transfer.FromUser)
+	ows.db.Where("id = ?", transfer.ToUserID).First(&transfer.ToUser)
+	ows.db.Where("id = ?", transfer.InitiatedByID).First(&transfer.InitiatedBy)
+}
+
+func (ows *OwnershipService) notify(transfer *models.OwnershipTransfer, userID uuid.UUID, title, message string) {
+	if _, err := ows.notificationService.Notify(userID, models.NotificationOwnershipTransfer, title, message,
+		models.ResourceOwnership, &transfer.ID); err != nil {
+		config.GetLogger().Error("Failed to send ownership transfer notification", "error", err, "transfer_id", transfer.ID)
+	}
+}
+
+// grantAtLeast gives the user or group of target at least the role on its file or folder. An
+// existing grant with a lower role, or one that has expired, is replaced.
+func grantAtLeast(tx *gorm.DB, target models.Collaborator, role models.CollaboratorRole, expiresAt *time.Time) error {
+	var existing models.Collaborator
+	var query *gorm.DB
+	if target.FileID != nil {
+		query = tx.Where("file_id = ?", *target.FileID)
+	} else {
+		query = tx.Where("folder_id = ?", *target.FolderID)
+	}
+	if target.UserID != nil {
+		query = query.Where("user_id = ?", *target.UserID)
+	} else {
+		query = query.Where("group_id = ?", *target.GroupID)
+	}
+
+	err := query.First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.Collaborator{
+			FileID:    target.FileID,
+			FolderID:  target.FolderID,
+			UserID:    target.UserID,
+			GroupID:   target.GroupID,
+			Role:      role,
+			ExpiresAt: expiresAt,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	expired := existing.ExpiresAt != nil && !existing.ExpiresAt.After(time.Now())
+	if !expired && existing.Role.Includes(role) {
+		return nil
+	}
+	return tx.Model(&existing).Updates(map[string]interface{}{"role": role, "expires_at": expiresAt}).Error
+}
+
+// inBatches calls fn with consecutive slices of at most ownershipBatchSize IDs
+func inBatches(ids []uuid.UUID, fn func([]uuid.UUID) error) error {
+	for start := 0; start < len(ids); start += ownershipBatchSize {
+		end := min(start+ownershipBatchSize, len(ids))
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func folderIDs(folders []models.Folder) []uuid.UUID {
+	ids := make([]uuid.UUID, len(folders))
+	for i := range folders {
+		ids[i] = folders[i].ID
+	}
+	return ids
+}
+
+func fileIDs(files []models.File) []uuid.UUID {
+	ids := make([]uuid.UUID, len(files))
+	for i := range files {
+		ids[i] = files[i].ID
+	}
+	return ids
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPlanNotFound         = errors.New("plan not found")
+	ErrPlanNameTaken        = errors.New("a plan with this name already exists")
+	ErrPlanInUse            = errors.New("plan is assigned to users or organizations")
+	ErrPlanUserNotFound     = errors.New("user not found")
+	ErrPlanOrgNotFound      = errors.New("organization not found")
+	ErrPlanPasswordRequired = errors.New("your plan requires share links to have a password")
+	ErrPlanFileSizeTooLarge = errors.New("max_file_size exceeds the server's maximum file size")
+)
+
+// PlanService manages plans and resolves the limits in effect for a user. A user gets the plan
+// assigned to them, otherwise the plan of the first organization they joined that has one. The
+// content of a shared drive gets the plan of its organization. Every limit a plan leaves empty,
+// and every limit of users without a plan, comes from the server configuration. A quota set on
+// the user by an admin wins over the plan's.
+type PlanService struct {
+	db *gorm.DB
+}
+
+func NewPlanService() *PlanService {
+	return &PlanService{db: database.GetDB()}
+}
+
+// ListPlans returns every plan with how many users and organizations it is assigned to
+func (ps *PlanService) ListPlans() ([]models.PlanResponse, error) {
+	var plans []models.Plan
+	if err := ps.db.Order("name ASC").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.PlanResponse, 0, len(plans))
+	for _, plan := range plans {
+		responses = append(responses, ps.planResponse(&plan))
+	}
+	return responses, nil
+}
+
+// GetPlan returns a plan with how many users and organizations it is assigned to
+func (ps *PlanService) GetPlan(planID uuid.UUID) (*models.PlanResponse, error) {
+	plan, err := ps.loadPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	response := ps.planResponse(plan)
+	return &response, nil
+}
+
+// CreatePlan creates a plan, plan names are unique
+func (ps *PlanService) CreatePlan(req models.PlanRequest) (*models.PlanResponse, error) {
+	if err := checkPlanRequest(req); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if ps.nameTaken(name, uuid.Nil) {
+		return nil, ErrPlanNameTaken
+	}
+
+	plan := &models.Plan{
+		Name:                 name,
+		Description:          req.Description,
+		MaxFileSize:          req.MaxFileSize,
+		StorageQuota:         req.StorageQuota,
+		MaxShareLinkDays:     req.MaxShareLinkDays,
+		RequireSharePassword: req.RequireSharePassword,
+		VersionRetention:     req.VersionRetention,
+	}
+	if err := ps.db.Create(plan).Error; err != nil {
+		return nil, err
+	}
+
+	response := ps.planResponse(plan)
+	return &response, nil
+}
+
+// UpdatePlan replaces every field of a plan. The new limits apply to uploads, share links and
+// versions from then on, existing content and links are kept as they are.
+func (ps *PlanService) UpdatePlan(planID uuid.UUID, req models.PlanRequest) (*models.PlanResponse, error) {
+	if err := checkPlanRequest(req); err != nil {
+		return nil, err
+	}
+	plan, err := ps.loadPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if ps.nameTaken(name, plan.ID) {
+		return nil, ErrPlanNameTaken
+	}
+
+	// Select writes the limits that were cleared as well
+	plan.Name = name
+	plan.Description = req.Description
+	plan.MaxFileSize = req.MaxFileSize
+	plan.StorageQuota = req.StorageQuota
+	plan.MaxShareLinkDays = req.MaxShareLinkDays
+	plan.RequireSharePassword = req.RequireSharePassword
+	plan.VersionRetention = req.VersionRetention
+	if err := ps.db.Model(plan).Select("name", "description", "max_file_size", "storage_quota",
+		"max_share_link_days", "require_share_password", "version_retention").Updates(plan).Error; err != nil {
+		return nil, err
+	}
+
+	response := ps.planResponse(plan)
+	return &response, nil
+}
+
+// checkPlanRequest rejects limits beyond what the server supports
+func checkPlanRequest(req models.PlanRequest) error {
+	if req.MaxFileSize != nil && *req.MaxFileSize > config.AppConfig.Upload.MaxFileSize {
+		return ErrPlanFileSizeTooLarge
+	}
+	return nil
+}
+
+// DeletePlan deletes a plan that is not assigned to anyone
+func (ps *PlanService) DeletePlan(planID uuid.UUID) (*models.Plan, error) {
+	plan, err := ps.loadPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	response := ps.planResponse(plan)
+	if response.UserCount > 0 || response.OrganizationCount > 0 {
+		return nil, ErrPlanInUse
+	}
+
+	if err := ps.db.Delete(plan).Error; err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// AssignToUser assigns a plan to a user, nil removes their plan
+func (ps *PlanService) AssignToUser(userID uuid.UUID, planID *uuid.UUID) (*models.PlanLimits, error) {
+	if planID != nil {
+		if _, err := ps.loadPlan(*planID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := ps.db.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("plan_id", planID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPlanUserNotFound
+	}
+	return ps.Limits(userID)
+}
+
+// AssignToOrganization assigns a plan to an organization, nil removes its plan
+func (ps *PlanService) AssignToOrganization(orgID uuid.UUID, planID *uuid.UUID) (*models.Organization, error) {
+	if planID != nil {
+		if _, err := ps.loadPlan(*planID); err != nil {
+			return nil, err
+		}
+	}
+
+	var org models.Organization
+	if err := ps.db.Where("id = ?", orgID).First(&org).Error; err != nil {
+		return nil, ErrPlanOrgNotFound
+	}
+	if err := ps.db.Model(&org).UpdateColumn("plan_id", planID).Error; err != nil {
+		return nil, err
+	}
+	org.PlanID = planID
+	return &org, nil
+}
+
+// Limits returns the limits in effect for a user, or for the content of a shared drive when
+// ownerID is a drive
+func (ps *PlanService) Limits(ownerID uuid.UUID) (*models.PlanLimits, error) {
+	var drives []models.SharedDrive
+	if err := ps.db.Where("id = ?", ownerID).Limit(1).Find(&drives).Error; err != nil {
+		return nil, err
+	}
+	if len(drives) > 0 {
+		limits := resolveDriveLimits(ps.db, &drives[0])
+		return &limits, nil
+	}
+
+	var user models.User
+	if err := ps.db.Where("id = ?", ownerID).First(&user).Error; err != nil {
+		return nil, ErrPlanUserNotFound
+	}
+	limits := resolveLimits(ps.db, &user)
+	return &limits, nil
+}
+
+// CheckShareLink applies the user's plan to the expiry and password of a share link. A link
+// without an expiry gets the longest lifetime the plan allows.
+func (ps *PlanService) CheckShareLink(userID uuid.UUID, expiresAt *time.Time, hasPassword bool) (*time.Time, error) {
+	limits, err := ps.Limits(userID)
+	if err != nil {
+		return nil, err
+	}
+	if limits.RequireSharePassword && !hasPassword {
+		return nil, ErrPlanPasswordRequired
+	}
+	if limits.MaxShareLinkDays > 0 {
+		latest := time.Now().AddDate(0, 0, limits.MaxShareLinkDays)
+		if expiresAt == nil {
+			return &latest, nil
+		}
+		if expiresAt.After(latest) {
+			return nil, fmt.Errorf("your plan requires share links to expire within %d days", limits.MaxShareLinkDays)
+		}
+	}
+	return expiresAt, nil
+}
+
+// resolveLimits returns the limits in effect for a user, see PlanService
+func resolveLimits(db *gorm.DB, user *models.User) models.PlanLimits {
+	limits := limitsOf(planOf(db, user))
+	if user.StorageQuota != nil {
+		limits.StorageQuota = *user.StorageQuota
+	}
+	return limits
+}
+
+// resolveDriveLimits returns the limits in effect for the content of a shared drive, see
+// PlanService
+func resolveDriveLimits(db *gorm.DB, drive *models.SharedDrive) models.PlanLimits {
+	var plan *models.Plan
+	var organizationPlan models.Plan
+	err := db.Select("plans.*").
+		Joins("JOIN organizations ON organizations.plan_id = plans.id AND organizations.deleted_at IS NULL").
+		Where("organizations.id = ?", drive.OrganizationID).First(&organizationPlan).Error
+	if err == nil {
+		plan = &organizationPlan
+	}
+
+	limits := limitsOf(plan)
+	if drive.StorageQuota != nil {
+		limits.StorageQuota = *drive.StorageQuota
+	}
+	return limits
+}
+
+// limitsOf returns the limits of a plan, the configuration gives every limit it leaves empty.
+// plan may be nil. The file size limit never goes beyond MAX_FILE_SIZE, also for plans saved
+// before it was lowered.
+func limitsOf(plan *models.Plan) models.PlanLimits {
+	limits := models.PlanLimits{
+		MaxFileSize:  utils.UploadSizeLimit(),
+		StorageQuota: config.AppConfig.Quota.DefaultQuota,
+	}
+
+	if plan != nil {
+		limits.PlanID = &plan.ID
+		limits.PlanName = plan.Name
+		if plan.MaxFileSize != nil {
+			limits.MaxFileSize = utils.ClampUploadSize(*plan.MaxFileSize)
+		}
+		if plan.StorageQuota != nil {
+			limits.StorageQuota = *plan.StorageQuota
+		}
+		if plan.MaxShareLinkDays != nil {
+			limits.MaxShareLinkDays = *plan.MaxShareLinkDays
+		}
+		limits.RequireSharePassword = plan.RequireSharePassword
+		if plan.VersionRetention != nil {
+			limits.VersionRetention = *plan.VersionRetention
+		}
+	}
+	return limits
+}
+
+// planOf returns the plan of a user, see PlanService, or nil when they have none
+func planOf(db *gorm.DB, user *models.User) *models.Plan {
+	var plan models.Plan
+	if user.PlanID != nil {
+		if err := db.Where("id = ?", *user.PlanID).First(&plan).Error; err == nil {
+			return &plan
+		}
+	}
+
+	err := db.Select("plans.*").
+		Joins("JOIN organizations ON organizations.plan_id = plans.id AND organizations.deleted_at IS NULL").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", user.ID).
+		Order("organization_members.created_at ASC").
+		First(&plan).Error
+	if err != nil {
+		return nil
+	}
+	return &plan
+}
+
+func (ps *PlanService) planResponse(plan *models.Plan) models.PlanResponse {
+	response := models.PlanResponse{Plan: *plan}
+	ps.db.Model(&models.User{}).Where("plan_id = ?", plan.ID).Count(&response.UserCount)
+	ps.db.Model(&models.Organization{}).Where("plan_id = ?", plan.ID).Count(&response.OrganizationCount)
+	return response
+}
+
+func (ps *PlanService) loadPlan(planID uuid.UUID) (*models.Plan, error) {
+	var plan models.Plan
+	if err := ps.db.Where("id = ?", planID).First(&plan).Error; err != nil {
+		return nil, ErrPlanNotFound
+	}
+	return &plan, nil
+}
+
+func (ps *PlanService) nameTaken(name string, exceptID uuid.UUID) bool {
+	var count int64
+	ps.db.Model(&models.Plan{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
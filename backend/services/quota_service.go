@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuotaExceeded      = errors.New("storage quota exceeded")
+	ErrQuotaUserNotFound  = errors.New("user not found")
+	ErrQuotaDriveNotFound = errors.New("shared drive not found")
+)
+
+// storedSizeExpr is the bytes a file takes in storage. A file with versions takes the sum of its
+// versions, the latest of which holds its current content, and its own size otherwise.
+const storedSizeExpr = "COALESCE((SELECT SUM(file_versions.file_size) FROM file_versions " +
+	"WHERE file_versions.file_id = files.id AND file_versions.deleted_at IS NULL), files.file_size)"
+
+// QuotaService keeps every user's storage usage counter and enforces their quota. Content is
+// charged to the owner of the file before it is stored and released when it is permanently
+// deleted, a background worker corrects counters that drifted. Shared drives keep their own
+// counter and quota for their content, owner IDs may be drive IDs throughout.
+type QuotaService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewQuotaService() *QuotaService {
+	return &QuotaService{
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// ownerUsage is the storage taken by files of one owner
+type ownerUsage struct {
+	OwnerID uuid.UUID
+	Total   int64
+}
+
+// usageByOwner returns the storage the files matched by query take, per owner
+func usageByOwner(query *gorm.DB) ([]ownerUsage, error) {
+	var usage []ownerUsage
+	err := query.Model(&models.File{}).
+		Select("COALESCE(drive_id, user_id) AS owner_id, COALESCE(SUM(" + storedSizeExpr + "), 0) AS total").
+		Group("COALESCE(drive_id, user_id)").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// quotaAccount is who storage is charged to: a user for their own content, a shared drive for the
+// content in it. Both keep their counter, quota override and warning threshold in the same columns.
+type quotaAccount struct {
+	user  *models.User
+	drive *models.SharedDrive
+}
+
+// loadQuotaAccount loads the account of a user or drive, failing with ErrQuotaUserNotFound when
+// ownerID is neither
+func loadQuotaAccount(db *gorm.DB, ownerID uuid.UUID) (*quotaAccount, error) {
+	var drives []models.SharedDrive
+	if err := db.Where("id = ?", ownerID).Limit(1).Find(&drives).Error; err != nil {
+		return nil, err
+	}
+	if len(drives) > 0 {
+		return &quotaAccount{drive: &drives[0]}, nil
+	}
+
+	var user models.User
+	if err := db.Where("id = ?", ownerID).First(&user).Error; err != nil {
+		return nil, ErrQuotaUserNotFound
+	}
+	return &quotaAccount{user: &user}, nil
+}
+
+// model returns the model the account's columns are on, for updates
+func (a *quotaAccount) model() interface{} {
+	if a.drive != nil {
+		return &models.SharedDrive{}
+	}
+	return &models.User{}
+}
+
+func (a *quotaAccount) used() int64 {
+	if a.drive != nil {
+		return a.drive.StorageUsed
+	}
+	return a.user.StorageUsed
+}
+
+func (a *quotaAccount) warnedPercent() int {
+	if a.drive != nil {
+		return a.drive.QuotaWarnedPercent
+	}
+	return a.user.QuotaWarnedPercent
+}
+
+// quota returns the account's quota in bytes, 0 when unlimited. An admin set quota wins over the
+// one of the plan, see PlanService.
+func (a *quotaAccount) quota(db *gorm.DB) int64 {
+	if a.drive != nil {
+		return resolveDriveLimits(db, a.drive).StorageQuota
+	}
+	return resolveLimits(db, a.user).StorageQuota
+}
+
+// accountModel returns the model that keeps the usage counter of ownerID, see quotaAccount
+func accountModel(db *gorm.DB, ownerID uuid.UUID) interface{} {
+	var count int64
+	db.Model(&models.SharedDrive{}).Where("id = ?", ownerID).Count(&count)
+	if count > 0 {
+		return &models.SharedDrive{}
+	}
+	return &models.User{}
+}
+
+// addUsage adds bytes to the owner's usage counter without checking their quota, for content that
+// changes owner
+func addUsage(db *gorm.DB, ownerID uuid.UUID, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+	return db.Model(accountModel(db, ownerID)).Where("id = ?", ownerID).
+		UpdateColumn("storage_used", gorm.Expr("storage_used + ?", bytes)).Error
+}
+
+// releaseUsage takes bytes off the owner's usage counter, it never goes below zero
+func releaseUsage(db *gorm.DB, ownerID uuid.UUID, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+	return db.Model(accountModel(db, ownerID)).Where("id = ?", ownerID).
+		UpdateColumn("storage_used", gorm.Expr("CASE WHEN storage_used > ? THEN storage_used - ? ELSE 0 END", bytes, bytes)).Error
+}
+
+// Reserve charges bytes to the owner's usage before they are stored, failing with ErrQuotaExceeded
+// when that takes the owner over their quota. Callers Release the bytes again if storing fails.
+func (qs *QuotaService) Reserve(ownerID uuid.UUID, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+
+	account, err := loadQuotaAccount(qs.db, ownerID)
+	if err != nil {
+		return err
+	}
+
+	// The check and the charge are one statement so concurrent uploads cannot both fit
+	query := qs.db.Model(account.model()).Where("id = ?", ownerID)
+	if quota := account.quota(qs.db); quota > 0 {
+		query = query.Where("storage_used + ? <= ?", bytes, quota)
+	}
+	result := query.UpdateColumn("storage_used", gorm.Expr("storage_used + ?", bytes))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuotaExceeded
+	}
+
+	qs.checkWarnings(ownerID)
+	return nil
+}
+
+// Release takes back bytes reserved for content that was not stored
+func (qs *QuotaService) Release(ownerID uuid.UUID, bytes int64) {
+	if err := releaseUsage(qs.db, ownerID, bytes); err != nil {
+		config.GetLogger().Error("Failed to release storage usage", "error", err, "owner_id", ownerID, "bytes", bytes)
+	}
+}
+
+// GetQuota returns the user's usage and quota
+func (qs *QuotaService) GetQuota(userID uuid.UUID) (*models.StorageQuotaResponse, error) {
+	var user models.User
+	if err := qs.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, ErrQuotaUserNotFound
+	}
+
+	response := quotaResponse(qs.db, &quotaAccount{user: &user})
+	response.UserID = &user.ID
+	response.IsDefault = user.StorageQuota == nil
+	return response, nil
+}
+
+// GetDriveQuota returns the usage and quota of a shared drive
+func (qs *QuotaService) GetDriveQuota(driveID uuid.UUID) (*models.StorageQuotaResponse, error) {
+	var drive models.SharedDrive
+	if err := qs.db.Where("id = ?", driveID).First(&drive).Error; err != nil {
+		return nil, ErrQuotaDriveNotFound
+	}
+
+	response := quotaResponse(qs.db, &quotaAccount{drive: &drive})
+	response.DriveID = &drive.ID
+	response.IsDefault = drive.StorageQuota == nil
+	return response, nil
+}
+
+// SetQuota overrides the user's quota, nil goes back to the configured default. Content already
+// stored is kept when the new quota is below the user's usage, only new content is rejected.
+func (qs *QuotaService) SetQuota(userID uuid.UUID, quota *int64) (*models.StorageQuotaResponse, error) {
+	if err := qs.setQuota(&models.User{}, userID, quota); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuotaUserNotFound
+		}
+		return nil, err
+	}
+	return qs.GetQuota(userID)
+}
+
+// SetDriveQuota overrides the quota of a shared drive, nil goes back to the quota of its
+// organization's plan or the configured default
+func (qs *QuotaService) SetDriveQuota(driveID uuid.UUID, quota *int64) (*models.StorageQuotaResponse, error) {
+	if err := qs.setQuota(&models.SharedDrive{}, driveID, quota); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuotaDriveNotFound
+		}
+		return nil, err
+	}
+	return qs.GetDriveQuota(driveID)
+}
+
+func (qs *QuotaService) setQuota(model interface{}, ownerID uuid.UUID, quota *int64) error {
+	var value interface{} = gorm.Expr("NULL")
+	if quota != nil {
+		value = *quota
+	}
+	result := qs.db.Model(model).Where("id = ?", ownerID).UpdateColumn("storage_quota", value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	qs.checkWarnings(ownerID)
+	return nil
+}
+
+// quotaResponse describes the usage of an account against its quota
+func quotaResponse(db *gorm.DB, account *quotaAccount) *models.StorageQuotaResponse {
+	used := account.used()
+	quota := account.quota(db)
+	response := &models.StorageQuotaResponse{
+		Used:      used,
+		Quota:     quota,
+		Unlimited: quota == 0,
+	}
+	if quota > 0 {
+		response.UsedPercent = float64(used) * 100 / float64(quota)
+		if used < quota {
+			response.Remaining = quota - used
+		}
+	}
+	return response
+}
+
+// ReconcileUsage recalculates every usage counter from the stored files and corrects the ones
+// that drifted, returning how many were corrected. It also fills in the counters of users that
+// had files before usage was counted. Content stored while it runs can be missed until the next
+// run.
+func (qs *QuotaService) ReconcileUsage() (int, error) {
+	usage, err := usageByOwner(qs.db)
+	if err != nil {
+		return 0, err
+	}
+	actual := make(map[uuid.UUID]int64, len(usage))
+	for _, owner := range usage {
+		actual[owner.OwnerID] = owner.Total
+	}
+
+	var users []models.User
+	if err := qs.db.Select("id", "storage_used").Where("storage_used <> 0 OR id IN (?)",
+		qs.db.Model(&models.File{}).Select("user_id").Where("drive_id IS NULL")).Find(&users).Error; err != nil {
+		return 0, err
+	}
+	var drives []models.SharedDrive
+	if err := qs.db.Select("id", "storage_used").Find(&drives).Error; err != nil {
+		return 0, err
+	}
+
+	counters := make(map[uuid.UUID]int64, len(users)+len(drives))
+	for _, user := range users {
+		counters[user.ID] = user.StorageUsed
+	}
+	for _, drive := range drives {
+		counters[drive.ID] = drive.StorageUsed
+	}
+
+	corrected := 0
+	for ownerID, used := range counters {
+		if used == actual[ownerID] {
+			continue
+		}
+		if err := qs.db.Model(accountModel(qs.db, ownerID)).Where("id = ?", ownerID).
+			UpdateColumn("storage_used", actual[ownerID]).Error; err != nil {
+			return corrected, err
+		}
+		qs.checkWarnings(ownerID)
+		corrected++
+	}
+	return corrected, nil
+}
+
+// RunReconcileWorker corrects usage counters once at start and then every interval until ctx is
+// done
+func (qs *QuotaService) RunReconcileWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := qs.ReconcileUsage()
+		if err != nil {
+			config.GetLogger().Error("Failed to reconcile storage usage", "error", err)
+		} else if count > 0 {
+			config.GetLogger().Info("Corrected storage usage", "users", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkWarnings notifies the user, or the managers of a drive, when usage reached a warning
+// threshold they were not warned about yet. Going back below a threshold lets it warn again later.
+func (qs *QuotaService) checkWarnings(ownerID uuid.UUID) {
+	account, err := loadQuotaAccount(qs.db, ownerID)
+	if err != nil {
+		return
+	}
+
+	used, warned := account.used(), account.warnedPercent()
+	quota := account.quota(qs.db)
+	reached := 0
+	if quota > 0 {
+		percent := used * 100 / quota
+		for _, threshold := range config.AppConfig.Quota.WarningPercents {
+			if percent >= int64(threshold) {
+				reached = threshold
+			}
+		}
+	}
+	if reached == warned {
+		return
+	}
+
+	// Only one of concurrent uploads crossing the same threshold sends the warning
+	result := qs.db.Model(account.model()).
+		Where("id = ? AND quota_warned_percent = ?", ownerID, warned).
+		UpdateColumn("quota_warned_percent", reached)
+	if result.Error != nil || result.RowsAffected == 0 || reached < warned {
+		return
+	}
+
+	usage := fmt.Sprintf("%s of %s", utils.FormatFileSize(used), utils.FormatFileSize(quota))
+	recipients := []uuid.UUID{ownerID}
+	title := fmt.Sprintf("You have used %d%% of your storage", reached)
+	message := fmt.Sprintf("You are using %s. Uploads that do not fit in your quota are rejected.", usage)
+	resourceType := models.ResourceUser
+	if drive := account.drive; drive != nil {
+		recipients = nil
+		qs.db.Model(&models.DriveMember{}).Where("drive_id = ? AND role = ?", drive.ID, models.DriveRoleManager).
+			Pluck("user_id", &recipients)
+		title = fmt.Sprintf("Shared drive %q has used %d%% of its storage", drive.Name, reached)
+		message = fmt.Sprintf("The drive is using %s. Uploads that do not fit in its quota are rejected.", usage)
+		resourceType = models.ResourceDrive
+	}
+
+	for _, recipientID := range recipients {
+		if _, err := qs.notificationService.Notify(recipientID, models.NotificationQuotaWarning, title, message,
+			resourceType, &ownerID); err != nil {
+			config.GetLogger().Error("Failed to send quota warning", "error", err, "user_id", recipientID)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// maxTopStats is how many entries the referrer and user agent breakdowns return
+const maxTopStats = 10
+
+type ShareAnalyticsService struct {
+	db *gorm.DB
+}
+
+func NewShareAnalyticsService() *ShareAnalyticsService {
+	return &ShareAnalyticsService{
+		db: database.GetDB(),
+	}
+}
+
+// RecordAccess stores a view or download event for a share link. Failures are logged and never
+// block the visitor.
+func (sas *ShareAnalyticsService) RecordAccess(shareLink *models.ShareLink, eventType string, fileID *uuid.UUID, visitor models.ShareVisitor) {
+	ua := utils.ParseUserAgent(visitor.UserAgent)
+	access := models.ShareLinkAccess{
+		ShareLinkID: shareLink.ID,
+		FileID:      fileID,
+		EventType:   eventType,
+		VisitorID:   visitorID(visitor),
+		Email:       visitor.Email,
+		IPAddress:   visitor.IPAddress,
+		Country:     visitor.Country,
+		City:        truncate(visitor.City, 100),
+		UserAgent:   truncate(visitor.UserAgent, 500),
+		Referrer:    truncate(visitor.Referrer, 500),
+		Browser:     ua.Browser,
+		OS:          ua.OS,
+		DeviceType:  ua.DeviceType,
+	}
+
+	if err := sas.db.Create(&access).Error; err != nil {
+		config.GetLogger().Error("Failed to record share access", "error", err, "share_link_id", shareLink.ID)
+	}
+}
+
+// RecordFileDownload stores a download row for a file delivered through a share link
+func (sas *ShareAnalyticsService) RecordFileDownload(shareLink *models.ShareLink, fileID uuid.UUID, visitor models.ShareVisitor) {
+	download := models.Download{
+		FileID:      fileID,
+		ShareLinkID: &shareLink.ID,
+		IPAddress:   visitor.IPAddress,
+		Country:     visitor.Country,
+		City:        truncate(visitor.City, 100),
+		UserAgent:   truncate(visitor.UserAgent, 500),
+		Referrer:    truncate(visitor.Referrer, 500),
+	}
+
+	if err := sas.db.Create(&download).Error; err != nil {
+		config.GetLogger().Error("Failed to record download", "error", err, "file_id", fileID)
+	}
+}
+
+// GetAccessEvents returns the access events of a share link in the given period, oldest first
+func (sas *ShareAnalyticsService) GetAccessEvents(linkID uuid.UUID, from, to time.Time) ([]models.ShareLinkAccess, error) {
+	var events []models.ShareLinkAccess
+	err := sas.db.Where("share_link_id = ? AND created_at >= ? AND created_at < ?", linkID, from, to).
+		Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+// GetAnalytics aggregates the access events of a share link in the given period. The counting is
+// done by the database, so the cost does not grow with how popular the link is.
+func (sas *ShareAnalyticsService) GetAnalytics(linkID uuid.UUID, from, to time.Time) (*models.ShareLinkAnalytics, error) {
+	events := func() *gorm.DB {
+		return sas.db.Model(&models.ShareLinkAccess{}).
+			Where("share_link_id = ? AND created_at >= ? AND created_at < ?", linkID, from, to)
+	}
+	eventCounts := "COUNT(CASE WHEN event_type = ? THEN 1 END) AS views, COUNT(CASE WHEN event_type = ? THEN 1 END) AS downloads"
+
+	analytics := &models.ShareLinkAnalytics{
+		ShareLinkID: linkID,
+		From:        from,
+		To:          to,
+	}
+
+	var totals struct {
+		Views     int
+		Downloads int
+		Visitors  int
+	}
+	if err := events().Select(eventCounts+", COUNT(DISTINCT visitor_id) AS visitors",
+		models.ShareEventView, models.ShareEventDownload).Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	analytics.TotalViews = totals.Views
+	analytics.TotalDownloads = totals.Downloads
+	analytics.UniqueVisitors = totals.Visitors
+
+	// Pre-fill every day so the series has no gaps
+	daily := make(map[string]*models.ShareDailyStats)
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		date := day.Format("2006-01-02")
+		analytics.Daily = append(analytics.Daily, models.ShareDailyStats{Date: date})
+	}
+	for i := range analytics.Daily {
+		daily[analytics.Daily[i].Date] = &analytics.Daily[i]
+	}
+
+	day := dayExpression(sas.db)
+	var days []struct {
+		Day       string
+		Views     int
+		Downloads int
+	}
+	if err := events().Select(day+" AS day, "+eventCounts, models.ShareEventView, models.ShareEventDownload).
+		Group(day).Scan(&days).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range days {
+		if stats := daily[d.Day]; stats != nil {
+			stats.Views = d.Views
+			stats.Downloads = d.Downloads
+		}
+	}
+
+	// Referrers are stored as full URLs and grouped by host here, the database already folded
+	// repeated URLs
+	referrers, err := countEvents(events(), "referrer", 0)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]int)
+	for _, stat := range referrers {
+		hosts[referrerHost(stat.Name)] += stat.Count
+	}
+	analytics.TopReferrers = topStats(hosts)
+
+	if analytics.Browsers, err = countEvents(events(), "browser", maxTopStats); err != nil {
+		return nil, err
+	}
+	if analytics.OperatingSystems, err = countEvents(events(), "os", maxTopStats); err != nil {
+		return nil, err
+	}
+	if analytics.Devices, err = countEvents(events(), "device_type", maxTopStats); err != nil {
+		return nil, err
+	}
+	if analytics.Countries, err = countEvents(events(), "country", maxTopStats); err != nil {
+		return nil, err
+	}
+	for i := range analytics.Countries {
+		analytics.Countries[i].Name = locationName(analytics.Countries[i].Name)
+	}
+
+	var cities []struct {
+		City    string
+		Country string
+		Hits    int
+	}
+	if err := events().Select("city, country, COUNT(*) AS hits").Where("city <> ?", "").
+		Group("city, country").Order("hits DESC, city ASC").Limit(maxTopStats).Scan(&cities).Error; err != nil {
+		return nil, err
+	}
+	analytics.Cities = make([]models.ShareCountStat, 0, len(cities))
+	for _, city := range cities {
+		analytics.Cities = append(analytics.Cities, models.ShareCountStat{Name: city.City + ", " + city.Country, Count: city.Hits})
+	}
+
+	return analytics, nil
+}
+
+// countEvents counts the events per value of a column, most frequent first. A limit of 0 returns
+// every value.
+func countEvents(query *gorm.DB, column string, limit int) ([]models.ShareCountStat, error) {
+	var rows []struct {
+		Name string
+		Hits int
+	}
+	query = query.Select(column + " AS name, COUNT(*) AS hits").Group(column).Order("hits DESC, name ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.ShareCountStat, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, models.ShareCountStat{Name: row.Name, Count: row.Hits})
+	}
+	return stats, nil
+}
+
+// dayExpression formats created_at as a UTC date in the SQL dialect of the database. MySQL
+// connections store local time, so there days follow the server's time zone.
+func dayExpression(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	case "mysql":
+		return "DATE_FORMAT(created_at, '%Y-%m-%d')"
+	default:
+		return "strftime('%Y-%m-%d', created_at)"
+	}
+}
+
+// visitorID derives a stable anonymous identifier so unique visitors can be counted without
+// keeping a separate visitor table
+func visitorID(visitor models.ShareVisitor) string {
+	sum := sha256.Sum256([]byte(visitor.IPAddress + "|" + visitor.UserAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// referrerHost groups referrers by host, direct visits have no referrer
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return "(direct)"
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return referrer
+	}
+	return u.Host
+}
+
+// locationName groups events whose location could not be resolved
+func locationName(name string) string {
+	if name == "" {
+		return "(unknown)"
+	}
+	return name
+}
+
+func topStats(counts map[string]int) []models.ShareCountStat {
+	stats := make([]models.ShareCountStat, 0, len(counts))
+	for name, count := range counts {
+		stats = append(stats, models.ShareCountStat{Name: name, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	if len(stats) > maxTopStats {
+		stats = stats[:maxTopStats]
+	}
+	return stats
+}
+
+// truncate cuts s to at most max bytes without splitting a UTF-8 character, which databases
+// would reject
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
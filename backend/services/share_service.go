@@ -1,50 +1,138 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"math/big"
+	"mime/multipart"
+	"net/mail"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
 	"github.com/manjurulhoque/swift-share/backend/database"
 	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// maxShareDepth bounds the parent walk when checking that a folder belongs to a shared subtree
+const maxShareDepth = 256
+
+// maxShareBundleItems limits how many files and folders one bundle link can reference
+const maxShareBundleItems = 100
+
+// Passcode settings for email restricted share links
+const (
+	shareCodeTTL         = 10 * time.Minute
+	shareCodeMaxAttempts = 5
+	shareCodeRateWindow  = 15 * time.Minute
+	shareCodeRateLimit   = 5 // codes per email address and link within the window
+)
+
+var (
+	ErrShareItemNotFound     = errors.New("item not found in share")
+	ErrShareLinkExhausted    = errors.New("share link usage limit reached")
+	ErrShareLinkExpired      = errors.New("share link has expired")
+	ErrShareLinkDisabled     = errors.New("share link has been disabled")
+	ErrShareLinkScheduled    = errors.New("share link is not active yet")
+	ErrShareLinkGeoBlocked   = errors.New("share link is not available in this region")
+	ErrShareEmailRequired    = errors.New("email verification required")
+	ErrShareEmailNotRequired = errors.New("share link does not require email verification")
+	ErrShareCodeInvalid      = errors.New("invalid or expired verification code")
+	ErrShareCodeRateLimited  = errors.New("too many verification codes requested")
+	ErrShareUploadNotAllowed = errors.New("share link does not allow uploads")
+)
+
 type ShareService struct {
-	db *gorm.DB
+	db                  *gorm.DB
+	analyticsService    *ShareAnalyticsService
+	mailService         *MailService
+	planService         *PlanService
+	notificationService *NotificationService
 }
 
 func NewShareService() *ShareService {
 	return &ShareService{
-		db: database.GetDB(),
+		db:                  database.GetDB(),
+		analyticsService:    NewShareAnalyticsService(),
+		mailService:         NewMailService(),
+		planService:         NewPlanService(),
+		notificationService: NewNotificationService(),
 	}
 }
 
-// CreateShareLink creates a new share link for a file or folder
+// CreateShareLink creates a new share link for a file, a folder or a bundle of files and folders
 func (ss *ShareService) CreateShareLink(userID uuid.UUID, req models.ShareLinkCreateRequest) (*models.ShareLink, error) {
-	// Validate that either FileID or FolderID is provided, but not both
-	if (req.FileID == nil && req.FolderID == nil) || (req.FileID != nil && req.FolderID != nil) {
-		return nil, errors.New("either file_id or folder_id must be provided, but not both")
+	// Validate that exactly one of FileID, FolderID or a bundle is provided
+	isBundle := len(req.FileIDs) > 0 || len(req.FolderIDs) > 0
+	targets := 0
+	for _, set := range []bool{req.FileID != nil, req.FolderID != nil, isBundle} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, errors.New("provide exactly one of file_id, folder_id or file_ids and folder_ids")
 	}
 
 	// Verify ownership
 	if req.FileID != nil {
 		var file models.File
-		if err := ss.db.Where("id = ? AND user_id = ? AND is_trashed = false", *req.FileID, userID).First(&file).Error; err != nil {
+		if err := ss.db.Where("id = ? AND is_trashed = false", *req.FileID).Scopes(models.OwnedBy(userID)).First(&file).Error; err != nil {
 			return nil, errors.New("file not found or access denied")
 		}
 	}
 
 	if req.FolderID != nil {
 		var folder models.Folder
-		if err := ss.db.Where("id = ? AND user_id = ? AND is_trashed = false", *req.FolderID, userID).First(&folder).Error; err != nil {
+		if err := ss.db.Where("id = ? AND is_trashed = false", *req.FolderID).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
 			return nil, errors.New("folder not found or access denied")
 		}
 	}
 
+	// The user's plan can require a password and an expiry, a missing expiry gets the latest allowed
+	expiresAt, err := ss.planService.CheckShareLink(userID, req.ExpiresAt, req.Password != "")
+	if err != nil {
+		return nil, err
+	}
+	req.ExpiresAt = expiresAt
+
+	if err := checkActivationWindow(req.StartsAt, req.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	var items []models.ShareLinkItem
+	if isBundle {
+		if items, err = ss.bundleItems(userID, req.FileIDs, req.FolderIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	allowedCountries, err := normalizeCountries(req.AllowedCountries)
+	if err != nil {
+		return nil, err
+	}
+	blockedCountries, err := normalizeCountries(req.BlockedCountries)
+	if err != nil {
+		return nil, err
+	}
+	allowedEmails, err := normalizeAllowedEmails(req.AllowedEmails)
+	if err != nil {
+		return nil, err
+	}
+	watermarkText, err := normalizeWatermarkText(req.WatermarkText)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate secure token
 	token, err := generateSecureToken(32)
 	if err != nil {
@@ -53,14 +141,24 @@ func (ss *ShareService) CreateShareLink(userID uuid.UUID, req models.ShareLinkCr
 
 	// Create share link
 	shareLink := &models.ShareLink{
-		UserID:        userID,
-		FileID:        req.FileID,
-		FolderID:      req.FolderID,
-		Token:         token,
-		Permission:    req.Permission,
-		ExpiresAt:     req.ExpiresAt,
-		AllowDownload: req.AllowDownload,
-		IsPublic:      true,
+		UserID:           userID,
+		FileID:           req.FileID,
+		FolderID:         req.FolderID,
+		Token:            token,
+		Permission:       req.Permission,
+		IsActive:         true,
+		StartsAt:         req.StartsAt,
+		ExpiresAt:        req.ExpiresAt,
+		AllowDownload:    req.AllowDownload,
+		MaxViews:         req.MaxViews,
+		MaxDownloads:     req.MaxDownloads,
+		OneTime:          req.OneTime,
+		AllowedCountries: allowedCountries,
+		BlockedCountries: blockedCountries,
+		AllowedEmails:    allowedEmails,
+		Watermark:        req.Watermark,
+		WatermarkText:    watermarkText,
+		IsPublic:         true,
 	}
 
 	// Handle password protection
@@ -73,7 +171,25 @@ func (ss *ShareService) CreateShareLink(userID uuid.UUID, req models.ShareLinkCr
 		shareLink.HasPassword = true
 	}
 
-	if err := ss.db.Create(shareLink).Error; err != nil {
+	err = ss.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(shareLink).Error; err != nil {
+			return err
+		}
+		// Create skips false because of the column default, so disabled downloads are written explicitly
+		if !req.AllowDownload {
+			if err := tx.Model(shareLink).Update("allow_download", false).Error; err != nil {
+				return err
+			}
+		}
+		for i := range items {
+			items[i].ShareLinkID = shareLink.ID
+		}
+		if len(items) > 0 {
+			return tx.Create(&items).Error
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -83,6 +199,46 @@ func (ss *ShareService) CreateShareLink(userID uuid.UUID, req models.ShareLinkCr
 	return shareLink, nil
 }
 
+// bundleItems checks that the user owns every file and folder of a bundle and returns its items,
+// folders first, in request order
+func (ss *ShareService) bundleItems(userID uuid.UUID, fileIDs, folderIDs []uuid.UUID) ([]models.ShareLinkItem, error) {
+	fileIDs, folderIDs = uniqueIDs(fileIDs), uniqueIDs(folderIDs)
+	if len(fileIDs)+len(folderIDs) > maxShareBundleItems {
+		return nil, fmt.Errorf("a share link can bundle at most %d items", maxShareBundleItems)
+	}
+
+	var count int64
+	if len(fileIDs) > 0 {
+		if err := ss.db.Model(&models.File{}).Where("id IN ? AND is_trashed = ?", fileIDs, false).Scopes(models.OwnedBy(userID)).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(fileIDs) {
+			return nil, errors.New("file not found or access denied")
+		}
+	}
+	if len(folderIDs) > 0 {
+		if err := ss.db.Model(&models.Folder{}).Where("id IN ? AND is_trashed = ?", folderIDs, false).Scopes(models.OwnedBy(userID)).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(folderIDs) {
+			return nil, errors.New("folder not found or access denied")
+		}
+	}
+
+	items := make([]models.ShareLinkItem, 0, len(fileIDs)+len(folderIDs))
+	for _, id := range folderIDs {
+		folderID := id
+		items = append(items, models.ShareLinkItem{FolderID: &folderID, Position: len(items)})
+	}
+	for _, id := range fileIDs {
+		fileID := id
+		items = append(items, models.ShareLinkItem{FileID: &fileID, Position: len(items)})
+	}
+	return items, nil
+}
+
 // GetShareLinks returns all share links for a user
 func (ss *ShareService) GetShareLinks(userID uuid.UUID, page, limit int) ([]models.ShareLink, int64, error) {
 	var shareLinks []models.ShareLink
@@ -97,6 +253,7 @@ func (ss *ShareService) GetShareLinks(userID uuid.UUID, page, limit int) ([]mode
 
 	// Get paginated results with relationships
 	if err := ss.db.Preload("User").Preload("File").Preload("Folder").
+		Preload("Items.File").Preload("Items.Folder").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Offset(offset).
@@ -112,6 +269,7 @@ func (ss *ShareService) GetShareLinks(userID uuid.UUID, page, limit int) ([]mode
 func (ss *ShareService) GetShareLinkByToken(token string) (*models.ShareLink, error) {
 	var shareLink models.ShareLink
 	if err := ss.db.Preload("User").Preload("File").Preload("Folder").
+		Preload("Items.File").Preload("Items.Folder").
 		Where("token = ?", token).First(&shareLink).Error; err != nil {
 		return nil, err
 	}
@@ -119,6 +277,18 @@ func (ss *ShareService) GetShareLinkByToken(token string) (*models.ShareLink, er
 	return &shareLink, nil
 }
 
+// GetShareLinkByID returns a share link owned by the user
+func (ss *ShareService) GetShareLinkByID(userID, linkID uuid.UUID) (*models.ShareLink, error) {
+	var shareLink models.ShareLink
+	if err := ss.db.Preload("User").Preload("File").Preload("Folder").
+		Preload("Items.File").Preload("Items.Folder").
+		Where("id = ? AND user_id = ?", linkID, userID).First(&shareLink).Error; err != nil {
+		return nil, errors.New("share link not found")
+	}
+
+	return &shareLink, nil
+}
+
 // UpdateShareLink updates an existing share link
 func (ss *ShareService) UpdateShareLink(userID uuid.UUID, linkID uuid.UUID, req models.ShareLinkUpdateRequest) (*models.ShareLink, error) {
 	var shareLink models.ShareLink
@@ -134,11 +304,88 @@ func (ss *ShareService) UpdateShareLink(userID uuid.UUID, linkID uuid.UUID, req
 	}
 
 	if req.ExpiresAt != nil {
+		// A new expiry gets its own notice
 		updates["expires_at"] = req.ExpiresAt
+		updates["expiry_notice"] = nil
+	}
+
+	startsAt := shareLink.StartsAt
+	if req.ClearStartsAt {
+		startsAt = nil
+		updates["starts_at"] = nil
+	} else if req.StartsAt != nil {
+		startsAt = req.StartsAt
+		updates["starts_at"] = req.StartsAt
+	}
+	expiresAt := shareLink.ExpiresAt
+	if req.ExpiresAt != nil {
+		expiresAt = req.ExpiresAt
+	}
+	if err := checkActivationWindow(startsAt, expiresAt); err != nil {
+		return nil, err
+	}
+
+	// The user's plan applies to the new expiry and to the password the link is left with, an
+	// expiry that is not changed is kept even when the plan would not allow it
+	if _, err := ss.planService.CheckShareLink(userID, req.ExpiresAt, req.Password != ""); err != nil {
+		return nil, err
+	}
+
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
 	}
 
 	updates["allow_download"] = req.AllowDownload
 
+	// A zero limit removes it
+	if req.MaxViews != nil {
+		updates["max_views"] = nilIfZero(*req.MaxViews)
+	}
+	if req.MaxDownloads != nil {
+		updates["max_downloads"] = nilIfZero(*req.MaxDownloads)
+	}
+	if req.OneTime != nil {
+		updates["one_time"] = *req.OneTime
+		// A used one-time link that stops being one-time can be opened again
+		if !*req.OneTime {
+			updates["burned_at"] = nil
+		}
+	}
+
+	// A missing list keeps the current restriction, an empty one removes it
+	if req.AllowedCountries != nil {
+		countries, err := normalizeCountries(req.AllowedCountries)
+		if err != nil {
+			return nil, err
+		}
+		updates["allowed_countries"] = countries
+	}
+	if req.BlockedCountries != nil {
+		countries, err := normalizeCountries(req.BlockedCountries)
+		if err != nil {
+			return nil, err
+		}
+		updates["blocked_countries"] = countries
+	}
+	if req.AllowedEmails != nil {
+		emails, err := normalizeAllowedEmails(req.AllowedEmails)
+		if err != nil {
+			return nil, err
+		}
+		updates["allowed_emails"] = emails
+	}
+
+	if req.Watermark != nil {
+		updates["watermark"] = *req.Watermark
+	}
+	if req.WatermarkText != nil {
+		text, err := normalizeWatermarkText(*req.WatermarkText)
+		if err != nil {
+			return nil, err
+		}
+		updates["watermark_text"] = text
+	}
+
 	// Handle password update
 	if req.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -163,45 +410,414 @@ func (ss *ShareService) UpdateShareLink(userID uuid.UUID, linkID uuid.UUID, req
 	return &shareLink, nil
 }
 
+// DisableItemShareLinks disables every active link of the user that shares the file or folder,
+// directly or as part of a bundle. It returns how many links were disabled.
+func (ss *ShareService) DisableItemShareLinks(userID uuid.UUID, fileID, folderID *uuid.UUID) (int64, error) {
+	column, itemID := "file_id", fileID
+	if folderID != nil {
+		column, itemID = "folder_id", folderID
+		var folder models.Folder
+		if err := ss.db.Where("id = ?", *folderID).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
+			return 0, errors.New("folder not found or access denied")
+		}
+	} else {
+		var file models.File
+		if err := ss.db.Where("id = ?", *fileID).Scopes(models.OwnedBy(userID)).First(&file).Error; err != nil {
+			return 0, errors.New("file not found or access denied")
+		}
+	}
+
+	bundles := ss.db.Model(&models.ShareLinkItem{}).Select("share_link_id").Where(column+" = ?", *itemID)
+	result := ss.db.Model(&models.ShareLink{}).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Where(ss.db.Where(column+" = ?", *itemID).Or("id IN (?)", bundles)).
+		Update("is_active", false)
+	return result.RowsAffected, result.Error
+}
+
 // DeleteShareLink deletes a share link
 func (ss *ShareService) DeleteShareLink(userID uuid.UUID, linkID uuid.UUID) error {
 	return ss.db.Where("id = ? AND user_id = ?", linkID, userID).Delete(&models.ShareLink{}).Error
 }
 
-// AccessShareLink handles public access to a share link
-func (ss *ShareService) AccessShareLink(token, password string) (*models.ShareLink, error) {
+// AccessShareLink handles public access to a share link and records the view. Email restricted
+// links also need the address and the passcode sent to it, the verified address is recorded on
+// the access event.
+func (ss *ShareService) AccessShareLink(token string, req models.ShareLinkAccessRequest, visitor models.ShareVisitor) (*models.ShareLink, error) {
 	shareLink, err := ss.GetShareLinkByToken(token)
 	if err != nil {
 		return nil, errors.New("share link not found")
 	}
 
-	// Check if expired
-	if shareLink.IsExpired() {
-		return nil, errors.New("share link has expired")
+	// Check if disabled, not active yet or expired
+	if err := CheckShareAvailable(shareLink); err != nil {
+		return nil, err
+	}
+
+	if !shareLink.AllowsCountry(visitor.Country) {
+		return nil, ErrShareLinkGeoBlocked
 	}
 
 	// Check password if required
 	if shareLink.HasPassword {
-		if password == "" {
+		if req.Password == "" {
 			return nil, errors.New("password required")
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(shareLink.Password), []byte(password)); err != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(shareLink.Password), []byte(req.Password)); err != nil {
 			return nil, errors.New("invalid password")
 		}
 	}
 
-	// Increment view count
-	ss.db.Model(shareLink).UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+	if shareLink.RequiresEmail() {
+		if req.Email == "" || req.Code == "" {
+			return nil, ErrShareEmailRequired
+		}
+		email := utils.NormalizeEmail(req.Email)
+		if err := ss.verifyAccessCode(shareLink, email, req.Code); err != nil {
+			return nil, err
+		}
+		visitor.Email = email
+	}
+
+	if shareLink.IsExhausted() {
+		return nil, ErrShareLinkExhausted
+	}
+
+	// Count the view only while the limits still allow it, so concurrent requests cannot
+	// exceed max_views or open a one-time link twice
+	updates := map[string]interface{}{"view_count": gorm.Expr("view_count + 1")}
+	if shareLink.OneTime {
+		updates["burned_at"] = time.Now()
+	}
+	result := ss.db.Model(&models.ShareLink{}).
+		Where("id = ? AND burned_at IS NULL AND (max_views IS NULL OR view_count < max_views)", shareLink.ID).
+		UpdateColumns(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrShareLinkExhausted
+	}
+	shareLink.ViewCount++
+
+	ss.analyticsService.RecordAccess(shareLink, models.ShareEventView, nil, visitor)
 
 	return shareLink, nil
 }
 
+// RequestAccessCode emails a one-time passcode for an email restricted share link. Addresses that
+// are not allowed get no code but no error either, so the allow list cannot be probed.
+func (ss *ShareService) RequestAccessCode(token, email string, visitor models.ShareVisitor) error {
+	shareLink, err := ss.GetShareLinkByToken(token)
+	if err != nil {
+		return errors.New("share link not found")
+	}
+	if err := CheckShareAvailable(shareLink); err != nil {
+		return err
+	}
+	if !shareLink.AllowsCountry(visitor.Country) {
+		return ErrShareLinkGeoBlocked
+	}
+	if !shareLink.RequiresEmail() {
+		return ErrShareEmailNotRequired
+	}
+	if !ss.mailService.Enabled() {
+		return ErrMailNotConfigured
+	}
+
+	email = utils.NormalizeEmail(email)
+	if !shareLink.AllowsEmail(email) {
+		return nil
+	}
+
+	var recent int64
+	if err := ss.db.Model(&models.ShareLinkOTP{}).
+		Where("share_link_id = ? AND email = ? AND created_at > ?", shareLink.ID, email, time.Now().Add(-shareCodeRateWindow)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent >= shareCodeRateLimit {
+		return ErrShareCodeRateLimited
+	}
+
+	code, err := generateAccessCode()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	otp := &models.ShareLinkOTP{
+		ShareLinkID: shareLink.ID,
+		Email:       email,
+		CodeHash:    string(hash),
+		ExpiresAt:   time.Now().Add(shareCodeTTL),
+	}
+	if err := ss.db.Create(otp).Error; err != nil {
+		return err
+	}
+
+	name := "a shared item"
+	if shareLink.File != nil {
+		name = shareLink.File.OriginalName
+	} else if shareLink.Folder != nil {
+		name = shareLink.Folder.Name
+	}
+	body := fmt.Sprintf("%s shared %q with you.\n\nYour verification code is: %s\n\nThe code expires in %d minutes. If you did not request it, you can ignore this email.\n",
+		shareLink.User.GetFullName(), name, code, int(shareCodeTTL.Minutes()))
+	return ss.mailService.Send(email, "Your verification code", body)
+}
+
+// verifyAccessCode redeems the latest passcode sent to the email address for the share link
+func (ss *ShareService) verifyAccessCode(shareLink *models.ShareLink, email, code string) error {
+	var otp models.ShareLinkOTP
+	if err := ss.db.Where("share_link_id = ? AND email = ?", shareLink.ID, email).
+		Order("created_at DESC").First(&otp).Error; err != nil {
+		return ErrShareCodeInvalid
+	}
+	if !otp.IsUsable(shareCodeMaxAttempts) {
+		return ErrShareCodeInvalid
+	}
+
+	// Count the attempt before comparing so parallel guesses cannot exceed the limit
+	result := ss.db.Model(&models.ShareLinkOTP{}).
+		Where("id = ? AND consumed_at IS NULL AND attempts < ?", otp.ID, shareCodeMaxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareCodeInvalid
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(otp.CodeHash), []byte(strings.TrimSpace(code))); err != nil {
+		return ErrShareCodeInvalid
+	}
+
+	result = ss.db.Model(&models.ShareLinkOTP{}).
+		Where("id = ? AND consumed_at IS NULL", otp.ID).
+		UpdateColumn("consumed_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareCodeInvalid
+	}
+	return nil
+}
+
 // IncrementDownloadCount increments the download count for a share link
 func (ss *ShareService) IncrementDownloadCount(token string) error {
 	return ss.db.Model(&models.ShareLink{}).Where("token = ?", token).
 		UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error
 }
 
+// ConsumeDownload counts a download if the link's download limit allows it and records it as an
+// access event. The check and the increment happen in one statement so concurrent downloads cannot
+// exceed the limit.
+func (ss *ShareService) ConsumeDownload(shareLink *models.ShareLink, fileID *uuid.UUID, visitor models.ShareVisitor) error {
+	if !shareLink.AllowsCountry(visitor.Country) {
+		return ErrShareLinkGeoBlocked
+	}
+
+	result := ss.db.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_downloads IS NULL OR download_count < max_downloads) AND (one_time = ? OR download_count < 1)",
+			shareLink.ID, false).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrShareLinkExhausted
+	}
+	shareLink.DownloadCount++
+
+	ss.analyticsService.RecordAccess(shareLink, models.ShareEventDownload, fileID, visitor)
+	ss.notifyDownload(shareLink, fileID, visitor)
+	return nil
+}
+
+// notifyDownload tells the owner of a share link that something was downloaded through it
+func (ss *ShareService) notifyDownload(shareLink *models.ShareLink, fileID *uuid.UUID, visitor models.ShareVisitor) {
+	name := shareLinkLabel(shareLink)
+	if fileID != nil {
+		var file models.File
+		if err := ss.db.Select("original_name").Where("id = ?", *fileID).First(&file).Error; err == nil {
+			name = file.OriginalName
+		}
+	}
+
+	message := fmt.Sprintf("%q was downloaded through your share link", name)
+	if visitor.Email != "" {
+		message += " by " + visitor.Email
+	}
+	if _, err := ss.notificationService.Notify(shareLink.UserID, models.NotificationShareDownload,
+		"Shared file downloaded", message, models.ResourceShareLink, &shareLink.ID); err != nil {
+		config.GetLogger().Error("Failed to notify share owner", "error", err, "share_link_id", shareLink.ID)
+	}
+}
+
+// NotifyExpiringLinks tells owners about active share links that expire within the configured
+// notice time. Each expiry is announced once, returning how many owners were told.
+func (ss *ShareService) NotifyExpiringLinks() (int, error) {
+	now := time.Now()
+	var shareLinks []models.ShareLink
+	if err := ss.db.Preload("File").Preload("Folder").Preload("Items.File").Preload("Items.Folder").
+		Where("is_active = ? AND burned_at IS NULL AND expiry_notice IS NULL AND expires_at > ? AND expires_at <= ?",
+			true, now, now.Add(config.AppConfig.Notification.ExpiryNoticeTime)).
+		Find(&shareLinks).Error; err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for i := range shareLinks {
+		shareLink := &shareLinks[i]
+		claim := ss.db.Model(&models.ShareLink{}).Where("id = ? AND expiry_notice IS NULL", shareLink.ID).
+			UpdateColumn("expiry_notice", now)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("Your share link for %q expires on %s", shareLinkLabel(shareLink),
+			shareLink.ExpiresAt.UTC().Format("Jan 2, 2006 15:04 MST"))
+		if _, err := ss.notificationService.Notify(shareLink.UserID, models.NotificationShareExpiring,
+			"Share link expiring soon", message, models.ResourceShareLink, &shareLink.ID); err != nil {
+			config.GetLogger().Error("Failed to notify share owner", "error", err, "share_link_id", shareLink.ID)
+			continue
+		}
+		notified++
+	}
+	return notified, nil
+}
+
+// RunExpiryNoticeWorker calls NotifyExpiringLinks every interval until ctx is cancelled
+func (ss *ShareService) RunExpiryNoticeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := ss.NotifyExpiringLinks()
+		if err != nil {
+			config.GetLogger().Error("Failed to check expiring share links", "error", err)
+		} else if count > 0 {
+			config.GetLogger().Info("Notified owners of expiring share links", "count", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetSharedFolder returns a folder that lies within the subtree of a folder link or of a folder
+// in a bundle link
+func (ss *ShareService) GetSharedFolder(shareLink *models.ShareLink, folderID uuid.UUID) (*models.Folder, error) {
+	if len(shareLink.SharedFolderIDs()) == 0 {
+		return nil, ErrShareItemNotFound
+	}
+
+	var folder models.Folder
+	if err := ss.db.Where("id = ? AND is_trashed = ?", folderID, false).Scopes(models.OwnedBy(shareLink.UserID)).
+		First(&folder).Error; err != nil {
+		return nil, ErrShareItemNotFound
+	}
+
+	if !ss.inSharedSubtree(shareLink, &folder.ID) {
+		return nil, ErrShareItemNotFound
+	}
+
+	return &folder, nil
+}
+
+// GetSharedFile returns the shared file of a file link, a file of a bundle link, or a file within
+// a shared folder subtree
+func (ss *ShareService) GetSharedFile(shareLink *models.ShareLink, fileID uuid.UUID) (*models.File, error) {
+	if shareLink.FileID != nil && *shareLink.FileID != fileID {
+		return nil, ErrShareItemNotFound
+	}
+
+	var file models.File
+	if err := ss.db.Where("id = ? AND is_trashed = ?", fileID, false).Scopes(models.OwnedBy(shareLink.UserID)).
+		First(&file).Error; err != nil {
+		return nil, ErrShareItemNotFound
+	}
+
+	if shareLink.FileID == nil && !shareLink.HasBundleFile(file.ID) && !ss.inSharedSubtree(shareLink, file.FolderID) {
+		return nil, ErrShareItemNotFound
+	}
+
+	return &file, nil
+}
+
+// GetUploadFolder returns the folder an upload through a share link goes to, the shared folder
+// itself when folderID is nil. Only links with edit permission that share folders accept uploads,
+// bundle links need the folder to be chosen.
+func (ss *ShareService) GetUploadFolder(shareLink *models.ShareLink, folderID *uuid.UUID) (*models.Folder, error) {
+	if len(shareLink.SharedFolderIDs()) == 0 || shareLink.Permission != models.PermissionEdit {
+		return nil, ErrShareUploadNotAllowed
+	}
+	if folderID == nil {
+		if shareLink.FolderID == nil {
+			return nil, ErrShareUploadNotAllowed
+		}
+		folderID = shareLink.FolderID
+	}
+	return ss.GetSharedFolder(shareLink, *folderID)
+}
+
+// SaveSharedUpload stores a file uploaded through a share link. The file belongs to the link
+// owner and records the link and the uploader's details.
+func (ss *ShareService) SaveSharedUpload(ctx context.Context, shareLink *models.ShareLink, folder *models.Folder, header *multipart.FileHeader, uploader models.Uploader, visitor models.ShareVisitor) (*models.File, error) {
+	file, err := storeUpload(ctx, ss.db, shareLink.UserID, folder.ID, header, uploader, func(file *models.File) {
+		file.UploadShareLinkID = &shareLink.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ss.analyticsService.RecordAccess(shareLink, models.ShareEventUpload, &file.ID, visitor)
+	return file, nil
+}
+
+// GetSharedFolderContents returns the direct subfolders and files of a folder inside a share
+func (ss *ShareService) GetSharedFolderContents(folder *models.Folder) ([]models.Folder, []models.File, error) {
+	var folders []models.Folder
+	if err := ss.db.Where("parent_id = ? AND is_trashed = ?", folder.ID, false).
+		Order("name ASC").Find(&folders).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var files []models.File
+	if err := ss.db.Where("folder_id = ? AND is_trashed = ?", folder.ID, false).
+		Order("original_name ASC").Find(&files).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return folders, files, nil
+}
+
+// inSharedSubtree walks up from folderID and reports whether it reaches a shared folder
+func (ss *ShareService) inSharedSubtree(shareLink *models.ShareLink, folderID *uuid.UUID) bool {
+	roots := shareLink.SharedFolderIDs()
+	current := folderID
+	for depth := 0; current != nil && depth < maxShareDepth; depth++ {
+		if slices.Contains(roots, *current) {
+			return true
+		}
+
+		var folder models.Folder
+		if err := ss.db.Select("id", "parent_id", "is_trashed").Where("id = ?", *current).First(&folder).Error; err != nil || folder.IsTrashed {
+			return false
+		}
+		current = folder.ParentID
+	}
+	return false
+}
+
 // GetFileShareLinks returns all share links for a specific file
 func (ss *ShareService) GetFileShareLinks(userID, fileID uuid.UUID) ([]models.ShareLink, error) {
 	var shareLinks []models.ShareLink
@@ -276,9 +892,130 @@ func (ss *ShareService) GetShareStats(userID uuid.UUID) (map[string]interface{},
 // Helper function to load relationships
 func (ss *ShareService) loadShareLinkRelations(shareLink *models.ShareLink) {
 	ss.db.Preload("User").Preload("File").Preload("Folder").
+		Preload("Items.File").Preload("Items.Folder").
 		Where("id = ?", shareLink.ID).First(shareLink)
 }
 
+// shareLinkLabel names what a share link shares, for notifications
+func shareLinkLabel(shareLink *models.ShareLink) string {
+	switch {
+	case shareLink.File != nil && shareLink.File.ID != uuid.Nil:
+		return shareLink.File.OriginalName
+	case shareLink.Folder != nil && shareLink.Folder.ID != uuid.Nil:
+		return shareLink.Folder.Name
+	case shareLink.IsBundle():
+		folders, files := shareLink.BundleItems()
+		return fmt.Sprintf("%d shared items", len(folders)+len(files))
+	}
+	return "a shared item"
+}
+
+// CheckShareAvailable returns why a link cannot be opened, nil when it can
+func CheckShareAvailable(shareLink *models.ShareLink) error {
+	switch shareLink.Status() {
+	case models.ShareStatusDisabled:
+		return ErrShareLinkDisabled
+	case models.ShareStatusScheduled:
+		return ErrShareLinkScheduled
+	case models.ShareStatusExpired:
+		return ErrShareLinkExpired
+	case models.ShareStatusExhausted:
+		return ErrShareLinkExhausted
+	}
+	return nil
+}
+
+// checkActivationWindow makes sure a link becomes active before it expires
+func checkActivationWindow(startsAt, expiresAt *time.Time) error {
+	if startsAt != nil && expiresAt != nil && !startsAt.Before(*expiresAt) {
+		return errors.New("starts_at must be before expires_at")
+	}
+	return nil
+}
+
+// uniqueIDs drops repeated IDs and keeps the first occurrence of each
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func nilIfZero(v int) *int {
+	if v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+// generateAccessCode returns a random six digit passcode
+func generateAccessCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// normalizeCountries validates ISO 3166-1 alpha-2 codes and joins them into the stored form
+func normalizeCountries(countries []string) (string, error) {
+	seen := make(map[string]bool, len(countries))
+	codes := make([]string, 0, len(countries))
+	for _, country := range countries {
+		code := strings.ToUpper(strings.TrimSpace(country))
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return "", fmt.Errorf("invalid country code: %q", country)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, ","), nil
+}
+
+// normalizeWatermarkText trims the custom watermark line and keeps it to one line of at most 200
+// characters
+func normalizeWatermarkText(text string) (string, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > 200 {
+		return "", errors.New("watermark_text must be at most 200 characters")
+	}
+	return text, nil
+}
+
+// normalizeAllowedEmails validates email addresses and domains and joins them into the stored
+// form, where domains are kept as "@example.com"
+func normalizeAllowedEmails(entries []string) (string, error) {
+	seen := make(map[string]bool, len(entries))
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		value := utils.NormalizeEmail(entry)
+		if strings.Index(value, "@") > 0 {
+			addr, err := mail.ParseAddress(value)
+			if err != nil || addr.Address != value {
+				return "", fmt.Errorf("invalid email address: %q", entry)
+			}
+		} else {
+			domain := strings.TrimPrefix(value, "@")
+			if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@, \t") || strings.HasPrefix(domain, ".") {
+				return "", fmt.Errorf("invalid email domain: %q", entry)
+			}
+			value = "@" + domain
+		}
+		if !seen[value] {
+			seen[value] = true
+			normalized = append(normalized, value)
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
+
 // generateSecureToken generates a cryptographically secure random token
 func generateSecureToken(length int) (string, error) {
 	bytes := make([]byte, length)
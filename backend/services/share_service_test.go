@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+)
+
+var accessCodePattern = regexp.MustCompile(`verification code is: (\d{6})`)
+
+// newEmailRestrictedLink creates a file share link that only the allowed addresses can open
+func newEmailRestrictedLink(t *testing.T, allowed string) *models.ShareLink {
+	t.Helper()
+	owner := createTestUser(t, "owner")
+	file := createTestFile(t, owner, nil, "report.pdf", "report")
+	token, err := generateSecureToken(32)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	shareLink := &models.ShareLink{
+		Token:         token,
+		UserID:        owner.ID,
+		FileID:        &file.ID,
+		Permission:    models.PermissionView,
+		IsPublic:      true,
+		AllowDownload: true,
+		IsActive:      true,
+		AllowedEmails: allowed,
+	}
+	if err := database.GetDB().Create(shareLink).Error; err != nil {
+		t.Fatalf("create share link: %v", err)
+	}
+	return shareLink
+}
+
+// lastAccessCode returns the passcode in the most recent email of the sink
+func lastAccessCode(t *testing.T, messages []string) string {
+	t.Helper()
+	match := accessCodePattern.FindStringSubmatch(messages[len(messages)-1])
+	if match == nil {
+		t.Fatalf("no passcode in email:\n%s", messages[len(messages)-1])
+	}
+	return match[1]
+}
+
+// wrongCode returns a six digit code that differs from code
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestShareAccessCode(t *testing.T) {
+	sink, port := startSMTPSink(t)
+	ss := NewShareService()
+	ss.mailService = newTestMailService(port)
+	visitor := models.ShareVisitor{IPAddress: "192.0.2.1"}
+
+	t.Run("code is sent and redeemed once", func(t *testing.T) {
+		shareLink := newEmailRestrictedLink(t, "alice@example.com")
+		sent := sink.count()
+
+		if err := ss.RequestAccessCode(shareLink.Token, "Alice@Example.com", visitor); err != nil {
+			t.Fatalf("RequestAccessCode: %v", err)
+		}
+		code := lastAccessCode(t, sink.wait(t, sent+1))
+
+		req := models.ShareLinkAccessRequest{Email: "alice@example.com", Code: code}
+		opened, err := ss.AccessShareLink(shareLink.Token, req, visitor)
+		if err != nil {
+			t.Fatalf("AccessShareLink: %v", err)
+		}
+		if opened.ViewCount != 1 {
+			t.Errorf("view count = %d, want 1", opened.ViewCount)
+		}
+
+		if _, err := ss.AccessShareLink(shareLink.Token, req, visitor); !errors.Is(err, ErrShareCodeInvalid) {
+			t.Errorf("second use = %v, want ErrShareCodeInvalid", err)
+		}
+	})
+
+	t.Run("wrong codes lock the code", func(t *testing.T) {
+		shareLink := newEmailRestrictedLink(t, "@example.com")
+		sent := sink.count()
+
+		if err := ss.RequestAccessCode(shareLink.Token, "bob@example.com", visitor); err != nil {
+			t.Fatalf("RequestAccessCode: %v", err)
+		}
+		code := lastAccessCode(t, sink.wait(t, sent+1))
+
+		for attempt := 1; attempt <= shareCodeMaxAttempts; attempt++ {
+			req := models.ShareLinkAccessRequest{Email: "bob@example.com", Code: wrongCode(code)}
+			if _, err := ss.AccessShareLink(shareLink.Token, req, visitor); !errors.Is(err, ErrShareCodeInvalid) {
+				t.Fatalf("attempt %d = %v, want ErrShareCodeInvalid", attempt, err)
+			}
+		}
+
+		req := models.ShareLinkAccessRequest{Email: "bob@example.com", Code: code}
+		if _, err := ss.AccessShareLink(shareLink.Token, req, visitor); !errors.Is(err, ErrShareCodeInvalid) {
+			t.Errorf("right code after lockout = %v, want ErrShareCodeInvalid", err)
+		}
+	})
+
+	t.Run("resending is rate limited", func(t *testing.T) {
+		shareLink := newEmailRestrictedLink(t, "carol@example.com")
+		sent := sink.count()
+
+		for i := 0; i < shareCodeRateLimit; i++ {
+			if err := ss.RequestAccessCode(shareLink.Token, "carol@example.com", visitor); err != nil {
+				t.Fatalf("request %d: %v", i+1, err)
+			}
+		}
+		if err := ss.RequestAccessCode(shareLink.Token, "carol@example.com", visitor); !errors.Is(err, ErrShareCodeRateLimited) {
+			t.Fatalf("request over the limit = %v, want ErrShareCodeRateLimited", err)
+		}
+		if got := len(sink.wait(t, sent+shareCodeRateLimit)) - sent; got != shareCodeRateLimit {
+			t.Errorf("sent %d emails, want %d", got, shareCodeRateLimit)
+		}
+	})
+
+	t.Run("addresses not on the allow list get no email", func(t *testing.T) {
+		shareLink := newEmailRestrictedLink(t, "alice@example.com,@corp.test")
+		sent := sink.count()
+
+		for _, email := range []string{"mallory@evil.test", "alice@example.org", "bob@sub.corp.test"} {
+			if err := ss.RequestAccessCode(shareLink.Token, email, visitor); err != nil {
+				t.Errorf("RequestAccessCode(%s) = %v, want nil", email, err)
+			}
+		}
+		if got := sink.count(); got != sent {
+			t.Errorf("sent %d emails, want none", got-sent)
+		}
+
+		var codes int64
+		database.GetDB().Model(&models.ShareLinkOTP{}).Where("share_link_id = ?", shareLink.ID).Count(&codes)
+		if codes != 0 {
+			t.Errorf("stored %d codes, want none", codes)
+		}
+	})
+}
+
+func TestUpdateShareLinkReopensUsedLink(t *testing.T) {
+	ss := NewShareService()
+	visitor := models.ShareVisitor{IPAddress: "192.0.2.1"}
+	one, two := 1, 2
+
+	tests := []struct {
+		name   string
+		create func(link *models.ShareLink)
+		update models.ShareLinkUpdateRequest
+	}{
+		{
+			name:   "one-time link made reusable",
+			create: func(link *models.ShareLink) { link.OneTime = true },
+			update: models.ShareLinkUpdateRequest{OneTime: new(bool)},
+		},
+		{
+			name:   "view limit raised",
+			create: func(link *models.ShareLink) { link.MaxViews = &one },
+			update: models.ShareLinkUpdateRequest{MaxViews: &two},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// An empty allow list leaves the link open to everyone
+			shareLink := newEmailRestrictedLink(t, "")
+			tt.create(shareLink)
+			if err := database.GetDB().Save(shareLink).Error; err != nil {
+				t.Fatalf("save link: %v", err)
+			}
+
+			if _, err := ss.AccessShareLink(shareLink.Token, models.ShareLinkAccessRequest{}, visitor); err != nil {
+				t.Fatalf("first view: %v", err)
+			}
+			if _, err := ss.AccessShareLink(shareLink.Token, models.ShareLinkAccessRequest{}, visitor); !errors.Is(err, ErrShareLinkExhausted) {
+				t.Fatalf("second view error = %v, want %v", err, ErrShareLinkExhausted)
+			}
+
+			if _, err := ss.UpdateShareLink(shareLink.UserID, shareLink.ID, tt.update); err != nil {
+				t.Fatalf("UpdateShareLink: %v", err)
+			}
+			if _, err := ss.AccessShareLink(shareLink.Token, models.ShareLinkAccessRequest{}, visitor); err != nil {
+				t.Errorf("view after update: %v", err)
+			}
+		})
+	}
+}
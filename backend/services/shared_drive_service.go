@@ -0,0 +1,447 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDriveNotFound       = errors.New("shared drive not found")
+	ErrDriveForbidden      = errors.New("only drive managers can do this")
+	ErrDriveNotEmpty       = errors.New("shared drive is not empty, including its trash")
+	ErrDriveMemberNotFound = errors.New("member not found")
+	ErrDriveMemberExists   = errors.New("user is already a member of this drive")
+	ErrDriveNotOrgMember   = errors.New("user is not a member of the drive's organization")
+	ErrDriveRootFolder     = errors.New("the root folder of a shared drive is changed through the drive")
+)
+
+type SharedDriveService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+	trashService        *TrashService
+	quotaService        *QuotaService
+}
+
+func NewSharedDriveService() *SharedDriveService {
+	return &SharedDriveService{
+		db:                  database.GetDB(),
+		notificationService: NewNotificationService(),
+		trashService:        NewTrashService(),
+		quotaService:        NewQuotaService(),
+	}
+}
+
+// driveRoleOf returns the user's role in a drive. Owners and admins of the organization manage
+// every drive of it.
+func driveRoleOf(db *gorm.DB, drive *models.SharedDrive, userID uuid.UUID) (models.DriveRole, error) {
+	orgRole, err := organizationRole(db, drive.OrganizationID, userID)
+	if err != nil {
+		return "", err
+	}
+	if orgRole.Includes(models.OrganizationRoleAdmin) {
+		return models.DriveRoleManager, nil
+	}
+	if orgRole == "" {
+		return "", nil
+	}
+
+	var memberships []models.DriveMember
+	if err := db.Where("drive_id = ? AND user_id = ?", drive.ID, userID).Limit(1).Find(&memberships).Error; err != nil {
+		return "", err
+	}
+	if len(memberships) == 0 {
+		return "", nil
+	}
+	return memberships[0].Role, nil
+}
+
+// driveContentRole returns the role the user has on the content of a shared drive, empty when
+// driveID is nil
+func driveContentRole(db *gorm.DB, userID uuid.UUID, driveID *uuid.UUID) (models.CollaboratorRole, error) {
+	if driveID == nil {
+		return "", nil
+	}
+	var drives []models.SharedDrive
+	if err := db.Select("id", "organization_id").Where("id = ?", *driveID).Limit(1).Find(&drives).Error; err != nil {
+		return "", err
+	}
+	if len(drives) == 0 {
+		return "", nil
+	}
+	role, err := driveRoleOf(db, &drives[0], userID)
+	if err != nil {
+		return "", err
+	}
+	return role.ContentRole(), nil
+}
+
+// IsDriveRoot reports whether the folder is the root folder of a shared drive
+func IsDriveRoot(folderID uuid.UUID) bool {
+	var count int64
+	database.GetDB().Model(&models.SharedDrive{}).Where("root_folder_id = ?", folderID).Count(&count)
+	return count > 0
+}
+
+// CreateDrive creates a shared drive in an organization, with the user as its first manager.
+// Only owners and admins of the organization can create drives.
+func (sds *SharedDriveService) CreateDrive(userID, organizationID uuid.UUID, req models.SharedDriveCreateRequest) (*models.SharedDriveResponse, error) {
+	if err := sds.requireOrganizationRole(userID, organizationID, models.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	drive := &models.SharedDrive{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		Name:           req.Name,
+		Description:    req.Description,
+		CreatedByID:    userID,
+	}
+	err := sds.db.Transaction(func(tx *gorm.DB) error {
+		root := &models.Folder{UserID: userID, DriveID: &drive.ID, Name: req.Name}
+		if err := tx.Create(root).Error; err != nil {
+			return err
+		}
+
+		drive.RootFolderID = root.ID
+		if err := tx.Create(drive).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.DriveMember{DriveID: drive.ID, UserID: userID, Role: models.DriveRoleManager}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sds.describe(drive.ID, userID, true)
+}
+
+// ListDrives returns the drives the user can open, in one organization or in all of them
+func (sds *SharedDriveService) ListDrives(userID uuid.UUID, organizationID *uuid.UUID, page, limit int) ([]models.SharedDriveResponse, int64, error) {
+	managed := sds.db.Model(&models.OrganizationMember{}).Select("organization_id").
+		Where("user_id = ? AND role IN ?", userID, []models.OrganizationRole{models.OrganizationRoleOwner, models.OrganizationRoleAdmin})
+	memberOf := sds.db.Model(&models.DriveMember{}).Select("drive_id").Where("user_id = ?", userID)
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where(sds.db.Where("organization_id IN (?)", managed).Or("id IN (?)", memberOf))
+		if organizationID != nil {
+			db = db.Where("organization_id = ?", *organizationID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := sds.db.Model(&models.SharedDrive{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var drives []models.SharedDrive
+	offset := (page - 1) * limit
+	if err := sds.db.Scopes(filter).Order("name ASC").Offset(offset).Limit(limit).Find(&drives).Error; err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.SharedDriveResponse, 0, len(drives))
+	for i := range drives {
+		response := drives[i].ToResponse()
+		role, err := driveRoleOf(sds.db, &drives[i], userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		response.MyRole = role
+		sds.db.Model(&models.DriveMember{}).Where("drive_id = ?", drives[i].ID).Count(&response.MemberCount)
+		responses = append(responses, response)
+	}
+	return responses, total, nil
+}
+
+// GetDrive returns a drive with its members and storage usage
+func (sds *SharedDriveService) GetDrive(userID, driveID uuid.UUID) (*models.SharedDriveResponse, error) {
+	if _, _, err := sds.visibleDrive(userID, driveID); err != nil {
+		return nil, err
+	}
+	return sds.describe(driveID, userID, true)
+}
+
+// UpdateDrive renames a drive or changes its description. The root folder is renamed with it.
+func (sds *SharedDriveService) UpdateDrive(userID, driveID uuid.UUID, req models.SharedDriveUpdateRequest) (*models.SharedDriveResponse, error) {
+	drive, err := sds.managedDrive(userID, driveID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		drive.Description = *req.Description
+	}
+	err = sds.db.Transaction(func(tx *gorm.DB) error {
+		if req.Name != "" && req.Name != drive.Name {
+			drive.Name = req.Name
+			var root models.Folder
+			if err := tx.Where("id = ?", drive.RootFolderID).First(&root).Error; err != nil {
+				return err
+			}
+			root.Name = req.Name
+			if err := tx.Save(&root).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(drive).Updates(map[string]interface{}{"name": drive.Name, "description": drive.Description}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sds.describe(drive.ID, userID, true)
+}
+
+// DeleteDrive deletes an empty drive. Only owners and admins of the organization can delete
+// drives, and everything in the drive has to be permanently deleted first.
+func (sds *SharedDriveService) DeleteDrive(userID, driveID uuid.UUID) (*models.SharedDriveResponse, error) {
+	drive, err := sds.loadDrive(driveID)
+	if err != nil {
+		return nil, err
+	}
+	if err := sds.requireOrganizationRole(userID, drive.OrganizationID, models.OrganizationRoleAdmin); err != nil {
+		if errors.Is(err, ErrOrganizationNotFound) {
+			return nil, ErrDriveNotFound
+		}
+		return nil, err
+	}
+
+	var files, folders int64
+	sds.db.Model(&models.File{}).Where("drive_id = ?", drive.ID).Count(&files)
+	sds.db.Model(&models.Folder{}).Where("drive_id = ? AND id <> ?", drive.ID, drive.RootFolderID).Count(&folders)
+	if files > 0 || folders > 0 {
+		return nil, ErrDriveNotEmpty
+	}
+
+	response := drive.ToResponse()
+	err = sds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("drive_id = ?", drive.ID).Delete(&models.DriveMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("folder_id = ?", drive.RootFolderID).Delete(&models.Collaborator{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Folder{}, "id = ?", drive.RootFolderID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(drive).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+	return &response, nil
+}
+
+// AddMember adds a member of the drive's organization to the drive
+func (sds *SharedDriveService) AddMember(userID, driveID uuid.UUID, req models.AddDriveMemberRequest) (*models.SharedDriveResponse, error) {
+	drive, err := sds.managedDrive(userID, driveID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgRole, err := organizationRole(sds.db, drive.OrganizationID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if orgRole == "" {
+		return nil, ErrDriveNotOrgMember
+	}
+	var count int64
+	sds.db.Model(&models.DriveMember{}).Where("drive_id = ? AND user_id = ?", drive.ID, req.UserID).Count(&count)
+	if count > 0 {
+		return nil, ErrDriveMemberExists
+	}
+
+	if err := sds.db.Create(&models.DriveMember{DriveID: drive.ID, UserID: req.UserID, Role: req.Role}).Error; err != nil {
+		return nil, err
+	}
+	InvalidatePermissions()
+
+	if req.UserID != userID {
+		if _, err := sds.notificationService.Notify(req.UserID, models.NotificationDriveAdded, "Added to a shared drive",
+			fmt.Sprintf("You were added to the shared drive %s as %s", drive.Name, req.Role), models.ResourceDrive, &drive.ID); err != nil {
+			config.GetLogger().Error("Failed to send shared drive notification", "error", err, "drive_id", drive.ID)
+		}
+	}
+	return sds.describe(drive.ID, userID, false)
+}
+
+// UpdateMember changes the role of a drive member
+func (sds *SharedDriveService) UpdateMember(userID, driveID, memberID uuid.UUID, req models.UpdateDriveMemberRequest) (*models.SharedDriveResponse, error) {
+	drive, err := sds.managedDrive(userID, driveID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sds.db.Model(&models.DriveMember{}).Where("drive_id = ? AND user_id = ?", drive.ID, memberID).
+		Update("role", req.Role)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDriveMemberNotFound
+	}
+	InvalidatePermissions()
+	return sds.describe(drive.ID, userID, false)
+}
+
+// RemoveMember takes a user out of a drive, members can also leave on their own. The content they
+// added stays in the drive.
+func (sds *SharedDriveService) RemoveMember(userID, driveID, memberID uuid.UUID) (*models.SharedDriveResponse, error) {
+	var drive *models.SharedDrive
+	var err error
+	if memberID == userID {
+		drive, _, err = sds.visibleDrive(userID, driveID)
+	} else {
+		drive, err = sds.managedDrive(userID, driveID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := sds.db.Where("drive_id = ? AND user_id = ?", drive.ID, memberID).Delete(&models.DriveMember{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDriveMemberNotFound
+	}
+	InvalidatePermissions()
+
+	if memberID == userID {
+		response := drive.ToResponse()
+		return &response, nil
+	}
+	return sds.describe(drive.ID, userID, false)
+}
+
+// GetTrash returns the trashed files and folders of a drive, for its managers
+func (sds *SharedDriveService) GetTrash(userID, driveID uuid.UUID, page, limit int) (map[string]interface{}, error) {
+	drive, err := sds.managedDrive(userID, driveID)
+	if err != nil {
+		return nil, err
+	}
+	return sds.trashService.GetTrashedItems(drive.ID, page, limit)
+}
+
+// ActingOwner returns whose content a trash operation on a file or folder works on: the drive's
+// for managers of the shared drive that owns the item, the user's own otherwise
+func (sds *SharedDriveService) ActingOwner(userID, resourceID uuid.UUID, isFile bool) uuid.UUID {
+	var driveIDs []*uuid.UUID
+	if isFile {
+		sds.db.Model(&models.File{}).Where("id = ?", resourceID).Limit(1).Pluck("drive_id", &driveIDs)
+	} else {
+		sds.db.Model(&models.Folder{}).Where("id = ?", resourceID).Limit(1).Pluck("drive_id", &driveIDs)
+	}
+	if len(driveIDs) == 0 || driveIDs[0] == nil {
+		return userID
+	}
+	if role, err := driveContentRole(sds.db, userID, driveIDs[0]); err == nil && role == models.RoleOwner {
+		return *driveIDs[0]
+	}
+	return userID
+}
+
+// Usage returns the storage a drive uses
+func (sds *SharedDriveService) Usage(drive *models.SharedDrive) models.DriveUsage {
+	var usage models.DriveUsage
+	sds.db.Model(&models.File{}).Where("drive_id = ? AND is_trashed = false", drive.ID).Count(&usage.FileCount)
+	sds.db.Model(&models.File{}).Where("drive_id = ? AND is_trashed = false", drive.ID).
+		Select("COALESCE(SUM(file_size), 0)").Scan(&usage.StorageUsed)
+	sds.db.Model(&models.File{}).Where("drive_id = ? AND is_trashed = true", drive.ID).
+		Select("COALESCE(SUM(file_size), 0)").Scan(&usage.TrashSize)
+	sds.db.Model(&models.Folder{}).Where("drive_id = ? AND is_trashed = false AND id <> ?", drive.ID, drive.RootFolderID).
+		Count(&usage.FolderCount)
+	if quota, err := sds.quotaService.GetDriveQuota(drive.ID); err == nil {
+		usage.QuotaUsed, usage.Quota = quota.Used, quota.Quota
+	}
+	return usage
+}
+
+// visibleDrive loads a drive the user has a role in
+func (sds *SharedDriveService) visibleDrive(userID, driveID uuid.UUID) (*models.SharedDrive, models.DriveRole, error) {
+	drive, err := sds.loadDrive(driveID)
+	if err != nil {
+		return nil, "", err
+	}
+	role, err := driveRoleOf(sds.db, drive, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, "", ErrDriveNotFound
+	}
+	return drive, role, nil
+}
+
+// managedDrive loads a drive the user manages
+func (sds *SharedDriveService) managedDrive(userID, driveID uuid.UUID) (*models.SharedDrive, error) {
+	drive, role, err := sds.visibleDrive(userID, driveID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.DriveRoleManager {
+		return nil, ErrDriveForbidden
+	}
+	return drive, nil
+}
+
+func (sds *SharedDriveService) requireOrganizationRole(userID, organizationID uuid.UUID, required models.OrganizationRole) error {
+	var organization models.Organization
+	if err := sds.db.Where("id = ?", organizationID).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrganizationNotFound
+		}
+		return err
+	}
+	role, err := organizationRole(sds.db, organizationID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrOrganizationNotFound
+	}
+	if !role.Includes(required) {
+		return ErrOrganizationForbidden
+	}
+	return nil
+}
+
+// describe loads a drive with its members and the user's role, and its usage when asked for
+func (sds *SharedDriveService) describe(driveID, userID uuid.UUID, withUsage bool) (*models.SharedDriveResponse, error) {
+	var drive models.SharedDrive
+	err := sds.db.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User").Where("id = ?", driveID).First(&drive).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDriveNotFound
+		}
+		return nil, err
+	}
+
+	response := drive.ToResponse()
+	if response.MyRole, err = driveRoleOf(sds.db, &drive, userID); err != nil {
+		return nil, err
+	}
+	if withUsage {
+		usage := sds.Usage(&drive)
+		response.Usage = &usage
+	}
+	return &response, nil
+}
+
+func (sds *SharedDriveService) loadDrive(driveID uuid.UUID) (*models.SharedDrive, error) {
+	var drive models.SharedDrive
+	if err := sds.db.Where("id = ?", driveID).First(&drive).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDriveNotFound
+		}
+		return nil, err
+	}
+	return &drive, nil
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+const maxTransferRecipients = 50
+
+var (
+	ErrTransferNotFound         = errors.New("transfer not found")
+	ErrTransferExpired          = errors.New("transfer has expired")
+	ErrTransferInvalidRecipient = errors.New("invalid recipient email address")
+)
+
+type TransferService struct {
+	db          *gorm.DB
+	mailService *MailService
+	cfg         config.TransferConfig
+}
+
+func NewTransferService() *TransferService {
+	return &TransferService{
+		db:          database.GetDB(),
+		mailService: NewMailService(),
+		cfg:         config.AppConfig.Transfer,
+	}
+}
+
+// ParseRecipients splits a comma, semicolon or newline separated list of email addresses.
+// Addresses are normalized and duplicates are dropped.
+func ParseRecipients(raw string) ([]string, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+
+	seen := make(map[string]bool)
+	recipients := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(field)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrTransferInvalidRecipient, field)
+		}
+		email := utils.NormalizeEmail(address.Address)
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		recipients = append(recipients, email)
+	}
+
+	if len(recipients) > maxTransferRecipients {
+		return nil, fmt.Errorf("a transfer can have at most %d recipients", maxTransferRecipients)
+	}
+	return recipients, nil
+}
+
+// ExpiryDays resolves the expiry_days form value, zero selects the configured default
+func (ts *TransferService) ExpiryDays(days int) (int, error) {
+	if days == 0 {
+		return ts.cfg.DefaultExpiryDays, nil
+	}
+	if days < 0 || days > ts.cfg.MaxExpiryDays {
+		return 0, fmt.Errorf("expiry_days must be between 1 and %d", ts.cfg.MaxExpiryDays)
+	}
+	return days, nil
+}
+
+// CreateTransfer bundles files the user just uploaded into a transfer for the given recipients
+func (ts *TransferService) CreateTransfer(userID uuid.UUID, fileIDs []uuid.UUID, req models.TransferCreateRequest) (*models.Transfer, error) {
+	if len(fileIDs) == 0 {
+		return nil, errors.New("a transfer needs at least one file")
+	}
+
+	expiryDays, err := ts.ExpiryDays(req.ExpiryDays)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []models.File
+	if err := ts.db.Where("id IN ? AND is_trashed = ?", fileIDs, false).Scopes(models.OwnedBy(userID)).
+		Find(&files).Error; err != nil {
+		return nil, err
+	}
+	if len(files) != len(fileIDs) {
+		return nil, errors.New("file not found or access denied")
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &models.Transfer{
+		UserID:    userID,
+		Token:     token,
+		Title:     req.Title,
+		Message:   req.Message,
+		ExpiresAt: time.Now().AddDate(0, 0, expiryDays),
+	}
+
+	err = ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Files.*").Create(transfer).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(transfer).Omit("Files.*").Association("Files").Append(files); err != nil {
+			return err
+		}
+
+		for _, email := range req.Recipients {
+			recipientToken, err := generateSecureToken(32)
+			if err != nil {
+				return err
+			}
+			recipient := models.TransferRecipient{
+				TransferID: transfer.ID,
+				Email:      email,
+				Token:      recipientToken,
+			}
+			if err := tx.Create(&recipient).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.GetTransfer(userID, transfer.ID)
+}
+
+// NotifyRecipients emails every recipient that has not been notified yet their download link
+func (ts *TransferService) NotifyRecipients(transfer *models.Transfer, sender models.User) error {
+	if !ts.mailService.Enabled() {
+		return ErrMailNotConfigured
+	}
+
+	subject := fmt.Sprintf("%s sent you files", sender.GetFullName())
+	if transfer.Title != "" {
+		subject = fmt.Sprintf("%s sent you %q", sender.GetFullName(), transfer.Title)
+	}
+
+	var failed int
+	for _, recipient := range transfer.Recipients {
+		if recipient.NotifiedAt != nil {
+			continue
+		}
+
+		var body strings.Builder
+		fmt.Fprintf(&body, "%s (%s) sent you %d file(s), %s in total.\n\n", sender.GetFullName(), sender.Email,
+			len(transfer.Files), utils.FormatFileSize(transfer.TotalSize()))
+		if transfer.Message != "" {
+			fmt.Fprintf(&body, "%s\n\n", transfer.Message)
+		}
+		fmt.Fprintf(&body, "Download them here: %s/transfer/%s\n\nThe link expires on %s.\n",
+			config.AppConfig.Server.AppURL, recipient.Token, transfer.ExpiresAt.UTC().Format("2 January 2006 15:04 MST"))
+
+		if err := ts.mailService.Send(recipient.Email, subject, body.String()); err != nil {
+			config.GetLogger().Error("Failed to email transfer recipient", "error", err, "transfer_id", transfer.ID)
+			failed++
+			continue
+		}
+		ts.db.Model(&models.TransferRecipient{}).Where("id = ?", recipient.ID).Update("notified_at", time.Now())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to email %d recipient(s)", failed)
+	}
+	return nil
+}
+
+// GetTransfers returns the user's transfers, newest first
+func (ts *TransferService) GetTransfers(userID uuid.UUID, page, limit int) ([]models.Transfer, int64, error) {
+	var transfers []models.Transfer
+	var total int64
+
+	if err := ts.db.Model(&models.Transfer{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := ts.db.Preload("Files").Preload("Recipients").Where("user_id = ?", userID).
+		Order("created_at DESC").Offset(offset).Limit(limit).
+		Find(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// GetTransfer returns a transfer owned by the user
+func (ts *TransferService) GetTransfer(userID, transferID uuid.UUID) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := ts.db.Preload("Files").Preload("Recipients").Where("id = ? AND user_id = ?", transferID, userID).
+		First(&transfer).Error; err != nil {
+		return nil, ErrTransferNotFound
+	}
+	return &transfer, nil
+}
+
+// GetTransferByToken resolves a download token. Recipient tokens also return the recipient, the
+// transfer's own token returns a nil recipient. Only files still outside the trash are loaded.
+func (ts *TransferService) GetTransferByToken(token string) (*models.Transfer, *models.TransferRecipient, error) {
+	var recipient *models.TransferRecipient
+	var byRecipient models.TransferRecipient
+	if err := ts.db.Where("token = ?", token).First(&byRecipient).Error; err == nil {
+		recipient = &byRecipient
+	}
+
+	query := ts.db.Preload("User").Preload("Files", "is_trashed = ?", false)
+	if recipient != nil {
+		query = query.Where("id = ?", recipient.TransferID)
+	} else {
+		query = query.Where("token = ?", token)
+	}
+
+	var transfer models.Transfer
+	if err := query.First(&transfer).Error; err != nil {
+		return nil, nil, ErrTransferNotFound
+	}
+
+	if transfer.IsExpired() {
+		return nil, nil, ErrTransferExpired
+	}
+	return &transfer, recipient, nil
+}
+
+// GetTransferFile returns a file that belongs to the transfer
+func (ts *TransferService) GetTransferFile(transfer *models.Transfer, fileID uuid.UUID) (*models.File, error) {
+	for i := range transfer.Files {
+		if transfer.Files[i].ID == fileID {
+			return &transfer.Files[i], nil
+		}
+	}
+	return nil, errors.New("file not found in transfer")
+}
+
+// RecordDownload counts a download of the transfer, per recipient when the recipient is known
+func (ts *TransferService) RecordDownload(transfer *models.Transfer, recipient *models.TransferRecipient, files []models.File) error {
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transfer{}).Where("id = ?", transfer.ID).
+			UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error; err != nil {
+			return err
+		}
+
+		if recipient != nil {
+			if err := tx.Model(&models.TransferRecipient{}).Where("id = ?", recipient.ID).
+				UpdateColumns(map[string]interface{}{
+					"download_count":     gorm.Expr("download_count + 1"),
+					"last_downloaded_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, file := range files {
+			if err := tx.Model(&models.File{}).Where("id = ?", file.ID).
+				UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteTransfer cancels a transfer: its links stop working and its files are moved to the trash
+func (ts *TransferService) DeleteTransfer(userID, transferID uuid.UUID) error {
+	transfer, err := ts.GetTransfer(userID, transferID)
+	if err != nil {
+		return err
+	}
+
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := ts.trashTransferFiles(tx, transfer, time.Now()); err != nil {
+			return err
+		}
+		return tx.Delete(transfer).Error
+	})
+}
+
+// ExpireTransfers trashes the files of every transfer past its expiry and returns how many
+// transfers were expired
+func (ts *TransferService) ExpireTransfers() (int, error) {
+	now := time.Now()
+
+	var transfers []models.Transfer
+	if err := ts.db.Where("expired_at IS NULL AND expires_at <= ?", now).Find(&transfers).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range transfers {
+		transfer := &transfers[i]
+		claimed := false
+		err := ts.db.Transaction(func(tx *gorm.DB) error {
+			// Another instance may have expired it already
+			result := tx.Model(&models.Transfer{}).Where("id = ? AND expired_at IS NULL", transfer.ID).
+				Update("expired_at", now)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			claimed = true
+			return ts.trashTransferFiles(tx, transfer, now)
+		})
+		if err != nil {
+			return expired, err
+		}
+		if claimed {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// RunExpiryWorker expires transfers on every tick until the context is cancelled
+func (ts *TransferService) RunExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := ts.ExpireTransfers()
+			if err != nil {
+				config.GetLogger().Error("Failed to expire transfers", "error", err)
+				continue
+			}
+			if count > 0 {
+				config.GetLogger().Info("Expired transfers", "count", count)
+			}
+		}
+	}
+}
+
+// trashTransferFiles moves the transfer's files to the owner's trash
+func (ts *TransferService) trashTransferFiles(tx *gorm.DB, transfer *models.Transfer, trashedAt time.Time) error {
+	return tx.Model(&models.File{}).
+		Where("id IN (?) AND is_trashed = ?",
+			tx.Table("transfer_files").Select("file_id").Where("transfer_id = ?", transfer.ID), false).
+		Scopes(models.OwnedBy(transfer.UserID)).
+		Updates(map[string]interface{}{
+			"is_trashed": true,
+			"trashed_at": trashedAt,
+			"updated_at": trashedAt,
+		}).Error
+}
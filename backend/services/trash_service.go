@@ -23,7 +23,7 @@ func NewTrashService() *TrashService {
 func (ts *TrashService) MoveFileToTrash(userID, fileID uuid.UUID) error {
 	now := time.Now()
 	return ts.db.Model(&models.File{}).
-		Where("id = ? AND user_id = ? AND is_trashed = false", fileID, userID).
+		Where("id = ? AND is_trashed = false", fileID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": true,
 			"trashed_at": now,
@@ -38,7 +38,7 @@ func (ts *TrashService) MoveFolderToTrash(userID, folderID uuid.UUID) error {
 
 		// Get the folder to check ownership
 		var folder models.Folder
-		if err := tx.Where("id = ? AND user_id = ?", folderID, userID).First(&folder).Error; err != nil {
+		if err := tx.Where("id = ?", folderID).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
 			return err
 		}
 
@@ -53,7 +53,7 @@ func (ts *TrashService) MoveFolderToTrash(userID, folderID uuid.UUID) error {
 
 		// Move all files in the folder to trash
 		if err := tx.Model(&models.File{}).
-			Where("folder_id = ? AND user_id = ? AND is_trashed = false", folderID, userID).
+			Where("folder_id = ? AND is_trashed = false", folderID).Scopes(models.OwnedBy(userID)).
 			Updates(map[string]interface{}{
 				"is_trashed": true,
 				"trashed_at": now,
@@ -64,7 +64,7 @@ func (ts *TrashService) MoveFolderToTrash(userID, folderID uuid.UUID) error {
 
 		// Recursively move subfolders to trash
 		var subfolders []models.Folder
-		if err := tx.Where("parent_id = ? AND user_id = ? AND is_trashed = false", folderID, userID).Find(&subfolders).Error; err != nil {
+		if err := tx.Where("parent_id = ? AND is_trashed = false", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 			return err
 		}
 
@@ -82,7 +82,7 @@ func (ts *TrashService) MoveFolderToTrash(userID, folderID uuid.UUID) error {
 func (ts *TrashService) moveFolderToTrashRecursive(tx *gorm.DB, userID, folderID uuid.UUID, trashedAt time.Time) error {
 	// Move current folder to trash
 	if err := tx.Model(&models.Folder{}).
-		Where("id = ? AND user_id = ?", folderID, userID).
+		Where("id = ?", folderID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": true,
 			"trashed_at": trashedAt,
@@ -93,7 +93,7 @@ func (ts *TrashService) moveFolderToTrashRecursive(tx *gorm.DB, userID, folderID
 
 	// Move all files in this folder to trash
 	if err := tx.Model(&models.File{}).
-		Where("folder_id = ? AND user_id = ? AND is_trashed = false", folderID, userID).
+		Where("folder_id = ? AND is_trashed = false", folderID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": true,
 			"trashed_at": trashedAt,
@@ -104,7 +104,7 @@ func (ts *TrashService) moveFolderToTrashRecursive(tx *gorm.DB, userID, folderID
 
 	// Get subfolders and recursively move them to trash
 	var subfolders []models.Folder
-	if err := tx.Where("parent_id = ? AND user_id = ? AND is_trashed = false", folderID, userID).Find(&subfolders).Error; err != nil {
+	if err := tx.Where("parent_id = ? AND is_trashed = false", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 		return err
 	}
 
@@ -120,7 +120,7 @@ func (ts *TrashService) moveFolderToTrashRecursive(tx *gorm.DB, userID, folderID
 // RestoreFileFromTrash restores a file from trash
 func (ts *TrashService) RestoreFileFromTrash(userID, fileID uuid.UUID) error {
 	return ts.db.Model(&models.File{}).
-		Where("id = ? AND user_id = ? AND is_trashed = true", fileID, userID).
+		Where("id = ? AND is_trashed = true", fileID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": false,
 			"trashed_at": nil,
@@ -135,7 +135,7 @@ func (ts *TrashService) RestoreFolderFromTrash(userID, folderID uuid.UUID) error
 
 		// Get the folder to check ownership
 		var folder models.Folder
-		if err := tx.Where("id = ? AND user_id = ? AND is_trashed = true", folderID, userID).First(&folder).Error; err != nil {
+		if err := tx.Where("id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
 			return err
 		}
 
@@ -150,7 +150,7 @@ func (ts *TrashService) RestoreFolderFromTrash(userID, folderID uuid.UUID) error
 
 		// Restore all files in the folder
 		if err := tx.Model(&models.File{}).
-			Where("folder_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).
+			Where("folder_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).
 			Updates(map[string]interface{}{
 				"is_trashed": false,
 				"trashed_at": nil,
@@ -161,7 +161,7 @@ func (ts *TrashService) RestoreFolderFromTrash(userID, folderID uuid.UUID) error
 
 		// Recursively restore subfolders
 		var subfolders []models.Folder
-		if err := tx.Where("parent_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).Find(&subfolders).Error; err != nil {
+		if err := tx.Where("parent_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 			return err
 		}
 
@@ -179,7 +179,7 @@ func (ts *TrashService) RestoreFolderFromTrash(userID, folderID uuid.UUID) error
 func (ts *TrashService) restoreFolderFromTrashRecursive(tx *gorm.DB, userID, folderID uuid.UUID, restoredAt time.Time) error {
 	// Restore current folder
 	if err := tx.Model(&models.Folder{}).
-		Where("id = ? AND user_id = ? AND is_trashed = true", folderID, userID).
+		Where("id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": false,
 			"trashed_at": nil,
@@ -190,7 +190,7 @@ func (ts *TrashService) restoreFolderFromTrashRecursive(tx *gorm.DB, userID, fol
 
 	// Restore all files in this folder
 	if err := tx.Model(&models.File{}).
-		Where("folder_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).
+		Where("folder_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).
 		Updates(map[string]interface{}{
 			"is_trashed": false,
 			"trashed_at": nil,
@@ -201,7 +201,7 @@ func (ts *TrashService) restoreFolderFromTrashRecursive(tx *gorm.DB, userID, fol
 
 	// Get subfolders and recursively restore them
 	var subfolders []models.Folder
-	if err := tx.Where("parent_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).Find(&subfolders).Error; err != nil {
+	if err := tx.Where("parent_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 		return err
 	}
 
@@ -223,13 +223,13 @@ func (ts *TrashService) GetTrashedItems(userID uuid.UUID, page, limit int) (map[
 	var totalFiles int64
 
 	if err := ts.db.Model(&models.File{}).
-		Where("user_id = ? AND is_trashed = true", userID).
+		Where("is_trashed = true").Scopes(models.OwnedBy(userID)).
 		Count(&totalFiles).Error; err != nil {
 		return nil, err
 	}
 
 	if err := ts.db.Preload("User").Preload("Folder").
-		Where("user_id = ? AND is_trashed = true", userID).
+		Where("is_trashed = true").Scopes(models.OwnedBy(userID)).
 		Order("trashed_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -242,13 +242,13 @@ func (ts *TrashService) GetTrashedItems(userID uuid.UUID, page, limit int) (map[
 	var totalFolders int64
 
 	if err := ts.db.Model(&models.Folder{}).
-		Where("user_id = ? AND is_trashed = true", userID).
+		Where("is_trashed = true").Scopes(models.OwnedBy(userID)).
 		Count(&totalFolders).Error; err != nil {
 		return nil, err
 	}
 
 	if err := ts.db.Preload("User").
-		Where("user_id = ? AND is_trashed = true", userID).
+		Where("is_trashed = true").Scopes(models.OwnedBy(userID)).
 		Order("trashed_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -283,9 +283,11 @@ func (ts *TrashService) GetTrashedItems(userID uuid.UUID, page, limit int) (map[
 
 // PermanentlyDeleteFile permanently deletes a file from trash
 func (ts *TrashService) PermanentlyDeleteFile(userID, fileID uuid.UUID) error {
-	return ts.db.Unscoped().
-		Where("id = ? AND user_id = ? AND is_trashed = true", fileID, userID).
-		Delete(&models.File{}).Error
+	return ts.db.Transaction(func(tx *gorm.DB) error {
+		return purgeFiles(tx, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id = ? AND is_trashed = true", fileID).Scopes(models.OwnedBy(userID))
+		})
+	})
 }
 
 // PermanentlyDeleteFolder permanently deletes a folder and all its contents
@@ -293,20 +295,18 @@ func (ts *TrashService) PermanentlyDeleteFolder(userID, folderID uuid.UUID) erro
 	return ts.db.Transaction(func(tx *gorm.DB) error {
 		// Get the folder to check ownership
 		var folder models.Folder
-		if err := tx.Unscoped().Where("id = ? AND user_id = ? AND is_trashed = true", folderID, userID).First(&folder).Error; err != nil {
+		if err := tx.Unscoped().Where("id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).First(&folder).Error; err != nil {
 			return err
 		}
 
 		// Permanently delete all files in the folder
-		if err := tx.Unscoped().
-			Where("folder_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).
-			Delete(&models.File{}).Error; err != nil {
+		if err := purgeFiles(tx, trashedIn(userID, folderID)); err != nil {
 			return err
 		}
 
 		// Recursively delete subfolders
 		var subfolders []models.Folder
-		if err := tx.Unscoped().Where("parent_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).Find(&subfolders).Error; err != nil {
+		if err := tx.Unscoped().Where("parent_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 			return err
 		}
 
@@ -317,22 +317,20 @@ func (ts *TrashService) PermanentlyDeleteFolder(userID, folderID uuid.UUID) erro
 		}
 
 		// Finally delete the folder itself
-		return tx.Unscoped().Where("id = ? AND user_id = ?", folderID, userID).Delete(&models.Folder{}).Error
+		return tx.Unscoped().Where("id = ?", folderID).Scopes(models.OwnedBy(userID)).Delete(&models.Folder{}).Error
 	})
 }
 
 // Helper function for recursive permanent folder deletion
 func (ts *TrashService) permanentlyDeleteFolderRecursive(tx *gorm.DB, userID, folderID uuid.UUID) error {
 	// Permanently delete all files in this folder
-	if err := tx.Unscoped().
-		Where("folder_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).
-		Delete(&models.File{}).Error; err != nil {
+	if err := purgeFiles(tx, trashedIn(userID, folderID)); err != nil {
 		return err
 	}
 
 	// Get subfolders and recursively delete them
 	var subfolders []models.Folder
-	if err := tx.Unscoped().Where("parent_id = ? AND user_id = ? AND is_trashed = true", folderID, userID).Find(&subfolders).Error; err != nil {
+	if err := tx.Unscoped().Where("parent_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(userID)).Find(&subfolders).Error; err != nil {
 		return err
 	}
 
@@ -343,22 +341,22 @@ func (ts *TrashService) permanentlyDeleteFolderRecursive(tx *gorm.DB, userID, fo
 	}
 
 	// Delete the folder itself
-	return tx.Unscoped().Where("id = ? AND user_id = ?", folderID, userID).Delete(&models.Folder{}).Error
+	return tx.Unscoped().Where("id = ?", folderID).Scopes(models.OwnedBy(userID)).Delete(&models.Folder{}).Error
 }
 
 // EmptyTrash permanently deletes all items in trash for a user
 func (ts *TrashService) EmptyTrash(userID uuid.UUID) error {
 	return ts.db.Transaction(func(tx *gorm.DB) error {
 		// Permanently delete all trashed files
-		if err := tx.Unscoped().
-			Where("user_id = ? AND is_trashed = true", userID).
-			Delete(&models.File{}).Error; err != nil {
+		if err := purgeFiles(tx, func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_trashed = true").Scopes(models.OwnedBy(userID))
+		}); err != nil {
 			return err
 		}
 
 		// Permanently delete all trashed folders
 		return tx.Unscoped().
-			Where("user_id = ? AND is_trashed = true", userID).
+			Where("is_trashed = true").Scopes(models.OwnedBy(userID)).
 			Delete(&models.Folder{}).Error
 	})
 }
@@ -369,9 +367,9 @@ func (ts *TrashService) CleanupOldTrashedItems(olderThanDays int) error {
 
 	return ts.db.Transaction(func(tx *gorm.DB) error {
 		// Permanently delete old trashed files
-		if err := tx.Unscoped().
-			Where("is_trashed = true AND trashed_at < ?", cutoff).
-			Delete(&models.File{}).Error; err != nil {
+		if err := purgeFiles(tx, func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_trashed = true AND trashed_at < ?", cutoff)
+		}); err != nil {
 			return err
 		}
 
@@ -381,3 +379,29 @@ func (ts *TrashService) CleanupOldTrashedItems(olderThanDays int) error {
 			Delete(&models.Folder{}).Error
 	})
 }
+
+// trashedIn matches the trashed files of an owner in a folder
+func trashedIn(ownerID, folderID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("folder_id = ? AND is_trashed = true", folderID).Scopes(models.OwnedBy(ownerID))
+	}
+}
+
+// purgeFiles permanently deletes the files matched by filter and releases the storage they took
+// from their owners' usage
+func purgeFiles(tx *gorm.DB, filter func(db *gorm.DB) *gorm.DB) error {
+	usage, err := usageByOwner(tx.Scopes(filter))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Unscoped().Scopes(filter).Delete(&models.File{}).Error; err != nil {
+		return err
+	}
+	for _, owner := range usage {
+		if err := releaseUsage(tx, owner.OwnerID, owner.Total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// storeUpload writes an anonymously uploaded file to the owner's storage prefix and creates its
+// record in folderID. fill sets the fields that say where the upload came from. The file is
+// charged to the owner's quota first, ErrQuotaExceeded is returned when it does not fit.
+func storeUpload(ctx context.Context, db *gorm.DB, ownerID, folderID uuid.UUID, header *multipart.FileHeader, uploader models.Uploader, fill func(*models.File)) (file *models.File, err error) {
+	quotaService := NewQuotaService()
+	if err := quotaService.Reserve(ownerID, header.Size); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			quotaService.Release(ownerID, header.Size)
+		}
+	}()
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	fileBytes, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	fileExtension := filepath.Ext(header.Filename)
+	fileName := uuid.New().String() + fileExtension
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = utils.GetMimeType(header.Filename)
+	}
+
+	storageSvc := storage.GetStorage()
+	objectKey := filepath.Join(ownerID.String(), fileName)
+	urlOrPath, err := storageSvc.UploadFile(ctx, objectKey, fileBytes, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	file = &models.File{
+		UserID:        ownerID,
+		FolderID:      &folderID,
+		FileName:      fileName,
+		OriginalName:  header.Filename,
+		FilePath:      urlOrPath,
+		FileSize:      header.Size,
+		MimeType:      contentType,
+		FileExtension: fileExtension,
+		UploaderName:  uploader.Name,
+		UploaderEmail: uploader.Email,
+	}
+	fill(file)
+
+	if err := db.Create(file).Error; err != nil {
+		storageSvc.DeleteFile(ctx, objectKey)
+		return nil, err
+	}
+	return file, nil
+}
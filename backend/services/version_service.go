@@ -1,83 +1,177 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/config"
 	"github.com/manjurulhoque/swift-share/backend/database"
 	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
 	"gorm.io/gorm"
 )
 
 type VersionService struct {
-	db *gorm.DB
+	db                   *gorm.DB
+	storage              storage.StorageService
+	authorizationService *AuthorizationService
+	quotaService         *QuotaService
+	planService          *PlanService
 }
 
 func NewVersionService() *VersionService {
 	return &VersionService{
-		db: database.GetDB(),
+		db:                   database.GetDB(),
+		storage:              storage.GetStorage(),
+		authorizationService: NewAuthorizationService(),
+		quotaService:         NewQuotaService(),
+		planService:          NewPlanService(),
 	}
 }
 
-// CreateVersion creates a new version of a file
-func (vs *VersionService) CreateVersion(userID, fileID uuid.UUID, filePath string, req models.FileVersionCreateRequest) (*models.FileVersion, error) {
-	// Get the original file
-	var file models.File
-	if err := vs.db.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
-		return nil, errors.New("file not found or access denied")
-	}
+// UploadVersion replaces the content of a file with data and records it as a new version. The
+// first upload also records the content the file had until then as version 1, so it can be
+// restored. Each version keeps its own object in storage, FilePath holds its object key. Content
+// identical to the latest version is not stored again, created is false in that case. New content
+// is charged to the file owner's quota, ErrQuotaExceeded is returned when it does not fit. Versions
+// beyond the retention of the owner's plan are deleted afterwards, oldest first.
+func (vs *VersionService) UploadVersion(ctx context.Context, userID uuid.UUID, file *models.File, data []byte, mimeType, comment string) (version *models.FileVersion, created bool, err error) {
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
 
-	// Calculate checksum
-	checksum, err := vs.calculateFileChecksum(filePath)
+	var latest models.FileVersion
+	err = vs.db.Where("file_id = ?", file.ID).Order("version_number DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		latest, err = vs.recordOriginalVersion(ctx, file)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+		return nil, false, err
 	}
-
-	// Check if a version with this checksum already exists
-	var existingVersion models.FileVersion
-	if err := vs.db.Where("file_id = ? AND checksum = ?", fileID, checksum).First(&existingVersion).Error; err == nil {
-		return &existingVersion, nil // Return existing version if content is identical
+	if latest.Checksum == checksum {
+		vs.db.Preload("User").Where("id = ?", latest.ID).First(&latest)
+		return &latest, false, nil
 	}
 
-	// Get file info
-	fileInfo, err := os.Stat(filePath)
+	if mimeType == "" {
+		mimeType = file.MimeType
+	}
+	size := int64(len(data))
+	if err := vs.quotaService.Reserve(file.OwnerID(), size); err != nil {
+		return nil, false, err
+	}
+	fileName := uuid.New().String() + filepath.Ext(file.OriginalName)
+	objectKey := filepath.Join(file.OwnerID().String(), fileName)
+	urlOrPath, err := vs.storage.UploadFile(ctx, objectKey, data, mimeType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		vs.quotaService.Release(file.OwnerID(), size)
+		return nil, false, fmt.Errorf("failed to store version: %w", err)
 	}
 
-	// Get next version number
-	var lastVersion models.FileVersion
-	vs.db.Where("file_id = ?", fileID).Order("version_number DESC").First(&lastVersion)
-	nextVersionNumber := lastVersion.VersionNumber + 1
-
-	// Create new version
-	version := &models.FileVersion{
-		FileID:        fileID,
+	version = &models.FileVersion{
+		FileID:        file.ID,
 		UserID:        userID,
-		VersionNumber: nextVersionNumber,
-		FileName:      file.FileName,
-		FilePath:      filePath,
-		FileSize:      fileInfo.Size(),
-		MimeType:      file.MimeType,
+		VersionNumber: latest.VersionNumber + 1,
+		FileName:      fileName,
+		FilePath:      objectKey,
+		FileSize:      size,
+		MimeType:      mimeType,
 		Checksum:      checksum,
-		Comment:       req.Comment,
-		IsAutoSave:    req.IsAutoSave,
+		Comment:       comment,
+	}
+	err = vs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Model(file).Updates(map[string]interface{}{
+			"file_name":   fileName,
+			"file_path":   urlOrPath,
+			"storage_key": "",
+			"file_size":   version.FileSize,
+			"mime_type":   mimeType,
+		}).Error
+	})
+	if err != nil {
+		vs.storage.DeleteFile(ctx, objectKey)
+		vs.quotaService.Release(file.OwnerID(), size)
+		return nil, false, fmt.Errorf("failed to create version: %w", err)
 	}
 
-	if err := vs.db.Create(version).Error; err != nil {
-		return nil, fmt.Errorf("failed to create version: %w", err)
+	if err := vs.storage.SetObjectPublic(ctx, objectKey, file.IsPublic); err != nil {
+		config.GetLogger().Error("Failed to set object ACL", "key", objectKey, "error", err)
 	}
 
-	// Load relationships
+	vs.pruneVersions(ctx, file)
+
 	vs.db.Preload("User").Where("id = ?", version.ID).First(version)
+	return version, true, nil
+}
+
+// pruneVersions deletes the oldest versions of a file beyond the version retention of its owner's
+// plan, together with their objects in storage, and takes them off the owner's usage. The latest
+// version holds the current content and is always kept.
+func (vs *VersionService) pruneVersions(ctx context.Context, file *models.File) {
+	limits, err := vs.planService.Limits(file.OwnerID())
+	if err != nil || limits.VersionRetention <= 0 {
+		return
+	}
+
+	var versions []models.FileVersion
+	if err := vs.db.Where("file_id = ?", file.ID).Order("version_number DESC").Find(&versions).Error; err != nil {
+		config.GetLogger().Error("Failed to load versions to prune", "error", err, "file_id", file.ID)
+		return
+	}
+	if len(versions) <= limits.VersionRetention {
+		return
+	}
+
+	var released int64
+	for _, version := range versions[limits.VersionRetention:] {
+		if err := vs.db.Unscoped().Delete(&version).Error; err != nil {
+			config.GetLogger().Error("Failed to prune version", "error", err, "version_id", version.ID)
+			continue
+		}
+		released += version.FileSize
+		if err := vs.storage.DeleteFile(ctx, version.FilePath); err != nil {
+			config.GetLogger().Error("Failed to delete pruned version object", "error", err, "key", version.FilePath)
+		}
+	}
+	vs.quotaService.Release(file.OwnerID(), released)
+}
+
+// recordOriginalVersion records the current content of a file that has no versions yet as version 1
+func (vs *VersionService) recordOriginalVersion(ctx context.Context, file *models.File) (models.FileVersion, error) {
+	rc, err := vs.storage.OpenFile(ctx, file.ObjectKey())
+	if err != nil {
+		return models.FileVersion{}, fmt.Errorf("failed to open current content: %w", err)
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, rc)
+	if err != nil {
+		return models.FileVersion{}, fmt.Errorf("failed to read current content: %w", err)
+	}
 
-	return version, nil
+	original := models.FileVersion{
+		FileID:        file.ID,
+		UserID:        file.UserID,
+		VersionNumber: 1,
+		FileName:      file.FileName,
+		FilePath:      file.ObjectKey(),
+		FileSize:      size,
+		MimeType:      file.MimeType,
+		Checksum:      fmt.Sprintf("%x", hash.Sum(nil)),
+		Comment:       "Original version",
+	}
+	if err := vs.db.Create(&original).Error; err != nil {
+		return models.FileVersion{}, err
+	}
+	return original, nil
 }
 
 // GetFileVersions returns all versions of a file
@@ -88,17 +182,9 @@ func (vs *VersionService) GetFileVersions(userID, fileID uuid.UUID, page, limit
 		return nil, errors.New("file not found")
 	}
 
-	// Check permissions (owner or collaborator)
-	hasAccess := file.UserID == userID
-	if !hasAccess {
-		var collaborator models.Collaborator
-		if err := vs.db.Where("file_id = ? AND user_id = ?", fileID, userID).First(&collaborator).Error; err == nil && !collaborator.IsExpired() {
-			hasAccess = true
-		}
-	}
-
-	if !hasAccess {
-		return nil, errors.New("access denied")
+	// Check permissions, including access inherited from the folders above the file
+	if err := vs.authorizationService.RequireFile(userID, &file, models.RoleViewer); err != nil {
+		return nil, err
 	}
 
 	// Get total count
@@ -149,17 +235,9 @@ func (vs *VersionService) GetVersion(userID, fileID, versionID uuid.UUID) (*mode
 		return nil, errors.New("file not found")
 	}
 
-	// Check permissions
-	hasAccess := file.UserID == userID
-	if !hasAccess {
-		var collaborator models.Collaborator
-		if err := vs.db.Where("file_id = ? AND user_id = ?", fileID, userID).First(&collaborator).Error; err == nil && !collaborator.IsExpired() {
-			hasAccess = true
-		}
-	}
-
-	if !hasAccess {
-		return nil, errors.New("access denied")
+	// Check permissions, including access inherited from the folders above the file
+	if err := vs.authorizationService.RequireFile(userID, &file, models.RoleViewer); err != nil {
+		return nil, err
 	}
 
 	// Get the version
@@ -173,134 +251,6 @@ func (vs *VersionService) GetVersion(userID, fileID, versionID uuid.UUID) (*mode
 	return &version, nil
 }
 
-// RestoreVersion restores a file to a specific version
-func (vs *VersionService) RestoreVersion(userID, fileID, versionID uuid.UUID, req models.RestoreVersionRequest) (*models.File, error) {
-	// Check file ownership (only owner can restore)
-	var file models.File
-	if err := vs.db.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
-		return nil, errors.New("file not found or access denied")
-	}
-
-	// Get the version to restore
-	var version models.FileVersion
-	if err := vs.db.Where("id = ? AND file_id = ?", versionID, fileID).First(&version).Error; err != nil {
-		return nil, errors.New("version not found")
-	}
-
-	// Check if version file exists
-	if _, err := os.Stat(version.FilePath); os.IsNotExist(err) {
-		return nil, errors.New("version file not found on disk")
-	}
-
-	// Create a backup of current file as a new version before restoring
-	currentChecksum, err := vs.calculateFileChecksum(file.FilePath)
-	if err == nil && currentChecksum != version.Checksum {
-		// Only create backup if content is different
-		vs.CreateVersion(userID, fileID, file.FilePath, models.FileVersionCreateRequest{
-			Comment:    "Backup before restore",
-			IsAutoSave: true,
-		})
-	}
-
-	// Copy version file to current file location
-	if err := vs.copyFile(version.FilePath, file.FilePath); err != nil {
-		return nil, fmt.Errorf("failed to restore file: %w", err)
-	}
-
-	// Update file metadata
-	fileInfo, err := os.Stat(file.FilePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get restored file info: %w", err)
-	}
-
-	// Update file record
-	updates := map[string]interface{}{
-		"file_size":  fileInfo.Size(),
-		"updated_at": time.Now(),
-	}
-
-	if err := vs.db.Model(&file).Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update file record: %w", err)
-	}
-
-	// Create new version record for the restore
-	_, err = vs.CreateVersion(userID, fileID, file.FilePath, models.FileVersionCreateRequest{
-		Comment:    fmt.Sprintf("Restored from version %d: %s", version.VersionNumber, req.Comment),
-		IsAutoSave: false,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create restore version: %w", err)
-	}
-
-	// Reload file with updated data
-	vs.db.Preload("User").Where("id = ?", fileID).First(&file)
-
-	return &file, nil
-}
-
-// DeleteVersion deletes a specific version (not the current file)
-func (vs *VersionService) DeleteVersion(userID, fileID, versionID uuid.UUID) error {
-	// Check file ownership
-	var file models.File
-	if err := vs.db.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
-		return errors.New("file not found or access denied")
-	}
-
-	// Get the version
-	var version models.FileVersion
-	if err := vs.db.Where("id = ? AND file_id = ?", versionID, fileID).First(&version).Error; err != nil {
-		return errors.New("version not found")
-	}
-
-	// Don't allow deletion of the latest version
-	var latestVersion models.FileVersion
-	vs.db.Where("file_id = ?", fileID).Order("version_number DESC").First(&latestVersion)
-	if version.ID == latestVersion.ID {
-		return errors.New("cannot delete the latest version")
-	}
-
-	// Delete version file from disk
-	if err := os.Remove(version.FilePath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete version file: %w", err)
-	}
-
-	// Delete version record
-	if err := vs.db.Delete(&version).Error; err != nil {
-		return fmt.Errorf("failed to delete version record: %w", err)
-	}
-
-	return nil
-}
-
-// CleanupOldVersions removes old versions beyond the specified limit
-func (vs *VersionService) CleanupOldVersions(fileID uuid.UUID, keepVersions int) error {
-	if keepVersions <= 0 {
-		return errors.New("must keep at least 1 version")
-	}
-
-	// Get versions to delete (older than the keep limit)
-	var versionsToDelete []models.FileVersion
-	if err := vs.db.Where("file_id = ?", fileID).
-		Order("version_number DESC").
-		Offset(keepVersions).
-		Find(&versionsToDelete).Error; err != nil {
-		return err
-	}
-
-	// Delete each version
-	for _, version := range versionsToDelete {
-		// Delete file from disk
-		if err := os.Remove(version.FilePath); err != nil && !os.IsNotExist(err) {
-			continue // Continue even if file deletion fails
-		}
-
-		// Delete record
-		vs.db.Delete(&version)
-	}
-
-	return nil
-}
-
 // GetVersionStats returns version statistics for a file
 func (vs *VersionService) GetVersionStats(userID, fileID uuid.UUID) (map[string]interface{}, error) {
 	// Check access
@@ -309,16 +259,9 @@ func (vs *VersionService) GetVersionStats(userID, fileID uuid.UUID) (map[string]
 		return nil, errors.New("file not found")
 	}
 
-	hasAccess := file.UserID == userID
-	if !hasAccess {
-		var collaborator models.Collaborator
-		if err := vs.db.Where("file_id = ? AND user_id = ?", fileID, userID).First(&collaborator).Error; err == nil && !collaborator.IsExpired() {
-			hasAccess = true
-		}
-	}
-
-	if !hasAccess {
-		return nil, errors.New("access denied")
+	// Check permissions, including access inherited from the folders above the file
+	if err := vs.authorizationService.RequireFile(userID, &file, models.RoleViewer); err != nil {
+		return nil, err
 	}
 
 	stats := make(map[string]interface{})
@@ -352,42 +295,3 @@ func (vs *VersionService) GetVersionStats(userID, fileID uuid.UUID) (map[string]
 
 	return stats, nil
 }
-
-// Helper functions
-
-func (vs *VersionService) calculateFileChecksum(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
-	}
-
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-}
-
-func (vs *VersionService) copyFile(src, dst string) error {
-	// Ensure destination directory exists
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return err
-	}
-
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer dstFile.Close()
-
-	_, err = io.Copy(dstFile, srcFile)
-	return err
-}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manjurulhoque/swift-share/backend/config"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/utils"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxWatermarkPixels bounds the size of decoded images, a small file can still decode to a huge bitmap
+const maxWatermarkPixels = 64 << 20
+
+// pdfWatermarkStyle is the pdfcpu description of the stamp put on every PDF page
+const pdfWatermarkStyle = "font:Helvetica, scale:0.8 rel, rot:45, opacity:0.3, fillcolor:#808080"
+
+var (
+	ErrWatermarkTooLarge    = errors.New("file is too large to watermark")
+	ErrWatermarkUnsupported = errors.New("file type cannot be watermarked")
+)
+
+var loadWatermarkFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+func init() {
+	// pdfcpu would otherwise create a config directory on first use and exit the process if it cannot
+	model.ConfigPath = "disable"
+}
+
+// Watermark is the text stamped on a rendition, one entry per line
+type Watermark struct {
+	Lines []string
+}
+
+// NewWatermark builds the watermark of a share link download: the viewer's verified email or IP
+// address, the time of the download and the link's custom text
+func NewWatermark(shareLink *models.ShareLink, visitor models.ShareVisitor, at time.Time) Watermark {
+	viewer := visitor.Email
+	if viewer == "" {
+		viewer = visitor.IPAddress
+	}
+	lines := []string{viewer, at.UTC().Format("2006-01-02 15:04:05 MST")}
+	if shareLink.WatermarkText != "" {
+		lines = append(lines, shareLink.WatermarkText)
+	}
+	return Watermark{Lines: lines}
+}
+
+type WatermarkService struct {
+	maxFileSize int64
+}
+
+func NewWatermarkService() *WatermarkService {
+	return &WatermarkService{
+		maxFileSize: config.AppConfig.Watermark.MaxFileSize,
+	}
+}
+
+// Check returns ErrWatermarkUnsupported when neither the file's MIME type nor its extension is an
+// image or PDF that can be watermarked, and ErrWatermarkTooLarge when it exceeds the configured
+// size limit. Render decides from the content itself, Check lets callers fail before streaming.
+func (ws *WatermarkService) Check(file *models.File) error {
+	if watermarkKind(file.MimeType) == "" && watermarkKind(utils.GetMimeType(file.OriginalName)) == "" {
+		return ErrWatermarkUnsupported
+	}
+	if file.FileSize > ws.maxFileSize {
+		return ErrWatermarkTooLarge
+	}
+	return nil
+}
+
+// Render reads the file's content from r and writes a watermarked rendition into w. The content
+// is held in memory while it is rendered, nothing is written to storage. The type is detected
+// from the content, since the stored MIME type is whatever the uploader sent, and content that is
+// not a JPEG, PNG or PDF fails with ErrWatermarkUnsupported.
+func (ws *WatermarkService) Render(w io.Writer, r io.Reader, file *models.File, mark Watermark) error {
+	if file.FileSize > ws.maxFileSize {
+		return ErrWatermarkTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, ws.maxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > ws.maxFileSize {
+		return ErrWatermarkTooLarge
+	}
+
+	switch watermarkKind(http.DetectContentType(data)) {
+	case "pdf":
+		return watermarkPDF(w, data, mark)
+	case "image":
+		return watermarkImage(w, data, mark)
+	}
+	return ErrWatermarkUnsupported
+}
+
+// watermarkKind maps a MIME type to the renderer handling it, empty when none does
+func watermarkKind(mimeType string) string {
+	mimeType, _, _ = strings.Cut(strings.ToLower(mimeType), ";")
+	switch strings.TrimSpace(mimeType) {
+	case "application/pdf":
+		return "pdf"
+	case "image/jpeg", "image/jpg", "image/png":
+		return "image"
+	}
+	return ""
+}
+
+// watermarkPDF stamps the watermark diagonally across every page
+func watermarkPDF(w io.Writer, data []byte, mark Watermark) error {
+	wm, err := api.TextWatermark(strings.Join(mark.Lines, "\n"), pdfWatermarkStyle, true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+	if err := api.AddWatermarks(bytes.NewReader(data), w, nil, wm, model.NewDefaultConfiguration()); err != nil {
+		return fmt.Errorf("watermark pdf: %w", err)
+	}
+	return nil
+}
+
+// watermarkImage tiles the watermark over the whole image, so cropping cannot remove it, and
+// encodes the result in the original format
+func watermarkImage(w io.Writer, data []byte, mark Watermark) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("watermark image: %w", err)
+	}
+	if format != "jpeg" && format != "png" {
+		return ErrWatermarkUnsupported
+	}
+	if cfg.Width*cfg.Height > maxWatermarkPixels {
+		return ErrWatermarkTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("watermark image: %w", err)
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
+
+	ttf, err := loadWatermarkFont()
+	if err != nil {
+		return err
+	}
+	size := min(max(float64(bounds.Dx())/40, 12), 96)
+	face, err := opentype.NewFace(ttf, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	lineHeight := face.Metrics().Height.Ceil()
+	blockWidth := 0
+	for _, line := range mark.Lines {
+		blockWidth = max(blockWidth, font.MeasureString(face, line).Ceil())
+	}
+	blockHeight := lineHeight * len(mark.Lines)
+	stepX := max(blockWidth+blockWidth/2, 1)
+	stepY := max(blockHeight*2, 1)
+
+	for row, y := 0, bounds.Min.Y; y < bounds.Max.Y; row, y = row+1, y+stepY {
+		offset := 0
+		if row%2 == 1 {
+			offset = stepX / 2
+		}
+		for x := bounds.Min.X - offset; x < bounds.Max.X; x += stepX {
+			drawWatermarkBlock(dst, face, mark.Lines, x, y, lineHeight)
+		}
+	}
+
+	if format == "png" {
+		return png.Encode(w, dst)
+	}
+	return jpeg.Encode(w, dst, &jpeg.Options{Quality: 90})
+}
+
+// drawWatermarkBlock draws the lines with a dark shadow so they stay readable on light and dark images
+func drawWatermarkBlock(dst draw.Image, face font.Face, lines []string, x, y, lineHeight int) {
+	ascent := face.Metrics().Ascent.Ceil()
+	shadow := image.NewUniform(color.NRGBA{A: 90})
+	text := image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 120})
+
+	for i, line := range lines {
+		baseline := y + ascent + i*lineHeight
+		for _, pass := range []struct {
+			src   image.Image
+			shift int
+		}{{shadow, 1}, {text, 0}} {
+			drawer := font.Drawer{
+				Dst:  dst,
+				Src:  pass.src,
+				Face: face,
+				Dot:  fixed.P(x+pass.shift, baseline+pass.shift),
+			}
+			drawer.DrawString(line)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/manjurulhoque/swift-share/backend/database"
+	"github.com/manjurulhoque/swift-share/backend/models"
+	"github.com/manjurulhoque/swift-share/backend/storage"
+	"gorm.io/gorm"
+)
+
+// ZipItem is a file or an empty directory to be written into a zip download
+type ZipItem struct {
+	Path string       // path inside the zip, directories end with "/"
+	File *models.File // nil for directories
+	// Render writes the entry from the stored content, nil copies it unchanged
+	Render func(w io.Writer, r io.Reader) error
+}
+
+type ZipService struct {
+	db      *gorm.DB
+	storage storage.StorageService
+}
+
+func NewZipService() *ZipService {
+	return &ZipService{
+		db:      database.GetDB(),
+		storage: storage.GetStorage(),
+	}
+}
+
+// CollectFolder returns the items of a folder subtree. Paths are built from Folder.Path relative
+// to the folder, so the folder itself becomes the top level directory of the zip.
+func (zs *ZipService) CollectFolder(folder *models.Folder) ([]ZipItem, error) {
+	folders := []models.Folder{*folder}
+	parentIDs := []uuid.UUID{folder.ID}
+	for len(parentIDs) > 0 {
+		var children []models.Folder
+		if err := zs.db.Where("parent_id IN ? AND is_trashed = ?", parentIDs, false).Find(&children).Error; err != nil {
+			return nil, err
+		}
+		parentIDs = parentIDs[:0]
+		for _, child := range children {
+			folders = append(folders, child)
+			parentIDs = append(parentIDs, child.ID)
+		}
+	}
+
+	folderIDs := make([]uuid.UUID, 0, len(folders))
+	dirs := make(map[uuid.UUID]string, len(folders))
+	for _, f := range folders {
+		folderIDs = append(folderIDs, f.ID)
+		dirs[f.ID] = zipDirName(folder, &f)
+	}
+
+	var files []models.File
+	if err := zs.db.Where("folder_id IN ? AND is_trashed = ?", folderIDs, false).
+		Order("original_name ASC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	nonEmpty := make(map[uuid.UUID]bool)
+	items := make([]ZipItem, 0, len(files)+len(folders))
+	for i := range files {
+		file := &files[i]
+		nonEmpty[*file.FolderID] = true
+		items = append(items, ZipItem{Path: path.Join(dirs[*file.FolderID], zipSafeName(file.OriginalName)), File: file})
+	}
+
+	// Keep empty folders so the structure is preserved
+	for _, f := range folders {
+		if !nonEmpty[f.ID] {
+			items = append(items, ZipItem{Path: dirs[f.ID] + "/"})
+		}
+	}
+
+	return items, nil
+}
+
+// WriteZip streams the items as a zip archive into w. Entries are stored without compression and
+// use data descriptors, so nothing has to be buffered and ZIP64 records are added as needed.
+// Items with a Render function are written through it. onFile is called after each file has been
+// written.
+func (zs *ZipService) WriteZip(ctx context.Context, w io.Writer, items []ZipItem, onFile func(*models.File)) error {
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool, len(items))
+
+	for _, item := range items {
+		name := uniqueZipPath(used, item.Path)
+
+		if item.File == nil {
+			if _, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		header := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Store,
+			Modified: item.File.UpdatedAt,
+		}
+		header.SetMode(0644)
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		rc, err := zs.storage.OpenFile(ctx, item.File.ObjectKey())
+		if err != nil {
+			return fmt.Errorf("open %s: %w", item.File.OriginalName, err)
+		}
+		if item.Render != nil {
+			err = item.Render(entry, rc)
+		} else {
+			_, err = io.Copy(entry, rc)
+		}
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("write %s: %w", item.File.OriginalName, err)
+		}
+
+		if onFile != nil {
+			onFile(item.File)
+		}
+	}
+
+	return zw.Close()
+}
+
+// zipDirName returns the directory of sub inside a zip rooted at root
+func zipDirName(root, sub *models.Folder) string {
+	rel := strings.TrimPrefix(sub.Path, root.Path)
+	parts := []string{zipSafeName(root.Name)}
+	for _, part := range strings.Split(rel, "/") {
+		if part != "" {
+			parts = append(parts, zipSafeName(part))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// zipSafeName makes a file or folder name usable as a single zip path component
+func zipSafeName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// uniqueZipPath appends a counter to duplicate names, e.g. "docs/report (2).pdf"
+func uniqueZipPath(used map[string]bool, name string) string {
+	if !used[name] {
+		used[name] = true
+		return name
+	}
+
+	dir, base := path.Split(strings.TrimSuffix(name, "/"))
+	suffix := ""
+	if strings.HasSuffix(name, "/") {
+		suffix = "/"
+	}
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s%s (%d)%s%s", dir, stem, i, ext, suffix)
+		if !used[candidate] {
+			used[candidate] = true
+			return candidate
+		}
+	}
+}
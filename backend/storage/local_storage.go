@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -62,3 +63,33 @@ func (l *localStorage) FileExists(ctx context.Context, key string) (bool, error)
 func (l *localStorage) SetObjectPublic(ctx context.Context, key string, isPublic bool) error {
 	return nil
 }
+
+func (l *localStorage) OpenFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.basePath, key))
+}
+
+func (l *localStorage) ReadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(l.basePath, key))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+func (l *localStorage) GetFileSize(ctx context.Context, key string) (int64, error) {
+	info, err := os.Stat(filepath.Join(l.basePath, key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// limitedReadCloser pairs a limited reader with the closer of the underlying file
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
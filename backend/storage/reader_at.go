@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// defaultReadChunkSize is how much is fetched per ranged request. Archive readers issue many
+// small reads (headers, directory records), so fetching larger chunks keeps round trips low.
+const defaultReadChunkSize = 1 << 20 // 1MB
+
+type rangeReaderAt struct {
+	ctx  context.Context
+	svc  StorageService
+	key  string
+	size int64
+
+	mu         sync.Mutex
+	chunk      []byte
+	chunkStart int64
+}
+
+// NewReaderAt returns an io.ReaderAt over the object with given key that is backed by ranged reads,
+// so random access formats (zip, tar) can be read without downloading the whole object.
+func NewReaderAt(ctx context.Context, svc StorageService, key string, size int64) io.ReaderAt {
+	return &rangeReaderAt{ctx: ctx, svc: svc, key: key, size: size, chunkStart: -1}
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off+int64(n) < r.size {
+		pos := off + int64(n)
+		if r.chunkStart < 0 || pos < r.chunkStart || pos >= r.chunkStart+int64(len(r.chunk)) {
+			if err := r.fetch(pos, len(p)-n); err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], r.chunk[pos-r.chunkStart:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch loads the chunk starting at pos, reading at least want bytes when available
+func (r *rangeReaderAt) fetch(pos int64, want int) error {
+	length := int64(defaultReadChunkSize)
+	if int64(want) > length {
+		length = int64(want)
+	}
+	if pos+length > r.size {
+		length = r.size - pos
+	}
+
+	rc, err := r.svc.ReadRange(r.ctx, r.key, pos, length)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(rc, buf); err != nil {
+		return err
+	}
+
+	r.chunk = buf
+	r.chunkStart = pos
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -114,3 +115,37 @@ func (s *s3Storage) FileExists(ctx context.Context, key string) (bool, error) {
 
 	return true, nil
 }
+
+func (s *s3Storage) OpenFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	return out.Body, nil
+}
+
+func (s *s3Storage) ReadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object range: %w", err)
+	}
+	return out.Body, nil
+}
+
+func (s *s3Storage) GetFileSize(ctx context.Context, key string) (int64, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get object size: %w", err)
+	}
+	return aws.ToInt64(out.ContentLength), nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"io"
 	"time"
 
 	"github.com/manjurulhoque/swift-share/backend/config"
@@ -20,6 +21,12 @@ type StorageService interface {
 	FileExists(ctx context.Context, key string) (bool, error)
 	// SetObjectPublic updates the ACL/visibility of the object (true => public-read, false => private)
 	SetObjectPublic(ctx context.Context, key string, isPublic bool) error
+	// OpenFile returns a reader over the whole object with given key
+	OpenFile(ctx context.Context, key string) (io.ReadCloser, error)
+	// ReadRange returns a reader over length bytes of the object starting at offset
+	ReadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// GetFileSize returns the size of the object in bytes
+	GetFileSize(ctx context.Context, key string) (int64, error)
 }
 
 var defaultStorage StorageService
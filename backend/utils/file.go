@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -76,6 +77,40 @@ func IsValidFileSize(size int64) bool {
 	return size <= config.AppConfig.Upload.MaxFileSize
 }
 
+var (
+	ErrFileTooLarge       = errors.New("file exceeds the maximum allowed size")
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed")
+)
+
+// handlerMaxUploadSize is the per-file limit enforced by the upload endpoints
+const handlerMaxUploadSize int64 = 10 << 20
+
+// UploadSizeLimit returns the largest file the upload endpoints accept, the lower of the
+// handler limit and the configured MAX_FILE_SIZE
+func UploadSizeLimit() int64 {
+	if config.AppConfig.Upload.MaxFileSize < handlerMaxUploadSize {
+		return config.AppConfig.Upload.MaxFileSize
+	}
+	return handlerMaxUploadSize
+}
+
+// ClampUploadSize caps a per-file limit, such as the one of a plan, at the configured
+// MAX_FILE_SIZE. Uploads are held in memory while they are stored, so no limit may go beyond it.
+func ClampUploadSize(size int64) int64 {
+	return min(size, config.AppConfig.Upload.MaxFileSize)
+}
+
+// ValidateUpload applies the configured upload policy to a file name and size
+func ValidateUpload(filename string, size int64) error {
+	if !IsValidFileSize(size) {
+		return ErrFileTooLarge
+	}
+	if !IsAllowedFileType(filename) {
+		return ErrFileTypeNotAllowed
+	}
+	return nil
+}
+
 // GetFileExtension returns the file extension without the dot
 func GetFileExtension(filename string) string {
 	ext := filepath.Ext(filename)
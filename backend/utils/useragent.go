@@ -0,0 +1,84 @@
+package utils
+
+import "strings"
+
+// Device types returned by ParseUserAgent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// UserAgentInfo is a coarse breakdown of a User-Agent header
+type UserAgentInfo struct {
+	Browser    string
+	OS         string
+	DeviceType string
+}
+
+// ParseUserAgent extracts browser, operating system and device type from a User-Agent header.
+// It only recognizes the common families, which is enough for analytics breakdowns.
+func ParseUserAgent(ua string) UserAgentInfo {
+	info := UserAgentInfo{Browser: "Other", OS: "Other", DeviceType: DeviceUnknown}
+	if ua == "" {
+		return info
+	}
+	lower := strings.ToLower(ua)
+
+	switch {
+	case containsAny(lower, "bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "go-http-client"):
+		info.DeviceType = DeviceBot
+	case containsAny(lower, "ipad", "tablet") || (strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		info.DeviceType = DeviceTablet
+	case containsAny(lower, "mobi", "iphone", "ipod", "android"):
+		info.DeviceType = DeviceMobile
+	case containsAny(lower, "windows", "macintosh", "x11", "linux", "cros"):
+		info.DeviceType = DeviceDesktop
+	}
+
+	switch {
+	case containsAny(lower, "iphone", "ipad", "ipod"):
+		info.OS = "iOS"
+	case strings.Contains(lower, "android"):
+		info.OS = "Android"
+	case strings.Contains(lower, "windows"):
+		info.OS = "Windows"
+	case strings.Contains(lower, "cros"):
+		info.OS = "Chrome OS"
+	case strings.Contains(lower, "mac os x"), strings.Contains(lower, "macintosh"):
+		info.OS = "macOS"
+	case strings.Contains(lower, "linux"):
+		info.OS = "Linux"
+	}
+
+	// Order matters, most browsers include "Safari" and "Chrome" in their tokens
+	switch {
+	case strings.Contains(lower, "edg/"), strings.Contains(lower, "edge/"):
+		info.Browser = "Edge"
+	case strings.Contains(lower, "opr/"), strings.Contains(lower, "opera"):
+		info.Browser = "Opera"
+	case strings.Contains(lower, "samsungbrowser"):
+		info.Browser = "Samsung Internet"
+	case strings.Contains(lower, "firefox/"), strings.Contains(lower, "fxios/"):
+		info.Browser = "Firefox"
+	case strings.Contains(lower, "chrome/"), strings.Contains(lower, "crios/"):
+		info.Browser = "Chrome"
+	case strings.Contains(lower, "safari/"):
+		info.Browser = "Safari"
+	case strings.Contains(lower, "curl/"):
+		info.Browser = "curl"
+	}
+
+	return info
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -74,3 +74,8 @@ func BindAndValidate(c *gin.Context, obj interface{}) bool {
 func GetValidator() *validator.Validate {
 	return validate
 }
+
+// NormalizeEmail lowercases and trims an email address so it can be compared
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}